@@ -0,0 +1,160 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+// Package assigner decides placements of workers between schedulers and task items between runtimes.
+//	Policies are registered by name and selected through Strategy.Assigner and Task.Assigner.
+package assigner
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Names of built-in assigners
+const (
+	// Even assigns the same number to every node in order regardless of weights and current owners
+	Even = "even"
+	// Sticky assigns proportionally to weights and keeps current owners as much as possible
+	Sticky = "sticky"
+	// Weighted assigns proportionally to weights regardless of current owners
+	Weighted = "weighted"
+	// ConsistentHash places units on a hash ring of nodes, so units move only when their nodes change
+	ConsistentHash = "consistent-hash"
+
+	// Default is used when no assigner is specified
+	Default = Sticky
+)
+
+var (
+	assignerMap sync.Map
+)
+
+// Node is a target of assignment, a scheduler for workers or a runtime for task items
+type Node struct {
+	ID     string
+	Weight int               // Capacity weight comparing to others, non-positive gets nothing
+	Limit  int               // Maximum total weight of units, non-positive indicates no limit
+	Group  string            // Units are spread across groups first if given, eg. zone of Strategy.SpreadBy
+	Labels map[string]string // Labels of scheduler if any
+}
+
+// Unit is what to be assigned, a worker or a task item
+type Unit struct {
+	ID     string
+	Owner  string // Node owning it now, empty for none
+	Moving bool   // Whether it's moving to the owner but not taken yet, it's cheaper to change
+	Weight int    // Load of it comparing to others, non-positive is treated as 1
+}
+
+// Load returns the weight of the unit which is at least 1
+func (u *Unit) Load() int {
+	if u.Weight > 0 {
+		return u.Weight
+	}
+	return 1
+}
+
+// State is the current state of an assignment
+type State struct {
+	Nodes []Node
+	Units []Unit
+}
+
+// Assigner decides owners of units between nodes
+type Assigner interface {
+	// Assign returns the desired owner of each unit in the same order as state.Units,
+	//	empty for leaving it unassigned. Owners should be ids of state.Nodes.
+	Assign(state *State) []string
+}
+
+// Func is an adapter to use functions as assigners
+type Func func(state *State) []string
+
+func (f Func) Assign(state *State) []string {
+	return f(state)
+}
+
+func init() {
+	assignerMap.Store(Even, Func(even))
+	assignerMap.Store(Sticky, Func(sticky))
+	assignerMap.Store(Weighted, Func(weighted))
+	assignerMap.Store(ConsistentHash, Func(consistentHash))
+}
+
+// Register registers an assigner with given name which can be referred by Strategy.Assigner and Task.Assigner
+func Register(name string, a Assigner) {
+	if name == "" || name == Even || name == Sticky || name == Weighted || name == ConsistentHash {
+		panic("Could not register an assigner using empty or reserved name")
+	}
+	if a == nil {
+		panic("Could not register an assigner using nil as value")
+	}
+	assignerMap.Store(name, a)
+	logrus.Info("Register an assigner: ", name)
+}
+
+// Get returns the assigner registered with given name, empty for the default one
+func Get(name string) (Assigner, error) {
+	if name == "" {
+		name = Default
+	}
+	if v, ok := assignerMap.Load(name); ok {
+		return v.(Assigner), nil
+	}
+	return nil, errors.New("No assigner registered for key: " + name)
+}
+
+// Assign assigns units with the assigner of given name and returns owners of them
+func Assign(name string, state *State) ([]string, error) {
+	a, err := Get(name)
+	if err != nil {
+		return nil, err
+	}
+	owners := a.Assign(state)
+	if len(owners) != len(state.Units) {
+		return nil, errors.New("Assigner " + name + " returned a wrong number of owners")
+	}
+	nodes := make(map[string]bool, len(state.Nodes))
+	for _, node := range state.Nodes {
+		nodes[node.ID] = true
+	}
+	for _, owner := range owners {
+		if owner != "" && !nodes[owner] {
+			return nil, errors.New("Assigner " + name + " returned an unknown owner: " + owner)
+		}
+	}
+	return owners, nil
+}
+
+// Counts returns the number of units assigned to each node in the same order as state.Nodes
+func Counts(state *State, owners []string) []int {
+	index := make(map[string]int, len(state.Nodes))
+	for i, node := range state.Nodes {
+		index[node.ID] = i
+	}
+	counts := make([]int, len(state.Nodes))
+	for _, owner := range owners {
+		if i, ok := index[owner]; ok {
+			counts[i]++
+		}
+	}
+	return counts
+}
+
+// Loads returns the total weight of units assigned to each node in the same order as state.Nodes
+func Loads(state *State, owners []string) []int {
+	index := make(map[string]int, len(state.Nodes))
+	for i, node := range state.Nodes {
+		index[node.ID] = i
+	}
+	loads := make([]int, len(state.Nodes))
+	for k, owner := range owners {
+		if i, ok := index[owner]; ok {
+			loads[i] += state.Units[k].Load()
+		}
+	}
+	return loads
+}
@@ -0,0 +1,236 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package assigner
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newState(nodes []string, owners ...string) *State {
+	state := &State{
+		Nodes: make([]Node, len(nodes)),
+		Units: make([]Unit, len(owners)),
+	}
+	for i, id := range nodes {
+		state.Nodes[i] = Node{ID: id, Weight: 1}
+	}
+	for k, owner := range owners {
+		state.Units[k] = Unit{ID: fmt.Sprint("u", k), Owner: owner}
+	}
+	return state
+}
+
+func assign(t *testing.T, name string, state *State) []int {
+	owners, err := Assign(name, state)
+	assert.Nil(t, err)
+	return Counts(state, owners)
+}
+
+func TestRegistry(t *testing.T) {
+	a, err := Get("")
+	assert.Nil(t, err)
+	assert.NotNil(t, a)
+	_, err = Get("unknown")
+	assert.NotNil(t, err)
+
+	assert.Panics(t, func() { Register("", Func(even)) })
+	assert.Panics(t, func() { Register(Sticky, Func(even)) })
+	assert.Panics(t, func() { Register("custom", nil) })
+
+	first := Func(func(state *State) []string {
+		owners := make([]string, len(state.Units))
+		for k := range owners {
+			owners[k] = state.Nodes[0].ID
+		}
+		return owners
+	})
+	Register("first", first)
+	assert.Equal(t, []int{3, 0}, assign(t, "first", newState([]string{"a", "b"}, "", "", "")))
+
+	Register("short", Func(func(state *State) []string {
+		return nil
+	}))
+	_, err = Assign("short", newState([]string{"a"}, ""))
+	assert.NotNil(t, err)
+	Register("unknown-owner", Func(func(state *State) []string {
+		return []string{"x"}
+	}))
+	_, err = Assign("unknown-owner", newState([]string{"a"}, ""))
+	assert.NotNil(t, err)
+}
+
+func TestEven(t *testing.T) {
+	state := newState([]string{"a", "b", "c"}, "", "", "", "", "")
+	state.Nodes[2].Weight = 5
+	assert.Equal(t, []int{2, 2, 1}, assign(t, Even, state))
+	state.Nodes[0].Weight = 0
+	assert.Equal(t, []int{0, 3, 2}, assign(t, Even, state))
+	state.Nodes[1].Limit = 1
+	assert.Equal(t, []int{0, 1, 4}, assign(t, Even, state))
+}
+
+func TestWeighted(t *testing.T) {
+	state := newState([]string{"a", "b"}, "b", "b", "b", "b")
+	state.Nodes[0].Weight = 3
+	assert.Equal(t, []int{3, 1}, assign(t, Weighted, state))
+	// spread across groups first
+	state = newState([]string{"a", "b", "c"}, "", "", "", "")
+	state.Nodes[0].Group = "x"
+	state.Nodes[1].Group = "x"
+	state.Nodes[2].Group = "y"
+	assert.Equal(t, []int{1, 1, 2}, assign(t, Weighted, state))
+}
+
+func TestSticky(t *testing.T) {
+	// remainder stays
+	state := newState([]string{"a", "b", "c"}, "c", "c", "b", "")
+	assert.Equal(t, []int{1, 1, 2}, assign(t, Sticky, state))
+
+	// owned units are kept and moving ones are given up first
+	state = newState([]string{"a", "b"}, "a", "a", "a", "a")
+	state.Units[1].Moving = true
+	owners, _ := Assign(Sticky, state)
+	assert.Equal(t, []string{"a", "b", "a", "b"}, owners)
+
+	// free units are taken before moving owned ones
+	state = newState([]string{"a", "b"}, "a", "a", "a", "")
+	state.Nodes[0].Limit = 2
+	state.Nodes[1].Limit = 1
+	owners, _ = Assign(Sticky, state)
+	assert.Equal(t, []string{"a", "a", "", "b"}, owners)
+
+	// the most overloaded ones are moved first when capacity is limited
+	state = newState([]string{"a", "b", "c"}, "a", "b", "b", "b")
+	state.Nodes[0].Limit = 1
+	state.Nodes[1].Limit = 2
+	state.Nodes[2].Weight = 0
+	owners, _ = Assign(Sticky, state)
+	assert.Equal(t, []string{"a", "b", "b", ""}, owners)
+}
+
+func TestWeightedUnits(t *testing.T) {
+	weigh := func(state *State, weights ...int) *State {
+		for k, w := range weights {
+			state.Units[k].Weight = w
+		}
+		return state
+	}
+	loads := func(name string, state *State) []int {
+		owners, err := Assign(name, state)
+		assert.Nil(t, err)
+		return Loads(state, owners)
+	}
+	// total weights are balanced instead of numbers
+	for _, name := range []string{Even, Sticky, Weighted} {
+		state := weigh(newState([]string{"a", "b"}, "", "", "", "", ""), 4, 1, 1, 1, 1)
+		assert.Equal(t, []int{4, 4}, loads(name, state), "%s", name)
+	}
+
+	// limits are on weights and units too heavy for any room are left
+	state := weigh(newState([]string{"a", "b"}, "", "", ""), 3, 3, 1)
+	state.Nodes[0].Limit = 3
+	state.Nodes[1].Limit = 3
+	owners, _ := Assign(Sticky, state)
+	assert.Equal(t, "", owners[2])
+	assert.Equal(t, []int{3, 3}, Loads(state, owners))
+
+	// the heavy one stays when a node joins and light ones are moved
+	state = weigh(newState([]string{"a", "b", "c"}, "a", "b", "b", "b", "b", "b"), 5)
+	owners, _ = Assign(Sticky, state)
+	assert.Equal(t, "a", owners[0])
+	assert.Equal(t, []int{5, 3, 2}, Loads(state, owners))
+
+	// consistent hash skips nodes without enough room
+	state = weigh(newState([]string{"a", "b"}, "", "", ""), 2, 2, 2)
+	state.Nodes[0].Limit = 3
+	state.Nodes[1].Limit = 3
+	owners, _ = Assign(ConsistentHash, state)
+	assert.Equal(t, []int{1, 1}, Counts(state, owners))
+}
+
+func TestConsistentHash(t *testing.T) {
+	nodes := []string{"a", "b", "c", "d"}
+	units := make([]string, 100)
+	state := newState(nodes, units...)
+	owners, err := Assign(ConsistentHash, state)
+	assert.Nil(t, err)
+	again, _ := Assign(ConsistentHash, state)
+	assert.Equal(t, owners, again)
+	for i, num := range Counts(state, owners) {
+		assert.True(t, num > 10, "unbalanced on %s", nodes[i])
+	}
+
+	// only units of the node left are moved
+	left := newState(nodes[:3], units...)
+	moved, _ := Assign(ConsistentHash, left)
+	for k := range owners {
+		if owners[k] != "d" {
+			assert.Equal(t, owners[k], moved[k])
+		}
+	}
+
+	// limited and weighted
+	state = newState(nodes, units[:10]...)
+	state.Nodes[0].Limit = 1
+	state.Nodes[1].Weight = 0
+	counts := assign(t, ConsistentHash, state)
+	assert.Equal(t, 1, counts[0])
+	assert.Equal(t, 0, counts[1])
+	assert.Equal(t, 10, counts[0]+counts[2]+counts[3])
+}
+
+// TestMovement checks units move only when they have to after nodes join or leave
+func TestMovement(t *testing.T) {
+	r := rand.New(rand.NewSource(2020))
+	for _, name := range []string{Sticky, ConsistentHash} {
+		nodes := []string{"n0"}
+		owners := make([]string, 30)
+		seq := 1
+		for step := 0; step < 200; step++ {
+			var joined, gone string
+			if len(nodes) < 2 || len(nodes) < 10 && r.Intn(2) == 0 {
+				joined = fmt.Sprint("n", seq)
+				seq++
+				nodes = append(nodes, joined)
+			} else {
+				i := r.Intn(len(nodes))
+				gone = nodes[i]
+				nodes = append(nodes[:i:i], nodes[i+1:]...)
+			}
+			state := newState(nodes, owners...)
+			for k := range state.Units {
+				if state.Units[k].Owner == gone {
+					state.Units[k].Owner = ""
+				}
+			}
+			result, err := Assign(name, state)
+			assert.Nil(t, err)
+			moved := 0
+			for k := range result {
+				assert.NotEmpty(t, result[k])
+				if owners[k] != result[k] && owners[k] != gone {
+					moved++
+					// hashing moves units to the new one only
+					if name == ConsistentHash {
+						assert.Equal(t, joined, result[k])
+					}
+				}
+			}
+			if name == Sticky {
+				// no more than the share of the new one
+				if gone != "" {
+					assert.Equal(t, 0, moved, "%s step %d", name, step)
+				} else {
+					assert.True(t, moved <= (len(owners)+len(nodes)-1)/len(nodes), "%s step %d", name, step)
+				}
+			}
+			owners = result
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package assigner
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/jasonjoo2010/goschedule/utils"
+)
+
+// replicas is the number of points of each node with weight 1 on the hash ring
+const replicas = 32
+
+// params returns groups, weights and limits of nodes for the functions of utils
+func params(state *State) ([]string, []int, []int) {
+	groups := make([]string, len(state.Nodes))
+	weights := make([]int, len(state.Nodes))
+	limits := make([]int, len(state.Nodes))
+	for i, node := range state.Nodes {
+		groups[i] = node.Group
+		if node.Weight > 0 {
+			weights[i] = node.Weight
+		}
+		limits[i] = node.Limit
+		if limits[i] <= 0 {
+			limits[i] = math.MaxInt32
+		}
+	}
+	return groups, weights, limits
+}
+
+func currentOwners(state *State) []string {
+	owners := make([]string, len(state.Units))
+	for k, unit := range state.Units {
+		owners[k] = unit.Owner
+	}
+	return owners
+}
+
+// totalLoad returns the total weight of units
+func totalLoad(state *State) int {
+	total := 0
+	for k := range state.Units {
+		total += state.Units[k].Load()
+	}
+	return total
+}
+
+func even(state *State) []string {
+	groups, weights, limits := params(state)
+	for i := range weights {
+		if weights[i] > 0 {
+			weights[i] = 1
+		}
+	}
+	return byLoads(state, utils.SpreadWorkers(groups, nil, weights, limits, totalLoad(state)), weights, limits)
+}
+
+func weighted(state *State) []string {
+	groups, weights, limits := params(state)
+	return byLoads(state, utils.SpreadWorkers(groups, nil, weights, limits, totalLoad(state)), weights, limits)
+}
+
+func sticky(state *State) []string {
+	groups, weights, limits := params(state)
+	current := Loads(state, currentOwners(state))
+	return byLoads(state, utils.SpreadWorkers(groups, current, weights, limits, totalLoad(state)), weights, limits)
+}
+
+// byLoads turns target loads of nodes into owners of units. Units are kept on their owners as much
+//	as possible, heavier ones first, and moving ones are given up first. The rest are placed heavier
+//	first and free ones before the ones of the most overloaded nodes, into the first node having room
+//	for them. A unit too heavy for any room goes to the node having the most room under its limit.
+func byLoads(state *State, targets, weights, limits []int) []string {
+	index := make(map[string]int, len(state.Nodes))
+	for i, node := range state.Nodes {
+		index[node.ID] = i
+	}
+	current := Loads(state, currentOwners(state))
+	left := make([]int, len(targets))
+	copy(left, targets)
+	used := make([]int, len(targets))
+	heavier := func(units []int) {
+		sort.SliceStable(units, func(x, y int) bool {
+			return state.Units[units[x]].Load() > state.Units[units[y]].Load()
+		})
+	}
+	units := make([]int, len(state.Units))
+	for k := range units {
+		units[k] = k
+	}
+	heavier(units)
+	owners := make([]string, len(state.Units))
+	for _, moving := range []bool{false, true} {
+		for _, k := range units {
+			unit := &state.Units[k]
+			i, ok := index[unit.Owner]
+			if !ok || unit.Moving != moving || left[i] < unit.Load() {
+				continue
+			}
+			owners[k] = unit.Owner
+			left[i] -= unit.Load()
+			used[i] += unit.Load()
+		}
+	}
+	overload := func(k int) int {
+		i, ok := index[state.Units[k].Owner]
+		if !ok {
+			// free
+			return math.MaxInt32
+		}
+		return current[i] - targets[i]
+	}
+	rest := make([]int, 0)
+	for k := range state.Units {
+		if owners[k] == "" {
+			rest = append(rest, k)
+		}
+	}
+	sort.SliceStable(rest, func(x, y int) bool {
+		return overload(rest[x]) > overload(rest[y])
+	})
+	heavier(rest)
+	for _, k := range rest {
+		load := state.Units[k].Load()
+		chosen := -1
+		for i := range left {
+			if left[i] >= load {
+				chosen = i
+				break
+			}
+		}
+		if chosen < 0 {
+			for i := range left {
+				if weights[i] <= 0 || limits[i]-used[i] < load {
+					continue
+				}
+				if chosen < 0 || left[i] > left[chosen] ||
+					left[i] == left[chosen] && state.Nodes[i].ID == state.Units[k].Owner {
+					chosen = i
+				}
+			}
+		}
+		if chosen < 0 {
+			// no capacity
+			continue
+		}
+		owners[k] = state.Nodes[chosen].ID
+		left[chosen] -= load
+		used[chosen] += load
+	}
+	return owners
+}
+
+func hashOf(s string) uint32 {
+	sum := md5.Sum([]byte(s))
+	return binary.BigEndian.Uint32(sum[:4])
+}
+
+func consistentHash(state *State) []string {
+	type point struct {
+		hash uint32
+		node int
+	}
+	_, weights, limits := params(state)
+	ring := make([]point, 0)
+	for i, node := range state.Nodes {
+		for r := 0; r < replicas*weights[i]; r++ {
+			ring = append(ring, point{hashOf(node.ID + "#" + strconv.Itoa(r)), i})
+		}
+	}
+	sort.Slice(ring, func(x, y int) bool {
+		if ring[x].hash == ring[y].hash {
+			return ring[x].node < ring[y].node
+		}
+		return ring[x].hash < ring[y].hash
+	})
+	owners := make([]string, len(state.Units))
+	if len(ring) == 0 {
+		return owners
+	}
+	for k, unit := range state.Units {
+		h := hashOf(unit.ID)
+		start := sort.Search(len(ring), func(x int) bool {
+			return ring[x].hash >= h
+		})
+		// the next node having capacity clockwise
+		for n := 0; n < len(ring); n++ {
+			p := ring[(start+n)%len(ring)]
+			if limits[p.node] >= unit.Load() {
+				owners[k] = state.Nodes[p.node].ID
+				limits[p.node] -= unit.Load()
+				break
+			}
+		}
+	}
+	return owners
+}
@@ -0,0 +1,22 @@
+module github.com/jasonjoo2010/goschedule/cmd/goschedule-migrate
+
+go 1.14
+
+require (
+	github.com/jasonjoo2010/goschedule v1.1.0
+	github.com/jasonjoo2010/goschedule/store/bolt v0.0.0
+	github.com/jasonjoo2010/goschedule/store/consul v0.0.0
+	github.com/jasonjoo2010/goschedule/store/etcdv3 v0.0.0
+	github.com/jasonjoo2010/goschedule/store/redis v0.0.0
+	github.com/jasonjoo2010/goschedule/store/zookeeper v0.0.0
+	github.com/stretchr/testify v1.7.0
+)
+
+replace (
+	github.com/jasonjoo2010/goschedule => ../..
+	github.com/jasonjoo2010/goschedule/store/bolt => ../../store/bolt
+	github.com/jasonjoo2010/goschedule/store/consul => ../../store/consul
+	github.com/jasonjoo2010/goschedule/store/etcdv3 => ../../store/etcdv3
+	github.com/jasonjoo2010/goschedule/store/redis => ../../store/redis
+	github.com/jasonjoo2010/goschedule/store/zookeeper => ../../store/zookeeper
+)
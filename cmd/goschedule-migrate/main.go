@@ -0,0 +1,97 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+// Command goschedule-migrate copies tasks and strategies from one storage to another, eg.
+//	goschedule-migrate -from redis://127.0.0.1:6379/schedule/demo -to etcdv3://127.0.0.1:2379/schedule/demo -dry-run
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jasonjoo2010/goschedule/store/migrate"
+)
+
+var conflictPolicies = map[string]migrate.ConflictPolicy{
+	"skip":      migrate.ConflictSkip,
+	"overwrite": migrate.ConflictOverwrite,
+	"fail":      migrate.ConflictFail,
+}
+
+func fatal(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
+
+func main() {
+	from := flag.String("from", "", "source storage, eg. redis://127.0.0.1:6379/schedule/demo")
+	to := flag.String("to", "", "target storage, eg. etcdv3://127.0.0.1:2379/schedule/demo")
+	dryRun := flag.Bool("dry-run", false, "print changes without writing into target")
+	conflict := flag.String("conflict", "skip", "policy on objects existed in target but different: skip, overwrite or fail")
+	assignments := flag.Bool("assignments", false, "copy task assignments as well")
+	itemsVersion := flag.Bool("items-version", false, "raise versions of task items config in target to the ones in source")
+	verifyOnly := flag.Bool("verify", false, "only diff source and target without copying")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: goschedule-migrate -from <storage> -to <storage> [options]")
+		fmt.Fprintln(os.Stderr, "Storage: redis|zookeeper|etcdv3|consul://host:port[,host:port]/prefix or bolt:///path/of/file")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *from == "" || *to == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	policy, ok := conflictPolicies[*conflict]
+	if !ok {
+		fatal("Unknown conflict policy: %s", *conflict)
+	}
+	opts := migrate.Options{
+		DryRun:       *dryRun,
+		Conflict:     policy,
+		Assignments:  *assignments,
+		ItemsVersion: *itemsVersion,
+	}
+
+	src, err := openStore(*from)
+	if err != nil {
+		fatal("Failed to open source: %s", err.Error())
+	}
+	defer src.Close()
+	dst, err := openStore(*to)
+	if err != nil {
+		fatal("Failed to open target: %s", err.Error())
+	}
+	defer dst.Close()
+
+	if !*verifyOnly {
+		result, err := migrate.Copy(src, dst, opts)
+		if result != nil {
+			for _, c := range result.Changes {
+				fmt.Println(c)
+			}
+			fmt.Printf("created: %d, overwritten: %d, skipped: %d, unchanged: %d\n",
+				result.Count(migrate.Created), result.Count(migrate.Overwritten),
+				result.Count(migrate.Skipped), result.Count(migrate.Unchanged))
+		}
+		if err != nil {
+			fatal("Failed to migrate: %s", err.Error())
+		}
+		if *dryRun {
+			return
+		}
+	}
+
+	diffs, err := migrate.Verify(src, dst, opts)
+	if err != nil {
+		fatal("Failed to verify: %s", err.Error())
+	}
+	for _, d := range diffs {
+		fmt.Println(d)
+	}
+	if len(diffs) > 0 {
+		fatal("%d differences found", len(diffs))
+	}
+	fmt.Println("verified: no difference")
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/store/bolt"
+	"github.com/jasonjoo2010/goschedule/store/consul"
+	"github.com/jasonjoo2010/goschedule/store/etcdv3"
+	"github.com/jasonjoo2010/goschedule/store/redis"
+	"github.com/jasonjoo2010/goschedule/store/zookeeper"
+)
+
+func hostPort(hostport string, defaultPort int) (string, int, error) {
+	if !strings.Contains(hostport, ":") {
+		return hostport, defaultPort, nil
+	}
+	host, portStr, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return "", 0, errors.New("Illegal port: " + portStr)
+	}
+	return host, port, nil
+}
+
+// endpoints converts comma separated host:port list into urls
+func endpoints(hosts string) []string {
+	addrs := strings.Split(hosts, ",")
+	for i, addr := range addrs {
+		addrs[i] = "http://" + addr
+	}
+	return addrs
+}
+
+// openStore opens a storage described as scheme://host:port/prefix, or bolt:///path/of/file for bolt
+func openStore(spec string) (store.Store, error) {
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, errors.New("Illegal storage " + spec + ": " + err.Error())
+	}
+	prefix := u.Path
+	if prefix == "" && u.Scheme != "bolt" {
+		return nil, errors.New("Prefix should be specified in path of storage: " + spec)
+	}
+	switch u.Scheme {
+	case "redis":
+		host, port, err := hostPort(u.Host, 6379)
+		if err != nil {
+			return nil, err
+		}
+		return redis.New(prefix, host, port), nil
+	case "zookeeper":
+		host, port, err := hostPort(u.Host, 2181)
+		if err != nil {
+			return nil, err
+		}
+		return zookeeper.New(prefix, host, port), nil
+	case "etcdv3":
+		return etcdv3.New(prefix, endpoints(u.Host))
+	case "consul":
+		return consul.New(prefix, u.Host)
+	case "bolt":
+		if prefix == "" {
+			return nil, errors.New("Path of file should be specified: " + spec)
+		}
+		return bolt.New(prefix)
+	}
+	return nil, errors.New("Unsupported storage: " + spec)
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostPort(t *testing.T) {
+	host, port, err := hostPort("127.0.0.1", 6379)
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1", host)
+	assert.Equal(t, 6379, port)
+
+	host, port, err = hostPort("127.0.0.1:6380", 6379)
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1", host)
+	assert.Equal(t, 6380, port)
+
+	_, _, err = hostPort("127.0.0.1:abc", 6379)
+	assert.NotNil(t, err)
+}
+
+func TestEndpoints(t *testing.T) {
+	assert.Equal(t, []string{"http://a:2379", "http://b:2379"}, endpoints("a:2379,b:2379"))
+}
+
+func TestOpenStore(t *testing.T) {
+	_, err := openStore("unknown://127.0.0.1/schedule")
+	assert.NotNil(t, err)
+	_, err = openStore("redis://127.0.0.1:6379")
+	assert.NotNil(t, err)
+	_, err = openStore("bolt://")
+	assert.NotNil(t, err)
+
+	path := filepath.Join(os.TempDir(), "goschedule-migrate-test.db")
+	os.Remove(path)
+	defer os.Remove(path)
+	s, err := openStore("bolt://" + path)
+	assert.Nil(t, err)
+	assert.Equal(t, "bolt", s.Name())
+	s.Close()
+}
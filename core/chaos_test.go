@@ -0,0 +1,201 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/core/worker/task_worker"
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store/chaos"
+	"github.com/jasonjoo2010/goschedule/store/memory"
+	"github.com/jasonjoo2010/goschedule/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// chaosTask records which worker selects which task item with which fencing token.
+//	Every worker has its own instance.
+type chaosTask struct {
+	selects int
+}
+
+type selection struct {
+	owner *chaosTask
+	at    time.Time
+}
+
+var chaosSelections = struct {
+	sync.Mutex
+	// owners of fencing tokens: item#token => instance
+	owners map[string]*chaosTask
+	// latest selections of items: item => instance => selection
+	latest     map[string]map[*chaosTask]time.Time
+	violations []string
+}{
+	owners: make(map[string]*chaosTask),
+	latest: make(map[string]map[*chaosTask]time.Time),
+}
+
+func (task *chaosTask) SelectContext(ctx context.Context, parameter, ownSign string, items []definition.TaskItem, eachFetchNum int) []interface{} {
+	chaosSelections.Lock()
+	defer chaosSelections.Unlock()
+	task.selects++
+	now := time.Now()
+	for _, item := range items {
+		key := item.ID + "#" + strconv.FormatUint(item.Token, 10)
+		if owner, ok := chaosSelections.owners[key]; ok && owner != task {
+			chaosSelections.violations = append(chaosSelections.violations, key)
+		}
+		chaosSelections.owners[key] = task
+		if chaosSelections.latest[item.ID] == nil {
+			chaosSelections.latest[item.ID] = make(map[*chaosTask]time.Time)
+		}
+		chaosSelections.latest[item.ID][task] = now
+	}
+	return nil
+}
+
+func (task *chaosTask) ExecuteContext(ctx context.Context, item interface{}, ownSign string) bool {
+	return true
+}
+
+// resetSelections clears selections left by previous runs, eg. with -count
+func resetSelections() {
+	chaosSelections.Lock()
+	defer chaosSelections.Unlock()
+	chaosSelections.owners = make(map[string]*chaosTask)
+	chaosSelections.latest = make(map[string]map[*chaosTask]time.Time)
+	chaosSelections.violations = nil
+}
+
+// selectedBy returns how many workers selected the item recently
+func selectedBy(itemId string, within time.Duration) int {
+	chaosSelections.Lock()
+	defer chaosSelections.Unlock()
+	cnt := 0
+	for _, at := range chaosSelections.latest[itemId] {
+		if time.Since(at) <= within {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+var _ types.TaskSingleContext = (*chaosTask)(nil)
+
+func TestChaosInvariants(t *testing.T) {
+	if testing.Short() {
+		t.Skip("chaos testing takes seconds")
+	}
+	resetSelections()
+	task_worker.RegisterTaskTypeContextName("chaosTask", &chaosTask{})
+	shared := memory.New()
+	defer shared.Close()
+	items := make([]definition.TaskItem, 6)
+	for i := range items {
+		items[i].ID = "item" + strconv.Itoa(i)
+	}
+	shared.CreateTask(&definition.Task{
+		ID:                "chaosTask",
+		Bind:              "chaosTask",
+		Items:             items,
+		ExecutorCount:     1,
+		IntervalNoData:    50,
+		HeartbeatInterval: 100,
+		DeathTimeout:      1000,
+	})
+	total := 4
+	shared.CreateStrategy(&definition.Strategy{
+		ID:      "chaosStrategy",
+		IPList:  []string{"127.0.0.1"},
+		Kind:    definition.TaskKind,
+		Bind:    "chaosTask",
+		Total:   total,
+		Enabled: true,
+	})
+
+	injector := chaos.NewInjector(2020)
+	nodes := []string{"node-1", "node-2", "node-3"}
+	managers := make([]*ScheduleManager, len(nodes))
+	for i, node := range nodes {
+		managers[i] = newManager(t, chaos.New(shared, node, injector))
+		managers[i].cfg.DeathTimeout = time.Second
+		managers[i].cfg.StallAfterStartup = 0
+		assert.Nil(t, managers[i].Start())
+	}
+	defer func() {
+		for _, m := range managers {
+			m.Shutdown()
+		}
+	}()
+
+	injector.Add(chaos.Rule{Kind: chaos.Error, Probability: 0.05})
+	injector.Add(chaos.Rule{Kind: chaos.Latency, Nodes: []string{"node-2"}, Latency: 20 * time.Millisecond, Probability: 0.3})
+	injector.Add(chaos.Rule{
+		Kind:        chaos.StaleRead,
+		Methods:     []string{"GetStrategyRuntimes", "GetTaskRuntimes", "GetTaskAssignments"},
+		Probability: 0.2,
+	})
+	injector.Add(chaos.Rule{
+		Kind:        chaos.DropWrite,
+		Methods:     []string{"SetTaskRuntime", "CompareAndSetStrategyRuntime"},
+		Probability: 0.1,
+	})
+	time.Sleep(2 * time.Second)
+	// long enough to be treated as dead
+	injector.Partition("node-3")
+	time.Sleep(2 * time.Second)
+	injector.Clear()
+
+	converged := func() bool {
+		runtimes, _ := shared.GetStrategyRuntimes("chaosStrategy")
+		if len(runtimes) != len(nodes) {
+			return false
+		}
+		num := 0
+		for _, r := range runtimes {
+			num += r.Num
+		}
+		if num != total {
+			return false
+		}
+		taskRuntimes, _ := shared.GetTaskRuntimes("chaosStrategy", "chaosTask")
+		if len(taskRuntimes) != total {
+			return false
+		}
+		alive := make(map[string]bool, len(taskRuntimes))
+		for _, r := range taskRuntimes {
+			alive[r.ID] = true
+		}
+		assignments, _ := shared.GetTaskAssignments("chaosStrategy", "chaosTask")
+		if len(assignments) != len(items) {
+			return false
+		}
+		for _, a := range assignments {
+			if !alive[a.RuntimeID] || a.RequestedRuntimeID != "" {
+				return false
+			}
+			if selectedBy(a.ItemID, 300*time.Millisecond) != 1 {
+				return false
+			}
+		}
+		return true
+	}
+	if !assert.Eventually(t, converged, 15*time.Second, 100*time.Millisecond) {
+		t.Log(shared.Dump())
+	}
+
+	chaosSelections.Lock()
+	assert.Empty(t, chaosSelections.violations, "fencing token selected by multiple workers")
+	chaosSelections.Unlock()
+	for _, kind := range []chaos.Kind{chaos.Error, chaos.Latency, chaos.StaleRead, chaos.DropWrite} {
+		assert.True(t, injector.Hits(kind) > 0, "no fault of ", kind)
+	}
+	assert.True(t, injector.PartitionHits() > 0)
+}
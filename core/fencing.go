@@ -0,0 +1,167 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/log"
+	"github.com/jasonjoo2010/goschedule/utils"
+)
+
+type EventKind int
+
+const (
+	UnknownEvent EventKind = iota
+	// FencedEvent happens when all workers are stopped because heartbeats have failed for too long
+	FencedEvent
+	// UnfencedEvent happens when heartbeats succeed again after fenced, workers are resumed by scheduling
+	UnfencedEvent
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case FencedEvent:
+		return "fenced"
+	case UnfencedEvent:
+		return "unfenced"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a change of state of the manager
+type Event struct {
+	Kind EventKind
+	// LastHeartbeat is the local time of the last successful heartbeat
+	LastHeartbeat time.Time
+}
+
+func (e Event) String() string {
+	return fmt.Sprint("{kind=", e.Kind, ",lastHeartbeat=", e.LastHeartbeat.Format(time.RFC3339Nano), "}")
+}
+
+const subscriberBufferSize = 16
+
+// Subscribe returns a channel receiving events of the manager until the given context is done.
+//	The channel will be closed when the context is done or the manager is closed.
+//	Events are dropped for subscribers whose buffer is full.
+func (s *ScheduleManager) Subscribe(ctx context.Context) <-chan Event {
+	s.fenceMu.Lock()
+	defer s.fenceMu.Unlock()
+
+	ch := make(chan Event, subscriberBufferSize)
+	if s.subscribers == nil {
+		// closed
+		close(ch)
+		return ch
+	}
+	s.subscribers[ch] = struct{}{}
+	go func() {
+		<-ctx.Done()
+		s.unsubscribe(ch)
+	}()
+	return ch
+}
+
+func (s *ScheduleManager) unsubscribe(ch chan Event) {
+	s.fenceMu.Lock()
+	defer s.fenceMu.Unlock()
+
+	if _, ok := s.subscribers[ch]; ok {
+		delete(s.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (s *ScheduleManager) closeSubscribers() {
+	s.fenceMu.Lock()
+	defer s.fenceMu.Unlock()
+
+	for ch := range s.subscribers {
+		close(ch)
+	}
+	s.subscribers = nil
+}
+
+// publish sends the event to all subscribers without blocking, fenceMu should be held
+func (s *ScheduleManager) publish(e Event) {
+	for ch := range s.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Fenced returns true if workers are stopped because heartbeats have failed for too long
+func (s *ScheduleManager) Fenced() bool {
+	s.fenceMu.Lock()
+	defer s.fenceMu.Unlock()
+	return s.fenced
+}
+
+// heartbeatSucceeded records the time of the successful heartbeat and resumes immediately if fenced
+func (s *ScheduleManager) heartbeatSucceeded() {
+	s.fenceMu.Lock()
+	s.lastHeartbeat = time.Now()
+	fenced := s.fenced
+	s.fenceMu.Unlock()
+	if fenced {
+		s.checkFencing()
+	}
+}
+
+// fencingCheckInterval returns the period of checkFencing. It's a fraction of FencingMargin so that
+//	workers are stopped at least 3/4 of the margin before being treated as dead in the worst case.
+func (s *ScheduleManager) fencingCheckInterval() time.Duration {
+	interval := s.cfg.FencingMargin / 4
+	if interval > s.cfg.HeartbeatInterval {
+		interval = s.cfg.HeartbeatInterval
+	}
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	return interval
+}
+
+// checkFencing stops all workers before this manager is treated as dead by others when heartbeats
+//	have failed for (DeathTimeout - FencingMargin), and resumes them when heartbeats succeed again.
+func (s *ScheduleManager) checkFencing() {
+	// keep events in order
+	s.checkMu.Lock()
+	defer s.checkMu.Unlock()
+
+	s.fenceMu.Lock()
+	elapsed := time.Since(s.lastHeartbeat)
+	if elapsed < s.cfg.DeathTimeout-s.cfg.FencingMargin {
+		if s.fenced {
+			s.fenced = false
+			log.Infof("Heartbeat recovered, resume workers")
+			s.publish(Event{Kind: UnfencedEvent, LastHeartbeat: s.lastHeartbeat})
+			utils.Trigger(s.scheduleC)
+		}
+		s.fenceMu.Unlock()
+		return
+	}
+	fencing := !s.fenced
+	s.fenced = true
+	s.fenceMu.Unlock()
+
+	if fencing {
+		log.Warnf("No successful heartbeat for %s, stop all workers before being treated as dead", elapsed)
+	}
+	// workers may be started again by a scheduling in progress
+	if len(s.workerSet.Strategies()) > 0 {
+		s.stopAllWorkers()
+	}
+	if fencing {
+		s.fenceMu.Lock()
+		s.publish(Event{Kind: FencedEvent, LastHeartbeat: s.lastHeartbeat})
+		s.fenceMu.Unlock()
+	}
+}
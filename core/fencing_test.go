@@ -0,0 +1,168 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/core/worker"
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store/chaos"
+	"github.com/jasonjoo2010/goschedule/store/memory"
+	"github.com/jasonjoo2010/goschedule/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func nextEvent(t *testing.T, events <-chan Event, timeout time.Duration) Event {
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(timeout):
+		assert.Fail(t, "no event received")
+		return Event{}
+	}
+}
+
+func TestFencing(t *testing.T) {
+	shared := memory.New()
+	defer shared.Close()
+	worker.RegisterName("quietWorker", &quietWorker{})
+	shared.CreateStrategy(&definition.Strategy{
+		ID:      "s0",
+		IPList:  []string{"127.0.0.1"},
+		Kind:    definition.SimpleKind,
+		Bind:    "quietWorker",
+		Total:   1,
+		Enabled: true,
+	})
+	injector := chaos.NewInjector(1)
+	manager := newManager(t, chaos.New(shared, "node-1", injector))
+	manager.cfg.StallAfterStartup = 0
+	events := manager.Subscribe(context.Background())
+	assert.Nil(t, manager.Start())
+	defer manager.Shutdown()
+
+	assert.Eventually(t, func() bool {
+		return manager.workerSet.WorkersCountFor("s0") == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.False(t, manager.Fenced())
+
+	// partitioned in different phases of checking
+	for round := 0; round < 3; round++ {
+		time.Sleep(time.Duration(round) * manager.fencingCheckInterval() / 3)
+		begin := time.Now()
+		injector.Partition("node-1")
+		e := nextEvent(t, events, 2*time.Second)
+		assert.Equal(t, FencedEvent, e.Kind)
+		assert.True(t, e.LastHeartbeat.Before(begin))
+		// the worst case leaves most of the margin
+		elapsed := time.Since(e.LastHeartbeat)
+		assert.True(t, elapsed <= manager.cfg.DeathTimeout-manager.cfg.FencingMargin/2,
+			"fenced %v after the last heartbeat", elapsed)
+		assert.True(t, manager.Fenced())
+		assert.Equal(t, 0, manager.workerSet.WorkersCountFor("s0"))
+
+		// no worker is started while fenced
+		time.Sleep(300 * time.Millisecond)
+		assert.Equal(t, 0, manager.workerSet.WorkersCountFor("s0"))
+
+		injector.Heal()
+		e = nextEvent(t, events, time.Second)
+		assert.Equal(t, UnfencedEvent, e.Kind)
+		assert.False(t, manager.Fenced())
+		assert.Eventually(t, func() bool {
+			return manager.workerSet.WorkersCountFor("s0") == 1
+		}, time.Second, 10*time.Millisecond)
+	}
+}
+
+func TestFencingHungStore(t *testing.T) {
+	shared := memory.New()
+	defer shared.Close()
+	worker.RegisterName("quietWorker", &quietWorker{})
+	shared.CreateStrategy(&definition.Strategy{
+		ID:      "s0",
+		IPList:  []string{"127.0.0.1"},
+		Kind:    definition.SimpleKind,
+		Bind:    "quietWorker",
+		Total:   1,
+		Enabled: true,
+	})
+	injector := chaos.NewInjector(1)
+	manager := newManager(t, chaos.New(shared, "node-1", injector))
+	manager.cfg.StallAfterStartup = 0
+	events := manager.Subscribe(context.Background())
+	assert.Nil(t, manager.Start())
+	defer manager.Shutdown()
+
+	assert.Eventually(t, func() bool {
+		return manager.workerSet.WorkersCountFor("s0") == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// every call including GetStrategy hangs far beyond DeathTimeout
+	remove := injector.Add(chaos.Rule{Kind: chaos.Latency, Nodes: []string{"node-1"}, Latency: 3 * time.Second})
+	defer remove()
+	e := nextEvent(t, events, 2*time.Second)
+	assert.Equal(t, FencedEvent, e.Kind)
+	elapsed := time.Since(e.LastHeartbeat)
+	assert.True(t, elapsed < manager.cfg.DeathTimeout, "fenced %v after the last heartbeat", elapsed)
+	assert.Equal(t, 0, manager.workerSet.WorkersCountFor("s0"))
+}
+
+func TestFencingCheckInterval(t *testing.T) {
+	cfg := types.ScheduleConfig{
+		HeartbeatInterval: 100 * time.Millisecond,
+		DeathTimeout:      time.Second,
+	}
+	assert.Nil(t, initCfg(&cfg))
+	assert.Equal(t, 200*time.Millisecond, cfg.FencingMargin)
+	manager := &ScheduleManager{cfg: cfg}
+	// fenced no later than DeathTimeout - FencingMargin + interval after the last heartbeat
+	assert.Equal(t, 50*time.Millisecond, manager.fencingCheckInterval())
+
+	cfg = types.ScheduleConfig{
+		HeartbeatInterval: 500 * time.Millisecond,
+		DeathTimeout:      time.Second,
+	}
+	assert.Nil(t, initCfg(&cfg))
+	assert.Equal(t, 500*time.Millisecond, cfg.FencingMargin)
+	manager.cfg = cfg
+	assert.Equal(t, 125*time.Millisecond, manager.fencingCheckInterval())
+
+	manager.cfg.FencingMargin = 10 * time.Second
+	assert.Equal(t, cfg.HeartbeatInterval, manager.fencingCheckInterval())
+	manager.cfg.FencingMargin = time.Millisecond
+	assert.Equal(t, 10*time.Millisecond, manager.fencingCheckInterval())
+}
+
+func TestFencingDisabled(t *testing.T) {
+	cfg := types.ScheduleConfig{
+		HeartbeatInterval: 100 * time.Millisecond,
+		DeathTimeout:      time.Second,
+		FencingMargin:     time.Second,
+	}
+	assert.NotNil(t, initCfg(&cfg))
+	cfg.FencingMargin = 0
+	assert.Nil(t, initCfg(&cfg))
+	assert.Equal(t, 2*cfg.HeartbeatInterval, cfg.FencingMargin)
+
+	injector := chaos.NewInjector(1)
+	manager := newManager(t, chaos.New(memory.New(), "node-1", injector))
+	manager.cfg.FencingMargin = -1
+	assert.Nil(t, manager.Start())
+	injector.Partition("node-1")
+	time.Sleep(manager.cfg.DeathTimeout + 200*time.Millisecond)
+	assert.False(t, manager.Fenced())
+
+	// subscribers are closed with the manager
+	events := manager.Subscribe(context.Background())
+	manager.Shutdown()
+	_, ok := <-events
+	assert.False(t, ok)
+	_, ok = <-manager.Subscribe(context.Background())
+	assert.False(t, ok)
+}
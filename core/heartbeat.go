@@ -4,6 +4,11 @@
 
 package core
 
+import (
+	"github.com/jasonjoo2010/goschedule/log"
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
 func (s *ScheduleManager) registerInfo() {
 	scheduler, err := s.store.GetScheduler(s.scheduler.ID)
 	if err == nil {
@@ -12,6 +17,9 @@ func (s *ScheduleManager) registerInfo() {
 			s.scheduler.Enabled = scheduler.Enabled
 		}
 	}
-	s.scheduler.LastHeartbeat = s.store.Time()
-	s.store.RegisterScheduler(s.scheduler)
+	if err := store.KeepSchedulerAlive(s.store, s.scheduler, s.cfg.DeathTimeout); err != nil {
+		log.Warnf("Heartbeat failed: %s", err.Error())
+		return
+	}
+	s.heartbeatSucceeded()
 }
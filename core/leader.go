@@ -6,9 +6,12 @@ package core
 
 import (
 	"errors"
+	"math"
+	"strconv"
 	"sync"
 	"time"
 
+	"github.com/jasonjoo2010/goschedule/assigner"
 	"github.com/jasonjoo2010/goschedule/core/worker"
 	"github.com/jasonjoo2010/goschedule/core/worker/task_worker"
 	"github.com/jasonjoo2010/goschedule/definition"
@@ -19,8 +22,8 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func (manager *ScheduleManager) isLeader(strategyId string) bool {
-	list, err := manager.store.GetStrategyRuntimes(strategyId)
+func (manager *ScheduleManager) isLeader(s store.Store, strategyId string) bool {
+	list, err := s.GetStrategyRuntimes(strategyId)
 	if err != nil || len(list) < 1 {
 		return false
 	}
@@ -42,44 +45,82 @@ func (manager *ScheduleManager) isLeader(strategyId string) bool {
 		return true
 	}
 	// double check the scheduler really exists
-	scheduler, _ := manager.store.GetScheduler(leaderUUID)
+	scheduler, _ := s.GetScheduler(leaderUUID)
 	if scheduler == nil {
 		// dirty runtime
-		manager.cleanScheduler(leaderUUID)
+		manager.cleanScheduler(s, leaderUUID)
 	}
 	return false
 }
 
-func (manager *ScheduleManager) cleanScheduler(schedulerId string) {
-	manager.store.UnregisterScheduler(schedulerId)
+func (manager *ScheduleManager) cleanScheduler(s store.Store, schedulerId string) {
+	s.UnregisterScheduler(schedulerId)
 	// clean dead runtimes binded to it
-	strategies, err := manager.store.GetStrategies()
+	strategies, err := s.GetStrategies()
 	if err != nil {
 		log.Warnf("Failed to fetch strategies: %s", err.Error())
 		return
 	}
 	for _, strategy := range strategies {
-		manager.store.RemoveStrategyRuntime(strategy.ID, schedulerId)
+		s.RemoveStrategyRuntime(strategy.ID, schedulerId)
 	}
 }
 
-func (manager *ScheduleManager) clearExpiredSchedulers() {
-	schedulers, err := manager.store.GetSchedulers()
+func (manager *ScheduleManager) clearExpiredSchedulers(s store.Store) {
+	// runtimes should be fetched before schedulers,
+	//	or runtimes of schedulers joined just now may be treated as orphans
+	strategies, err := s.GetStrategies()
+	if err != nil {
+		logrus.Warn("Get strategies failed: ", err.Error())
+		return
+	}
+	runtimes := make([]*definition.StrategyRuntime, 0, len(strategies))
+	for _, strategy := range strategies {
+		list, err := s.GetStrategyRuntimes(strategy.ID)
+		if err != nil {
+			logrus.Warn("Get runtimes of strategy ", strategy.ID, " failed: ", err.Error())
+			return
+		}
+		runtimes = append(runtimes, list...)
+	}
+	schedulers, err := s.GetSchedulers()
 	if err != nil {
 		logrus.Warnf("Get scheudlers failed: %s", err.Error())
 		return
 	}
-	now := time.Now().UnixNano() / 1e6
+	alive := make(map[string]bool, len(schedulers))
 	for _, scheduler := range schedulers {
-		if now-scheduler.LastHeartbeat > manager.cfg.DeathTimeout.Milliseconds() {
+		// liveness is an optional capability of the underlying storage
+		if !store.IsSchedulerAlive(manager.store, scheduler, manager.cfg.DeathTimeout) {
 			logrus.Info("Clear expired scheduler: ", scheduler.ID, ", last reach at ", scheduler.LastHeartbeat)
-			manager.cleanScheduler(scheduler.ID)
+			manager.cleanScheduler(s, scheduler.ID)
+			continue
+		}
+		alive[scheduler.ID] = true
+	}
+	// schedulers may be expired and removed by storage directly
+	for _, runtime := range runtimes {
+		if !alive[runtime.SchedulerID] {
+			logrus.Info("Clear orphan runtime of strategy ", runtime.StrategyID, " on scheduler ", runtime.SchedulerID)
+			s.RemoveStrategyRuntime(runtime.StrategyID, runtime.SchedulerID)
 		}
 	}
 }
 
-func (manager *ScheduleManager) generateRuntimes() {
-	strategies, err := manager.store.GetStrategies()
+// canSchedule decides whether the strategy can be scheduled on current scheduler by its labels
+//	if selector is given, otherwise by IPList.
+func (manager *ScheduleManager) canSchedule(strategy *definition.Strategy, hostname, ip string) bool {
+	if len(strategy.Selector) == 0 {
+		return utils.CanSchedule(strategy.IPList, hostname, ip)
+	}
+	if !strategy.Selector.Matches(manager.scheduler.Labels) {
+		return false
+	}
+	return len(strategy.IPList) == 0 || utils.CanSchedule(strategy.IPList, hostname, ip)
+}
+
+func (manager *ScheduleManager) generateRuntimes(s store.Store) {
+	strategies, err := s.GetStrategies()
 	if err != nil {
 		logrus.Warn("Get strategies failed: ", err.Error())
 		return
@@ -87,8 +128,8 @@ func (manager *ScheduleManager) generateRuntimes() {
 	hostname := utils.GetHostName()
 	ip := utils.GetHostIPv4()
 	for _, strategy := range strategies {
-		canSchedule := utils.CanSchedule(strategy.IPList, hostname, ip)
-		runtime, err := manager.store.GetStrategyRuntime(strategy.ID, manager.scheduler.ID)
+		canSchedule := manager.canSchedule(strategy, hostname, ip)
+		runtime, err := s.GetStrategyRuntime(strategy.ID, manager.scheduler.ID)
 		if err != nil && err != store.NotExist {
 			continue
 		}
@@ -100,13 +141,14 @@ func (manager *ScheduleManager) generateRuntimes() {
 					StrategyID:  strategy.ID,
 					CreateAt:    time.Now().Unix() * 1000,
 				}
-				manager.store.SetStrategyRuntime(runtime)
+				// it may be created concurrently by previous cycle
+				s.CompareAndSetStrategyRuntime(runtime)
 			}
 		} else {
 			// clear runtimes if any
 			if runtime != nil {
 				logrus.Info("Clean runtime for strategy: ", runtime.StrategyID, " with scheduler ", runtime.SchedulerID)
-				manager.store.RemoveStrategyRuntime(strategy.ID, manager.scheduler.ID)
+				s.RemoveStrategyRuntime(strategy.ID, manager.scheduler.ID)
 				// stop the workers
 				manager.stopWorkers(strategy)
 			}
@@ -114,43 +156,175 @@ func (manager *ScheduleManager) generateRuntimes() {
 	}
 }
 
-func (manager *ScheduleManager) assign() {
-	strategies, err := manager.store.GetStrategies()
+func (manager *ScheduleManager) assign(s store.Store) {
+	strategies, err := s.GetStrategies()
 	if err != nil {
 		logrus.Warn("Failed to fetch strategies", err)
 		return
 	}
 
+	requested := requestedWorkers(s, strategies)
 	for _, strategy := range strategies {
 		if !strategy.Enabled {
 			continue
 		}
-		if !manager.isLeader(strategy.ID) {
+		if !manager.isLeader(s, strategy.ID) {
 			continue
 		}
 		// It's the leader to specific strategy
-		runtimes, err := manager.store.GetStrategyRuntimes(strategy.ID)
+		runtimes, err := s.GetStrategyRuntimes(strategy.ID)
 		if err != nil {
 			logrus.Warn("Failed to fetch runtimes for ", strategy.ID, ": ", err.Error())
 			continue
 		}
-		workerRequiredArr := utils.AssignWorkers(len(runtimes), strategy.Total, strategy.MaxOnSingleScheduler)
-		utils.SortRuntimesWithShuffle(runtimes)
+		utils.SortRuntimes(runtimes, strategy.ID)
+		workerRequiredArr, err := manager.requiredWorkers(s, strategy, runtimes, requested)
+		if err != nil {
+			logrus.Warn("Failed to assign workers for ", strategy.ID, ": ", err.Error())
+			continue
+		}
 		for i := 0; i < len(runtimes); i++ {
 			if workerRequiredArr[i] != runtimes[i].RequestedNum {
 				runtimes[i].RequestedNum = workerRequiredArr[i]
-				manager.store.SetStrategyRuntime(runtimes[i])
+				if err := s.CompareAndSetStrategyRuntime(runtimes[i]); err != nil {
+					// assign again with fresh state
+					logrus.Warn("Failed to update runtime of ", strategy.ID, " on ", runtimes[i].SchedulerID, ": ", err.Error())
+					if err == store.Conflict {
+						utils.Trigger(manager.scheduleC)
+					}
+					break
+				}
+				if requested[runtimes[i].SchedulerID] == nil {
+					requested[runtimes[i].SchedulerID] = make(map[string]int)
+				}
+				requested[runtimes[i].SchedulerID][strategy.ID] = workerRequiredArr[i]
+			}
+		}
+	}
+}
+
+// requestedWorkers counts requested workers of each strategy on each scheduler, indexed by scheduler id.
+func requestedWorkers(s store.Store, strategies []*definition.Strategy) map[string]map[string]int {
+	requested := make(map[string]map[string]int)
+	for _, strategy := range strategies {
+		if !strategy.Enabled {
+			continue
+		}
+		runtimes, err := s.GetStrategyRuntimes(strategy.ID)
+		if err != nil {
+			continue
+		}
+		for _, runtime := range runtimes {
+			if runtime.RequestedNum <= 0 {
+				continue
 			}
+			if requested[runtime.SchedulerID] == nil {
+				requested[runtime.SchedulerID] = make(map[string]int)
+			}
+			requested[runtime.SchedulerID][strategy.ID] = runtime.RequestedNum
 		}
 	}
+	return requested
+}
+
+// requiredWorkers decides how many workers should be running on each of the sorted runtimes through
+//	the assigner of the strategy. Workers never exceed MaxWorkers of schedulers including workers
+//	requested by other strategies.
+//	Schedulers not matching the selector get none, eg. labels changed after joining.
+func (manager *ScheduleManager) requiredWorkers(s store.Store, strategy *definition.Strategy,
+	runtimes []*definition.StrategyRuntime, requested map[string]map[string]int) ([]int, error) {
+	required := make([]int, len(runtimes))
+	eligible := make([]int, 0, len(runtimes))
+	state := &assigner.State{
+		Nodes: make([]assigner.Node, 0, len(runtimes)),
+	}
+	for i, runtime := range runtimes {
+		scheduler, err := s.GetScheduler(runtime.SchedulerID)
+		if err != nil && err != store.NotExist {
+			return nil, err
+		}
+		if scheduler == nil {
+			scheduler = &definition.Scheduler{ID: runtime.SchedulerID}
+		}
+		if !strategy.Selector.Matches(scheduler.Labels) {
+			continue
+		}
+		limit := math.MaxInt32
+		if strategy.Kind == definition.SingletonKind {
+			limit = 1
+		} else if strategy.MaxOnSingleScheduler > 0 {
+			limit = strategy.MaxOnSingleScheduler
+		}
+		if scheduler.MaxWorkers > 0 {
+			free := scheduler.MaxWorkers
+			for strategyId, num := range requested[scheduler.ID] {
+				if strategyId != strategy.ID {
+					free -= num
+				}
+			}
+			if free < limit {
+				limit = free
+			}
+		}
+		weight := scheduler.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		if limit <= 0 {
+			// full
+			weight = 0
+		}
+		eligible = append(eligible, i)
+		state.Nodes = append(state.Nodes, assigner.Node{
+			ID:     scheduler.ID,
+			Weight: weight,
+			Limit:  limit,
+			Group:  scheduler.Labels[strategy.SpreadBy],
+			Labels: scheduler.Labels,
+		})
+	}
+	if len(eligible) == 0 {
+		return required, nil
+	}
+	total := strategy.Total
+	if strategy.Kind == definition.SingletonKind {
+		// every scheduler competes for fire times
+		total = len(eligible)
+	}
+	// workers are identified by their sequence and owned by schedulers in order
+	state.Units = make([]assigner.Unit, total)
+	k := 0
+	for n, pos := range eligible {
+		for i := 0; i < runtimes[pos].RequestedNum && k < total; i++ {
+			state.Units[k].Owner = state.Nodes[n].ID
+			k++
+		}
+	}
+	for k := range state.Units {
+		state.Units[k].ID = strategy.ID + "$" + strconv.Itoa(k)
+	}
+	owners, err := assigner.Assign(strategy.Assigner, state)
+	if err != nil {
+		return nil, err
+	}
+	for n, num := range assigner.Counts(state, owners) {
+		required[eligible[n]] = num
+	}
+	return required, nil
 }
 
 func (manager *ScheduleManager) createWorker(strategy *definition.Strategy) (types.Worker, error) {
+	// reject illegal strategies rather than running them wrongly, e.g. always running for a wrong cron
+	if err := strategy.Validate(); err != nil {
+		return nil, err
+	}
 	switch strategy.Kind {
 	case definition.SimpleKind:
 		return worker.NewSimple(*strategy)
 	case definition.FuncKind:
-		return worker.NewFunc(*strategy)
+		return worker.NewFuncWithStore(*strategy, manager.store)
+	case definition.SingletonKind:
+		return worker.NewSingleton(*strategy, manager.store)
 	case definition.TaskKind:
 		task, err := manager.store.GetTask(strategy.Bind)
 		if err != nil {
@@ -172,7 +346,7 @@ func (manager *ScheduleManager) maintainWorkers(strategy *definition.Strategy, t
 		for i := 0; i < delta; i++ {
 			w, err := manager.createWorker(strategy)
 			if err != nil {
-				logrus.Error("Can't create worker for: ", strategy.ID)
+				logrus.Error("Can't create worker for ", strategy.ID, ": ", err.Error())
 				continue
 			}
 			go func() {
@@ -182,7 +356,7 @@ func (manager *ScheduleManager) maintainWorkers(strategy *definition.Strategy, t
 				}
 			}()
 			logrus.Info("Worker of strategy ", strategy.ID, " started")
-			manager.workerSet.AddWorker(strategy.ID, w)
+			manager.workerSet.AddWorker(strategy, w)
 		}
 	} else if delta < 0 {
 		// decrease
@@ -200,8 +374,8 @@ func (manager *ScheduleManager) maintainWorkers(strategy *definition.Strategy, t
 	}
 }
 
-func (manager *ScheduleManager) adjustWorkers() {
-	strategies, err := manager.store.GetStrategies()
+func (manager *ScheduleManager) adjustWorkers(s store.Store) {
+	strategies, err := s.GetStrategies()
 	if err != nil {
 		logrus.Warn("Failed to fetch strategies ", err)
 		return
@@ -211,7 +385,7 @@ func (manager *ScheduleManager) adjustWorkers() {
 		if !strategy.Enabled {
 			continue
 		}
-		runtime, err := manager.store.GetStrategyRuntime(strategy.ID, manager.scheduler.ID)
+		runtime, err := s.GetStrategyRuntime(strategy.ID, manager.scheduler.ID)
 		if err == store.NotExist {
 			continue
 		}
@@ -224,25 +398,51 @@ func (manager *ScheduleManager) adjustWorkers() {
 			runtime.RequestedNum = 0
 		}
 		workersCnt := manager.workerSet.WorkersCountFor(runtime.StrategyID)
-		if workersCnt != runtime.RequestedNum {
-			manager.maintainWorkers(strategy, runtime.RequestedNum)
+		target := runtime.RequestedNum
+		if manager.cfg.MaxWorkers > 0 {
+			// never overload local node even if leaders assign concurrently
+			free := manager.cfg.MaxWorkers - (manager.workerSet.Count() - workersCnt)
+			if free < 0 {
+				free = 0
+			}
+			if target > free {
+				target = free
+			}
+		}
+		if workersCnt != target {
+			manager.maintainWorkers(strategy, target)
 			workersCnt = manager.workerSet.WorkersCountFor(runtime.StrategyID)
 		}
 		// update info in storage
 		if runtime.Num != workersCnt {
 			runtime.Num = workersCnt
-			manager.store.SetStrategyRuntime(runtime)
+			if err := s.CompareAndSetStrategyRuntime(runtime); err == store.Conflict {
+				// requested number may be changed, adjust again with fresh state
+				utils.Trigger(manager.scheduleC)
+			}
 		}
 	}
 }
 
 func (manager *ScheduleManager) schedule() {
-	manager.clearExpiredSchedulers()
-	manager.generateRuntimes()
+	if manager.Fenced() {
+		// workers are kept stopped until heartbeats succeed again
+		return
+	}
+	// every collection is read at most once in a cycle
+	s := newSnapshot(manager.store)
+	if store.IsDegraded(manager.store) {
+		// decisions on a failing storage may be wrong, eg. clearing healthy schedulers
+		logrus.Warn("Storage is degraded, skip rebalancing")
+		manager.adjustWorkers(s)
+		return
+	}
+	manager.clearExpiredSchedulers(s)
+	manager.generateRuntimes(s)
 	// calculate schedule table
-	manager.assign()
+	manager.assign(s)
 	// adjust local workers
-	manager.adjustWorkers()
+	manager.adjustWorkers(s)
 }
 
 // stopWorkers stop group of workers binded to specific strategy
@@ -275,13 +475,14 @@ func (manager *ScheduleManager) stopWorkers(strategy *definition.Strategy) error
 	return nil
 }
 
+// stopAllWorkers stops workers of all strategies with the ones they were started with. No storage is
+//	accessed because it's also called for fencing when storage may be hung.
 func (manager *ScheduleManager) stopAllWorkers() {
 	wg := sync.WaitGroup{}
 	names := manager.workerSet.Strategies()
 	for _, name := range names {
-		strategy, _ := manager.store.GetStrategy(name)
+		strategy := manager.workerSet.StrategyFor(name)
 		if strategy == nil {
-			logrus.Warn("Strategy not found: ", name)
 			strategy = &definition.Strategy{
 				ID:        name,
 				Parameter: "",
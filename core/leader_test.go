@@ -5,13 +5,16 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/jasonjoo2010/goschedule/assigner"
 	"github.com/jasonjoo2010/goschedule/core/worker"
 	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
 	"github.com/jasonjoo2010/goschedule/store/memory"
 	"github.com/stretchr/testify/assert"
 )
@@ -83,21 +86,21 @@ func TestLeader(t *testing.T) {
 		Enabled: true,
 	})
 
-	assert.False(t, manager1.isLeader("s0"))
-	assert.False(t, manager2.isLeader("s0"))
+	assert.False(t, manager1.isLeader(store, "s0"))
+	assert.False(t, manager2.isLeader(store, "s0"))
 
 	manager1.registerInfo()
-	manager1.generateRuntimes()
+	manager1.generateRuntimes(store)
 
 	time.Sleep(time.Second)
-	assert.True(t, manager1.isLeader("s0"))
-	assert.False(t, manager2.isLeader("s0"))
+	assert.True(t, manager1.isLeader(store, "s0"))
+	assert.False(t, manager2.isLeader(store, "s0"))
 
 	manager1.registerInfo()
-	manager2.generateRuntimes()
+	manager2.generateRuntimes(store)
 
-	isLeader1 := manager1.isLeader("s0")
-	isLeader2 := manager2.isLeader("s0")
+	isLeader1 := manager1.isLeader(store, "s0")
+	isLeader2 := manager2.isLeader(store, "s0")
 	assert.False(t, isLeader1 && isLeader2)
 	assert.True(t, isLeader1 || isLeader2)
 }
@@ -124,7 +127,7 @@ func TestExpiredSchedulers(t *testing.T) {
 
 	// Register an expired one
 	managerExpired.registerInfo()
-	managerExpired.generateRuntimes()
+	managerExpired.generateRuntimes(store)
 	list, _ = store.GetSchedulers()
 	assert.Equal(t, 2, len(list))
 
@@ -166,7 +169,7 @@ func TestGenerateRuntimes(t *testing.T) {
 	list, _ = store.GetStrategyRuntimes("s1")
 	assert.Equal(t, 0, len(list))
 
-	manager.generateRuntimes()
+	manager.generateRuntimes(store)
 	list, _ = store.GetStrategyRuntimes("s0")
 	assert.Equal(t, 1, len(list))
 	list, _ = store.GetStrategyRuntimes("s1")
@@ -217,3 +220,423 @@ func TestAssign(t *testing.T) {
 	manager2.Shutdown()
 	manager3.Shutdown()
 }
+
+func TestAssignDeterministic(t *testing.T) {
+	manager := newManager(t, memory.New())
+	manager.cfg.StallAfterStartup = 0
+	var expected map[string]int
+	for round := 0; round < 10; round++ {
+		s := memory.New()
+		s.CreateStrategy(&definition.Strategy{
+			ID:                   "S",
+			Kind:                 definition.SimpleKind,
+			Total:                2,
+			MaxOnSingleScheduler: 1,
+			Enabled:              true,
+		})
+		s.RegisterScheduler(manager.scheduler)
+		s.SetStrategyRuntime(&definition.StrategyRuntime{SchedulerID: manager.scheduler.ID, StrategyID: "S"})
+		for i := 0; i < 4; i++ {
+			id := fmt.Sprint(manager.scheduler.ID, i)
+			s.RegisterScheduler(&definition.Scheduler{ID: id})
+			s.SetStrategyRuntime(&definition.StrategyRuntime{SchedulerID: id, StrategyID: "S"})
+		}
+
+		manager.assign(s)
+
+		runtimes, err := s.GetStrategyRuntimes("S")
+		assert.Nil(t, err)
+		requested := make(map[string]int)
+		for _, runtime := range runtimes {
+			requested[runtime.SchedulerID] = runtime.RequestedNum
+		}
+		if expected == nil {
+			expected = requested
+		}
+		assert.Equal(t, expected, requested, "round %d", round)
+		assert.Nil(t, s.Close())
+	}
+}
+
+func TestAssignAmongStrategies(t *testing.T) {
+	s := memory.New()
+	defer func() {
+		assert.Nil(t, s.Close())
+	}()
+	manager := newManager(t, s)
+	manager.cfg.StallAfterStartup = 0
+	s.RegisterScheduler(manager.scheduler)
+	ids := []string{manager.scheduler.ID}
+	for i := 0; i < 2; i++ {
+		id := fmt.Sprint(manager.scheduler.ID, i)
+		s.RegisterScheduler(&definition.Scheduler{ID: id})
+		ids = append(ids, id)
+	}
+	for i := 0; i < 9; i++ {
+		strategyId := fmt.Sprint("S", i)
+		s.CreateStrategy(&definition.Strategy{
+			ID:      strategyId,
+			Kind:    definition.SimpleKind,
+			Total:   1,
+			Enabled: true,
+		})
+		for _, id := range ids {
+			s.SetStrategyRuntime(&definition.StrategyRuntime{SchedulerID: id, StrategyID: strategyId})
+		}
+	}
+
+	manager.assign(s)
+
+	counts := make(map[string]int)
+	for i := 0; i < 9; i++ {
+		runtimes, _ := s.GetStrategyRuntimes(fmt.Sprint("S", i))
+		for _, runtime := range runtimes {
+			counts[runtime.SchedulerID] += runtime.RequestedNum
+		}
+	}
+	total := 0
+	for _, id := range ids {
+		// single-worker strategies start from different schedulers
+		assert.True(t, counts[id] > 0, "no worker on %s: %v", id, counts)
+		total += counts[id]
+	}
+	assert.Equal(t, 9, total)
+}
+
+func TestAssignSingleton(t *testing.T) {
+	worker.RegisterFuncContext("demoSingleton", func(ctx context.Context, strategyId, parameter string) {})
+	store := memory.New()
+	defer func() {
+		assert.Nil(t, store.Close())
+	}()
+	managers := []*ScheduleManager{newManager(t, store), newManager(t, store), newManager(t, store)}
+	for _, m := range managers {
+		m.cfg.ScheduleInterval = 200 * time.Millisecond
+		m.cfg.StallAfterStartup = 0
+	}
+
+	// total is ignored
+	store.CreateStrategy(&definition.Strategy{
+		ID:        "S",
+		IPList:    []string{"127.0.0.1"},
+		Total:     1,
+		Kind:      definition.SingletonKind,
+		Bind:      "demoSingleton",
+		CronBegin: "0 0 0 1 1 ?",
+		Enabled:   true,
+	})
+
+	for _, m := range managers {
+		m.Start()
+	}
+
+	time.Sleep(time.Second)
+	runtimes, _ := store.GetStrategyRuntimes("S")
+	assert.Equal(t, len(managers), len(runtimes))
+	for _, r := range runtimes {
+		assert.Equal(t, 1, r.Num)
+	}
+
+	for _, m := range managers {
+		m.Shutdown()
+	}
+}
+
+func TestCreateWorkerValidate(t *testing.T) {
+	worker.RegisterName("demo", &DemoWorker{})
+	store := memory.New()
+	defer func() {
+		assert.Nil(t, store.Close())
+	}()
+	manager := newManager(t, store)
+	strategy := &definition.Strategy{
+		ID:        "S",
+		Kind:      definition.SimpleKind,
+		Bind:      "demo",
+		CronBegin: "0 0 9 * * ?",
+	}
+	_, err := manager.createWorker(strategy)
+	assert.Nil(t, err)
+
+	// a typo shouldn't turn into always running
+	strategy.CronBegin = "0 0 9 * * ? *"
+	_, err = manager.createWorker(strategy)
+	assert.NotNil(t, err)
+}
+
+// degradedStore reports storage is always degraded
+type degradedStore struct {
+	store.Store
+}
+
+func (s degradedStore) Unwrap() store.Store {
+	return s.Store
+}
+
+func (s degradedStore) Degraded() bool {
+	return true
+}
+
+func TestScheduleDegraded(t *testing.T) {
+	s := memory.New()
+	defer func() {
+		assert.Nil(t, s.Close())
+	}()
+	manager := newManager(t, degradedStore{s})
+	s.CreateStrategy(&definition.Strategy{
+		ID:      "s0",
+		IPList:  []string{"localhost"},
+		Enabled: true,
+	})
+	manager.registerInfo()
+
+	// rebalancing is skipped
+	manager.schedule()
+	list, _ := s.GetStrategyRuntimes("s0")
+	assert.Equal(t, 0, len(list))
+
+	manager.store = s
+	manager.schedule()
+	list, _ = s.GetStrategyRuntimes("s0")
+	assert.Equal(t, 1, len(list))
+}
+
+func TestAssignLabels(t *testing.T) {
+	worker.RegisterName("quietWorker", &quietWorker{})
+	s := memory.New()
+	defer func() {
+		assert.Nil(t, s.Close())
+	}()
+	zones := []string{"a", "a", "b", ""}
+	managers := make([]*ScheduleManager, len(zones))
+	for i, zone := range zones {
+		managers[i] = newManager(t, s)
+		managers[i].cfg.StallAfterStartup = 0
+		if zone != "" {
+			managers[i].scheduler.Labels = map[string]string{"zone": zone}
+		}
+	}
+	s.CreateStrategy(&definition.Strategy{
+		ID:       "spread",
+		Selector: definition.Selector{{Key: "zone", Operator: definition.SelectorExists}},
+		SpreadBy: "zone",
+		Total:    2,
+		Kind:     definition.SimpleKind,
+		Bind:     "quietWorker",
+		Enabled:  true,
+	})
+	s.CreateStrategy(&definition.Strategy{
+		ID:       "zoneB",
+		IPList:   []string{"localhost"},
+		Selector: definition.Selector{{Key: "zone", Operator: definition.SelectorIn, Values: []string{"b"}}},
+		Total:    2,
+		Kind:     definition.SimpleKind,
+		Bind:     "quietWorker",
+		Enabled:  true,
+	})
+	s.CreateStrategy(&definition.Strategy{
+		ID:      "legacy",
+		IPList:  []string{"127.0.0.1"},
+		Total:   4,
+		Kind:    definition.SimpleKind,
+		Bind:    "quietWorker",
+		Enabled: true,
+	})
+	for _, m := range managers {
+		m.registerInfo()
+	}
+	for i := 0; i < 3; i++ {
+		for _, m := range managers {
+			m.schedule()
+		}
+	}
+	defer func() {
+		for _, m := range managers {
+			m.stopAllWorkers()
+		}
+	}()
+
+	workers := func(strategyId string) []int {
+		arr := make([]int, len(managers))
+		for i, m := range managers {
+			arr[i] = m.workerSet.WorkersCountFor(strategyId)
+		}
+		return arr
+	}
+	spread := workers("spread")
+	assert.Equal(t, 1, spread[0]+spread[1])
+	assert.Equal(t, 1, spread[2])
+	assert.Equal(t, 0, spread[3])
+	runtimes, _ := s.GetStrategyRuntimes("spread")
+	assert.Equal(t, 3, len(runtimes))
+
+	assert.Equal(t, []int{0, 0, 2, 0}, workers("zoneB"))
+	assert.Equal(t, []int{1, 1, 1, 1}, workers("legacy"))
+}
+
+func TestRequiredWorkers(t *testing.T) {
+	s := memory.New()
+	defer func() {
+		assert.Nil(t, s.Close())
+	}()
+	manager := newManager(t, s)
+	labels := []map[string]string{{"zone": "a"}, {"zone": "a"}, {"zone": "b"}, nil}
+	runtimes := make([]*definition.StrategyRuntime, len(labels)+1)
+	for i, l := range labels {
+		id := fmt.Sprint("scheduler-", i)
+		s.RegisterScheduler(&definition.Scheduler{ID: id, Labels: l})
+		runtimes[i] = &definition.StrategyRuntime{SchedulerID: id, StrategyID: "s0"}
+	}
+	// gone
+	runtimes[len(labels)] = &definition.StrategyRuntime{SchedulerID: "scheduler-x", StrategyID: "s0"}
+
+	strategy := &definition.Strategy{ID: "s0", Kind: definition.SimpleKind, Total: 5}
+	required, err := manager.requiredWorkers(s, strategy, runtimes, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{1, 1, 1, 1, 1}, required)
+
+	// labels changed after joining
+	strategy.Selector = definition.Selector{{Key: "zone", Operator: definition.SelectorIn, Values: []string{"a"}}}
+	required, _ = manager.requiredWorkers(s, strategy, runtimes, nil)
+	assert.Equal(t, []int{3, 2, 0, 0, 0}, required)
+
+	strategy.Selector = nil
+	strategy.SpreadBy = "zone"
+	strategy.Total = 6
+	strategy.MaxOnSingleScheduler = 2
+	required, _ = manager.requiredWorkers(s, strategy, runtimes, nil)
+	assert.Equal(t, []int{1, 1, 2, 1, 1}, required)
+
+	strategy.Kind = definition.SingletonKind
+	strategy.Selector = definition.Selector{{Key: "zone", Operator: definition.SelectorExists}}
+	required, _ = manager.requiredWorkers(s, strategy, runtimes, nil)
+	assert.Equal(t, []int{1, 1, 1, 0, 0}, required)
+}
+
+func TestRequiredWorkersWeighted(t *testing.T) {
+	s := memory.New()
+	defer func() {
+		assert.Nil(t, s.Close())
+	}()
+	manager := newManager(t, s)
+	schedulers := []*definition.Scheduler{
+		{ID: "scheduler-0", Weight: 2},
+		{ID: "scheduler-1"},
+		{ID: "scheduler-2", Weight: 1, MaxWorkers: 3},
+	}
+	runtimes := make([]*definition.StrategyRuntime, len(schedulers))
+	for i, scheduler := range schedulers {
+		s.RegisterScheduler(scheduler)
+		runtimes[i] = &definition.StrategyRuntime{SchedulerID: scheduler.ID, StrategyID: "s0"}
+	}
+
+	strategy := &definition.Strategy{ID: "s0", Kind: definition.SimpleKind, Total: 8}
+	required, err := manager.requiredWorkers(s, strategy, runtimes, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{4, 2, 2}, required)
+
+	// capped by workers of other strategies
+	requested := map[string]map[string]int{
+		"scheduler-2": {"s0": 2, "s1": 2},
+	}
+	required, _ = manager.requiredWorkers(s, strategy, runtimes, requested)
+	assert.Equal(t, []int{5, 2, 1}, required)
+
+	requested["scheduler-2"]["s1"] = 5
+	required, _ = manager.requiredWorkers(s, strategy, runtimes, requested)
+	assert.Equal(t, []int{5, 3, 0}, required)
+
+	strategy.MaxOnSingleScheduler = 3
+	required, _ = manager.requiredWorkers(s, strategy, runtimes, requested)
+	assert.Equal(t, []int{3, 3, 0}, required)
+}
+
+func TestAdjustWorkersMaxWorkers(t *testing.T) {
+	s := memory.New()
+	defer func() {
+		assert.Nil(t, s.Close())
+	}()
+	worker.RegisterName("quietWorker", &quietWorker{})
+	manager := newManager(t, s)
+	manager.cfg.MaxWorkers = 3
+	defer manager.stopAllWorkers()
+	for _, id := range []string{"s0", "s1"} {
+		s.CreateStrategy(&definition.Strategy{
+			ID:      id,
+			Kind:    definition.SimpleKind,
+			Bind:    "quietWorker",
+			Total:   2,
+			Enabled: true,
+		})
+		s.SetStrategyRuntime(&definition.StrategyRuntime{
+			SchedulerID:  manager.scheduler.ID,
+			StrategyID:   id,
+			RequestedNum: 2,
+		})
+	}
+	manager.adjustWorkers(s)
+	assert.Equal(t, 3, manager.workerSet.Count())
+	// strategies are adjusted in any order
+	for _, id := range []string{"s0", "s1"} {
+		runtime, _ := s.GetStrategyRuntime(id, manager.scheduler.ID)
+		assert.Equal(t, manager.workerSet.WorkersCountFor(id), runtime.Num)
+		assert.True(t, runtime.Num == 1 || runtime.Num == 2)
+	}
+}
+
+func TestRequiredWorkersSticky(t *testing.T) {
+	s := memory.New()
+	defer func() {
+		assert.Nil(t, s.Close())
+	}()
+	manager := newManager(t, s)
+	runtimes := make([]*definition.StrategyRuntime, 4)
+	for i := range runtimes {
+		runtimes[i] = &definition.StrategyRuntime{SchedulerID: fmt.Sprint("scheduler-", i), StrategyID: "s0"}
+	}
+	runtimes[1].RequestedNum = 2
+	runtimes[3].RequestedNum = 1
+
+	strategy := &definition.Strategy{ID: "s0", Kind: definition.SimpleKind, Total: 3}
+	required, _ := manager.requiredWorkers(s, strategy, runtimes, nil)
+	assert.Equal(t, []int{1, 1, 0, 1}, required)
+
+	// one of the new ones takes over the one moved
+	strategy.Total = 6
+	runtimes[1].RequestedNum = 3
+	runtimes[3].RequestedNum = 3
+	required, _ = manager.requiredWorkers(s, strategy, runtimes, nil)
+	assert.Equal(t, []int{1, 2, 1, 2}, required)
+}
+
+func TestRequiredWorkersAssigner(t *testing.T) {
+	s := memory.New()
+	defer func() {
+		assert.Nil(t, s.Close())
+	}()
+	manager := newManager(t, s)
+	runtimes := make([]*definition.StrategyRuntime, 3)
+	for i := range runtimes {
+		runtimes[i] = &definition.StrategyRuntime{SchedulerID: fmt.Sprint("scheduler-", i), StrategyID: "s0"}
+	}
+	assigner.Register("lastScheduler", assigner.Func(func(state *assigner.State) []string {
+		owners := make([]string, len(state.Units))
+		for k := range owners {
+			owners[k] = state.Nodes[len(state.Nodes)-1].ID
+		}
+		return owners
+	}))
+
+	strategy := &definition.Strategy{ID: "s0", Kind: definition.SimpleKind, Total: 4, Assigner: "lastScheduler"}
+	required, err := manager.requiredWorkers(s, strategy, runtimes, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{0, 0, 4}, required)
+
+	strategy.Assigner = assigner.Even
+	required, _ = manager.requiredWorkers(s, strategy, runtimes, nil)
+	assert.Equal(t, []int{2, 1, 1}, required)
+
+	strategy.Assigner = "unknown"
+	_, err = manager.requiredWorkers(s, strategy, runtimes, nil)
+	assert.NotNil(t, err)
+}
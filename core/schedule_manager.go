@@ -32,6 +32,13 @@ type ScheduleManager struct {
 	scheduler *definition.Scheduler
 
 	workerSet *u.WorkerSet
+	scheduleC chan struct{} // trigger of rescheduling immediately
+
+	checkMu       sync.Mutex
+	fenceMu       sync.Mutex
+	lastHeartbeat time.Time // local time of the last successful heartbeat
+	fenced        bool
+	subscribers   map[chan Event]struct{}
 }
 
 func initCfg(cfg *types.ScheduleConfig) error {
@@ -47,10 +54,26 @@ func initCfg(cfg *types.ScheduleConfig) error {
 	if cfg.ScheduleInterval <= 0 {
 		cfg.ScheduleInterval = 10 * time.Second
 	}
+	if cfg.Weight <= 0 {
+		cfg.Weight = 1
+	}
+	if cfg.MaxWorkers < 0 {
+		cfg.MaxWorkers = 0
+	}
 
 	if cfg.HeartbeatInterval*2 > cfg.DeathTimeout {
 		return errors.New("Heartbeat interval should be no more than half of the death timeout")
 	}
+	if cfg.FencingMargin == 0 {
+		// leave room for checking and stopping workers
+		cfg.FencingMargin = 2 * cfg.HeartbeatInterval
+		if cfg.FencingMargin >= cfg.DeathTimeout {
+			cfg.FencingMargin = cfg.DeathTimeout / 2
+		}
+	}
+	if cfg.FencingMargin >= cfg.DeathTimeout {
+		return errors.New("Fencing margin should be less than the death timeout")
+	}
 
 	return nil
 }
@@ -67,15 +90,25 @@ func New(cfg types.ScheduleConfig, store store.Store) (*ScheduleManager, error)
 	}
 	uuid := utils.GenerateUUID(seq)
 	s := &definition.Scheduler{
-		ID:      uuid,
-		Enabled: true,
+		ID:         uuid,
+		Enabled:    true,
+		Weight:     cfg.Weight,
+		MaxWorkers: cfg.MaxWorkers,
+	}
+	if len(cfg.Labels) > 0 {
+		s.Labels = make(map[string]string, len(cfg.Labels))
+		for k, v := range cfg.Labels {
+			s.Labels[k] = v
+		}
 	}
 
 	m := &ScheduleManager{
-		store:     store,
-		scheduler: s,
-		workerSet: u.NewWorkerSet(),
-		cfg:       cfg,
+		store:       store,
+		scheduler:   s,
+		workerSet:   u.NewWorkerSet(),
+		scheduleC:   make(chan struct{}, 1),
+		subscribers: make(map[chan Event]struct{}),
+		cfg:         cfg,
 	}
 	return m, nil
 }
@@ -96,21 +129,37 @@ func (s *ScheduleManager) Start() error {
 	}
 
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
+	s.fenceMu.Lock()
+	s.lastHeartbeat = time.Now()
+	s.fenceMu.Unlock()
 	s.wg.Add(2)
 	go utils.LoopContext(s.ctx,
 		s.cfg.HeartbeatInterval,
 		s.registerInfo,
 		func() {
 			defer s.wg.Done()
-			defer s.cleanScheduler(s.scheduler.ID)
+			defer s.cleanScheduler(s.store, s.scheduler.ID)
 		})
-	go utils.LoopContext(s.ctx,
+	go utils.LoopContextWithTrigger(s.ctx,
 		s.cfg.ScheduleInterval,
+		s.scheduleC,
 		s.schedule,
 		func() {
 			defer s.wg.Done()
 			defer s.stopAllWorkers()
 		})
+	if watcher, ok := store.AsWatcher(s.store); ok {
+		s.wg.Add(1)
+		go s.watch(watcher)
+	}
+	if s.cfg.FencingMargin > 0 {
+		// separated from heartbeat which may hang on storage
+		s.wg.Add(1)
+		go utils.LoopContext(s.ctx,
+			s.fencingCheckInterval(),
+			s.checkFencing,
+			s.wg.Done)
+	}
 	return nil
 }
 
@@ -119,7 +168,8 @@ func (s *ScheduleManager) Shutdown() {
 }
 
 func (s *ScheduleManager) cleanup() {
-	s.cleanScheduler(s.scheduler.ID)
+	s.cleanScheduler(s.store, s.scheduler.ID)
+	s.closeSubscribers()
 	log.Info("Manager has been shutdown")
 }
 
@@ -0,0 +1,224 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+// snapshot is a read-through cache over storage for a single schedule cycle.
+//	Strategies, schedulers and runtimes of each strategy are read at most once unless
+//	they are changed through it, which invalidates the related collection.
+//	Objects returned are deep copies so that modifying them never pollutes the cache.
+//	It's not safe for concurrent use and should be dropped after the cycle.
+type snapshot struct {
+	store.Store
+
+	strategies []*definition.Strategy
+	schedulers []*definition.Scheduler
+	runtimes   map[string][]*definition.StrategyRuntime
+}
+
+func newSnapshot(s store.Store) *snapshot {
+	return &snapshot{
+		Store:    s,
+		runtimes: make(map[string][]*definition.StrategyRuntime),
+	}
+}
+
+func copyStrings(arr []string) []string {
+	if arr == nil {
+		return nil
+	}
+	return append(make([]string, 0, len(arr)), arr...)
+}
+
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
+
+func copyStrategy(strategy *definition.Strategy) *definition.Strategy {
+	copied := *strategy
+	copied.IPList = copyStrings(strategy.IPList)
+	copied.Extra = copyMap(strategy.Extra)
+	if strategy.Selector != nil {
+		copied.Selector = make(definition.Selector, len(strategy.Selector))
+		for i, r := range strategy.Selector {
+			r.Values = copyStrings(r.Values)
+			copied.Selector[i] = r
+		}
+	}
+	return &copied
+}
+
+func copyScheduler(scheduler *definition.Scheduler) *definition.Scheduler {
+	copied := *scheduler
+	copied.Labels = copyMap(scheduler.Labels)
+	return &copied
+}
+
+func (s *snapshot) loadStrategies() error {
+	if s.strategies != nil {
+		return nil
+	}
+	list, err := s.Store.GetStrategies()
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		list = make([]*definition.Strategy, 0)
+	}
+	s.strategies = list
+	return nil
+}
+
+func (s *snapshot) GetStrategies() ([]*definition.Strategy, error) {
+	if err := s.loadStrategies(); err != nil {
+		return nil, err
+	}
+	result := make([]*definition.Strategy, len(s.strategies))
+	for i, strategy := range s.strategies {
+		result[i] = copyStrategy(strategy)
+	}
+	return result, nil
+}
+
+func (s *snapshot) GetStrategy(id string) (*definition.Strategy, error) {
+	if err := s.loadStrategies(); err != nil {
+		return nil, err
+	}
+	for _, strategy := range s.strategies {
+		if strategy.ID == id {
+			return copyStrategy(strategy), nil
+		}
+	}
+	return nil, store.NotExist
+}
+
+func (s *snapshot) CreateStrategy(strategy *definition.Strategy) error {
+	s.strategies = nil
+	return s.Store.CreateStrategy(strategy)
+}
+
+func (s *snapshot) UpdateStrategy(strategy *definition.Strategy) error {
+	s.strategies = nil
+	return s.Store.UpdateStrategy(strategy)
+}
+
+func (s *snapshot) RemoveStrategy(id string) error {
+	s.strategies = nil
+	delete(s.runtimes, id)
+	return s.Store.RemoveStrategy(id)
+}
+
+func (s *snapshot) loadSchedulers() error {
+	if s.schedulers != nil {
+		return nil
+	}
+	list, err := s.Store.GetSchedulers()
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		list = make([]*definition.Scheduler, 0)
+	}
+	s.schedulers = list
+	return nil
+}
+
+func (s *snapshot) GetSchedulers() ([]*definition.Scheduler, error) {
+	if err := s.loadSchedulers(); err != nil {
+		return nil, err
+	}
+	result := make([]*definition.Scheduler, len(s.schedulers))
+	for i, scheduler := range s.schedulers {
+		result[i] = copyScheduler(scheduler)
+	}
+	return result, nil
+}
+
+func (s *snapshot) GetScheduler(id string) (*definition.Scheduler, error) {
+	if err := s.loadSchedulers(); err != nil {
+		return nil, err
+	}
+	for _, scheduler := range s.schedulers {
+		if scheduler.ID == id {
+			return copyScheduler(scheduler), nil
+		}
+	}
+	return nil, store.NotExist
+}
+
+func (s *snapshot) RegisterScheduler(scheduler *definition.Scheduler) error {
+	s.schedulers = nil
+	return s.Store.RegisterScheduler(scheduler)
+}
+
+func (s *snapshot) UnregisterScheduler(id string) error {
+	s.schedulers = nil
+	return s.Store.UnregisterScheduler(id)
+}
+
+func (s *snapshot) loadRuntimes(strategyId string) ([]*definition.StrategyRuntime, error) {
+	if list, ok := s.runtimes[strategyId]; ok {
+		return list, nil
+	}
+	list, err := s.Store.GetStrategyRuntimes(strategyId)
+	if err != nil {
+		return nil, err
+	}
+	s.runtimes[strategyId] = list
+	return list, nil
+}
+
+func (s *snapshot) GetStrategyRuntimes(strategyId string) ([]*definition.StrategyRuntime, error) {
+	list, err := s.loadRuntimes(strategyId)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*definition.StrategyRuntime, len(list))
+	for i, runtime := range list {
+		copied := *runtime
+		result[i] = &copied
+	}
+	return result, nil
+}
+
+func (s *snapshot) GetStrategyRuntime(strategyId, schedulerId string) (*definition.StrategyRuntime, error) {
+	list, err := s.loadRuntimes(strategyId)
+	if err != nil {
+		return nil, err
+	}
+	for _, runtime := range list {
+		if runtime.SchedulerID == schedulerId {
+			copied := *runtime
+			return &copied, nil
+		}
+	}
+	return nil, store.NotExist
+}
+
+func (s *snapshot) SetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	delete(s.runtimes, runtime.StrategyID)
+	return s.Store.SetStrategyRuntime(runtime)
+}
+
+func (s *snapshot) CompareAndSetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	delete(s.runtimes, runtime.StrategyID)
+	return s.Store.CompareAndSetStrategyRuntime(runtime)
+}
+
+func (s *snapshot) RemoveStrategyRuntime(strategyId, schedulerId string) error {
+	delete(s.runtimes, strategyId)
+	return s.Store.RemoveStrategyRuntime(strategyId, schedulerId)
+}
@@ -0,0 +1,273 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/core/worker"
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/store/memory"
+	"github.com/jasonjoo2010/goschedule/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingStore counts reads of collections used in scheduling
+type countingStore struct {
+	*memory.MemoryStore
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func newCountingStore() *countingStore {
+	return &countingStore{
+		MemoryStore: memory.New(),
+		calls:       make(map[string]int),
+	}
+}
+
+func (s *countingStore) count(name string) {
+	s.mu.Lock()
+	s.calls[name]++
+	s.mu.Unlock()
+}
+
+func (s *countingStore) reset() {
+	s.mu.Lock()
+	s.calls = make(map[string]int)
+	s.mu.Unlock()
+}
+
+func (s *countingStore) get(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[name]
+}
+
+func (s *countingStore) total() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := 0
+	for _, cnt := range s.calls {
+		total += cnt
+	}
+	return total
+}
+
+func (s *countingStore) GetStrategies() ([]*definition.Strategy, error) {
+	s.count("GetStrategies")
+	return s.MemoryStore.GetStrategies()
+}
+
+func (s *countingStore) GetStrategy(id string) (*definition.Strategy, error) {
+	s.count("GetStrategy")
+	return s.MemoryStore.GetStrategy(id)
+}
+
+func (s *countingStore) GetSchedulers() ([]*definition.Scheduler, error) {
+	s.count("GetSchedulers")
+	return s.MemoryStore.GetSchedulers()
+}
+
+func (s *countingStore) GetScheduler(id string) (*definition.Scheduler, error) {
+	s.count("GetScheduler")
+	return s.MemoryStore.GetScheduler(id)
+}
+
+func (s *countingStore) GetStrategyRuntimes(strategyId string) ([]*definition.StrategyRuntime, error) {
+	s.count("GetStrategyRuntimes")
+	return s.MemoryStore.GetStrategyRuntimes(strategyId)
+}
+
+func (s *countingStore) GetStrategyRuntime(strategyId, schedulerId string) (*definition.StrategyRuntime, error) {
+	s.count("GetStrategyRuntime")
+	return s.MemoryStore.GetStrategyRuntime(strategyId, schedulerId)
+}
+
+type quietWorker struct{}
+
+func (w *quietWorker) Start(strategyId, parameter string) error { return nil }
+func (w *quietWorker) Stop(strategyId, parameter string) error  { return nil }
+
+func prepareSchedule(t testing.TB, s store.Store, n int) *ScheduleManager {
+	worker.RegisterName("quietWorker", &quietWorker{})
+	manager, err := New(types.ScheduleConfig{
+		ScheduleInterval:  100 * time.Millisecond,
+		HeartbeatInterval: 100 * time.Millisecond,
+		DeathTimeout:      time.Minute,
+	}, s)
+	assert.Nil(t, err)
+	manager.cfg.StallAfterStartup = 0
+	for i := 0; i < n; i++ {
+		s.CreateStrategy(&definition.Strategy{
+			ID:      "s" + strconv.Itoa(i),
+			IPList:  []string{"127.0.0.1"},
+			Kind:    definition.SimpleKind,
+			Bind:    "quietWorker",
+			Total:   1,
+			Enabled: true,
+		})
+	}
+	manager.registerInfo()
+	return manager
+}
+
+// scheduleDirectly schedules in the same steps as schedule() but without snapshot
+func scheduleDirectly(manager *ScheduleManager) {
+	manager.clearExpiredSchedulers(manager.store)
+	manager.generateRuntimes(manager.store)
+	manager.assign(manager.store)
+	manager.adjustWorkers(manager.store)
+}
+
+func TestSnapshot(t *testing.T) {
+	s := newCountingStore()
+	defer s.Close()
+	s.CreateStrategy(&definition.Strategy{ID: "s0"})
+	s.SetStrategyRuntime(&definition.StrategyRuntime{StrategyID: "s0", SchedulerID: "a"})
+
+	snapshot := newSnapshot(s)
+	for i := 0; i < 3; i++ {
+		strategies, err := snapshot.GetStrategies()
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(strategies))
+		strategy, err := snapshot.GetStrategy("s0")
+		assert.Nil(t, err)
+		assert.Equal(t, "s0", strategy.ID)
+		runtime, err := snapshot.GetStrategyRuntime("s0", "a")
+		assert.Nil(t, err)
+		// modifying returned objects doesn't affect the cache
+		runtime.RequestedNum = 100
+		runtimes, err := snapshot.GetStrategyRuntimes("s0")
+		assert.Nil(t, err)
+		assert.Equal(t, 0, runtimes[0].RequestedNum)
+	}
+	_, err := snapshot.GetStrategy("s1")
+	assert.Equal(t, store.NotExist, err)
+	_, err = snapshot.GetStrategyRuntime("s0", "b")
+	assert.Equal(t, store.NotExist, err)
+	_, err = snapshot.GetScheduler("a")
+	assert.Equal(t, store.NotExist, err)
+	assert.Equal(t, 1, s.get("GetStrategies"))
+	assert.Equal(t, 0, s.get("GetStrategy"))
+	assert.Equal(t, 1, s.get("GetStrategyRuntimes"))
+	assert.Equal(t, 0, s.get("GetStrategyRuntime"))
+	assert.Equal(t, 1, s.get("GetSchedulers"))
+
+	// writes invalidate
+	runtime, _ := snapshot.GetStrategyRuntime("s0", "a")
+	runtime.RequestedNum = 1
+	assert.Nil(t, snapshot.CompareAndSetStrategyRuntime(runtime))
+	runtime, _ = snapshot.GetStrategyRuntime("s0", "a")
+	assert.Equal(t, 1, runtime.RequestedNum)
+	assert.Equal(t, 2, s.get("GetStrategyRuntimes"))
+
+	assert.Nil(t, snapshot.RemoveStrategyRuntime("s0", "a"))
+	_, err = snapshot.GetStrategyRuntime("s0", "a")
+	assert.Equal(t, store.NotExist, err)
+
+	assert.Nil(t, snapshot.CreateStrategy(&definition.Strategy{ID: "s1"}))
+	strategies, _ := snapshot.GetStrategies()
+	assert.Equal(t, 2, len(strategies))
+	assert.Equal(t, 2, s.get("GetStrategies"))
+
+	assert.Nil(t, snapshot.RegisterScheduler(&definition.Scheduler{ID: "a"}))
+	scheduler, err := snapshot.GetScheduler("a")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", scheduler.ID)
+	assert.Equal(t, 2, s.get("GetSchedulers"))
+}
+
+func TestSnapshotDeepCopy(t *testing.T) {
+	s := newCountingStore()
+	defer s.Close()
+	s.CreateStrategy(&definition.Strategy{
+		ID:       "s0",
+		IPList:   []string{"127.0.0.1"},
+		Selector: definition.Selector{{Key: "zone", Operator: definition.SelectorIn, Values: []string{"a"}}},
+		Extra:    map[string]string{"Interval": "1"},
+	})
+	s.RegisterScheduler(&definition.Scheduler{ID: "a", Labels: map[string]string{"zone": "a"}})
+
+	snapshot := newSnapshot(s)
+	strategy, _ := snapshot.GetStrategy("s0")
+	strategy.IPList[0] = "polluted"
+	strategy.Selector[0].Values[0] = "polluted"
+	strategy.Extra["Interval"] = "polluted"
+	strategies, _ := snapshot.GetStrategies()
+	strategies[0].Selector[0].Key = "polluted"
+	scheduler, _ := snapshot.GetScheduler("a")
+	scheduler.Labels["zone"] = "polluted"
+
+	strategy, _ = snapshot.GetStrategy("s0")
+	assert.Equal(t, []string{"127.0.0.1"}, strategy.IPList)
+	assert.Equal(t, definition.Selector{{Key: "zone", Operator: definition.SelectorIn, Values: []string{"a"}}}, strategy.Selector)
+	assert.Equal(t, map[string]string{"Interval": "1"}, strategy.Extra)
+	schedulers, _ := snapshot.GetSchedulers()
+	assert.Equal(t, map[string]string{"zone": "a"}, schedulers[0].Labels)
+	assert.Equal(t, 1, s.get("GetStrategies"))
+	assert.Equal(t, 1, s.get("GetSchedulers"))
+}
+
+func TestScheduleReads(t *testing.T) {
+	s := newCountingStore()
+	defer s.Close()
+	n := 10
+	manager := prepareSchedule(t, s, n)
+	defer manager.stopAllWorkers()
+
+	// first cycle creates runtimes and workers
+	manager.schedule()
+	runtimes, _ := s.MemoryStore.GetStrategyRuntimes("s0")
+	assert.Equal(t, 1, len(runtimes))
+	assert.Equal(t, 1, runtimes[0].Num)
+
+	// stable
+	s.reset()
+	manager.schedule()
+	assert.Equal(t, 1, s.get("GetStrategies"))
+	assert.Equal(t, 1, s.get("GetSchedulers"))
+	assert.Equal(t, n, s.get("GetStrategyRuntimes"))
+	assert.Equal(t, 0, s.get("GetStrategyRuntime"))
+	assert.Equal(t, 0, s.get("GetScheduler"))
+
+	s.reset()
+	scheduleDirectly(manager)
+	assert.Equal(t, 4, s.get("GetStrategies"))
+	assert.Equal(t, 4*n, s.get("GetStrategyRuntimes"))
+	assert.Equal(t, 2*n, s.get("GetStrategyRuntime"))
+}
+
+func benchmarkSchedule(b *testing.B, cached bool) {
+	s := newCountingStore()
+	defer s.Close()
+	manager := prepareSchedule(b, s, 50)
+	defer manager.stopAllWorkers()
+	manager.schedule()
+
+	s.reset()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if cached {
+			manager.schedule()
+		} else {
+			scheduleDirectly(manager)
+		}
+	}
+	b.ReportMetric(float64(s.total())/float64(b.N), "reads/op")
+}
+
+func BenchmarkSchedule(b *testing.B) {
+	b.Run("Direct", func(b *testing.B) {
+		benchmarkSchedule(b, false)
+	})
+	b.Run("Snapshot", func(b *testing.B) {
+		benchmarkSchedule(b, true)
+	})
+}
@@ -3,17 +3,20 @@ package utils
 import (
 	"sync"
 
+	"github.com/jasonjoo2010/goschedule/definition"
 	"github.com/jasonjoo2010/goschedule/types"
 )
 
 type WorkerSet struct {
-	mu      sync.Mutex
-	workers map[string][]types.Worker
+	mu         sync.Mutex
+	workers    map[string][]types.Worker
+	strategies map[string]definition.Strategy // strategies workers are started with
 }
 
 func NewWorkerSet() *WorkerSet {
 	return &WorkerSet{
-		workers: make(map[string][]types.Worker),
+		workers:    make(map[string][]types.Worker),
+		strategies: make(map[string]definition.Strategy),
 	}
 }
 
@@ -47,11 +50,25 @@ func (set *WorkerSet) WorkersFor(strategyName string) []types.Worker {
 	return result
 }
 
+// StrategyFor returns the strategy which workers were started with, nil if there is no worker of it.
+//	It's held locally so workers can be stopped without accessing storage.
+func (set *WorkerSet) StrategyFor(strategyName string) *definition.Strategy {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	strategy, ok := set.strategies[strategyName]
+	if !ok {
+		return nil
+	}
+	return &strategy
+}
+
 func (set *WorkerSet) Delete(strategyName string) {
 	set.mu.Lock()
 	defer set.mu.Unlock()
 
 	delete(set.workers, strategyName)
+	delete(set.strategies, strategyName)
 }
 
 func (set *WorkerSet) WorkersCountFor(strategyName string) int {
@@ -61,17 +78,30 @@ func (set *WorkerSet) WorkersCountFor(strategyName string) int {
 	return len(set.workers[strategyName])
 }
 
-func (set *WorkerSet) AddWorker(strategyName string, w types.Worker) {
+// Count returns the count of workers of all strategies
+func (set *WorkerSet) Count() int {
 	set.mu.Lock()
 	defer set.mu.Unlock()
 
-	workers, ok := set.workers[strategyName]
+	cnt := 0
+	for _, workers := range set.workers {
+		cnt += len(workers)
+	}
+	return cnt
+}
+
+func (set *WorkerSet) AddWorker(strategy *definition.Strategy, w types.Worker) {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	workers, ok := set.workers[strategy.ID]
 	if !ok {
 		workers = make([]types.Worker, 0, 1)
 	}
 
 	workers = append(workers, w)
-	set.workers[strategyName] = workers
+	set.workers[strategy.ID] = workers
+	set.strategies[strategy.ID] = *strategy
 }
 
 func (set *WorkerSet) RemoveWorker(strategyName string) types.Worker {
@@ -87,6 +117,7 @@ func (set *WorkerSet) RemoveWorker(strategyName string) types.Worker {
 	workers = workers[:len(workers)-1]
 	if len(workers) == 0 {
 		delete(set.workers, strategyName)
+		delete(set.strategies, strategyName)
 	} else {
 		set.workers[strategyName] = workers
 	}
@@ -0,0 +1,32 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"github.com/jasonjoo2010/goschedule/log"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/utils"
+)
+
+// watch triggers an immediate rescheduling when related changes happen in storage.
+//	Polling in schedule loop is still working as the fallback.
+func (s *ScheduleManager) watch(watcher store.Watcher) {
+	defer s.wg.Done()
+
+	events, err := watcher.Watch(s.ctx)
+	if err != nil {
+		log.Warnf("Watch store failed, fallback to polling: %s", err.Error())
+		return
+	}
+	for e := range events {
+		switch e.Kind {
+		case store.SchedulerEvent,
+			store.TaskEvent,
+			store.StrategyEvent,
+			store.StrategyRuntimeEvent:
+			utils.Trigger(s.scheduleC)
+		}
+	}
+}
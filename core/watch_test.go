@@ -0,0 +1,42 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchTriggerSchedule(t *testing.T) {
+	store := memory.New()
+	defer func() {
+		assert.Nil(t, store.Close())
+	}()
+	manager := newManager(t, store)
+	// make sure it's not triggered by polling
+	manager.cfg.ScheduleInterval = time.Hour
+
+	assert.Nil(t, manager.Start())
+	time.Sleep(200 * time.Millisecond)
+
+	list, _ := store.GetStrategyRuntimes("s0")
+	assert.Equal(t, 0, len(list))
+
+	store.CreateStrategy(&definition.Strategy{
+		ID:      "s0",
+		IPList:  []string{"localhost"},
+		Enabled: true,
+	})
+	time.Sleep(200 * time.Millisecond)
+
+	list, _ = store.GetStrategyRuntimes("s0")
+	assert.Equal(t, 1, len(list))
+
+	assert.Nil(t, manager.Close())
+}
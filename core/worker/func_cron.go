@@ -0,0 +1,137 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/log"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/utils"
+)
+
+// cronRunner runs the func for fire times according to overlap policy
+type cronRunner struct {
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	running bool
+	pending int
+	run     func()
+}
+
+func (r *cronRunner) loop() {
+	defer r.wg.Done()
+	for {
+		r.run()
+		r.mu.Lock()
+		if r.pending == 0 {
+			r.running = false
+			r.mu.Unlock()
+			return
+		}
+		r.pending--
+		r.mu.Unlock()
+	}
+}
+
+// busy returns whether a run is going or pending
+func (r *cronRunner) busy() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running
+}
+
+// submit requests runs for fire times
+func (r *cronRunner) submit(runs int, policy definition.OverlapPolicy) {
+	if runs <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if policy == definition.OverlapConcurrent {
+		for i := 0; i < runs; i++ {
+			r.wg.Add(1)
+			go func() {
+				defer r.wg.Done()
+				r.run()
+			}()
+		}
+		return
+	}
+	if r.running {
+		if policy == definition.OverlapQueue {
+			if r.pending < 1 {
+				r.pending = 1
+			}
+		} else {
+			log.Infof("Previous run is still going, skip the fire time")
+		}
+		return
+	}
+	r.pending += runs - 1
+	r.running = true
+	r.wg.Add(1)
+	go r.loop()
+}
+
+func (w *FuncWorker) lastFire() time.Time {
+	if millis := store.GetLastFireTime(w.store, w.strategyId); millis > 0 {
+		return time.Unix(0, millis*int64(time.Millisecond))
+	}
+	return time.Time{}
+}
+
+func (w *FuncWorker) fired(t time.Time) {
+	if err := store.SetLastFireTime(w.store, w.strategyId, t.UnixNano()/int64(time.Millisecond)); err != nil {
+		log.Warnf("Persist last fire time of strategy %s failed: %s", w.strategyId, err.Error())
+	}
+}
+
+// fireLoop runs the func at every fire time of CronBegin applying overlap and misfire policies
+func (w *FuncWorker) fireLoop(ctx context.Context) {
+	runner := &cronRunner{
+		run: func() {
+			if utils.ContextDone(ctx) {
+				return
+			}
+			w.invoke(ctx)
+			utils.DelayContext(ctx, w.interval)
+		},
+	}
+	defer runner.wg.Wait()
+
+	now := time.Now()
+	last := w.lastFire()
+	if last.IsZero() {
+		last = now
+	} else {
+		missed, latest := utils.CronMissed(w.schedBegin, last, now, definition.MaxMisfires)
+		if missed > 0 {
+			log.Infof("Strategy %s missed %d fire time(s) since %v", w.strategyId, missed, last)
+			last = latest
+			if w.take(last) {
+				runner.submit(w.misfire.Runs(missed), definition.OverlapQueue)
+			}
+		}
+	}
+	for {
+		next := w.schedBegin.Next(last)
+		if next.IsZero() || !utils.DelayContext(ctx, time.Until(next)) {
+			return
+		}
+		// fire times passed during a late wakeup are merged
+		_, last = utils.CronMissed(w.schedBegin, next, time.Now(), definition.MaxMisfires)
+		if w.claims != nil && w.overlap == definition.OverlapSkip && runner.busy() {
+			// leave it to other schedulers
+			continue
+		}
+		if w.take(last) {
+			runner.submit(1, w.overlap)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/store/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBlockingRunner(counter *int32, release chan struct{}) *cronRunner {
+	return &cronRunner{
+		run: func() {
+			atomic.AddInt32(counter, 1)
+			<-release
+		},
+	}
+}
+
+func TestCronRunnerOverlap(t *testing.T) {
+	var cnt int32
+	release := make(chan struct{})
+	r := newBlockingRunner(&cnt, release)
+	r.submit(1, definition.OverlapSkip)
+	r.submit(1, definition.OverlapSkip)
+	r.submit(1, definition.OverlapSkip)
+	close(release)
+	r.wg.Wait()
+	assert.Equal(t, int32(1), cnt)
+
+	cnt = 0
+	release = make(chan struct{})
+	r = newBlockingRunner(&cnt, release)
+	r.submit(1, definition.OverlapQueue)
+	r.submit(1, definition.OverlapQueue)
+	r.submit(1, definition.OverlapQueue)
+	close(release)
+	r.wg.Wait()
+	assert.Equal(t, int32(2), cnt)
+
+	cnt = 0
+	release = make(chan struct{})
+	r = newBlockingRunner(&cnt, release)
+	r.submit(1, definition.OverlapConcurrent)
+	r.submit(1, definition.OverlapConcurrent)
+	r.submit(1, definition.OverlapConcurrent)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&cnt))
+	close(release)
+	r.wg.Wait()
+}
+
+func TestFuncWorkerMisfire(t *testing.T) {
+	s := memory.New()
+	defer s.Close()
+	var cnt int32
+	RegisterFuncContext("demoMisfire", func(ctx context.Context, strategyId, parameter string) {
+		atomic.AddInt32(&cnt, 1)
+	})
+	strategy := definition.Strategy{
+		ID:          "s-misfire",
+		Kind:        definition.FuncKind,
+		Bind:        "demoMisfire",
+		CronBegin:   "0 0 0 1 1 ?",
+		CronMisfire: definition.MisfireFireAll,
+	}
+	// missed 3 fire times
+	last := time.Date(time.Now().Year()-3, 1, 1, 0, 0, 0, 0, time.Local)
+	store.SetLastFireTime(s, strategy.ID, last.UnixNano()/int64(time.Millisecond))
+	w, err := NewFuncWithStore(strategy, s)
+	assert.Nil(t, err)
+	w.Start(strategy.ID, strategy.Parameter)
+	time.Sleep(100 * time.Millisecond)
+	w.Stop(strategy.ID, strategy.Parameter)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&cnt))
+	fired := time.Date(time.Now().Year(), 1, 1, 0, 0, 0, 0, time.Local)
+	assert.Equal(t, fired.UnixNano()/int64(time.Millisecond), store.GetLastFireTime(s, strategy.ID))
+
+	// restarted without missing
+	atomic.StoreInt32(&cnt, 0)
+	strategy.CronMisfire = definition.MisfireFireOnce
+	w, _ = NewFuncWithStore(strategy, s)
+	w.Start(strategy.ID, strategy.Parameter)
+	time.Sleep(100 * time.Millisecond)
+	w.Stop(strategy.ID, strategy.Parameter)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&cnt))
+
+	// fire once
+	store.SetLastFireTime(s, strategy.ID, last.UnixNano()/int64(time.Millisecond))
+	w, _ = NewFuncWithStore(strategy, s)
+	w.Start(strategy.ID, strategy.Parameter)
+	time.Sleep(100 * time.Millisecond)
+	w.Stop(strategy.ID, strategy.Parameter)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&cnt))
+
+	// ignore
+	atomic.StoreInt32(&cnt, 0)
+	strategy.CronMisfire = definition.MisfireIgnore
+	store.SetLastFireTime(s, strategy.ID, last.UnixNano()/int64(time.Millisecond))
+	w, _ = NewFuncWithStore(strategy, s)
+	w.Start(strategy.ID, strategy.Parameter)
+	time.Sleep(100 * time.Millisecond)
+	w.Stop(strategy.ID, strategy.Parameter)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&cnt))
+}
+
+func TestFuncWorkerFire(t *testing.T) {
+	s := memory.New()
+	defer s.Close()
+	var cnt int32
+	RegisterFuncContext("demoFire", func(ctx context.Context, strategyId, parameter string) {
+		atomic.AddInt32(&cnt, 1)
+	})
+	strategy := definition.Strategy{
+		ID:        "s-fire",
+		Kind:      definition.FuncKind,
+		Bind:      "demoFire",
+		CronBegin: "* * * * * ?",
+	}
+	w, _ := NewFuncWithStore(strategy, s)
+	w.Start(strategy.ID, strategy.Parameter)
+	time.Sleep(2100 * time.Millisecond)
+	w.Stop(strategy.ID, strategy.Parameter)
+	assert.True(t, atomic.LoadInt32(&cnt) >= 2)
+	assert.True(t, store.GetLastFireTime(s, strategy.ID) > 0)
+}
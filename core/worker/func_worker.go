@@ -13,6 +13,7 @@ import (
 
 	"github.com/jasonjoo2010/goschedule/definition"
 	"github.com/jasonjoo2010/goschedule/log"
+	"github.com/jasonjoo2010/goschedule/store"
 	"github.com/jasonjoo2010/goschedule/types"
 	"github.com/jasonjoo2010/goschedule/utils"
 	"github.com/robfig/cron/v3"
@@ -29,25 +30,42 @@ type FuncWorker struct {
 
 	strategyId string
 	parameter  string
-	fn         types.FuncInterface
+	fn         types.FuncContextInterface
+	store      store.Store          // used to persist last fire time, optional
+	claims     store.FireClaimStore // used to claim fire times by SingletonKind
 
 	schedBegin cron.Schedule
 	schedEnd   cron.Schedule
 	interval   time.Duration
+	timeout    time.Duration
+	overlap    definition.OverlapPolicy
+	misfire    definition.MisfirePolicy
 }
 
 func NewFunc(strategy definition.Strategy) (types.Worker, error) {
+	return NewFuncWithStore(strategy, nil)
+}
+
+// NewFuncWithStore creates a func worker which persists the last fire time of CronBegin into storage
+//	so that misfires can be detected after restarting or moving.
+func NewFuncWithStore(strategy definition.Strategy, s store.Store) (types.Worker, error) {
 	if strategy.Kind != definition.FuncKind {
 		return nil, errors.New("Wrong kind of strategy, should be FuncKind")
 	}
+	return newFunc(strategy, s)
+}
 
-	fn := GetFunc(strategy.Bind)
+func newFunc(strategy definition.Strategy, s store.Store) (*FuncWorker, error) {
+	fn := GetFuncContext(strategy.Bind)
 	if fn == nil {
 		return nil, errors.New("Could not get the binding func")
 	}
 
 	w := &FuncWorker{
-		fn: fn,
+		fn:      fn,
+		store:   s,
+		overlap: strategy.CronOverlap,
+		misfire: strategy.CronMisfire,
 	}
 
 	w.schedBegin, w.schedEnd = utils.ParseStrategyCron(&strategy)
@@ -57,15 +75,40 @@ func NewFunc(strategy definition.Strategy) (types.Worker, error) {
 				w.interval = time.Duration(millis) * time.Millisecond
 			}
 		}
+		if millisStr, ok := strategy.Extra["Timeout"]; ok {
+			if millis, err := strconv.Atoi(millisStr); err == nil && millis > 0 {
+				w.timeout = time.Duration(millis) * time.Millisecond
+			}
+		}
 	}
 
 	log.Infof("Create a func worker, cron=%v, interval=%v", w.schedBegin, w.interval)
 	return w, nil
 }
 
+// invoke calls the func once with a context cancelled when stopping or timeout is reached
+func (w *FuncWorker) invoke(ctx context.Context) {
+	var cancel context.CancelFunc
+	if w.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, w.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+	w.fn(ctx, w.strategyId, w.parameter)
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Warnf("Func of strategy %s exceeded the deadline of %v", w.strategyId, w.timeout)
+	}
+}
+
 func (w *FuncWorker) FuncExecutor(ctx context.Context) {
 	defer w.wg.Done()
 
+	if w.schedBegin != nil && w.schedEnd == nil {
+		w.fireLoop(ctx)
+		return
+	}
+
 LOOP:
 	for {
 		// cron
@@ -74,7 +117,7 @@ LOOP:
 			break LOOP
 		}
 
-		w.fn(w.strategyId, w.parameter)
+		w.invoke(ctx)
 
 		if !utils.DelayContext(ctx, w.interval) {
 			break LOOP
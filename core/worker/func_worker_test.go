@@ -5,7 +5,9 @@
 package worker
 
 import (
+	"context"
 	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -54,3 +56,48 @@ func TestFuncWorkerWithCron(t *testing.T) {
 	assert.True(t, counter >= 3)
 	w.Stop(strategy.ID, strategy.Parameter)
 }
+
+func TestFuncWorkerContext(t *testing.T) {
+	var calls, timeouts int32
+	RegisterFuncContext("demoContext", func(ctx context.Context, strategyId, parameter string) {
+		atomic.AddInt32(&calls, 1)
+		<-ctx.Done()
+		if ctx.Err() == context.DeadlineExceeded {
+			atomic.AddInt32(&timeouts, 1)
+		}
+	})
+	strategy := definition.Strategy{
+		ID:   "s0",
+		Kind: definition.FuncKind,
+		Bind: "demoContext",
+		Extra: map[string]string{
+			"Interval": "10",
+			"Timeout":  "100",
+		},
+	}
+	w, err := NewFunc(strategy)
+	assert.Nil(t, err)
+	w.Start(strategy.ID, strategy.Parameter)
+	time.Sleep(250 * time.Millisecond)
+	assert.True(t, atomic.LoadInt32(&timeouts) >= 2)
+
+	// stopping interrupts the hanging call
+	strategy.Extra = nil
+	w, _ = NewFunc(strategy)
+	w.Start(strategy.ID, strategy.Parameter)
+	time.Sleep(50 * time.Millisecond)
+	t0 := time.Now()
+	w.Stop(strategy.ID, strategy.Parameter)
+	assert.True(t, time.Since(t0) < time.Second)
+}
+
+func TestFuncWorkerAdapted(t *testing.T) {
+	var called int32
+	RegisterFunc("demoAdapted", func(strategyId, parameter string) {
+		atomic.StoreInt32(&called, 1)
+	})
+	assert.NotNil(t, GetFuncContext("demoAdapted"))
+	GetFuncContext("demoAdapted")(context.Background(), "s0", "")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&called))
+	assert.Nil(t, GetFuncContext("notExisted"))
+}
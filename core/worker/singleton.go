@@ -0,0 +1,64 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/log"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/types"
+)
+
+// FireClaimRetention is how long claims of fire times are kept before the latest one.
+//	Claims are only kept to reject late claimers so it should be far longer than possible delays.
+const FireClaimRetention = 10 * time.Minute
+
+// NewSingleton creates a func worker for SingletonKind strategy. Every scheduler runs such a worker
+//	and each fire time of CronBegin is claimed in storage first, so only one of them runs it.
+func NewSingleton(strategy definition.Strategy, s store.Store) (types.Worker, error) {
+	if strategy.Kind != definition.SingletonKind {
+		return nil, errors.New("Wrong kind of strategy, should be SingletonKind")
+	}
+	if strategy.CronBegin == "" || strategy.CronEnd != "" {
+		return nil, errors.New("SingletonKind requires CronBegin only")
+	}
+	claims, ok := store.AsFireClaimStore(s)
+	if !ok {
+		return nil, errors.New("Storage doesn't support claiming fire times")
+	}
+	w, err := newFunc(strategy, s)
+	if err != nil {
+		return nil, err
+	}
+	if w.schedBegin == nil {
+		return nil, errors.New("Illegal CronBegin: " + strategy.CronBegin)
+	}
+	w.claims = claims
+	return w, nil
+}
+
+// take decides whether the fire time should be run by current worker and persists it if so.
+//	Fire times are always taken by FuncKind while SingletonKind should claim them first.
+func (w *FuncWorker) take(t time.Time) bool {
+	if w.claims != nil {
+		millis := t.UnixNano() / int64(time.Millisecond)
+		err := w.claims.ClaimFireTime(w.strategyId, millis)
+		if err == store.AlreadyExist {
+			return false
+		}
+		if err != nil {
+			log.Warnf("Claim fire time %v of strategy %s failed: %s", t, w.strategyId, err.Error())
+			return false
+		}
+		if err := w.claims.RemoveFireClaims(w.strategyId, millis-FireClaimRetention.Milliseconds()); err != nil {
+			log.Warnf("Remove old fire claims of strategy %s failed: %s", w.strategyId, err.Error())
+		}
+	}
+	w.fired(t)
+	return true
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/store/memory"
+	"github.com/jasonjoo2010/goschedule/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSingleton(t *testing.T) {
+	s := memory.New()
+	defer s.Close()
+	RegisterFuncContext("demoSingleton", func(ctx context.Context, strategyId, parameter string) {})
+	strategy := definition.Strategy{
+		ID:        "s-singleton",
+		Kind:      definition.SingletonKind,
+		Bind:      "demoSingleton",
+		CronBegin: "* * * * * ?",
+	}
+	_, err := NewSingleton(strategy, s)
+	assert.Nil(t, err)
+
+	// claims are not supported
+	_, err = NewSingleton(strategy, nil)
+	assert.NotNil(t, err)
+
+	// range of cron
+	strategy.CronEnd = "* * * * * ?"
+	_, err = NewSingleton(strategy, s)
+	assert.NotNil(t, err)
+
+	strategy.CronEnd = ""
+	strategy.CronBegin = ""
+	_, err = NewSingleton(strategy, s)
+	assert.NotNil(t, err)
+
+	strategy.CronBegin = "* * * * * ?"
+	strategy.Kind = definition.FuncKind
+	_, err = NewSingleton(strategy, s)
+	assert.NotNil(t, err)
+}
+
+func startSingletons(t *testing.T, strategy definition.Strategy, s store.Store, n int) []types.Worker {
+	workers := make([]types.Worker, n)
+	for i := range workers {
+		w, err := NewSingleton(strategy, s)
+		assert.Nil(t, err)
+		assert.Nil(t, w.Start(strategy.ID, strategy.Parameter))
+		workers[i] = w
+	}
+	return workers
+}
+
+func stopSingletons(strategy definition.Strategy, workers []types.Worker) {
+	for _, w := range workers {
+		w.Stop(strategy.ID, strategy.Parameter)
+	}
+}
+
+func TestSingletonFire(t *testing.T) {
+	s := memory.New()
+	defer s.Close()
+	var cnt int32
+	RegisterFuncContext("demoSingletonFire", func(ctx context.Context, strategyId, parameter string) {
+		atomic.AddInt32(&cnt, 1)
+	})
+	strategy := definition.Strategy{
+		ID:        "s-singleton-fire",
+		Kind:      definition.SingletonKind,
+		Bind:      "demoSingletonFire",
+		CronBegin: "* * * * * ?",
+	}
+	workers := startSingletons(t, strategy, s, 3)
+	time.Sleep(2500 * time.Millisecond)
+	stopSingletons(strategy, workers)
+	// once per fire time whatever count of workers is
+	fires := atomic.LoadInt32(&cnt)
+	assert.True(t, fires >= 2 && fires <= 3, "fired %d times", fires)
+	assert.True(t, store.GetLastFireTime(s, strategy.ID) > 0)
+}
+
+func TestSingletonMisfire(t *testing.T) {
+	s := memory.New()
+	defer s.Close()
+	var cnt int32
+	RegisterFuncContext("demoSingletonMisfire", func(ctx context.Context, strategyId, parameter string) {
+		atomic.AddInt32(&cnt, 1)
+	})
+	strategy := definition.Strategy{
+		ID:          "s-singleton-misfire",
+		Kind:        definition.SingletonKind,
+		Bind:        "demoSingletonMisfire",
+		CronBegin:   "0 0 0 1 1 ?",
+		CronMisfire: definition.MisfireFireAll,
+	}
+	last := time.Date(time.Now().Year()-3, 1, 1, 0, 0, 0, 0, time.Local)
+	store.SetLastFireTime(s, strategy.ID, last.UnixNano()/int64(time.Millisecond))
+	workers := startSingletons(t, strategy, s, 3)
+	time.Sleep(100 * time.Millisecond)
+	stopSingletons(strategy, workers)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&cnt))
+
+	// the claim is kept
+	fired := time.Date(time.Now().Year(), 1, 1, 0, 0, 0, 0, time.Local)
+	assert.Equal(t, store.AlreadyExist, s.ClaimFireTime(strategy.ID, fired.UnixNano()/int64(time.Millisecond)))
+}
@@ -0,0 +1,89 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package task_worker
+
+import (
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// A run of firing lasts from the fire time of CronBegin until nothing selected.
+
+func (w *TaskWorker) fired(t time.Time) {
+	w.lastFire = t
+	if err := store.SetLastFireTime(w.store, w.strategyDefine.ID, t.UnixNano()/int64(time.Millisecond)); err != nil {
+		logrus.Warn("Persist last fire time failed: ", err.Error())
+	}
+}
+
+// initFire loads the last fire time persisted and applies misfire policy
+func (w *TaskWorker) initFire() {
+	w.fireLoaded = true
+	now := time.Now()
+	millis := store.GetLastFireTime(w.store, w.strategyDefine.ID)
+	if millis <= 0 {
+		w.lastFire = now
+		return
+	}
+	w.lastFire = time.Unix(0, millis*int64(time.Millisecond))
+	missed, latest := utils.CronMissed(w.schedStart, w.lastFire, now, definition.MaxMisfires)
+	if missed > 0 {
+		logrus.Info("Missed ", missed, " fire time(s) since ", w.lastFire)
+		w.pendingFires = w.strategyDefine.CronMisfire.Runs(missed)
+		w.fired(latest)
+	}
+}
+
+// waitFire waits until next fire time of CronBegin and starts a run
+func (w *TaskWorker) waitFire() {
+	if !w.fireLoaded {
+		w.initFire()
+	}
+	if w.pendingFires > 0 {
+		w.pendingFires--
+		w.inCron = true
+		return
+	}
+	next := w.schedStart.Next(w.lastFire)
+	if next.IsZero() {
+		return
+	}
+	if delay := time.Until(next); delay > 0 {
+		w.NextBeginTime = next.UnixNano() / int64(time.Millisecond)
+		ok := utils.DelayContext(w.ctx, delay)
+		w.NextBeginTime = 0
+		if !ok {
+			return
+		}
+	}
+	// fire times passed during a late wakeup are merged
+	_, latest := utils.CronMissed(w.schedStart, next, time.Now(), definition.MaxMisfires)
+	w.fired(latest)
+	w.inCron = true
+}
+
+// runFinished handles fire times passed during the run according to overlap policy
+func (w *TaskWorker) runFinished() {
+	if w.schedStart == nil || w.schedEnd != nil {
+		return
+	}
+	missed, latest := utils.CronMissed(w.schedStart, w.lastFire, time.Now(), definition.MaxMisfires)
+	if missed == 0 {
+		return
+	}
+	w.fired(latest)
+	if w.strategyDefine.CronOverlap == definition.OverlapSkip {
+		logrus.Info("Skip ", missed, " fire time(s) during running")
+		return
+	}
+	// selecting is serial so concurrent runs are queued too
+	if w.pendingFires < 1 {
+		w.pendingFires = 1
+	}
+}
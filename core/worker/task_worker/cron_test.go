@@ -0,0 +1,100 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package task_worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/store/memory"
+	"github.com/jasonjoo2010/goschedule/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCronWorker(s store.Store, cronBegin string, overlap definition.OverlapPolicy, misfire definition.MisfirePolicy) *TaskWorker {
+	w := &TaskWorker{
+		store: s,
+		strategyDefine: definition.Strategy{
+			ID:          "s-cron",
+			CronBegin:   cronBegin,
+			CronOverlap: overlap,
+			CronMisfire: misfire,
+		},
+	}
+	w.schedStart, w.schedEnd = utils.ParseStrategyCron(&w.strategyDefine)
+	w.ctx, w.ctxCancel = context.WithCancel(context.Background())
+	return w
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func TestCronMisfire(t *testing.T) {
+	s := memory.New()
+	defer s.Close()
+	last := time.Date(time.Now().Year()-3, 1, 1, 0, 0, 0, 0, time.Local)
+	latest := time.Date(time.Now().Year(), 1, 1, 0, 0, 0, 0, time.Local)
+
+	// never fired
+	w := newCronWorker(s, "0 0 0 1 1 ?", definition.OverlapSkip, definition.MisfireFireAll)
+	w.initFire()
+	assert.Equal(t, 0, w.pendingFires)
+
+	store.SetLastFireTime(s, "s-cron", millis(last))
+	w = newCronWorker(s, "0 0 0 1 1 ?", definition.OverlapSkip, definition.MisfireFireAll)
+	w.initFire()
+	assert.Equal(t, 3, w.pendingFires)
+	assert.Equal(t, millis(latest), store.GetLastFireTime(s, "s-cron"))
+	for i := 0; i < 3; i++ {
+		w.inCron = false
+		w.waitFire()
+		assert.True(t, w.inCron)
+	}
+	assert.Equal(t, 0, w.pendingFires)
+
+	store.SetLastFireTime(s, "s-cron", millis(last))
+	w = newCronWorker(s, "0 0 0 1 1 ?", definition.OverlapSkip, definition.MisfireFireOnce)
+	w.initFire()
+	assert.Equal(t, 1, w.pendingFires)
+
+	store.SetLastFireTime(s, "s-cron", millis(last))
+	w = newCronWorker(s, "0 0 0 1 1 ?", definition.OverlapSkip, definition.MisfireIgnore)
+	w.initFire()
+	assert.Equal(t, 0, w.pendingFires)
+	assert.Equal(t, latest, w.lastFire)
+
+	// waiting for next year is interrupted by stopping
+	w.ctxCancel()
+	w.waitFire()
+	assert.False(t, w.inCron)
+}
+
+func TestCronOverlap(t *testing.T) {
+	s := memory.New()
+	defer s.Close()
+	for _, overlap := range []definition.OverlapPolicy{definition.OverlapSkip, definition.OverlapQueue, definition.OverlapConcurrent} {
+		w := newCronWorker(s, "* * * * * ?", overlap, definition.MisfireIgnore)
+		s.RemoveStrategy("s-cron")
+		w.waitFire()
+		assert.True(t, w.inCron)
+		fired := w.lastFire
+		assert.Equal(t, millis(fired), store.GetLastFireTime(s, "s-cron"))
+
+		// a long run
+		time.Sleep(2100 * time.Millisecond)
+		w.inCron = false
+		w.runFinished()
+		assert.True(t, w.lastFire.After(fired))
+		if overlap == definition.OverlapSkip {
+			assert.Equal(t, 0, w.pendingFires)
+		} else {
+			assert.Equal(t, 1, w.pendingFires)
+		}
+	}
+}
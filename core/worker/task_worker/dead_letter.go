@@ -0,0 +1,119 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package task_worker
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// DeadLetterStorage is the reserved name of sink which saves dead letters into storage of scheduler,
+//	storage should implement store.DeadLetterStore.
+const DeadLetterStorage = "store"
+
+var (
+	deadLetterSinkMap sync.Map
+)
+
+// DeadLetterSink receives task data which still failed after all attempts
+type DeadLetterSink interface {
+	Put(letter *definition.DeadLetter) error
+}
+
+// RegisterDeadLetterSink registers a sink with given key which can be referred by Task.DeadLetter
+func RegisterDeadLetterSink(name string, sink DeadLetterSink) {
+	if name == "" || name == DeadLetterStorage {
+		panic("Could not register a dead-letter sink using empty or reserved name")
+	}
+	if sink == nil {
+		panic("Could not register a dead-letter sink using nil as value")
+	}
+	deadLetterSinkMap.Store(name, sink)
+	logrus.Info("Register a dead-letter sink: ", name)
+}
+
+func getDeadLetterSink(name string, s store.Store) (DeadLetterSink, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if name == DeadLetterStorage {
+		return NewStoreDeadLetterSink(s), nil
+	}
+	if v, ok := deadLetterSinkMap.Load(name); ok {
+		return v.(DeadLetterSink), nil
+	}
+	return nil, errors.New("No dead-letter sink registered for key: " + name)
+}
+
+// MemoryDeadLetterSink keeps the latest dead letters in memory
+type MemoryDeadLetterSink struct {
+	mu       sync.Mutex
+	capacity int
+	letters  []*definition.DeadLetter
+}
+
+// NewMemoryDeadLetterSink creates a sink keeping at most capacity letters, the oldest ones are dropped first.
+//	capacity = 0 indicates no limit at all
+func NewMemoryDeadLetterSink(capacity int) *MemoryDeadLetterSink {
+	return &MemoryDeadLetterSink{
+		capacity: capacity,
+		letters:  make([]*definition.DeadLetter, 0),
+	}
+}
+
+func (s *MemoryDeadLetterSink) Put(letter *definition.DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.letters = append(s.letters, letter)
+	if s.capacity > 0 && len(s.letters) > s.capacity {
+		s.letters = s.letters[len(s.letters)-s.capacity:]
+	}
+	return nil
+}
+
+// Letters returns dead letters received in order
+func (s *MemoryDeadLetterSink) Letters() []*definition.DeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*definition.DeadLetter{}, s.letters...)
+}
+
+// Drain returns dead letters received in order and removes them from sink
+func (s *MemoryDeadLetterSink) Drain() []*definition.DeadLetter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	letters := s.letters
+	s.letters = make([]*definition.DeadLetter, 0)
+	return letters
+}
+
+// StoreDeadLetterSink saves dead letters into storage
+type StoreDeadLetterSink struct {
+	store store.Store
+}
+
+func NewStoreDeadLetterSink(s store.Store) *StoreDeadLetterSink {
+	return &StoreDeadLetterSink{store: s}
+}
+
+func (s *StoreDeadLetterSink) Put(letter *definition.DeadLetter) error {
+	deadLetters, ok := store.AsDeadLetterStore(s.store)
+	if !ok {
+		return errors.New("Dead letters are not supported by storage: " + s.store.Name())
+	}
+	if letter.ID == "" {
+		sequence, err := s.store.Sequence()
+		if err != nil {
+			return err
+		}
+		letter.ID = utils.GenerateUUID(sequence)
+	}
+	return deadLetters.PutDeadLetter(letter)
+}
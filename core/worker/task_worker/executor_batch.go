@@ -5,6 +5,8 @@
 package task_worker
 
 import (
+	"context"
+	"fmt"
 	"sync"
 	"time"
 
@@ -16,28 +18,43 @@ import (
 
 type BatchExecutor struct {
 	worker *TaskWorker
-	task   types.TaskBatch
+	task   types.TaskBatchContext
 	pool   sync.Pool
 }
 
 func (m *BatchExecutor) execute(items []interface{}) {
 	var (
-		succ bool
-		cost int64
+		succ   bool
+		cost   int64
+		reason = "execution returned false"
 	)
+	ctx, cancel := m.worker.callContext(m.worker.executeTimeout)
+	defer cancel()
+	t0 := time.Now()
 	defer func() {
+		cost = int64(time.Now().Sub(t0) / time.Millisecond)
 		if r := recover(); r != nil {
 			logrus.Error("Execute error: ", r)
 			traceData := utils.StackTraceData()
 			defer traceData.Recycle()
 			log.Error("Trace: ", traceData.String())
 			succ = false
+			reason = fmt.Sprint("panic: ", r)
+		}
+		if !succ && ctx.Err() == context.DeadlineExceeded {
+			reason = "execution exceeded the deadline"
+			m.worker.Statistics.ExecuteTimeout(cost)
+		} else {
+			m.worker.Statistics.Execute(succ, cost)
+		}
+		if !succ {
+			// retry items of the batch individually
+			for _, item := range items {
+				m.worker.fail(item, reason)
+			}
 		}
-		m.worker.Statistics.Execute(succ, cost)
 	}()
-	t0 := time.Now()
-	succ = m.task.Execute(items, m.worker.ownSign)
-	cost = int64(time.Now().Sub(t0) / time.Millisecond)
+	succ = m.task.ExecuteContext(ctx, unwrapItems(items), m.worker.ownSign)
 }
 
 func (m *BatchExecutor) ExecuteOrReturn() bool {
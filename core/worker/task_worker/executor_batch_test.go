@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -35,7 +36,7 @@ func TestExecutorBatch(t *testing.T) {
 				BatchCount: 6,
 			},
 		},
-		task: demo,
+		task: types.BatchContext(demo),
 		pool: sync.Pool{
 			New: func() interface{} {
 				return make([]interface{}, 0, 6)
@@ -5,6 +5,8 @@
 package task_worker
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	"github.com/jasonjoo2010/goschedule/log"
@@ -15,27 +17,39 @@ import (
 
 type SingleExecutor struct {
 	worker *TaskWorker
-	task   types.TaskSingle
+	task   types.TaskSingleContext
 }
 
 func (m *SingleExecutor) execute(item interface{}) {
 	var (
-		succ bool
-		cost int64
+		succ   bool
+		cost   int64
+		reason = "execution returned false"
 	)
+	ctx, cancel := m.worker.callContext(m.worker.executeTimeout)
+	defer cancel()
+	t0 := time.Now()
 	defer func() {
+		cost = int64(time.Now().Sub(t0) / time.Millisecond)
 		if r := recover(); r != nil {
 			logrus.Error("Execute error: ", r)
 			traceData := utils.StackTraceData()
 			defer traceData.Recycle()
 			log.Error("Trace: ", traceData.String())
 			succ = false
+			reason = fmt.Sprint("panic: ", r)
+		}
+		if !succ && ctx.Err() == context.DeadlineExceeded {
+			reason = "execution exceeded the deadline"
+			m.worker.Statistics.ExecuteTimeout(cost)
+		} else {
+			m.worker.Statistics.Execute(succ, cost)
+		}
+		if !succ {
+			m.worker.fail(item, reason)
 		}
-		m.worker.Statistics.Execute(succ, cost)
 	}()
-	t0 := time.Now()
-	succ = m.task.Execute(item, m.worker.ownSign)
-	cost = int64(time.Now().Sub(t0) / time.Millisecond)
+	succ = m.task.ExecuteContext(ctx, unwrapItem(item), m.worker.ownSign)
 }
 
 func (m *SingleExecutor) ExecuteOrReturn() bool {
@@ -5,10 +5,13 @@
 package task_worker
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store/memory"
+	"github.com/jasonjoo2010/goschedule/types"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,7 +34,7 @@ func TestExecutorSingle(t *testing.T) {
 		worker: &TaskWorker{
 			data: make(chan interface{}, 100),
 		},
-		task: demo,
+		task: types.SingleContext(demo),
 	}
 	single.worker.data <- 1
 	single.worker.data <- 2
@@ -51,3 +54,70 @@ func TestExecutorSingle(t *testing.T) {
 	assert.Equal(t, int64(3), single.worker.Statistics.ExecuteSuccCount)
 	assert.Equal(t, int64(2), single.worker.Statistics.ExecuteFailCount)
 }
+
+type demoTaskSingleContext struct {
+	demoTaskSingle
+}
+
+func (demo *demoTaskSingleContext) SelectContext(ctx context.Context, parameter, ownSign string, items []definition.TaskItem, eachFetchNum int) []interface{} {
+	<-ctx.Done()
+	return nil
+}
+
+func (demo *demoTaskSingleContext) ExecuteContext(ctx context.Context, task interface{}, ownSign string) bool {
+	if task == "hang" {
+		<-ctx.Done()
+		return false
+	}
+	return demo.succ
+}
+
+func TestExecutorSingleTimeout(t *testing.T) {
+	demo := &demoTaskSingleContext{demoTaskSingle{succ: true}}
+	task, ok := singleTask(demo)
+	assert.True(t, ok)
+	assert.Equal(t, demo, task)
+	single := SingleExecutor{
+		worker: &TaskWorker{
+			data:           make(chan interface{}, 100),
+			executeTimeout: 50 * time.Millisecond,
+		},
+		task: task,
+	}
+	single.worker.data <- "hang"
+	single.worker.data <- 1
+	t0 := time.Now()
+	single.ExecuteOrReturn()
+	assert.True(t, time.Since(t0) < time.Second)
+	single.ExecuteOrReturn()
+
+	assert.Equal(t, int64(1), single.worker.Statistics.ExecuteSuccCount)
+	assert.Equal(t, int64(0), single.worker.Statistics.ExecuteFailCount)
+	assert.Equal(t, int64(1), single.worker.Statistics.ExecuteTimeoutCount)
+
+	// stopping interrupts the execution
+	single.worker.ctx, single.worker.ctxCancel = context.WithCancel(context.Background())
+	single.worker.executeTimeout = 0
+	single.worker.data <- "hang"
+	time.AfterFunc(50*time.Millisecond, single.worker.ctxCancel)
+	single.ExecuteOrReturn()
+	assert.Equal(t, int64(1), single.worker.Statistics.ExecuteFailCount)
+	assert.Equal(t, int64(1), single.worker.Statistics.ExecuteTimeoutCount)
+}
+
+func TestSelectTimeout(t *testing.T) {
+	w := &TaskWorker{
+		data:          make(chan interface{}, 100),
+		task:          &demoTaskSingleContext{},
+		taskItems:     []definition.TaskItem{{ID: TEST_ITEM_ID1}},
+		selectTimeout: 50 * time.Millisecond,
+	}
+	w.ctx, w.ctxCancel = context.WithCancel(context.Background())
+	defer w.ctxCancel()
+	s := memory.New()
+	defer s.Close()
+	s.SetTaskAssignment(&definition.TaskAssignment{ItemID: TEST_ITEM_ID1})
+	w.store = s
+	w.selectOnce()
+	assert.Equal(t, int64(1), w.Statistics.SelectTimeoutCount)
+}
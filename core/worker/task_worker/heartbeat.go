@@ -6,13 +6,13 @@ package task_worker
 
 import (
 	"time"
+
+	"github.com/jasonjoo2010/goschedule/store"
 )
 
 func (w *TaskWorker) registerTaskRuntime() {
-	now := time.Now().Unix() * 1000
 	w.runtime.NextRunnable = w.NextBeginTime
-	w.runtime.LastHeartbeat = now
 	w.runtime.Version++
 	w.runtime.Statistics = w.Statistics
-	w.store.SetTaskRuntime(&w.runtime)
+	store.KeepTaskRuntimeAlive(w.store, &w.runtime, time.Duration(w.taskDefine.DeathTimeout)*time.Millisecond)
 }
@@ -0,0 +1,203 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package task_worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// retryItem wraps task data which failed before, it goes through data channel
+//	again with its attempts after backoff.
+type retryItem struct {
+	data      interface{}
+	items     []definition.TaskItem // owned task items with fencing tokens the data may belong to
+	attempts  int                   // failed attempts
+	reason    string
+	notBefore time.Time
+}
+
+// unwrapItem returns the original task data
+func unwrapItem(item interface{}) interface{} {
+	if r, ok := item.(*retryItem); ok {
+		return r.data
+	}
+	return item
+}
+
+// unwrapItems returns original task data of items, a new slice is allocated only if necessary
+func unwrapItems(items []interface{}) []interface{} {
+	var result []interface{}
+	for i, item := range items {
+		r, ok := item.(*retryItem)
+		if !ok {
+			continue
+		}
+		if result == nil {
+			result = make([]interface{}, len(items))
+			copy(result, items)
+		}
+		result[i] = r.data
+	}
+	if result == nil {
+		return items
+	}
+	return result
+}
+
+// fail handles a failed execution of item. It will be retried after backoff
+//	or be sent to dead-letter sink if all attempts have been exhausted.
+//	Items whose task items are no longer owned or which fail during stopping are discarded
+//	because they will be selected again by the owner.
+func (w *TaskWorker) fail(item interface{}, reason string) {
+	r, ok := item.(*retryItem)
+	if !ok {
+		r = &retryItem{data: item}
+	}
+	r.attempts++
+	r.reason = reason
+	policy := &w.taskDefine.Retry
+	if r.attempts >= policy.MaxAttempts {
+		w.deadLetter(r)
+		return
+	}
+	if w.ctx != nil && utils.ContextDone(w.ctx) {
+		logrus.Info("Task data is discarded for stopping after ", r.attempts, " attempt(s)")
+		return
+	}
+	w.retryMu.Lock()
+	defer w.retryMu.Unlock()
+	r.items = w.ownerItems(r.data)
+	if len(r.items) == 0 {
+		logrus.Info("Task data is discarded because its task item is no longer owned")
+		return
+	}
+	r.notBefore = time.Now().Add(policy.Delay(r.attempts))
+	w.Statistics.Retry()
+	w.retries = append(w.retries, r)
+}
+
+// ownerItems returns owned task items the data may belong to, which is the exact one if the task
+//	implements types.TaskItemResolver. Lock of retries should be held.
+func (w *TaskWorker) ownerItems(data interface{}) []definition.TaskItem {
+	if w.resolver == nil {
+		return w.ownedItems
+	}
+	id := w.resolver.ItemOf(data)
+	for _, item := range w.ownedItems {
+		if item.ID == id {
+			return []definition.TaskItem{item}
+		}
+	}
+	return nil
+}
+
+// ownsItem returns whether the task item is in items with the same fencing token
+func ownsItem(items []definition.TaskItem, item definition.TaskItem) bool {
+	for _, owned := range items {
+		if owned.ID == item.ID {
+			return owned.Token == item.Token
+		}
+	}
+	return false
+}
+
+// syncRetries publishes task items owned currently and drops items waiting to be retried whose
+//	task items have been released or superseded. It should be called once task items are changed.
+func (w *TaskWorker) syncRetries() {
+	w.retryMu.Lock()
+	defer w.retryMu.Unlock()
+	w.ownedItems = append(w.ownedItems[:0:0], w.taskItems...)
+	if len(w.retries) == 0 {
+		return
+	}
+	pending := w.retries[:0]
+	for _, r := range w.retries {
+		items := r.items[:0]
+		for _, item := range r.items {
+			if ownsItem(w.ownedItems, item) {
+				items = append(items, item)
+			}
+		}
+		r.items = items
+		if len(items) == 0 {
+			logrus.Info("Task data waiting to be retried is discarded because its task item is no longer owned")
+			continue
+		}
+		pending = append(pending, r)
+	}
+	for i := len(pending); i < len(w.retries); i++ {
+		w.retries[i] = nil
+	}
+	w.retries = pending
+}
+
+// dueRetries pops out items whose backoff has elapsed
+func (w *TaskWorker) dueRetries() []interface{} {
+	w.retryMu.Lock()
+	defer w.retryMu.Unlock()
+	if len(w.retries) == 0 {
+		return nil
+	}
+	now := time.Now()
+	due := make([]interface{}, 0)
+	pending := w.retries[:0]
+	for _, r := range w.retries {
+		if r.notBefore.After(now) {
+			pending = append(pending, r)
+		} else {
+			due = append(due, r)
+		}
+	}
+	w.retries = pending
+	return due
+}
+
+// abandonRetries discards items still waiting to be retried when worker is stopping,
+//	they will be selected again by the next owner of their task items.
+func (w *TaskWorker) abandonRetries() {
+	w.retryMu.Lock()
+	retries := w.retries
+	w.retries = nil
+	w.retryMu.Unlock()
+	if len(retries) > 0 {
+		logrus.Info(len(retries), " task data waiting to be retried are discarded for stopping")
+	}
+}
+
+func (w *TaskWorker) deadLetter(r *retryItem) {
+	if w.deadLetterSink == nil && !w.taskDefine.Retry.Enabled() {
+		// Dropped silently like before
+		return
+	}
+	w.Statistics.DeadLetter()
+	if w.deadLetterSink == nil {
+		logrus.Warn("Task data is dropped after ", r.attempts, " attempt(s): ", r.reason)
+		return
+	}
+	letter := &definition.DeadLetter{
+		StrategyID: w.strategyDefine.ID,
+		TaskID:     w.taskDefine.ID,
+		RuntimeID:  w.runtime.ID,
+		OwnSign:    w.ownSign,
+		Attempts:   r.attempts,
+		Reason:     r.reason,
+		Createtime: time.Now().Unix() * 1000,
+		Item:       r.data,
+	}
+	if data, err := json.Marshal(r.data); err == nil {
+		letter.Data = string(data)
+	} else {
+		letter.Data = fmt.Sprint(r.data)
+	}
+	if err := w.deadLetterSink.Put(letter); err != nil {
+		logrus.Error("Put dead letter failed: ", err.Error())
+	}
+}
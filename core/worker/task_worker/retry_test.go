@@ -0,0 +1,316 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package task_worker
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store/memory"
+	"github.com/jasonjoo2010/goschedule/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyTask fails specific times for every item before succeeding
+type flakyTask struct {
+	mu       sync.Mutex
+	failures int
+	attempts map[interface{}]int
+	panicky  bool
+}
+
+func (demo *flakyTask) Select(parameter, ownSign string, items []definition.TaskItem, eachFetchNum int) []interface{} {
+	return nil
+}
+
+func (demo *flakyTask) attempt(item interface{}) bool {
+	demo.mu.Lock()
+	defer demo.mu.Unlock()
+	demo.attempts[item]++
+	return demo.attempts[item] > demo.failures
+}
+
+func (demo *flakyTask) Execute(item interface{}, ownSign string) bool {
+	succ := demo.attempt(item)
+	if !succ && demo.panicky {
+		panic("flaky")
+	}
+	return succ
+}
+
+type flakyBatchTask struct {
+	flakyTask
+}
+
+func (demo *flakyBatchTask) Execute(items []interface{}, ownSign string) bool {
+	succ := true
+	for _, item := range items {
+		succ = demo.attempt(item) && succ
+	}
+	return succ
+}
+
+// itemOfTask resolves task items of data in form of "item/data"
+type itemOfTask struct {
+	flakyTask
+}
+
+func (demo *itemOfTask) ItemOf(task interface{}) string {
+	return strings.SplitN(task.(string), "/", 2)[0]
+}
+
+func newRetryWorker(retry definition.RetryPolicy, sink DeadLetterSink) *TaskWorker {
+	w := &TaskWorker{
+		data: make(chan interface{}, 100),
+		taskDefine: definition.Task{
+			ID:         TEST_TASK_ID,
+			BatchCount: 3,
+			Retry:      retry,
+		},
+		strategyDefine: definition.Strategy{ID: TEST_STRATEGY_ID},
+		deadLetterSink: sink,
+		taskItems:      []definition.TaskItem{{ID: "0", Token: 1}},
+	}
+	w.syncRetries()
+	return w
+}
+
+// drain executes items until nothing queued or waiting for retry
+func drain(w *TaskWorker, executor TaskExecutor) {
+	for i := 0; i < 100; i++ {
+		for executor.ExecuteOrReturn() {
+		}
+		w.fillOrQueued(w.dueRetries())
+		if len(w.data) == 0 && len(w.retries) == 0 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRetrySingle(t *testing.T) {
+	sink := NewMemoryDeadLetterSink(0)
+	demo := &flakyTask{failures: 2, attempts: make(map[interface{}]int)}
+	w := newRetryWorker(definition.RetryPolicy{MaxAttempts: 3, Backoff: 10}, sink)
+	single := &SingleExecutor{worker: w, task: types.SingleContext(demo)}
+	w.data <- 1
+	w.data <- 2
+
+	drain(w, single)
+	assert.Equal(t, 3, demo.attempts[1])
+	assert.Equal(t, 3, demo.attempts[2])
+	assert.Equal(t, int64(2), w.Statistics.ExecuteSuccCount)
+	assert.Equal(t, int64(4), w.Statistics.ExecuteFailCount)
+	assert.Equal(t, int64(4), w.Statistics.RetryCount)
+	assert.Equal(t, int64(0), w.Statistics.DeadLetterCount)
+	assert.Empty(t, sink.Letters())
+}
+
+func TestRetryExhausted(t *testing.T) {
+	sink := NewMemoryDeadLetterSink(0)
+	demo := &flakyTask{failures: 5, attempts: make(map[interface{}]int), panicky: true}
+	w := newRetryWorker(definition.RetryPolicy{MaxAttempts: 3, Backoff: 10}, sink)
+	single := &SingleExecutor{worker: w, task: types.SingleContext(demo)}
+	w.data <- "a"
+
+	drain(w, single)
+	assert.Equal(t, 3, demo.attempts["a"])
+	assert.Equal(t, int64(2), w.Statistics.RetryCount)
+	assert.Equal(t, int64(1), w.Statistics.DeadLetterCount)
+	letters := sink.Drain()
+	assert.Equal(t, 1, len(letters))
+	assert.Equal(t, "a", letters[0].Item)
+	assert.Equal(t, `"a"`, letters[0].Data)
+	assert.Equal(t, 3, letters[0].Attempts)
+	assert.Equal(t, "panic: flaky", letters[0].Reason)
+	assert.Equal(t, TEST_STRATEGY_ID, letters[0].StrategyID)
+	assert.Equal(t, TEST_TASK_ID, letters[0].TaskID)
+	assert.Empty(t, sink.Letters())
+}
+
+func TestRetryDisabled(t *testing.T) {
+	demo := &flakyTask{failures: 1, attempts: make(map[interface{}]int)}
+	w := newRetryWorker(definition.RetryPolicy{}, nil)
+	single := &SingleExecutor{worker: w, task: types.SingleContext(demo)}
+	w.data <- 1
+
+	drain(w, single)
+	assert.Equal(t, 1, demo.attempts[1])
+	assert.Equal(t, int64(0), w.Statistics.RetryCount)
+	assert.Equal(t, int64(0), w.Statistics.DeadLetterCount)
+}
+
+func TestRetryBatch(t *testing.T) {
+	sink := NewMemoryDeadLetterSink(0)
+	demo := &flakyBatchTask{flakyTask{failures: 1, attempts: make(map[interface{}]int)}}
+	w := newRetryWorker(definition.RetryPolicy{MaxAttempts: 2}, sink)
+	batch := &BatchExecutor{
+		worker: w,
+		task:   types.BatchContext(demo),
+		pool: sync.Pool{
+			New: func() interface{} {
+				return make([]interface{}, 0, 3)
+			},
+		},
+	}
+	w.data <- 1
+	w.data <- 2
+
+	drain(w, batch)
+	assert.Equal(t, 2, demo.attempts[1])
+	assert.Equal(t, 2, demo.attempts[2])
+	assert.Equal(t, int64(1), w.Statistics.ExecuteSuccCount)
+	assert.Equal(t, int64(1), w.Statistics.ExecuteFailCount)
+	assert.Equal(t, int64(2), w.Statistics.RetryCount)
+	assert.Empty(t, sink.Letters())
+}
+
+func TestRetryBackoff(t *testing.T) {
+	demo := &flakyTask{failures: 1, attempts: make(map[interface{}]int)}
+	w := newRetryWorker(definition.RetryPolicy{MaxAttempts: 2, Backoff: 200}, nil)
+	single := &SingleExecutor{worker: w, task: types.SingleContext(demo)}
+	w.data <- 1
+	single.ExecuteOrReturn()
+
+	assert.Empty(t, w.dueRetries())
+	time.Sleep(250 * time.Millisecond)
+	due := w.dueRetries()
+	assert.Equal(t, 1, len(due))
+	assert.Equal(t, 1, due[0].(*retryItem).attempts)
+	assert.Empty(t, w.retries)
+}
+
+func TestAbandonRetries(t *testing.T) {
+	sink := NewMemoryDeadLetterSink(0)
+	demo := &flakyTask{failures: 1, attempts: make(map[interface{}]int)}
+	w := newRetryWorker(definition.RetryPolicy{MaxAttempts: 2, Backoff: 10000}, sink)
+	single := &SingleExecutor{worker: w, task: types.SingleContext(demo)}
+	w.data <- 1
+	single.ExecuteOrReturn()
+
+	w.abandonRetries()
+	assert.Empty(t, w.retries)
+	// not exhausted
+	assert.Empty(t, sink.Letters())
+	assert.Equal(t, int64(0), w.Statistics.DeadLetterCount)
+}
+
+func TestRetryStopping(t *testing.T) {
+	sink := NewMemoryDeadLetterSink(0)
+	w := newRetryWorker(definition.RetryPolicy{MaxAttempts: 2}, sink)
+	w.ctx, w.ctxCancel = context.WithCancel(context.Background())
+	w.ctxCancel()
+
+	w.fail(1, "failed")
+	assert.Empty(t, w.retries)
+	assert.Empty(t, sink.Letters())
+
+	w.fail(&retryItem{data: 2, attempts: 1}, "failed")
+	assert.Equal(t, 1, len(sink.Letters()))
+	assert.Equal(t, 2, sink.Letters()[0].Attempts)
+}
+
+func TestRetryOwnership(t *testing.T) {
+	sink := NewMemoryDeadLetterSink(0)
+	w := newRetryWorker(definition.RetryPolicy{MaxAttempts: 3, Backoff: 10000}, sink)
+	w.taskItems = []definition.TaskItem{{ID: "a", Token: 1}, {ID: "b", Token: 2}}
+	w.syncRetries()
+
+	w.fail(1, "failed")
+	assert.Equal(t, 1, len(w.retries))
+	assert.Equal(t, w.taskItems, w.retries[0].items)
+
+	// superseded
+	w.taskItems = []definition.TaskItem{{ID: "a", Token: 1}, {ID: "b", Token: 3}}
+	w.syncRetries()
+	assert.Equal(t, 1, len(w.retries))
+	assert.Equal(t, []definition.TaskItem{{ID: "a", Token: 1}}, w.retries[0].items)
+
+	// released
+	w.taskItems = []definition.TaskItem{{ID: "b", Token: 3}}
+	w.syncRetries()
+	assert.Empty(t, w.retries)
+
+	// resolved exactly
+	w.resolver = &itemOfTask{}
+	w.taskItems = []definition.TaskItem{{ID: "a", Token: 4}, {ID: "b", Token: 3}}
+	w.syncRetries()
+	w.fail("a/1", "failed")
+	w.fail("b/1", "failed")
+	w.fail("c/1", "failed")
+	assert.Equal(t, 2, len(w.retries))
+	w.taskItems = w.taskItems[1:]
+	w.syncRetries()
+	assert.Equal(t, 1, len(w.retries))
+	assert.Equal(t, "b/1", w.retries[0].data)
+	assert.Equal(t, int64(3), w.Statistics.RetryCount)
+	assert.Empty(t, sink.Letters())
+}
+
+func TestRetrySuperseded(t *testing.T) {
+	clearStore()
+	w := newTaskWorker()
+	w.resolver = &itemOfTask{}
+	w.taskDefine.Retry = definition.RetryPolicy{MaxAttempts: 3, Backoff: 10000}
+	w.registerTaskRuntime()
+	w.distributeTaskItems()
+	w.reloadTaskItems()
+	w.fail(TEST_ITEM_ID1+"/1", "failed")
+	w.fail(TEST_ITEM_ID2+"/1", "failed")
+	assert.Equal(t, 2, len(w.retries))
+
+	// taken by others while paused
+	assignment, _ := memoryStore.GetTaskAssignment(TEST_STRATEGY_ID, TEST_TASK_ID, TEST_ITEM_ID1)
+	other := newTaskWorker()
+	assert.Nil(t, other.transfer(assignment, other.runtime.ID))
+	memoryStore.SetTaskAssignment(assignment)
+	w.dropSupersededItems()
+	assert.Equal(t, 1, len(w.retries))
+	assert.Equal(t, TEST_ITEM_ID2+"/1", w.retries[0].data)
+
+	// released
+	assignment, _ = memoryStore.GetTaskAssignment(TEST_STRATEGY_ID, TEST_TASK_ID, TEST_ITEM_ID2)
+	assignment.RequestedRuntimeID = other.runtime.ID
+	memoryStore.SetTaskAssignment(assignment)
+	w.reloadTaskItems()
+	assert.Empty(t, w.taskItems)
+	assert.Empty(t, w.retries)
+}
+
+func TestMemoryDeadLetterSink(t *testing.T) {
+	sink := NewMemoryDeadLetterSink(2)
+	sink.Put(&definition.DeadLetter{Data: "1"})
+	sink.Put(&definition.DeadLetter{Data: "2"})
+	sink.Put(&definition.DeadLetter{Data: "3"})
+	letters := sink.Letters()
+	assert.Equal(t, 2, len(letters))
+	assert.Equal(t, "2", letters[0].Data)
+	assert.Equal(t, "3", letters[1].Data)
+}
+
+func TestStoreDeadLetterSink(t *testing.T) {
+	s := memory.New()
+	defer s.Close()
+	sink, err := getDeadLetterSink(DeadLetterStorage, s)
+	assert.Nil(t, err)
+	assert.Nil(t, sink.Put(&definition.DeadLetter{StrategyID: TEST_STRATEGY_ID, TaskID: TEST_TASK_ID, Data: "1"}))
+	assert.Nil(t, sink.Put(&definition.DeadLetter{StrategyID: TEST_STRATEGY_ID, TaskID: TEST_TASK_ID, Data: "2"}))
+	letters, err := s.GetDeadLetters(TEST_STRATEGY_ID, TEST_TASK_ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(letters))
+	assert.Equal(t, "1", letters[0].Data)
+	assert.NotEmpty(t, letters[0].ID)
+
+	_, err = getDeadLetterSink("notRegistered", s)
+	assert.NotNil(t, err)
+	RegisterDeadLetterSink("memoryForTest", NewMemoryDeadLetterSink(0))
+	sink, err = getDeadLetterSink("memoryForTest", s)
+	assert.Nil(t, err)
+	assert.NotNil(t, sink)
+}
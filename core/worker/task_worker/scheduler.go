@@ -7,9 +7,12 @@ package task_worker
 import (
 	"fmt"
 	"sort"
+	"sync/atomic"
 	"time"
 
+	"github.com/jasonjoo2010/goschedule/assigner"
 	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
 	"github.com/jasonjoo2010/goschedule/utils"
 	"github.com/sirupsen/logrus"
 )
@@ -28,7 +31,6 @@ func (r *runtimeAssign) String() string {
 }
 
 func (w *TaskWorker) clearExpiredRuntimes() ([]string, []*definition.TaskRuntime, error) {
-	now := time.Now().Unix() * 1000
 	runtimes, err := w.store.GetTaskRuntimes(w.strategyDefine.ID, w.taskDefine.ID)
 	if err != nil || len(runtimes) < 1 {
 		return nil, nil, err
@@ -37,7 +39,7 @@ func (w *TaskWorker) clearExpiredRuntimes() ([]string, []*definition.TaskRuntime
 	validRuntimes := make([]*definition.TaskRuntime, 0, len(runtimes))
 	for _, r := range runtimes {
 		// expired?
-		if now-r.LastHeartbeat > int64(w.taskDefine.DeathTimeout) {
+		if !store.IsTaskRuntimeAlive(w.store, r, time.Duration(w.taskDefine.DeathTimeout)*time.Millisecond) {
 			w.store.RemoveTaskRuntime(w.runtime.StrategyID, w.runtime.TaskID, r.ID)
 			logrus.Warn("Clean expired task runtime: ", r.ID)
 			continue
@@ -85,11 +87,24 @@ func (w *TaskWorker) getCurrentAssignments() (map[string]*definition.TaskAssignm
 	spareAssignments := make([]*definition.TaskAssignment, 0, 1)
 	for _, t := range assignments {
 		assignMap[t.ItemID] = t
+		if _, ok := runtimesMap[t.RuntimeID]; t.RuntimeID != "" && !ok {
+			// its owner is dead and will never handle the request
+			logrus.Warn("Owner of task item ", t.ItemID, " cannot be found: ", t.RuntimeID)
+			t.RuntimeID = ""
+			t.RequestedRuntimeID = ""
+			spareAssignments = append(spareAssignments, t)
+			continue
+		}
 		rid := t.RequestedRuntimeID
 		if rid == "" {
 			rid = t.RuntimeID
 		}
 		if rid == RUNTIME_EMPTY {
+			if t.RuntimeID == "" {
+				// released already, eg. its owner stopped before handling the request
+				t.RequestedRuntimeID = ""
+				spareAssignments = append(spareAssignments, t)
+			}
 			continue
 		}
 		if rid == "" {
@@ -118,16 +133,22 @@ func (w *TaskWorker) getCurrentAssignments() (map[string]*definition.TaskAssignm
 				TaskID:     w.taskDefine.ID,
 				ItemID:     t.ID,
 				Parameter:  t.Parameter,
+				Weight:     t.Weight,
+			}
+			if err := w.store.CompareAndSetTaskAssignment(assign); err != nil {
+				return nil, nil, nil, err
 			}
 			spareAssignments = append(spareAssignments, assign)
-			w.store.SetTaskAssignment(assign)
 			assignMap[t.ID] = assign
 			continue
 		}
 		// check consistent
-		if assignRemote.Parameter != t.Parameter {
+		if assignRemote.Parameter != t.Parameter || assignRemote.Weight != t.Weight {
 			assignRemote.Parameter = t.Parameter
-			w.store.SetTaskAssignment(assignRemote)
+			assignRemote.Weight = t.Weight
+			if err := w.store.CompareAndSetTaskAssignment(assignRemote); err != nil {
+				return nil, nil, nil, err
+			}
 			continue
 		}
 	}
@@ -155,9 +176,15 @@ func (w *TaskWorker) distributeTaskItems() {
 	if !utils.IsLeader(uuids, w.runtime.ID) {
 		return
 	}
-	assignMap, spares, assigned, err := w.getCurrentAssignments()
+	assignMap, _, assigned, err := w.getCurrentAssignments()
+	if err == store.Conflict {
+		logrus.Warn("Assignments of task items have been changed by others, retry")
+		utils.Trigger(w.distributeC)
+		return
+	}
 	if err != nil {
 		logrus.Error("Fetch assignments of task items error: ", err.Error())
+		return
 	}
 	// regenerate uuids array to guarantee consistence
 	uuids = uuids[:0]
@@ -168,81 +195,109 @@ func (w *TaskWorker) distributeTaskItems() {
 		// empty runtimes
 		return
 	}
-	// try balance the task items
-	items := w.taskDefine.Items
-	balanced := utils.AssignWorkers(len(uuids), len(items), w.taskDefine.MaxTaskItems)
-	var changed bool
-	for pos, target := range balanced {
-		cur := assigned[pos]
-		cnt := len(cur.Items)
-		if cnt == target {
+	// try balance the task items through the assigner of task
+	state := &assigner.State{
+		Nodes: make([]assigner.Node, len(assigned)),
+		Units: make([]assigner.Unit, len(w.taskDefine.Items)),
+	}
+	owners := make(map[string]string, len(assignMap))
+	for pos, cur := range assigned {
+		state.Nodes[pos] = assigner.Node{
+			ID:     cur.RuntimeId,
+			Weight: 1,
+			Limit:  w.taskDefine.MaxTaskItems,
+		}
+		for _, itemId := range cur.Items {
+			owners[itemId] = cur.RuntimeId
+		}
+	}
+	for k, item := range w.taskDefine.Items {
+		owner := owners[item.ID]
+		state.Units[k] = assigner.Unit{
+			ID:     item.ID,
+			Owner:  owner,
+			Moving: owner != "" && assignMap[item.ID].RuntimeID != owner,
+			Weight: item.Weight,
+		}
+	}
+	targets, err := assigner.Assign(w.taskDefine.Assigner, state)
+	if err != nil {
+		logrus.Error("Assign task items error: ", err.Error())
+		return
+	}
+	var changed, conflicted bool
+MOVE:
+	for k, unit := range state.Units {
+		target := targets[k]
+		if target == unit.Owner {
 			continue
 		}
-		changed = true
-		if cnt > target {
-			// decrease
-			for i := 0; i < cnt-target; i++ {
-				len := len(cur.Items)
-				itemId := cur.Items[len-1]
-				cur.Items = cur.Items[:len-1]
-				item := assignMap[itemId]
-				item.RequestedRuntimeID = RUNTIME_EMPTY
-				spares = append(spares, item)
-				w.store.SetTaskAssignment(item)
+		item := assignMap[unit.ID]
+		switch {
+		case target == "" && item.RuntimeID == "":
+			// not claimed yet
+			item.RequestedRuntimeID = ""
+		case target == "":
+			item.RequestedRuntimeID = RUNTIME_EMPTY
+		case item.RuntimeID == "":
+			if err := w.transfer(item, target); err != nil {
+				logrus.Warn("Failed to move task item ", item.ItemID, " to ", target, ": ", err.Error())
+				break MOVE
 			}
-			logrus.Info("Decrease ", cnt-target, " task item(s) from ", cur.RuntimeId)
-		} else if cnt < target {
-			// increase
-			for i := 0; i < target-cnt; i++ {
-				len := len(spares)
-				if len < 1 {
-					logrus.Error("Not enough spared task item to assign")
-					break
-				}
-				item := spares[len-1]
-				cur.Items = append(cur.Items, item.ItemID)
-				spares = spares[:len-1]
-				if item.RuntimeID == "" {
-					item.RuntimeID = cur.RuntimeId
-					item.RequestedRuntimeID = ""
-				} else {
-					item.RequestedRuntimeID = cur.RuntimeId
-				}
-				w.store.SetTaskAssignment(item)
-			}
-			logrus.Info("Increase ", target-cnt, " task item(s) to ", cur.RuntimeId)
+			item.RequestedRuntimeID = ""
+		case item.RuntimeID == target:
+			// cancel the request of moving away
+			item.RequestedRuntimeID = ""
+		default:
+			item.RequestedRuntimeID = target
+		}
+		changed = true
+		if err := w.store.CompareAndSetTaskAssignment(item); err != nil {
+			logrus.Warn("Failed to move task item ", item.ItemID, " to ", target, ": ", err.Error())
+			conflicted = err == store.Conflict
+			break
 		}
+		logrus.Info("Move task item ", item.ItemID, " from ", unit.Owner, " to ", target)
 	}
 	if changed {
 		w.store.IncreaseTaskItemsConfigVersion(w.strategyDefine.ID, w.taskDefine.ID)
 	}
+	if conflicted {
+		// distribute again with fresh state
+		utils.Trigger(w.distributeC)
+	}
 }
 
 // assignTaskItems reloads task items and release items others requests
 //	When call this PLEASE make sure that you have NO queued data in channel
-func (w *TaskWorker) reloadTaskItems() {
+//	It returns false if it should be reloaded again because of failures.
+func (w *TaskWorker) reloadTaskItems() bool {
 	assignments, err := w.store.GetTaskAssignments(w.strategyDefine.ID, w.taskDefine.ID)
 	if err != nil {
 		logrus.Error("Fetch assignments error: ", err.Error())
-		return
+		return false
 	}
+	defer w.syncRetries()
 	newItems := 0
 	removedItems := 0
+	succ := true
 	for _, assignment := range assignments {
 		if assignment.RuntimeID == "" {
 			if assignment.RequestedRuntimeID == w.runtime.ID {
-				// mine, update
-				if !utils.ContainsTaskItem(w.taskItems, assignment.ItemID) {
-					// remove from local
-					w.taskItems = append(w.taskItems, definition.TaskItem{
-						ID:        assignment.ItemID,
-						Parameter: assignment.Parameter,
-					})
+				// mine, claim it first
+				err := w.transfer(assignment, w.runtime.ID)
+				if err == nil {
+					assignment.RequestedRuntimeID = ""
+					err = w.store.CompareAndSetTaskAssignment(assignment)
+				}
+				if err != nil {
+					logrus.Warn("Failed to claim task item [", assignment.ItemID, "]: ", err.Error())
+					succ = false
+					continue
+				}
+				if w.takeTaskItem(assignment) {
 					newItems++
 				}
-				assignment.RuntimeID = w.runtime.ID
-				assignment.RequestedRuntimeID = ""
-				w.store.SetTaskAssignment(assignment)
 			} else {
 				// not mine, none of my business
 				if utils.ContainsTaskItem(w.taskItems, assignment.ItemID) {
@@ -261,24 +316,27 @@ func (w *TaskWorker) reloadTaskItems() {
 			// should release it
 			// update TaskWorker first
 			w.taskItems = utils.RemoveTaskItem(w.taskItems, assignment.ItemID)
-			if assignment.RequestedRuntimeID == RUNTIME_EMPTY {
-				assignment.RuntimeID = ""
-			} else {
-				assignment.RuntimeID = assignment.RequestedRuntimeID
+			target := assignment.RequestedRuntimeID
+			if target == RUNTIME_EMPTY {
+				target = ""
+			}
+			removedItems++
+			err := w.transfer(assignment, target)
+			if err == nil {
+				assignment.RequestedRuntimeID = ""
+				err = w.store.CompareAndSetTaskAssignment(assignment)
+			}
+			if err != nil {
+				logrus.Warn("Failed to release task item [", assignment.ItemID, "]: ", err.Error())
+				succ = false
+				continue
 			}
-			assignment.RequestedRuntimeID = ""
-			w.store.SetTaskAssignment(assignment)
 			w.store.IncreaseTaskItemsConfigVersion(w.strategyDefine.ID, w.taskDefine.ID)
 			logrus.Info("Release task item [", assignment.ItemID, "] for ", assignment.TaskID, " to ", assignment.RuntimeID)
-			removedItems++
 			continue
 		}
-		if !utils.ContainsTaskItem(w.taskItems, assignment.ItemID) {
+		if w.takeTaskItem(assignment) {
 			// mine, new
-			w.taskItems = append(w.taskItems, definition.TaskItem{
-				ID:        assignment.ItemID,
-				Parameter: assignment.Parameter,
-			})
 			newItems++
 		}
 	}
@@ -287,6 +345,87 @@ func (w *TaskWorker) reloadTaskItems() {
 	} else {
 		logrus.Info("Reload task items, ", newItems, " items added, ", removedItems, " items released")
 	}
+	return succ
+}
+
+// transfer moves ownership of the assignment to specific runtime with a new fencing token,
+//	empty runtime means releasing it.
+func (w *TaskWorker) transfer(assignment *definition.TaskAssignment, runtimeId string) error {
+	token, err := w.store.Sequence()
+	if err != nil {
+		return err
+	}
+	assignment.RuntimeID = runtimeId
+	assignment.Token = token
+	return nil
+}
+
+// takeTaskItem puts the task item of assignment into local or refreshes its token,
+//	returns true if it's a new one.
+func (w *TaskWorker) takeTaskItem(assignment *definition.TaskAssignment) bool {
+	for i := range w.taskItems {
+		if w.taskItems[i].ID == assignment.ItemID {
+			w.taskItems[i].Token = assignment.Token
+			return false
+		}
+	}
+	w.taskItems = append(w.taskItems, definition.TaskItem{
+		ID:        assignment.ItemID,
+		Parameter: assignment.Parameter,
+		Weight:    assignment.Weight,
+		Token:     assignment.Token,
+	})
+	return true
+}
+
+// shouldCheckAssignments returns whether assignments should be checked for dropSupersededItems,
+//	which is once a heartbeat or once they are changed if they can be watched.
+func (w *TaskWorker) shouldCheckAssignments() bool {
+	now := time.Now()
+	if atomic.SwapInt32(&w.assignChanged, 0) == 0 &&
+		now.Sub(w.checkedAt) < time.Duration(w.taskDefine.HeartbeatInterval)*time.Millisecond {
+		return false
+	}
+	w.checkedAt = now
+	return true
+}
+
+// dropSupersededItems stops selecting task items whose ownership has been taken by others,
+//	eg. this runtime was paused and treated as dead.
+//	It returns true if there are changes for this runtime not reloaded yet, eg. requests of
+//	releasing or claiming and items assigned directly, which may be missed when the version
+//	of config was read before assignments were updated or it failed to be increased.
+func (w *TaskWorker) dropSupersededItems() bool {
+	assignments, err := w.store.GetTaskAssignments(w.strategyDefine.ID, w.taskDefine.ID)
+	if err != nil {
+		logrus.Warn("Fetch assignments failed: ", err.Error())
+		return false
+	}
+	defer w.syncRetries()
+	pending := false
+	owned := make(map[string]uint64, len(assignments))
+	for _, assignment := range assignments {
+		if assignment.RuntimeID == w.runtime.ID {
+			owned[assignment.ItemID] = assignment.Token
+			pending = pending || assignment.RequestedRuntimeID != "" ||
+				!utils.ContainsTaskItem(w.taskItems, assignment.ItemID)
+		} else if assignment.RuntimeID == "" {
+			pending = pending || assignment.RequestedRuntimeID == w.runtime.ID
+		}
+	}
+	if len(w.taskItems) == 0 {
+		return pending
+	}
+	items := make([]definition.TaskItem, 0, len(w.taskItems))
+	for _, item := range w.taskItems {
+		if token, ok := owned[item.ID]; !ok || token != item.Token {
+			logrus.Warn("Task item [", item.ID, "] has been superseded, stop selecting it")
+			continue
+		}
+		items = append(items, item)
+	}
+	w.taskItems = items
+	return pending
 }
 
 func (w *TaskWorker) cleanupSchedule() {
@@ -296,9 +435,16 @@ func (w *TaskWorker) cleanupSchedule() {
 		return
 	}
 	for _, assignment := range assignments {
-		if assignment.RuntimeID == w.runtime.ID {
-			assignment.RuntimeID = ""
-			w.store.SetTaskAssignment(assignment)
+		// retry with fresh state if it's changed concurrently
+		for i := 0; i < 3 && assignment != nil && assignment.RuntimeID == w.runtime.ID; i++ {
+			if err := w.transfer(assignment, ""); err != nil {
+				logrus.Warn("Failed to release task item [", assignment.ItemID, "]: ", err.Error())
+				break
+			}
+			if err := w.store.CompareAndSetTaskAssignment(assignment); err != store.Conflict {
+				break
+			}
+			assignment, _ = w.store.GetTaskAssignment(w.strategyDefine.ID, w.taskDefine.ID, assignment.ItemID)
 		}
 	}
 }
@@ -5,10 +5,16 @@
 package task_worker
 
 import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/jasonjoo2010/goschedule/assigner"
 	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/store/memory"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -159,6 +165,138 @@ func TestReloadTaskItems(t *testing.T) {
 	assert.True(t, ver1 > ver)
 }
 
+// racingStore simulates concurrent writings happened just before compare-and-set
+type racingStore struct {
+	store.Store
+	races int
+}
+
+func (s *racingStore) CompareAndSetTaskAssignment(assignment *definition.TaskAssignment) error {
+	if s.races > 0 {
+		s.races--
+		fresh, _ := s.Store.GetTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID)
+		s.Store.SetTaskAssignment(fresh)
+	}
+	return s.Store.CompareAndSetTaskAssignment(assignment)
+}
+
+func TestReloadTaskItemsConflict(t *testing.T) {
+	clearStore()
+	w := newTaskWorker()
+	w.registerTaskRuntime()
+	for _, id := range []string{TEST_ITEM_ID1, TEST_ITEM_ID2} {
+		memoryStore.SetTaskAssignment(&definition.TaskAssignment{
+			StrategyID:         TEST_STRATEGY_ID,
+			TaskID:             TEST_TASK_ID,
+			ItemID:             id,
+			RequestedRuntimeID: w.runtime.ID,
+		})
+	}
+	w.store = &racingStore{Store: memoryStore, races: 1}
+
+	// the conflicted one should not be taken
+	assert.False(t, w.reloadTaskItems())
+	assert.Equal(t, 1, len(w.taskItems))
+
+	assert.True(t, w.reloadTaskItems())
+	assert.Equal(t, 2, len(w.taskItems))
+	assignments, _ := memoryStore.GetTaskAssignments(TEST_STRATEGY_ID, TEST_TASK_ID)
+	for _, assignment := range assignments {
+		assert.Equal(t, w.runtime.ID, assignment.RuntimeID)
+	}
+}
+
+func TestFencingToken(t *testing.T) {
+	clearStore()
+	w := newTaskWorker()
+	w.registerTaskRuntime()
+	w.distributeTaskItems()
+	w.reloadTaskItems()
+	assert.Equal(t, 2, len(w.taskItems))
+	for _, item := range w.taskItems {
+		assignment, _ := memoryStore.GetTaskAssignment(TEST_STRATEGY_ID, TEST_TASK_ID, item.ID)
+		assert.True(t, item.Token > 0)
+		assert.Equal(t, assignment.Token, item.Token)
+	}
+
+	// nothing changed
+	assert.False(t, w.dropSupersededItems())
+	assert.Equal(t, 2, len(w.taskItems))
+
+	// taken by others while paused
+	assignment, _ := memoryStore.GetTaskAssignment(TEST_STRATEGY_ID, TEST_TASK_ID, TEST_ITEM_ID1)
+	oldToken := assignment.Token
+	other := newTaskWorker()
+	assert.Nil(t, other.transfer(assignment, other.runtime.ID))
+	assert.True(t, assignment.Token > oldToken)
+	memoryStore.SetTaskAssignment(assignment)
+
+	w.dropSupersededItems()
+	assert.Equal(t, 1, len(w.taskItems))
+	assert.Equal(t, TEST_ITEM_ID2, w.taskItems[0].ID)
+}
+
+func TestPendingChanges(t *testing.T) {
+	clearStore()
+	w := newTaskWorker()
+	w.registerTaskRuntime()
+	w.distributeTaskItems()
+	w.reloadTaskItems()
+	assert.False(t, w.dropSupersededItems())
+
+	// requested to be released but version of config was missed
+	assignment, _ := memoryStore.GetTaskAssignment(TEST_STRATEGY_ID, TEST_TASK_ID, TEST_ITEM_ID1)
+	assignment.RequestedRuntimeID = RUNTIME_EMPTY
+	memoryStore.SetTaskAssignment(assignment)
+	assert.True(t, w.dropSupersededItems())
+	w.reloadTaskItems()
+	assert.False(t, w.dropSupersededItems())
+	assert.Equal(t, 1, len(w.taskItems))
+
+	// assigned directly
+	assignment, _ = memoryStore.GetTaskAssignment(TEST_STRATEGY_ID, TEST_TASK_ID, TEST_ITEM_ID1)
+	assert.Nil(t, w.transfer(assignment, w.runtime.ID))
+	memoryStore.SetTaskAssignment(assignment)
+	assert.True(t, w.dropSupersededItems())
+	w.reloadTaskItems()
+	assert.False(t, w.dropSupersededItems())
+	assert.Equal(t, 2, len(w.taskItems))
+}
+
+func TestReleasedSpares(t *testing.T) {
+	clearStore()
+	w := newTaskWorker()
+	w.registerTaskRuntime()
+	// owner stopped before releasing it
+	memoryStore.SetTaskAssignment(&definition.TaskAssignment{
+		StrategyID:         TEST_STRATEGY_ID,
+		TaskID:             TEST_TASK_ID,
+		ItemID:             TEST_ITEM_ID1,
+		RequestedRuntimeID: RUNTIME_EMPTY,
+	})
+	// owner died before handing it over
+	memoryStore.SetTaskAssignment(&definition.TaskAssignment{
+		StrategyID:         TEST_STRATEGY_ID,
+		TaskID:             TEST_TASK_ID,
+		ItemID:             TEST_ITEM_ID2,
+		RuntimeID:          "dead",
+		RequestedRuntimeID: w.runtime.ID,
+	})
+	_, spares, _, err := w.getCurrentAssignments()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(spares))
+
+	w.distributeTaskItems()
+	w.reloadTaskItems()
+	assert.Equal(t, 2, len(w.taskItems))
+	assignment, _ := memoryStore.GetTaskAssignment(TEST_STRATEGY_ID, TEST_TASK_ID, TEST_ITEM_ID1)
+	assert.Equal(t, w.runtime.ID, assignment.RuntimeID)
+	assert.Equal(t, "", assignment.RequestedRuntimeID)
+	assignment, _ = memoryStore.GetTaskAssignment(TEST_STRATEGY_ID, TEST_TASK_ID, TEST_ITEM_ID2)
+	assert.Equal(t, w.runtime.ID, assignment.RuntimeID)
+	assert.Equal(t, "", assignment.RequestedRuntimeID)
+}
+
 func TestSchedule(t *testing.T) {
 	clearStore()
 	w := newTaskWorker()
@@ -179,3 +317,186 @@ func TestSchedule(t *testing.T) {
 	assert.NotNil(t, r)
 	assert.NotEqual(t, w.runtime.ID, r.RuntimeID)
 }
+
+func newItemsWorker(s store.Store, items []definition.TaskItem) *TaskWorker {
+	RegisterTaskTypeName("demoHeartbeat", &DemoHeartbeatTask{})
+	w, _ := NewTask(definition.Strategy{
+		ID:      TEST_STRATEGY_ID,
+		Total:   1,
+		Kind:    definition.TaskKind,
+		Bind:    TEST_TASK_ID,
+		Enabled: true,
+	}, definition.Task{
+		ID:                TEST_TASK_ID,
+		Bind:              "demoHeartbeat",
+		BatchCount:        1,
+		ExecutorCount:     1,
+		HeartbeatInterval: 200,
+		DeathTimeout:      30000,
+		Items:             items,
+	}, s, "test_manager")
+	return w.(*TaskWorker)
+}
+
+// owners distributes and reloads task items until settled and returns owners of them
+func owners(t *testing.T, s store.Store, workers []*TaskWorker) map[string]string {
+	for round := 0; round < 5; round++ {
+		for _, w := range workers {
+			w.distributeTaskItems()
+		}
+		for _, w := range workers {
+			w.reloadTaskItems()
+		}
+	}
+	result := make(map[string]string)
+	assignments, _ := s.GetTaskAssignments(TEST_STRATEGY_ID, TEST_TASK_ID)
+	for _, assignment := range assignments {
+		assert.Empty(t, assignment.RequestedRuntimeID)
+		result[assignment.ItemID] = assignment.RuntimeID
+	}
+	return result
+}
+
+func TestDistributeMovement(t *testing.T) {
+	s := memory.New()
+	defer s.Close()
+	items := make([]definition.TaskItem, 12)
+	for i := range items {
+		items[i].ID = fmt.Sprint("item", i)
+	}
+	r := rand.New(rand.NewSource(2020))
+	workers := make([]*TaskWorker, 0)
+	before := make(map[string]string)
+	for step := 0; step < 40; step++ {
+		if len(workers) < 2 || len(workers) < 8 && r.Intn(2) == 0 {
+			// join
+			w := newItemsWorker(s, items)
+			w.registerTaskRuntime()
+			workers = append(workers, w)
+		} else {
+			// leave
+			i := r.Intn(len(workers))
+			s.RemoveTaskRuntime(TEST_STRATEGY_ID, TEST_TASK_ID, workers[i].runtime.ID)
+			workers = append(workers[:i], workers[i+1:]...)
+		}
+		after := owners(t, s, workers)
+		assert.Equal(t, len(items), len(after))
+
+		// only the minimum is moved: all of the ones left and the ones exceeding their fair share
+		current := make(map[string]int)
+		counts := make(map[string]int)
+		for _, item := range items {
+			current[before[item.ID]]++
+			counts[after[item.ID]]++
+		}
+		avg := len(items) / len(workers)
+		other := len(items) % len(workers)
+		kept := 0
+		for _, w := range workers {
+			num := counts[w.runtime.ID]
+			assert.True(t, num == avg || num == avg+1, "unbalanced: %v", counts)
+			if cur := current[w.runtime.ID]; cur > avg {
+				kept += avg
+				if other > 0 {
+					kept++
+					other--
+				}
+			} else {
+				kept += cur
+			}
+		}
+		moved := 0
+		for _, item := range items {
+			if before[item.ID] != after[item.ID] {
+				moved++
+			}
+		}
+		assert.Equal(t, len(items)-kept, moved, "step %d", step)
+		before = after
+	}
+}
+
+func TestDistributeAssigner(t *testing.T) {
+	s := memory.New()
+	defer s.Close()
+	items := []definition.TaskItem{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	assigner.Register("firstRuntime", assigner.Func(func(state *assigner.State) []string {
+		owners := make([]string, len(state.Units))
+		for k := range owners {
+			owners[k] = state.Nodes[0].ID
+		}
+		return owners
+	}))
+	workers := []*TaskWorker{newItemsWorker(s, items), newItemsWorker(s, items)}
+	for _, w := range workers {
+		w.taskDefine.Assigner = "firstRuntime"
+		w.registerTaskRuntime()
+	}
+	result := owners(t, s, workers)
+	assert.Equal(t, 3, len(result))
+	first := result["a"]
+	assert.NotEmpty(t, first)
+	for _, owner := range result {
+		assert.Equal(t, first, owner)
+	}
+
+	_, err := NewTask(workers[0].strategyDefine, definition.Task{
+		ID:       TEST_TASK_ID,
+		Bind:     "demoHeartbeat",
+		Assigner: "unknown",
+	}, s, "test_manager")
+	assert.NotNil(t, err)
+}
+
+func TestDistributeWeighted(t *testing.T) {
+	s := memory.New()
+	defer s.Close()
+	items := []definition.TaskItem{{ID: "big", Weight: 4}, {ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}}
+	workers := []*TaskWorker{newItemsWorker(s, items), newItemsWorker(s, items)}
+	for _, w := range workers {
+		w.registerTaskRuntime()
+	}
+	result := owners(t, s, workers)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		assert.NotEqual(t, result["big"], result[id], "%s shares the runtime of big", id)
+	}
+	assignment, _ := s.GetTaskAssignment(TEST_STRATEGY_ID, TEST_TASK_ID, "big")
+	assert.Equal(t, 4, assignment.Weight)
+	for _, w := range workers {
+		if w.runtime.ID == result["big"] {
+			assert.Equal(t, []definition.TaskItem{{ID: "big", Weight: 4, Token: assignment.Token}}, w.taskItems)
+		}
+	}
+
+	// weights are capped by MaxTaskItems
+	for _, w := range workers {
+		w.taskDefine.Items[0].Weight = 3
+		w.taskDefine.MaxTaskItems = 3
+	}
+	result = owners(t, s, workers)
+	assignment, _ = s.GetTaskAssignment(TEST_STRATEGY_ID, TEST_TASK_ID, "big")
+	assert.Equal(t, 3, assignment.Weight)
+	assigned := 0
+	for _, owner := range result {
+		if owner != "" {
+			assigned++
+		}
+	}
+	assert.Equal(t, 4, assigned)
+}
+
+func TestShouldCheckAssignments(t *testing.T) {
+	s := memory.New()
+	defer s.Close()
+	w := newItemsWorker(s, []definition.TaskItem{{ID: "a"}})
+	assert.True(t, w.shouldCheckAssignments())
+	// once a heartbeat
+	assert.False(t, w.shouldCheckAssignments())
+	w.checkedAt = time.Now().Add(-time.Duration(w.taskDefine.HeartbeatInterval) * time.Millisecond)
+	assert.True(t, w.shouldCheckAssignments())
+	assert.False(t, w.shouldCheckAssignments())
+	// or changed
+	atomic.StoreInt32(&w.assignChanged, 1)
+	assert.True(t, w.shouldCheckAssignments())
+	assert.False(t, w.shouldCheckAssignments())
+}
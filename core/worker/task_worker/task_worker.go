@@ -12,6 +12,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/jasonjoo2010/goschedule/assigner"
 	"github.com/jasonjoo2010/goschedule/definition"
 	"github.com/jasonjoo2010/goschedule/log"
 	"github.com/jasonjoo2010/goschedule/store"
@@ -44,15 +45,29 @@ type TaskWorker struct {
 	wg             sync.WaitGroup
 	data           chan interface{}
 	queuedData     []interface{}
+	retryMu        sync.Mutex
+	retries        []*retryItem          // failed items waiting for backoff
+	ownedItems     []definition.TaskItem // snapshot of taskItems for retrying, guarded by retryMu
+	deadLetterSink DeadLetterSink
 	model          TaskModel
 	executor       TaskExecutor
-	task           types.TaskBase
+	task           types.TaskBaseContext
+	resolver       types.TaskItemResolver // optional, resolving task items of data for retrying
 	executors      int32
 	schedStart     cron.Schedule
 	schedEnd       cron.Schedule
 	interval       time.Duration
 	intervalNoData time.Duration
-	inCron         bool // Flagged indicating schedStart was triggered
+	selectTimeout  time.Duration
+	executeTimeout time.Duration
+	inCron         bool          // Flagged indicating schedStart was triggered
+	lastFire       time.Time     // Last fire time of schedStart
+	fireLoaded     bool          // Whether last fire time has been loaded from storage
+	pendingFires   int           // Runs waiting to start because of overlap or misfire
+	distributeC    chan struct{} // trigger of distributing task items immediately
+	reloadC        chan struct{} // trigger of reloading task items immediately
+	checkedAt      time.Time     // Last time of checking superseded task items
+	assignChanged  int32         // Whether assignments have been changed since last checking, set by watching
 
 	ctx       context.Context
 	ctxCancel context.CancelFunc
@@ -63,15 +78,24 @@ type TaskWorker struct {
 	Statistics    definition.Statistics
 }
 
-func getTaskFromType(t reflect.Type) types.TaskBase {
-	if v, ok := reflect.New(t).Interface().(types.TaskBase); ok {
+// isTask returns whether v implements either TaskBase or TaskBaseContext
+func isTask(v interface{}) bool {
+	switch v.(type) {
+	case types.TaskBase, types.TaskBaseContext:
+		return true
+	}
+	return false
+}
+
+func getTaskFromType(t reflect.Type) interface{} {
+	if v := reflect.New(t).Interface(); isTask(v) {
 		return v
 	}
 	logrus.Warn("Entry registered is not a convertable type: ", t)
 	return nil
 }
 
-func getTask(name string) types.TaskBase {
+func getTask(name string) interface{} {
 	var (
 		ok bool
 		v  interface{}
@@ -84,14 +108,49 @@ func getTask(name string) types.TaskBase {
 	if ok {
 		return getTaskFromType(t)
 	}
-	val, ok := v.(types.TaskBase)
-	if ok {
-		return val
+	if isTask(v) {
+		return v
 	}
 	logrus.Warn("Entry registered for key: ", name, " is not either a type nor inst")
 	return nil
 }
 
+// singleTask returns the context-aware form of task, adapting it if necessary
+func singleTask(inst interface{}) (types.TaskSingleContext, bool) {
+	switch t := inst.(type) {
+	case types.TaskSingleContext:
+		return t, true
+	case types.TaskSingle:
+		return types.SingleContext(t), true
+	}
+	return nil, false
+}
+
+// batchTask returns the context-aware form of task, adapting it if necessary
+func batchTask(inst interface{}) (types.TaskBatchContext, bool) {
+	switch t := inst.(type) {
+	case types.TaskBatchContext:
+		return t, true
+	case types.TaskBatch:
+		return types.BatchContext(t), true
+	}
+	return nil, false
+}
+
+func registerTaskType(name string, task interface{}) {
+	if name == "" {
+		panic("Could not register a task using empty name")
+	}
+	t := reflect.TypeOf(utils.Dereference(task))
+	taskRegistryMap.Store(name, t)
+	logrus.Info("Register new task type: ", name)
+}
+
+func registerTaskInst(name string, task interface{}) {
+	taskRegistryMap.Store(name, task)
+	logrus.Info("Register a task instance: ", name)
+}
+
 // RegisterTaskType registers a task type with key inferred by its type
 func RegisterTaskType(task types.TaskBase) {
 	if task == nil {
@@ -102,15 +161,26 @@ func RegisterTaskType(task types.TaskBase) {
 
 // RegisterTaskTypeName registers a task type with key
 func RegisterTaskTypeName(name string, task types.TaskBase) {
-	if name == "" {
-		panic("Could not register a task using empty name")
+	if task == nil {
+		panic("Could not register a task using nil as value")
 	}
+	registerTaskType(name, task)
+}
+
+// RegisterTaskTypeContext registers a context-aware task type with key inferred by its type
+func RegisterTaskTypeContext(task types.TaskBaseContext) {
 	if task == nil {
 		panic("Could not register a task using nil as value")
 	}
-	t := reflect.TypeOf(utils.Dereference(task))
-	taskRegistryMap.Store(name, t)
-	logrus.Info("Register new task type: ", name)
+	RegisterTaskTypeContextName(utils.TypeName(utils.Dereference(task)), task)
+}
+
+// RegisterTaskTypeContextName registers a context-aware task type with key
+func RegisterTaskTypeContextName(name string, task types.TaskBaseContext) {
+	if task == nil {
+		panic("Could not register a task using nil as value")
+	}
+	registerTaskType(name, task)
 }
 
 // RegisterTaskInst registers a task in single instance model with key inferred by its type
@@ -120,14 +190,23 @@ func RegisterTaskInst(task types.TaskBase) {
 
 // RegisterTaskInstName registers a task in single instance model with given key
 func RegisterTaskInstName(name string, task types.TaskBase) {
-	taskRegistryMap.Store(name, task)
-	logrus.Info("Register a task instance: ", name)
+	registerTaskInst(name, task)
+}
+
+// RegisterTaskInstContext registers a context-aware task in single instance model with key inferred by its type
+func RegisterTaskInstContext(task types.TaskBaseContext) {
+	RegisterTaskInstContextName(utils.TypeName(task), task)
+}
+
+// RegisterTaskInstContextName registers a context-aware task in single instance model with given key
+func RegisterTaskInstContextName(name string, task types.TaskBaseContext) {
+	registerTaskInst(name, task)
 }
 
 // NewTask creates a new task and initials necessary fields
 //	Please don't initial TaskWorker manually
 func NewTask(strategy definition.Strategy, task definition.Task, store store.Store, schedulerId string) (types.Worker, error) {
-	var inst types.TaskBase
+	var inst interface{}
 	sequence, err := store.Sequence()
 	if err != nil {
 		logrus.Error("Generate sequence from storage failed: ", err.Error())
@@ -138,21 +217,32 @@ func NewTask(strategy definition.Strategy, task definition.Task, store store.Sto
 		logrus.Warn("Create task worker failed: ", task.Bind)
 		return nil, errors.New("Convert to TaskBase failed: " + task.Bind)
 	}
+	sink, err := getDeadLetterSink(task.DeadLetter, store)
+	if err != nil {
+		logrus.Warn("Create task worker failed: ", err.Error())
+		return nil, err
+	}
+	if _, err := assigner.Get(task.Assigner); err != nil {
+		logrus.Warn("Create task worker failed: ", err.Error())
+		return nil, err
+	}
 	logrus.Info("New task ", task.ID, " created")
 	w := &TaskWorker{
 		data:           make(chan interface{}, utils.Max(10, task.FetchCount*len(task.Items)*2)),
-		task:           inst,
 		strategyDefine: strategy,
 		ownSign:        utils.OwnSign(strategy.ID),
 		taskDefine:     task,
 		taskItems:      make([]definition.TaskItem, 0),
 		parameter:      task.Parameter,
 		store:          store,
+		deadLetterSink: sink,
+		distributeC:    make(chan struct{}, 1),
+		reloadC:        make(chan struct{}, 1),
 		runtime: definition.TaskRuntime{
 			ID:            utils.GenerateUUID(sequence),
 			Version:       1,
 			Createtime:    time.Now().Unix() * 1000,
-			LastHeartbeat: time.Now().Unix() * 1000,
+			LastHeartbeat: store.Time(),
 			Hostname:      utils.GetHostName(),
 			IP:            utils.GetHostIPv4(),
 			ExecutorCount: task.ExecutorCount,
@@ -163,6 +253,7 @@ func NewTask(strategy definition.Strategy, task definition.Task, store store.Sto
 			Bind:          task.Bind,
 		},
 	}
+	w.resolver, _ = inst.(types.TaskItemResolver)
 	w.schedStart, w.schedEnd = utils.ParseStrategyCron(&strategy)
 	if task.Interval > 0 {
 		w.interval = time.Duration(task.Interval) * time.Millisecond
@@ -170,16 +261,23 @@ func NewTask(strategy definition.Strategy, task definition.Task, store store.Sto
 	if task.IntervalNoData > 0 {
 		w.intervalNoData = time.Duration(task.IntervalNoData) * time.Millisecond
 	}
+	if task.SelectTimeout > 0 {
+		w.selectTimeout = time.Duration(task.SelectTimeout) * time.Millisecond
+	}
+	if task.ExecuteTimeout > 0 {
+		w.executeTimeout = time.Duration(task.ExecuteTimeout) * time.Millisecond
+	}
 	if task.Model == definition.Stream {
 		w.model = NewStreamModel(w)
 	} else {
 		w.model = NewNormalModel(w)
 	}
 	if w.taskDefine.BatchCount > 1 {
-		t, ok := inst.(types.TaskBatch)
+		t, ok := batchTask(inst)
 		if !ok {
 			return nil, errors.New("Specific bind is not a TaskBatch: " + task.Bind)
 		}
+		w.task = t
 		w.executor = &BatchExecutor{
 			worker: w,
 			task:   t,
@@ -190,10 +288,11 @@ func NewTask(strategy definition.Strategy, task definition.Task, store store.Sto
 			},
 		}
 	} else {
-		t, ok := inst.(types.TaskSingle)
+		t, ok := singleTask(inst)
 		if !ok {
 			return nil, errors.New("Specific bind is not a TaskSingle: " + task.Bind)
 		}
+		w.task = t
 		w.executor = &SingleExecutor{
 			worker: w,
 			task:   t,
@@ -217,6 +316,19 @@ func (w *TaskWorker) shouldRun() bool {
 	return false
 }
 
+// callContext returns context of a call to task which is cancelled when worker is stopping
+//	or timeout is reached.
+func (w *TaskWorker) callContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx := w.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
 func (w *TaskWorker) executeOnceOrReturn() bool {
 	return w.executor.ExecuteOrReturn()
 }
@@ -251,17 +363,19 @@ func (w *TaskWorker) selectOnce() {
 	}()
 	// cron
 	if !w.shouldRun() {
-		delay := utils.CronDelay(w.schedStart, w.schedEnd)
-		if delay > 0 {
-			next := time.Now().Unix()*1000 + int64(delay/time.Millisecond)
-			if next%1000 > 0 {
-				next = (next/1000 + 1) * 1000
+		if w.schedEnd == nil {
+			w.waitFire()
+		} else {
+			delay := utils.CronDelay(w.schedStart, w.schedEnd)
+			if delay > 0 {
+				next := time.Now().Unix()*1000 + int64(delay/time.Millisecond)
+				if next%1000 > 0 {
+					next = (next/1000 + 1) * 1000
+				}
+				w.NextBeginTime = next
+				utils.DelayContext(w.ctx, delay)
 			}
-			w.NextBeginTime = next
-			utils.DelayContext(w.ctx, delay)
-		}
-		w.NextBeginTime = 0
-		if w.schedStart != nil {
+			w.NextBeginTime = 0
 			w.inCron = true
 		}
 	}
@@ -270,6 +384,10 @@ func (w *TaskWorker) selectOnce() {
 		return
 	}
 
+	// retried items go through the queue again after backoff
+	if due := w.dueRetries(); len(due) > 0 {
+		w.queuedData = append(w.queuedData, due...)
+	}
 	if len(w.queuedData) > 0 {
 		arr := w.queuedData
 		w.queuedData = nil
@@ -288,8 +406,11 @@ func (w *TaskWorker) selectOnce() {
 			logrus.Info("Queue is not empty and wait to reload next time")
 			return
 		}
+		if w.reloadTaskItems() {
+			w.configVersion = ver
+		}
+	} else if w.shouldCheckAssignments() && w.dropSupersededItems() && len(w.data) == 0 {
 		w.reloadTaskItems()
-		w.configVersion = ver
 	}
 	// Check available task item
 	if len(w.taskItems) < 1 {
@@ -298,15 +419,24 @@ func (w *TaskWorker) selectOnce() {
 			logrus.Warn("Cannot get any task item after quite a long time.")
 			w.noItemsCycles = 0
 		}
-		utils.DelayContext(w.ctx, time.Duration(w.taskDefine.HeartbeatInterval)*time.Millisecond)
+		utils.DelayContextWithTrigger(w.ctx, time.Duration(w.taskDefine.HeartbeatInterval)*time.Millisecond, w.reloadC)
 		return
 	}
 	w.noItemsCycles = 0
-	arr := w.task.Select(w.parameter, w.ownSign, w.taskItems, w.taskDefine.FetchCount)
+	ctx, cancel := w.callContext(w.selectTimeout)
+	arr := w.task.SelectContext(ctx, w.parameter, w.ownSign, w.taskItems, w.taskDefine.FetchCount)
+	if ctx.Err() == context.DeadlineExceeded {
+		logrus.Warn("Selecting exceeded the deadline of ", w.selectTimeout)
+		w.Statistics.SelectTimeout()
+	}
+	cancel()
 	arr_size := len(arr)
 	w.Statistics.Select(int64(arr_size))
 	if arr_size < 1 {
-		w.inCron = false
+		if w.inCron {
+			w.inCron = false
+			w.runFinished()
+		}
 		if w.intervalNoData > 0 {
 			utils.DelayContext(w.ctx, w.intervalNoData)
 		} else if w.interval > 0 {
@@ -341,6 +471,7 @@ func (w *TaskWorker) consumeRemained() {
 			w.fillOrQueued(arr)
 		}
 	}
+	w.abandonRetries()
 }
 
 // main loop(outer)
@@ -394,19 +525,31 @@ func (w *TaskWorker) Start(strategyId, parameter string) error {
 		})
 
 	// schedule loop
-	go utils.LoopContext(w.ctx,
+	go utils.LoopContextWithTrigger(w.ctx,
 		10*time.Second,
+		w.distributeC,
 		w.distributeTaskItems,
 		func() {
 			defer w.wg.Done()
 			defer w.cleanupSchedule()
 		})
+
+	if watcher, ok := store.AsWatcher(w.store); ok {
+		w.wg.Add(1)
+		go w.watch(watcher)
+	}
 	return nil
 }
 
 func (w *TaskWorker) Stop(strategyId, parameter string) error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	if w.ctx == nil {
+		// stopped before started asynchronously, never start it later
+		w.ctx, w.ctxCancel = context.WithCancel(context.Background())
+		w.ctxCancel()
+		return nil
+	}
 	if utils.ContextDone(w.ctx) {
 		return errors.New("The task worker has been closed")
 	}
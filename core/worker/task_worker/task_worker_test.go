@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/types"
 	"github.com/jasonjoo2010/goschedule/utils"
 	"github.com/stretchr/testify/assert"
 )
@@ -65,7 +66,7 @@ func TestRegister(t *testing.T) {
 	RegisterTaskTypeName("a", &DemoHeartbeatTask{})
 	assert.Equal(t, reflect.TypeOf(&DemoHeartbeatTask{}), reflect.TypeOf(getTask("a")))
 
-	heartbeatTask := getTask("a")
+	heartbeatTask := getTask("a").(types.TaskBase)
 	assert.NotNil(t, heartbeatTask)
 	assert.Equal(t, 3, len(heartbeatTask.Select("asdf", "", []definition.TaskItem{}, 10)))
 
@@ -82,4 +83,22 @@ func TestRegister(t *testing.T) {
 	demoTask, ok = getTask("b").(*DemoHeartbeatTask)
 	assert.True(t, ok)
 	assert.Equal(t, "i0", demoTask.Name)
+
+	// context-aware
+	RegisterTaskTypeContext(&demoTaskSingleContext{})
+	assert.IsType(t, &demoTaskSingleContext{}, getTask(utils.TypeName(demoTaskSingleContext{})))
+	RegisterTaskTypeContextName("c", &demoTaskSingleContext{})
+	assert.IsType(t, &demoTaskSingleContext{}, getTask("c"))
+	contextInst := &demoTaskSingleContext{}
+	RegisterTaskInstContext(contextInst)
+	RegisterTaskInstContextName("d", contextInst)
+	assert.Equal(t, contextInst, getTask(utils.TypeName(contextInst)))
+	assert.Equal(t, contextInst, getTask("d"))
+}
+
+func TestStopBeforeStart(t *testing.T) {
+	w := newTaskWorker()
+	assert.Nil(t, w.Stop("s0", ""))
+	assert.NotNil(t, w.Start("s0", ""))
+	assert.NotNil(t, w.Stop("s0", ""))
 }
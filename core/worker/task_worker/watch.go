@@ -0,0 +1,38 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package task_worker
+
+import (
+	"sync/atomic"
+
+	"github.com/jasonjoo2010/goschedule/log"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/utils"
+)
+
+// watch triggers distributing or reloading of task items when related changes happen in storage
+func (w *TaskWorker) watch(watcher store.Watcher) {
+	defer w.wg.Done()
+
+	events, err := watcher.Watch(w.ctx)
+	if err != nil {
+		log.Warnf("Watch store failed, fallback to polling: %s", err.Error())
+		return
+	}
+	for e := range events {
+		if e.StrategyID != w.strategyDefine.ID || e.TaskID != w.taskDefine.ID {
+			continue
+		}
+		switch e.Kind {
+		case store.TaskRuntimeEvent:
+			utils.Trigger(w.distributeC)
+		case store.TaskAssignmentEvent:
+			atomic.StoreInt32(&w.assignChanged, 1)
+			utils.Trigger(w.distributeC)
+		case store.TaskItemsConfigVersionEvent:
+			utils.Trigger(w.reloadC)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package task_worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchTriggerDistribute(t *testing.T) {
+	clearStore()
+	w := newTaskWorker()
+	w.Start(TEST_STRATEGY_ID, "")
+	time.Sleep(200 * time.Millisecond)
+
+	assignments, _ := memoryStore.GetTaskAssignments(TEST_STRATEGY_ID, TEST_TASK_ID)
+	assert.Equal(t, 2, len(assignments))
+
+	// a new runtime joins and it should be balanced before the next polling
+	memoryStore.SetTaskRuntime(&definition.TaskRuntime{
+		ID:            "r1$99999999999999",
+		LastHeartbeat: time.Now().Unix() * 1000,
+		TaskID:        TEST_TASK_ID,
+		StrategyID:    TEST_STRATEGY_ID,
+	})
+	time.Sleep(200 * time.Millisecond)
+
+	requested := 0
+	assignments, _ = memoryStore.GetTaskAssignments(TEST_STRATEGY_ID, TEST_TASK_ID)
+	for _, assign := range assignments {
+		if assign.RequestedRuntimeID == "r1$99999999999999" || assign.RuntimeID == "r1$99999999999999" {
+			requested++
+		}
+	}
+	assert.Equal(t, 1, requested)
+
+	w.Stop(TEST_STRATEGY_ID, "")
+	clearStore()
+}
@@ -60,6 +60,22 @@ func GetFunc(name string) types.FuncInterface {
 	return nil
 }
 
+// GetFuncContext returns the func registered in context-aware form, plain funcs are adapted
+func GetFuncContext(name string) types.FuncContextInterface {
+	if v, ok := registryMap.Load(name); ok {
+		switch fn := v.(type) {
+		case types.FuncContextInterface:
+			return fn
+		case types.FuncInterface:
+			return fn.WithContext()
+		}
+		logrus.Warn("Func registered for key: ", name, " is in incorrect type")
+		return nil
+	}
+	logrus.Warn("No func registered for key: ", name)
+	return nil
+}
+
 // Register registers specific type with its full package path as key
 func Register(worker types.Worker) {
 	if worker == nil {
@@ -97,3 +113,9 @@ func RegisterFunc(name string, fn types.FuncInterface) {
 	registryMap.Store(name, fn)
 	logrus.Info("Register new worker func: ", name)
 }
+
+// RegisterFuncContext registers context-aware func worker into registry which could be fetch through GetFuncContext(name string)
+func RegisterFuncContext(name string, fn types.FuncContextInterface) {
+	registryMap.Store(name, fn)
+	logrus.Info("Register new worker func: ", name)
+}
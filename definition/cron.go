@@ -0,0 +1,193 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package definition
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+	weekdays   = map[string]int{"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6}
+)
+
+// maxCronDays limits the days to look forward when searching next time matching extensions of day
+const maxCronDays = 366 * 5
+
+// daySchedule filters days of a schedule by extensions which are not supported by the underlying parser
+type daySchedule struct {
+	base  cron.Schedule
+	match func(t time.Time) bool
+}
+
+func (s *daySchedule) Next(t time.Time) time.Time {
+	next := s.base.Next(t)
+	for i := 0; i < maxCronDays && !next.IsZero(); i++ {
+		if s.match(next) {
+			return next
+		}
+		// skip the rest of the day
+		y, m, d := next.Date()
+		next = s.base.Next(time.Date(y, m, d+1, 0, 0, 0, 0, next.Location()).Add(-time.Nanosecond))
+	}
+	return time.Time{}
+}
+
+func lastDayOfMonth(t time.Time) int {
+	y, m, _ := t.Date()
+	return time.Date(y, m+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
+
+// nearestWeekday returns the weekday nearest to the given day in the same month
+func nearestWeekday(t time.Time, day int) int {
+	last := lastDayOfMonth(t)
+	if day > last {
+		day = last
+	}
+	y, m, _ := t.Date()
+	switch time.Date(y, m, day, 0, 0, 0, 0, t.Location()).Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return 3
+		}
+		return day - 1
+	case time.Sunday:
+		if day == last {
+			return day - 2
+		}
+		return day + 1
+	}
+	return day
+}
+
+func parseWeekday(str string) (int, error) {
+	if n, ok := weekdays[strings.ToUpper(str)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(str)
+	if err != nil || n < 0 || n > 7 {
+		return 0, errors.New("Illegal day of week: " + str)
+	}
+	return n % 7, nil
+}
+
+// parseDom parses extensions of day of month: L, LW and nW
+func parseDom(field string) (func(t time.Time) bool, error) {
+	switch {
+	case field == "L":
+		return func(t time.Time) bool {
+			return t.Day() == lastDayOfMonth(t)
+		}, nil
+	case field == "LW":
+		return func(t time.Time) bool {
+			return t.Day() == nearestWeekday(t, lastDayOfMonth(t))
+		}, nil
+	case strings.HasSuffix(field, "W"):
+		day, err := strconv.Atoi(strings.TrimSuffix(field, "W"))
+		if err != nil || day < 1 || day > 31 {
+			return nil, errors.New("Illegal day of month: " + field)
+		}
+		return func(t time.Time) bool {
+			return t.Day() == nearestWeekday(t, day)
+		}, nil
+	}
+	return nil, errors.New("Illegal day of month: " + field)
+}
+
+// parseDow parses extensions of day of week: nL and n#k
+func parseDow(field string) (func(t time.Time) bool, error) {
+	if pos := strings.Index(field, "#"); pos > 0 {
+		weekday, err := parseWeekday(field[:pos])
+		if err != nil {
+			return nil, err
+		}
+		nth, err := strconv.Atoi(field[pos+1:])
+		if err != nil || nth < 1 || nth > 5 {
+			return nil, errors.New("Illegal day of week: " + field)
+		}
+		return func(t time.Time) bool {
+			return int(t.Weekday()) == weekday && (t.Day()-1)/7+1 == nth
+		}, nil
+	}
+	if strings.HasSuffix(field, "L") && len(field) > 1 {
+		weekday, err := parseWeekday(strings.TrimSuffix(field, "L"))
+		if err != nil {
+			return nil, err
+		}
+		return func(t time.Time) bool {
+			return int(t.Weekday()) == weekday && t.Day()+7 > lastDayOfMonth(t)
+		}, nil
+	}
+	return nil, errors.New("Illegal day of week: " + field)
+}
+
+func isAny(field string) bool {
+	return field == "*" || field == "?"
+}
+
+// ParseCron parses a cron expression. Besides standard fields it supports:
+//	An optional leading field of seconds, 6 fields in total, e.g. "0 0 * * * ?"
+//	Descriptors like @every 5m, @hourly, @daily, @weekly, @monthly and @yearly
+//	Day of month: L(last day), LW(last weekday) and 15W(weekday nearest to the 15th)
+//	Day of week: 5L(last Friday) and 5#3(the third Friday), only one of both days can be extended
+//	Prefix of time zone like CRON_TZ=Asia/Shanghai
+func ParseCron(spec string) (cron.Schedule, error) {
+	expr := strings.TrimSpace(spec)
+	prefix := ""
+	if strings.HasPrefix(expr, "CRON_TZ=") || strings.HasPrefix(expr, "TZ=") {
+		pos := strings.IndexAny(expr, " \t")
+		if pos < 0 {
+			return nil, errors.New("Missing fields in cron expression: " + spec)
+		}
+		prefix, expr = expr[:pos+1], strings.TrimSpace(expr[pos:])
+	}
+	fields := strings.Fields(expr)
+	if strings.HasPrefix(expr, "@") || (len(fields) != 5 && len(fields) != 6) {
+		return cronParser.Parse(spec)
+	}
+	domIdx, dowIdx := len(fields)-3, len(fields)-1
+	dom, dow := fields[domIdx], fields[dowIdx]
+	domExt := strings.ContainsAny(dom, "LW")
+	dowExt := strings.ContainsAny(dow, "L#")
+	if !domExt && !dowExt {
+		return cronParser.Parse(spec)
+	}
+	var (
+		match func(t time.Time) bool
+		err   error
+	)
+	switch {
+	case domExt && isAny(dow):
+		match, err = parseDom(dom)
+		fields[domIdx] = "*"
+	case dowExt && isAny(dom):
+		match, err = parseDow(dow)
+		fields[dowIdx] = "*"
+	default:
+		err = errors.New("Only one of day of month and day of week can be specified with extensions: " + spec)
+	}
+	if err != nil {
+		return nil, err
+	}
+	base, err := cronParser.Parse(prefix + strings.Join(fields, " "))
+	if err != nil {
+		return nil, err
+	}
+	return &daySchedule{base: base, match: match}, nil
+}
+
+// ParseCronIn parses a cron expression in the given time zone, local time zone is used if it's empty.
+//	A CRON_TZ= or TZ= prefix of expression takes precedence over the time zone.
+func ParseCronIn(spec, timeZone string) (cron.Schedule, error) {
+	if timeZone != "" && !strings.HasPrefix(spec, "CRON_TZ=") && !strings.HasPrefix(spec, "TZ=") {
+		spec = "CRON_TZ=" + timeZone + " " + spec
+	}
+	return ParseCron(spec)
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package definition
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func nextTimes(t *testing.T, spec string, from time.Time, n int) []time.Time {
+	sched, err := ParseCron(spec)
+	assert.Nil(t, err, spec)
+	if err != nil {
+		return nil
+	}
+	result := make([]time.Time, 0, n)
+	for next := from; len(result) < n; {
+		next = sched.Next(next)
+		result = append(result, next)
+	}
+	return result
+}
+
+func day(year int, month time.Month, day, hour, min, sec int) time.Time {
+	return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+}
+
+func TestParseCron(t *testing.T) {
+	from := day(2021, 1, 1, 0, 0, 30)
+
+	// seconds are optional
+	assert.Equal(t, []time.Time{day(2021, 1, 1, 1, 0, 0)}, nextTimes(t, "TZ=UTC 0 0 * * * ?", from, 1))
+	assert.Equal(t, []time.Time{day(2021, 1, 1, 1, 0, 0)}, nextTimes(t, "TZ=UTC 0 * * * *", from, 1))
+
+	// descriptors
+	assert.Equal(t, []time.Time{day(2021, 1, 1, 1, 0, 0)}, nextTimes(t, "TZ=UTC @hourly", from, 1))
+	assert.Equal(t, []time.Time{day(2021, 1, 2, 0, 0, 0)}, nextTimes(t, "TZ=UTC @daily", from, 1))
+	assert.Equal(t, []time.Time{day(2021, 1, 1, 0, 5, 30)}, nextTimes(t, "@every 5m", from, 1))
+
+	// errors
+	for _, spec := range []string{"", "* * *", "61 * * * * ?", "@never", "0 0 0 L * 5L", "0 0 0 32W * ?",
+		"0 0 0 ? * 8L", "0 0 0 ? * 5#6", "0 0 0 XW * ?", "CRON_TZ=UTC", "CRON_TZ=Nowhere/Unknown 0 * * * *"} {
+		_, err := ParseCron(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+func TestParseCronExtensions(t *testing.T) {
+	from := day(2021, 1, 1, 0, 0, 0)
+
+	// last day of month
+	assert.Equal(t, []time.Time{
+		day(2021, 1, 31, 12, 0, 0),
+		day(2021, 2, 28, 12, 0, 0),
+		day(2021, 3, 31, 12, 0, 0),
+		day(2021, 4, 30, 12, 0, 0),
+	}, nextTimes(t, "TZ=UTC 0 0 12 L * ?", from, 4))
+
+	// last weekday of month: 2021-01-31 and 2021-10-31 are Sundays
+	assert.Equal(t, []time.Time{day(2021, 1, 29, 0, 0, 0), day(2021, 2, 26, 0, 0, 0)},
+		nextTimes(t, "TZ=UTC 0 0 0 LW * ?", from, 2))
+	assert.Equal(t, []time.Time{day(2021, 10, 29, 0, 0, 0)},
+		nextTimes(t, "TZ=UTC 0 0 0 LW 10 ?", from, 1))
+
+	// nearest weekday: 2021-05-15 is Saturday, 2021-08-15 is Sunday, 2021-05-01 is Saturday
+	assert.Equal(t, []time.Time{day(2021, 5, 14, 0, 0, 0)}, nextTimes(t, "TZ=UTC 0 0 0 15W 5 ?", from, 1))
+	assert.Equal(t, []time.Time{day(2021, 8, 16, 0, 0, 0)}, nextTimes(t, "TZ=UTC 0 0 0 15W 8 ?", from, 1))
+	assert.Equal(t, []time.Time{day(2021, 5, 3, 0, 0, 0)}, nextTimes(t, "TZ=UTC 0 0 0 1W 5 ?", from, 1))
+
+	// the third Friday
+	assert.Equal(t, []time.Time{day(2021, 1, 15, 9, 0, 0), day(2021, 2, 19, 9, 0, 0)},
+		nextTimes(t, "TZ=UTC 0 0 9 ? * 5#3", from, 2))
+	assert.Equal(t, []time.Time{day(2021, 1, 15, 9, 0, 0)}, nextTimes(t, "TZ=UTC 0 9 ? * FRI#3", from, 1))
+
+	// the last Friday
+	assert.Equal(t, []time.Time{day(2021, 1, 29, 9, 0, 0), day(2021, 2, 26, 9, 0, 0)},
+		nextTimes(t, "TZ=UTC 0 0 9 ? * 5L", from, 2))
+
+	// every second of last day only
+	assert.Equal(t, []time.Time{day(2021, 1, 31, 0, 0, 0), day(2021, 1, 31, 0, 0, 1)},
+		nextTimes(t, "TZ=UTC * * * L * ?", from, 2))
+
+	// leap year
+	assert.Equal(t, []time.Time{day(2024, 2, 29, 0, 0, 0)}, nextTimes(t, "TZ=UTC 0 0 0 L 2 ?", day(2023, 3, 1, 0, 0, 0), 1))
+}
+
+func TestParseCronIn(t *testing.T) {
+	from := day(2021, 1, 1, 0, 0, 0)
+	sched, err := ParseCronIn("0 0 9 * * ?", "Asia/Shanghai")
+	assert.Nil(t, err)
+	assert.Equal(t, day(2021, 1, 1, 1, 0, 0).Unix(), sched.Next(from).Unix())
+
+	sched, err = ParseCronIn("TZ=UTC 0 0 9 * * ?", "Asia/Shanghai")
+	assert.Nil(t, err)
+	assert.Equal(t, day(2021, 1, 1, 9, 0, 0).Unix(), sched.Next(from).Unix())
+
+	_, err = ParseCronIn("0 0 9 * * ?", "Nowhere/Unknown")
+	assert.NotNil(t, err)
+}
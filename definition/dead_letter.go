@@ -0,0 +1,26 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package definition
+
+import "encoding/json"
+
+// DeadLetter records task data which still failed after all attempts
+type DeadLetter struct {
+	ID         string
+	StrategyID string
+	TaskID     string
+	RuntimeID  string
+	OwnSign    string
+	Attempts   int
+	Reason     string // Reason of last failure
+	Createtime int64
+	Data       string      // Task data encoded in json
+	Item       interface{} `json:"-"` // Original task data, only available in current process
+}
+
+func (d *DeadLetter) String() string {
+	data, _ := json.Marshal(d)
+	return string(data)
+}
@@ -9,9 +9,13 @@ import "fmt"
 type Scheduler struct {
 	ID            string
 	LastHeartbeat int64
-	Enabled       bool // Whether it should begin to schedule
+	Enabled       bool              // Whether it should begin to schedule
+	Labels        map[string]string // Matched by selectors of strategies, eg. zone
+	Weight        int               // Capacity weight comparing to others, non-positive is treated as 1
+	MaxWorkers    int               // Maximum of workers over all strategies, 0 indicates no limit
 }
 
 func (s *Scheduler) String() string {
-	return fmt.Sprint("{id=", s.ID, ",lastHeartbeat=", s.LastHeartbeat, ",enabled=", s.Enabled, "}")
+	return fmt.Sprint("{id=", s.ID, ",lastHeartbeat=", s.LastHeartbeat, ",enabled=", s.Enabled, ",labels=", s.Labels,
+		",weight=", s.Weight, ",maxWorkers=", s.MaxWorkers, "}")
 }
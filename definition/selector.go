@@ -0,0 +1,88 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package definition
+
+import "errors"
+
+type SelectorOperator string
+
+const (
+	// SelectorIn requires the label to exist with one of the values
+	SelectorIn SelectorOperator = "In"
+	// SelectorNotIn requires the label to have none of the values, schedulers without the label match too
+	SelectorNotIn SelectorOperator = "NotIn"
+	// SelectorExists requires the label to exist whatever the value is
+	SelectorExists SelectorOperator = "Exists"
+)
+
+// LabelRequirement is a rule on a label of schedulers, eg. {Key: "zone", Operator: SelectorIn, Values: ["a", "b"]}
+type LabelRequirement struct {
+	Key      string
+	Operator SelectorOperator
+	Values   []string
+}
+
+func (r *LabelRequirement) Matches(labels map[string]string) bool {
+	val, ok := labels[r.Key]
+	switch r.Operator {
+	case SelectorIn:
+		return ok && contains(r.Values, val)
+	case SelectorNotIn:
+		return !ok || !contains(r.Values, val)
+	case SelectorExists:
+		return ok
+	}
+	return false
+}
+
+func (r *LabelRequirement) Validate() error {
+	if r.Key == "" {
+		return errors.New("Key of label requirement should not be empty")
+	}
+	switch r.Operator {
+	case SelectorIn, SelectorNotIn:
+		if len(r.Values) == 0 {
+			return errors.New("Values of label " + r.Key + " should not be empty for " + string(r.Operator))
+		}
+	case SelectorExists:
+		if len(r.Values) > 0 {
+			return errors.New("Values of label " + r.Key + " should be empty for " + string(r.Operator))
+		}
+	default:
+		return errors.New("Unknown operator of label " + r.Key + ": " + string(r.Operator))
+	}
+	return nil
+}
+
+// Selector selects schedulers by their labels, all requirements should be satisfied.
+//	An empty selector matches all schedulers.
+type Selector []LabelRequirement
+
+func (s Selector) Matches(labels map[string]string) bool {
+	for i := range s {
+		if !s[i].Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Selector) Validate() error {
+	for i := range s {
+		if err := s[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
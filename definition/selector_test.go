@@ -0,0 +1,59 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package definition
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectorMatches(t *testing.T) {
+	labels := map[string]string{"zone": "a", "gpu": ""}
+	assert.True(t, Selector(nil).Matches(labels))
+	assert.True(t, Selector(nil).Matches(nil))
+
+	for _, c := range []struct {
+		requirement LabelRequirement
+		matched     bool
+	}{
+		{LabelRequirement{Key: "zone", Operator: SelectorIn, Values: []string{"a", "b"}}, true},
+		{LabelRequirement{Key: "zone", Operator: SelectorIn, Values: []string{"b"}}, false},
+		{LabelRequirement{Key: "region", Operator: SelectorIn, Values: []string{"a"}}, false},
+		{LabelRequirement{Key: "zone", Operator: SelectorNotIn, Values: []string{"b"}}, true},
+		{LabelRequirement{Key: "zone", Operator: SelectorNotIn, Values: []string{"a"}}, false},
+		{LabelRequirement{Key: "region", Operator: SelectorNotIn, Values: []string{"a"}}, true},
+		{LabelRequirement{Key: "gpu", Operator: SelectorExists}, true},
+		{LabelRequirement{Key: "region", Operator: SelectorExists}, false},
+		{LabelRequirement{Key: "zone", Operator: "Unknown"}, false},
+	} {
+		assert.Equal(t, c.matched, c.requirement.Matches(labels), c.requirement)
+	}
+
+	selector := Selector{
+		{Key: "zone", Operator: SelectorIn, Values: []string{"a"}},
+		{Key: "gpu", Operator: SelectorExists},
+	}
+	assert.True(t, selector.Matches(labels))
+	assert.False(t, selector.Matches(map[string]string{"zone": "a"}))
+	assert.False(t, selector.Matches(nil))
+}
+
+func TestSelectorValidate(t *testing.T) {
+	assert.Nil(t, Selector{
+		{Key: "zone", Operator: SelectorIn, Values: []string{"a"}},
+		{Key: "zone", Operator: SelectorNotIn, Values: []string{"b"}},
+		{Key: "gpu", Operator: SelectorExists},
+	}.Validate())
+	for _, r := range []LabelRequirement{
+		{Operator: SelectorExists},
+		{Key: "zone", Operator: SelectorIn},
+		{Key: "zone", Operator: SelectorNotIn},
+		{Key: "zone", Operator: SelectorExists, Values: []string{"a"}},
+		{Key: "zone", Operator: "in", Values: []string{"a"}},
+	} {
+		assert.NotNil(t, Selector{r}.Validate(), r)
+	}
+}
@@ -10,13 +10,17 @@ import (
 )
 
 type Statistics struct {
-	LastFetchTime     int64
-	SelectCount       int64
-	SelectItemCount   int64
-	OtherCompareCount int64
-	ExecuteSuccCount  int64 // concurrent
-	ExecuteFailCount  int64 // concurrent
-	ExecuteSpendTime  int64 // concurrent
+	LastFetchTime       int64
+	SelectCount         int64
+	SelectItemCount     int64
+	OtherCompareCount   int64
+	SelectTimeoutCount  int64
+	ExecuteSuccCount    int64 // concurrent
+	ExecuteFailCount    int64 // concurrent
+	ExecuteSpendTime    int64 // concurrent
+	ExecuteTimeoutCount int64 // concurrent
+	RetryCount          int64 // concurrent
+	DeadLetterCount     int64 // concurrent
 }
 
 func (s *Statistics) Select(cnt int64) {
@@ -37,3 +41,26 @@ func (s *Statistics) Execute(succ bool, cost int64) {
 		atomic.AddInt64(&s.ExecuteFailCount, 1)
 	}
 }
+
+// SelectTimeout counts a select() call exceeding its deadline
+func (s *Statistics) SelectTimeout() {
+	atomic.AddInt64(&s.SelectTimeoutCount, 1)
+}
+
+// ExecuteTimeout counts a failed execution exceeding its deadline, it's not counted in ExecuteFailCount
+func (s *Statistics) ExecuteTimeout(cost int64) {
+	if cost > 0 {
+		atomic.AddInt64(&s.ExecuteSpendTime, cost)
+	}
+	atomic.AddInt64(&s.ExecuteTimeoutCount, 1)
+}
+
+// Retry counts a failed execution which will be retried later
+func (s *Statistics) Retry() {
+	atomic.AddInt64(&s.RetryCount, 1)
+}
+
+// DeadLetter counts an item which still failed after all attempts
+func (s *Statistics) DeadLetter() {
+	atomic.AddInt64(&s.DeadLetterCount, 1)
+}
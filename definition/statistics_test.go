@@ -17,6 +17,8 @@ func TestStatistics(t *testing.T) {
 	stat.Execute(true, 20)
 	stat.Execute(true, 30)
 	stat.Execute(false, 10)
+	stat.Retry()
+	stat.DeadLetter()
 	now := time.Now().Unix() * 1000
 	assert.True(t, now-stat.LastFetchTime < 2000)
 	assert.Equal(t, int64(1), stat.SelectCount)
@@ -24,4 +26,6 @@ func TestStatistics(t *testing.T) {
 	assert.Equal(t, int64(2), stat.ExecuteSuccCount)
 	assert.Equal(t, int64(1), stat.ExecuteFailCount)
 	assert.Equal(t, int64(60), stat.ExecuteSpendTime)
+	assert.Equal(t, int64(1), stat.RetryCount)
+	assert.Equal(t, int64(1), stat.DeadLetterCount)
 }
@@ -6,6 +6,9 @@ package definition
 
 import (
 	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
 )
 
 type StrategyKind int
@@ -15,8 +18,49 @@ const (
 	SimpleKind
 	FuncKind
 	TaskKind
+	// SingletonKind binds a func like FuncKind but fires exactly once cluster wide at every fire time of CronBegin.
+	//	Every scheduler runs a worker and a fire time is claimed in storage before running.
+	SingletonKind
 )
 
+// OverlapPolicy decides what happens when previous run is still going at next fire time of CronBegin
+type OverlapPolicy int
+
+const (
+	OverlapSkip       OverlapPolicy = iota // Fire times during running are skipped
+	OverlapQueue                           // One more run is queued and started right after current one
+	OverlapConcurrent                      // Runs concurrently, TaskWorker treats it as OverlapQueue because selecting is serial
+)
+
+// MisfirePolicy decides what happens to fire times of CronBegin missed during downtime
+type MisfirePolicy int
+
+const (
+	MisfireIgnore   MisfirePolicy = iota // Missed fire times are ignored
+	MisfireFireOnce                      // Runs once for all missed fire times
+	MisfireFireAll                       // Runs once for every missed fire time, limited by MaxMisfires
+)
+
+// MaxMisfires limits the number of missed fire times to be counted
+const MaxMisfires = 100
+
+// Runs returns how many runs should be made for missed fire times
+func (p MisfirePolicy) Runs(missed int) int {
+	if missed <= 0 {
+		return 0
+	}
+	switch p {
+	case MisfireFireOnce:
+		return 1
+	case MisfireFireAll:
+		if missed > MaxMisfires {
+			return MaxMisfires
+		}
+		return missed
+	}
+	return 0
+}
+
 type Strategy struct {
 	ID                   string
 	IPList               []string // Which can be scheduled on
@@ -27,14 +71,76 @@ type Strategy struct {
 	Parameter            string
 	Enabled              bool // Whether it should begin to schedule
 
+	// Selector restricts schedulers by their labels. IPList is only checked if it's not empty
+	//	when Selector is given.
+	Selector Selector
+	// SpreadBy is a key of labels, workers are spread across schedulers of different values
+	//	as evenly as possible, eg. "zone". It's a soft rule taking effect after Selector.
+	SpreadBy string
+	// Assigner is the name of registered assigner placing workers between schedulers,
+	//	empty for the default one.
+	Assigner string
+
 	// format  0     *     *     *     *     ?
 	//         sec   min   hour  day   month week
 	CronBegin, CronEnd string
+	// Time zone of cron expressions like "Asia/Shanghai", local time zone if empty
+	TimeZone string
+	// Policies of firing when only CronBegin is set
+	CronOverlap OverlapPolicy
+	CronMisfire MisfirePolicy
 
-	// Interval for FuncWorker
+	// Interval and Timeout(in millis) for FuncWorker
 	Extra map[string]string
 }
 
+// Validate checks the strategy and returns an error describing the first problem found.
+//	It's suggested to be called before saving strategies into storage.
+func (s *Strategy) Validate() error {
+	if s.ID == "" {
+		return errors.New("ID of strategy should not be empty")
+	}
+	switch s.Kind {
+	case SimpleKind, FuncKind, TaskKind, SingletonKind:
+	default:
+		return errors.New("Unknown kind of strategy: " + strconv.Itoa(int(s.Kind)))
+	}
+	if s.Bind == "" {
+		return errors.New("Bind of strategy should not be empty")
+	}
+	if err := s.Selector.Validate(); err != nil {
+		return err
+	}
+	if s.TimeZone != "" {
+		if _, err := time.LoadLocation(s.TimeZone); err != nil {
+			return errors.New("Illegal time zone " + s.TimeZone + ": " + err.Error())
+		}
+	}
+	if s.CronBegin != "" {
+		if _, err := ParseCronIn(s.CronBegin, s.TimeZone); err != nil {
+			return errors.New("Illegal CronBegin " + s.CronBegin + ": " + err.Error())
+		}
+	}
+	if s.CronEnd != "" {
+		if s.CronBegin == "" {
+			return errors.New("CronEnd should be used together with CronBegin")
+		}
+		if _, err := ParseCronIn(s.CronEnd, s.TimeZone); err != nil {
+			return errors.New("Illegal CronEnd " + s.CronEnd + ": " + err.Error())
+		}
+	}
+	if s.Kind == SingletonKind && (s.CronBegin == "" || s.CronEnd != "") {
+		return errors.New("SingletonKind requires CronBegin only")
+	}
+	if s.CronOverlap < OverlapSkip || s.CronOverlap > OverlapConcurrent {
+		return errors.New("Unknown overlap policy: " + strconv.Itoa(int(s.CronOverlap)))
+	}
+	if s.CronMisfire < MisfireIgnore || s.CronMisfire > MisfireFireAll {
+		return errors.New("Unknown misfire policy: " + strconv.Itoa(int(s.CronMisfire)))
+	}
+	return nil
+}
+
 func (s *Strategy) String() string {
 	data, _ := json.Marshal(s)
 	return string(data)
@@ -46,6 +152,10 @@ type StrategyRuntime struct {
 	CreateAt     int64
 	Num          int
 	RequestedNum int
+
+	// Revision is maintained by storage and changes on every writing, zero means not stored yet.
+	//	It's used in compare-and-set.
+	Revision int64
 }
 
 func (s *StrategyRuntime) String() string {
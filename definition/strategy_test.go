@@ -0,0 +1,47 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package definition
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrategyValidate(t *testing.T) {
+	valid := Strategy{
+		ID:        "s0",
+		Kind:      FuncKind,
+		Bind:      "demo",
+		CronBegin: "0 0 9 * * MON-FRI",
+		CronEnd:   "0 0 18 * * ?",
+		TimeZone:  "UTC",
+	}
+	assert.Nil(t, valid.Validate())
+
+	s := valid
+	s.CronBegin, s.CronEnd = "@daily", ""
+	s.Kind = SingletonKind
+	assert.Nil(t, s.Validate())
+
+	for _, modify := range []func(s *Strategy){
+		func(s *Strategy) { s.ID = "" },
+		func(s *Strategy) { s.Kind = UnknownKind },
+		func(s *Strategy) { s.Kind = StrategyKind(100) },
+		func(s *Strategy) { s.Bind = "" },
+		func(s *Strategy) { s.TimeZone = "Nowhere/Unknown" },
+		func(s *Strategy) { s.CronBegin = "0 0 25 * * ?" },
+		func(s *Strategy) { s.CronEnd = "0 0 9 * * * *" },
+		func(s *Strategy) { s.CronBegin = "" },
+		func(s *Strategy) { s.Kind = SingletonKind },
+		func(s *Strategy) { s.CronOverlap = OverlapPolicy(-1) },
+		func(s *Strategy) { s.CronMisfire = MisfirePolicy(3) },
+		func(s *Strategy) { s.Selector = Selector{{Key: "zone", Operator: SelectorIn}} },
+	} {
+		s := valid
+		modify(&s)
+		assert.NotNil(t, s.Validate(), s.String())
+	}
+}
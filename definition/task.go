@@ -4,7 +4,12 @@
 
 package definition
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"time"
+)
 
 // Sleep Model:
 //	t0        t1        t2        t3
@@ -48,12 +53,61 @@ type Task struct {
 	Parameter      string // Parameter of task
 	Bind           string // Bond to registry
 	Items          []TaskItem
-	MaxTaskItems   int // max task items per Worker
+	MaxTaskItems   int // max total weight of task items per Worker, which is the count if items are not weighted
 
 	// Interval of heartbeat, in millis
 	HeartbeatInterval int
 	// Timeout to be death, in millis
 	DeathTimeout int
+
+	// Deadline of each select()/execute() call, in millis, 0 means no limit.
+	//	Only context-aware tasks can be interrupted.
+	SelectTimeout  int
+	ExecuteTimeout int
+
+	// Retry policy of failed executions
+	Retry RetryPolicy
+	// Name of registered sink which receives data still failed after all attempts,
+	//	empty to drop them.
+	DeadLetter string
+	// Name of registered assigner placing task items between workers, empty for the default one
+	Assigner string
+}
+
+// RetryPolicy decides how a failed execution(returning false or panicking) is retried.
+//	The delay before n-th retry is Backoff * Multiplier^(n-1) limited by MaxBackoff,
+//	then randomized by Jitter.
+type RetryPolicy struct {
+	MaxAttempts int     // Total attempts including the first one, retry is disabled if less than 2
+	Backoff     int     // Delay before first retry, in millis
+	MaxBackoff  int     // Upper limit of delay, in millis, 0 means no limit
+	Multiplier  float64 // Growth factor of delay, 2 will be used if less than 1
+	Jitter      float64 // Delay is randomized in range of [delay*(1-Jitter), delay*(1+Jitter)], in [0, 1]
+}
+
+// Enabled returns whether failed executions should be retried
+func (p *RetryPolicy) Enabled() bool {
+	return p.MaxAttempts > 1
+}
+
+// Delay returns the backoff before next attempt when given attempts have been failed
+func (p *RetryPolicy) Delay(attempts int) time.Duration {
+	if p.Backoff <= 0 || attempts < 1 {
+		return 0
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	delay := float64(p.Backoff) * math.Pow(multiplier, float64(attempts-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		delay *= 1 + jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(delay * float64(time.Millisecond))
 }
 
 func (t *Task) String() string {
@@ -6,6 +6,7 @@ package definition
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -14,10 +15,24 @@ import (
 type TaskItem struct {
 	ID        string
 	Parameter string
+	// Weight is the relative load of the item comparing to others, eg. a big tenant.
+	//	Non-positive is treated as 1.
+	Weight int
+	// Token is the fencing token of current ownership which is increasing only,
+	//	it can be used to reject stale work of previous owners. Zero means no fencing.
+	Token uint64
 }
 
 func (item *TaskItem) String() string {
-	return fmt.Sprint("(t=", item.ID, ",p=", item.Parameter, ")")
+	return fmt.Sprint("(t=", item.ID, ",p=", item.Parameter, ",w=", item.Load(), ")")
+}
+
+// Load returns the weight of the item which is at least 1
+func (item *TaskItem) Load() int {
+	if item.Weight > 0 {
+		return item.Weight
+	}
+	return 1
 }
 
 type TaskAssignment struct {
@@ -27,12 +42,24 @@ type TaskAssignment struct {
 	RuntimeID          string
 	RequestedRuntimeID string
 	Parameter          string
+	Weight             int // Weight of the task item
+	// Token is a fencing token from store.Sequence which is renewed once ownership changes
+	Token uint64
+
+	// Revision is maintained by storage and changes on every writing, zero means not stored yet.
+	//	It's used in compare-and-set.
+	Revision int64
 }
 
 func (assign *TaskAssignment) String() string {
 	b := strings.Builder{}
 	b.WriteRune('{')
 	b.WriteString(assign.ItemID)
+	if assign.Weight > 1 {
+		b.WriteString("(w=")
+		b.WriteString(strconv.Itoa(assign.Weight))
+		b.WriteRune(')')
+	}
 	b.WriteString(" => ")
 	if assign.RuntimeID == "" && assign.RequestedRuntimeID == "" {
 		b.WriteString("<empty>")
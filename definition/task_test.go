@@ -0,0 +1,35 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package definition
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts: 5,
+		Backoff:     100,
+		MaxBackoff:  300,
+	}
+	assert.Equal(t, time.Duration(0), policy.Delay(0))
+	assert.Equal(t, 100*time.Millisecond, policy.Delay(1))
+	assert.Equal(t, 200*time.Millisecond, policy.Delay(2))
+	assert.Equal(t, 300*time.Millisecond, policy.Delay(3))
+	assert.Equal(t, 300*time.Millisecond, policy.Delay(10))
+
+	policy.Multiplier = 3
+	policy.MaxBackoff = 0
+	assert.Equal(t, 900*time.Millisecond, policy.Delay(3))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := policy.Delay(1)
+		assert.True(t, delay >= 50*time.Millisecond && delay <= 150*time.Millisecond)
+	}
+}
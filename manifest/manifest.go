@@ -0,0 +1,135 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+// Package manifest manages tasks and strategies declaratively through files in YAML or JSON,
+//	which can be kept in version control and reviewed. Changes against a storage are planned
+//	before applying like:
+//
+//	m, err := manifest.Load("schedule.yaml")
+//	plan, err := manifest.NewPlan(m, store, false)
+//	fmt.Print(plan)
+//	err = manifest.Apply(plan, store)
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest declares tasks and strategies. Field names are the same as definitions
+//	and matched case-insensitively, eg. id, bind or cronBegin.
+type Manifest struct {
+	Tasks      []*definition.Task
+	Strategies []*definition.Strategy
+}
+
+// kindNames are names of StrategyKind accepted besides numbers
+var kindNames = map[string]definition.StrategyKind{
+	"simple":    definition.SimpleKind,
+	"func":      definition.FuncKind,
+	"task":      definition.TaskKind,
+	"singleton": definition.SingletonKind,
+}
+
+// Load reads the manifest from a file in YAML or JSON
+func Load(path string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Parse(data)
+	if err != nil {
+		return nil, errors.New("Failed to parse " + path + ": " + err.Error())
+	}
+	return m, nil
+}
+
+// replaceKinds replaces names of kind in strategies with numbers
+func replaceKinds(doc map[string]interface{}) error {
+	for key, val := range doc {
+		if !strings.EqualFold(key, "strategies") {
+			continue
+		}
+		strategies, ok := val.([]interface{})
+		if !ok {
+			return errors.New("Strategies should be a list")
+		}
+		for _, obj := range strategies {
+			strategy, ok := obj.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for field, v := range strategy {
+				name, ok := v.(string)
+				if !ok || !strings.EqualFold(field, "kind") {
+					continue
+				}
+				kind, ok := kindNames[strings.ToLower(name)]
+				if !ok {
+					return errors.New("Unknown kind of strategy: " + name)
+				}
+				strategy[field] = int(kind)
+			}
+		}
+	}
+	return nil
+}
+
+// Parse parses the manifest in YAML or JSON(which is also YAML) and validates it
+func Parse(data []byte) (*Manifest, error) {
+	// converted into JSON first so that definitions can be used without tags of yaml
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if err := replaceKinds(doc); err != nil {
+		return nil, err
+	}
+	converted, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err = json.Unmarshal(converted, m); err != nil {
+		return nil, err
+	}
+	if err = m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Validate checks IDs of definitions are unique and strategies are valid
+func (m *Manifest) Validate() error {
+	tasks := make(map[string]bool, len(m.Tasks))
+	for i, task := range m.Tasks {
+		if task == nil || task.ID == "" {
+			return errors.New("ID of task #" + strconv.Itoa(i) + " should not be empty")
+		}
+		if tasks[task.ID] {
+			return errors.New("Duplicated task: " + task.ID)
+		}
+		tasks[task.ID] = true
+	}
+	strategies := make(map[string]bool, len(m.Strategies))
+	for i, strategy := range m.Strategies {
+		if strategy == nil {
+			return errors.New("Strategy #" + strconv.Itoa(i) + " should not be empty")
+		}
+		if err := strategy.Validate(); err != nil {
+			return err
+		}
+		if strategies[strategy.ID] {
+			return errors.New("Duplicated strategy: " + strategy.ID)
+		}
+		strategies[strategy.ID] = true
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+const demoYAML = `
+tasks:
+  - id: t0
+    bind: demo-task
+    fetchCount: 10
+    items:
+      - id: a
+      - id: b
+strategies:
+  - id: s0
+    kind: task
+    bind: t0
+    total: 2
+    enabled: true
+  - id: s1
+    kind: func
+    bind: demo-func
+    cronBegin: "0 * * * * ?"
+    extra:
+      Timeout: "1000"
+`
+
+const demoJSON = `{
+	"Tasks": [{"ID": "t0", "Bind": "demo-task", "FetchCount": 10, "Items": [{"ID": "a"}, {"ID": "b"}]}],
+	"Strategies": [
+		{"ID": "s0", "Kind": 3, "Bind": "t0", "Total": 2, "Enabled": true},
+		{"ID": "s1", "Kind": "func", "Bind": "demo-func", "CronBegin": "0 * * * * ?", "Extra": {"Timeout": "1000"}}
+	]
+}`
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(demoYAML))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(m.Tasks))
+	assert.Equal(t, 10, m.Tasks[0].FetchCount)
+	assert.Equal(t, []definition.TaskItem{{ID: "a"}, {ID: "b"}}, m.Tasks[0].Items)
+	assert.Equal(t, 2, len(m.Strategies))
+	assert.Equal(t, definition.TaskKind, m.Strategies[0].Kind)
+	assert.Equal(t, definition.FuncKind, m.Strategies[1].Kind)
+	assert.Equal(t, "1000", m.Strategies[1].Extra["Timeout"])
+
+	fromJSON, err := Parse([]byte(demoJSON))
+	assert.Nil(t, err)
+	assert.Equal(t, m, fromJSON)
+}
+
+func TestParseIllegal(t *testing.T) {
+	_, err := Parse([]byte("strategies:\n  - id: s0\n    kind: unknown\n    bind: b\n"))
+	assert.NotNil(t, err)
+	_, err = Parse([]byte("tasks:\n  - id: t0\n  - id: t0\n"))
+	assert.NotNil(t, err)
+	_, err = Parse([]byte("strategies:\n  - id: s0\n    kind: func\n"))
+	assert.NotNil(t, err)
+	_, err = Parse([]byte("tasks: ["))
+	assert.NotNil(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goschedule-manifest")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "schedule.yaml")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(demoYAML), 0644))
+
+	m, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(m.Strategies))
+
+	_, err = Load(filepath.Join(dir, "missing.yaml"))
+	assert.NotNil(t, err)
+}
+
+func TestPlanApply(t *testing.T) {
+	s := memory.New()
+	s.CreateTask(&definition.Task{ID: "t0", Bind: "demo-task", FetchCount: 5})
+	s.CreateTask(&definition.Task{ID: "legacy", Bind: "legacy"})
+	s.CreateStrategy(&definition.Strategy{ID: "legacy", Kind: definition.SimpleKind, Bind: "legacy"})
+	m, err := Parse([]byte(demoYAML))
+	assert.Nil(t, err)
+
+	plan, err := NewPlan(m, s, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(plan.Operations))
+	assert.Equal(t, OpUpdate, plan.Operations[0].Type)
+	assert.Equal(t, []FieldDiff{
+		{Field: "FetchCount", Old: "5", New: "10"},
+		{Field: "Items", Old: "null", New: `[{"ID":"a","Parameter":"","Weight":0,"Token":0},{"ID":"b","Parameter":"","Weight":0,"Token":0}]`},
+	}, plan.Operations[0].Diffs)
+	assert.Equal(t, OpCreate, plan.Operations[1].Type)
+	assert.Equal(t, "s0", plan.Operations[1].ID)
+	assert.Contains(t, plan.String(), "~ task t0\n    FetchCount: 5 => 10")
+	assert.Contains(t, plan.String(), "+ strategy s1")
+
+	assert.Nil(t, Apply(plan, s))
+	// idempotent
+	assert.Nil(t, Apply(plan, s))
+	task, _ := s.GetTask("t0")
+	assert.Equal(t, 10, task.FetchCount)
+
+	plan, err = NewPlan(m, s, false)
+	assert.Nil(t, err)
+	assert.True(t, plan.Empty())
+	assert.Equal(t, "No changes.\n", plan.String())
+
+	// prune
+	plan, err = NewPlan(m, s, true)
+	assert.Nil(t, err)
+	assert.Equal(t, []Operation{
+		{Type: OpDelete, Kind: "strategy", ID: "legacy"},
+		{Type: OpDelete, Kind: "task", ID: "legacy"},
+	}, plan.Operations)
+	assert.Nil(t, Apply(plan, s))
+	assert.Nil(t, Apply(plan, s))
+	tasks, _ := s.GetTasks()
+	assert.Equal(t, 1, len(tasks))
+	strategies, _ := s.GetStrategies()
+	assert.Equal(t, 2, len(strategies))
+
+	plan, err = NewPlan(m, s, true)
+	assert.Nil(t, err)
+	assert.True(t, plan.Empty())
+}
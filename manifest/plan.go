@@ -0,0 +1,281 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package manifest
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+type OpType int
+
+const (
+	OpCreate OpType = iota
+	OpUpdate
+	OpDelete
+)
+
+func (t OpType) String() string {
+	switch t {
+	case OpCreate:
+		return "create"
+	case OpUpdate:
+		return "update"
+	case OpDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// FieldDiff is a changed field in JSON
+type FieldDiff struct {
+	Field    string
+	Old, New string
+}
+
+// Operation is a change on a task or strategy
+type Operation struct {
+	Type OpType
+	// Kind is "task" or "strategy"
+	Kind string
+	ID   string
+	// Diffs lists changed fields of update
+	Diffs []FieldDiff
+
+	task     *definition.Task
+	strategy *definition.Strategy
+}
+
+func (op Operation) String() string {
+	b := strings.Builder{}
+	switch op.Type {
+	case OpCreate:
+		b.WriteString("+ ")
+	case OpUpdate:
+		b.WriteString("~ ")
+	case OpDelete:
+		b.WriteString("- ")
+	}
+	b.WriteString(op.Kind)
+	b.WriteRune(' ')
+	b.WriteString(op.ID)
+	for _, d := range op.Diffs {
+		b.WriteString("\n    ")
+		b.WriteString(d.Field)
+		b.WriteString(": ")
+		b.WriteString(d.Old)
+		b.WriteString(" => ")
+		b.WriteString(d.New)
+	}
+	return b.String()
+}
+
+// Plan is the operations to make a storage match a manifest, in order of applying
+type Plan struct {
+	Operations []Operation
+}
+
+// Empty returns whether the storage has already matched the manifest
+func (p *Plan) Empty() bool {
+	return len(p.Operations) == 0
+}
+
+func (p *Plan) String() string {
+	if p.Empty() {
+		return "No changes.\n"
+	}
+	b := strings.Builder{}
+	for _, op := range p.Operations {
+		b.WriteString(op.String())
+		b.WriteRune('\n')
+	}
+	return b.String()
+}
+
+// diffFields compares objects field by field in their JSON forms
+func diffFields(old, new interface{}) ([]FieldDiff, error) {
+	fields := func(obj interface{}) (map[string]json.RawMessage, error) {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		result := make(map[string]json.RawMessage)
+		return result, json.Unmarshal(data, &result)
+	}
+	oldFields, err := fields(old)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := fields(new)
+	if err != nil {
+		return nil, err
+	}
+	diffs := make([]FieldDiff, 0)
+	for name, val := range newFields {
+		if oldVal := oldFields[name]; !reflect.DeepEqual(normalize(oldVal), normalize(val)) {
+			diffs = append(diffs, FieldDiff{Field: name, Old: string(oldVal), New: string(val)})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Field < diffs[j].Field
+	})
+	return diffs, nil
+}
+
+// normalize decodes JSON so that null and empty collections are equivalent as storages may not keep the difference
+func normalize(raw json.RawMessage) interface{} {
+	var v interface{}
+	json.Unmarshal(raw, &v)
+	switch val := v.(type) {
+	case []interface{}:
+		if len(val) == 0 {
+			return nil
+		}
+	case map[string]interface{}:
+		if len(val) == 0 {
+			return nil
+		}
+	}
+	return v
+}
+
+// NewPlan compares the manifest with definitions in storage.
+//	Definitions not declared are deleted only when prune is true.
+func NewPlan(m *Manifest, s store.Store, prune bool) (*Plan, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	tasks, err := s.GetTasks()
+	if err != nil {
+		return nil, errors.New("Failed to get tasks: " + err.Error())
+	}
+	strategies, err := s.GetStrategies()
+	if err != nil {
+		return nil, errors.New("Failed to get strategies: " + err.Error())
+	}
+	existedTasks := make(map[string]*definition.Task, len(tasks))
+	for _, t := range tasks {
+		existedTasks[t.ID] = t
+	}
+	existedStrategies := make(map[string]*definition.Strategy, len(strategies))
+	for _, s := range strategies {
+		existedStrategies[s.ID] = s
+	}
+
+	plan := &Plan{Operations: make([]Operation, 0)}
+	// tasks are created before strategies referring them and deleted after
+	for _, task := range m.Tasks {
+		op := Operation{Type: OpCreate, Kind: "task", ID: task.ID, task: task}
+		if old, ok := existedTasks[task.ID]; ok {
+			if op.Diffs, err = diffFields(old, task); err != nil {
+				return nil, err
+			}
+			if len(op.Diffs) == 0 {
+				continue
+			}
+			op.Type = OpUpdate
+		}
+		plan.Operations = append(plan.Operations, op)
+	}
+	for _, strategy := range m.Strategies {
+		op := Operation{Type: OpCreate, Kind: "strategy", ID: strategy.ID, strategy: strategy}
+		if old, ok := existedStrategies[strategy.ID]; ok {
+			if op.Diffs, err = diffFields(old, strategy); err != nil {
+				return nil, err
+			}
+			if len(op.Diffs) == 0 {
+				continue
+			}
+			op.Type = OpUpdate
+		}
+		plan.Operations = append(plan.Operations, op)
+	}
+	if !prune {
+		return plan, nil
+	}
+	declaredStrategies := make(map[string]bool, len(m.Strategies))
+	for _, s := range m.Strategies {
+		declaredStrategies[s.ID] = true
+	}
+	for _, s := range strategies {
+		if !declaredStrategies[s.ID] {
+			plan.Operations = append(plan.Operations, Operation{Type: OpDelete, Kind: "strategy", ID: s.ID})
+		}
+	}
+	declaredTasks := make(map[string]bool, len(m.Tasks))
+	for _, t := range m.Tasks {
+		declaredTasks[t.ID] = true
+	}
+	for _, t := range tasks {
+		if !declaredTasks[t.ID] {
+			plan.Operations = append(plan.Operations, Operation{Type: OpDelete, Kind: "task", ID: t.ID})
+		}
+	}
+	return plan, nil
+}
+
+func applyTask(op *Operation, s store.Store) error {
+	switch op.Type {
+	case OpCreate:
+		if err := s.CreateTask(op.task); err != store.AlreadyExist {
+			return err
+		}
+		return s.UpdateTask(op.task)
+	case OpUpdate:
+		if err := s.UpdateTask(op.task); err != store.NotExist {
+			return err
+		}
+		return s.CreateTask(op.task)
+	case OpDelete:
+		if err := s.RemoveTask(op.ID); err != store.NotExist {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyStrategy(op *Operation, s store.Store) error {
+	switch op.Type {
+	case OpCreate:
+		if err := s.CreateStrategy(op.strategy); err != store.AlreadyExist {
+			return err
+		}
+		return s.UpdateStrategy(op.strategy)
+	case OpUpdate:
+		if err := s.UpdateStrategy(op.strategy); err != store.NotExist {
+			return err
+		}
+		return s.CreateStrategy(op.strategy)
+	case OpDelete:
+		if err := s.RemoveStrategy(op.ID); err != store.NotExist {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply executes operations of the plan in order. It's idempotent that applying again or
+//	applying a plan which has been partially applied makes the same result.
+func Apply(p *Plan, s store.Store) error {
+	for i := range p.Operations {
+		op := &p.Operations[i]
+		var err error
+		if op.Kind == "task" {
+			err = applyTask(op, s)
+		} else {
+			err = applyStrategy(op, s)
+		}
+		if err != nil {
+			return errors.New("Failed to " + op.Type.String() + " " + op.Kind + " " + op.ID + ": " + err.Error())
+		}
+	}
+	return nil
+}
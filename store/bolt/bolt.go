@@ -0,0 +1,510 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package bolt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/utils"
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltStore saves everything into a single local file through bbolt. It's suitable for single node
+//	deployments because the file can only be opened by one process at the same time.
+type BoltStore struct {
+	mu     sync.Mutex
+	closed bool
+	db     *bolt.DB
+	events *store.Broadcaster
+}
+
+const (
+	keyTasks           = "/tasks"
+	keyStrategies      = "/strategies"
+	keySchedulers      = "/schedulers"
+	keyRuntimes        = "/runtimes"
+	keyTaskRuntimes    = "/taskRuntimes"
+	keyTaskAssignments = "/taskAssignments"
+	keyTaskReloads     = "/taskReload"
+)
+
+func (s *BoltStore) keyTask(id string) string {
+	return keyTasks + "/" + id
+}
+
+func (s *BoltStore) keyStrategy(id string) string {
+	return keyStrategies + "/" + id
+}
+
+func (s *BoltStore) keyScheduler(id string) string {
+	return keySchedulers + "/" + id
+}
+
+func (s *BoltStore) keyRuntime(strategyId, schedulerId string) string {
+	return s.keyRuntimes(strategyId) + "/" + schedulerId
+}
+
+func (s *BoltStore) keyRuntimes(strategyId string) string {
+	return keyRuntimes + "/" + strategyId
+}
+
+func (s *BoltStore) keyTaskRuntime(strategyId, taskId, runtimeId string) string {
+	return s.keyTaskRuntimes(strategyId, taskId) + "/" + runtimeId
+}
+
+func (s *BoltStore) keyTaskRuntimes(strategyId, taskId string) string {
+	return keyTaskRuntimes + "/" + strategyId + "/" + taskId
+}
+
+func (s *BoltStore) keyTaskAssignment(strategyId, taskId, itemId string) string {
+	return s.keyTaskAssignments(strategyId, taskId) + "/" + itemId
+}
+
+func (s *BoltStore) keyTaskAssignments(strategyId, taskId string) string {
+	return keyTaskAssignments + "/" + strategyId + "/" + taskId
+}
+
+func (s *BoltStore) keyTaskReload(strategyId, taskId string) string {
+	return keyTaskReloads + "/" + strategyId + "/" + taskId
+}
+
+// cleanTemporary removes records which are only meaningful to alive processes
+func (s *BoltStore) cleanTemporary() error {
+	for _, key := range []string{keySchedulers, keyRuntimes, keyTaskRuntimes, keyTaskAssignments} {
+		if err := s.removePrefix(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *BoltStore) Name() string {
+	return "bolt"
+}
+
+func (s *BoltStore) Time() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+func (s *BoltStore) Sequence() (uint64, error) {
+	var seq uint64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		seq, err = tx.Bucket(bucketSequence).NextSequence()
+		return err
+	})
+	return seq, err
+}
+
+func (s *BoltStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	s.events.Close()
+	return s.db.Close()
+}
+
+func (s *BoltStore) Watch(ctx context.Context) (<-chan store.Event, error) {
+	return s.events.Subscribe(ctx), nil
+}
+
+//
+// Scheduler related
+//
+
+func (s *BoltStore) RegisterScheduler(scheduler *definition.Scheduler) error {
+	if scheduler == nil {
+		return errors.New("scheduler should not be nil")
+	}
+	created, err := s.updateOrInsert(s.keyScheduler(scheduler.ID), scheduler)
+	if err == nil && created {
+		s.events.Publish(store.Event{Kind: store.SchedulerEvent, ID: scheduler.ID})
+	}
+	return err
+}
+
+func (s *BoltStore) UnregisterScheduler(id string) error {
+	err := s.remove(s.keyScheduler(id))
+	if err == nil {
+		s.events.Publish(store.Event{Kind: store.SchedulerEvent, ID: id})
+	}
+	// ignore not exist
+	if err == store.NotExist {
+		return nil
+	}
+	return err
+}
+
+func (s *BoltStore) GetSchedulers() ([]*definition.Scheduler, error) {
+	arr, err := s.getObjects(keySchedulers, reflect.TypeOf(definition.Scheduler{}))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*definition.Scheduler, 0, len(arr))
+	for _, obj := range arr {
+		if scheduler, ok := obj.(*definition.Scheduler); ok {
+			result = append(result, scheduler)
+		}
+	}
+	utils.SortSchedulers(result)
+	return result, nil
+}
+
+func (s *BoltStore) GetScheduler(id string) (*definition.Scheduler, error) {
+	obj := &definition.Scheduler{}
+	if err := s.getObject(s.keyScheduler(id), obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+//
+// Task related
+//
+
+func (s *BoltStore) GetTask(id string) (*definition.Task, error) {
+	obj := &definition.Task{}
+	if err := s.getObject(s.keyTask(id), obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *BoltStore) GetTasks() ([]*definition.Task, error) {
+	arr, err := s.getObjects(keyTasks, reflect.TypeOf(definition.Task{}))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*definition.Task, 0, len(arr))
+	for _, obj := range arr {
+		if task, ok := obj.(*definition.Task); ok {
+			result = append(result, task)
+		}
+	}
+	return result, nil
+}
+
+func (s *BoltStore) CreateTask(task *definition.Task) error {
+	if task == nil {
+		return errors.New("task should not be nil")
+	}
+	err := s.create(s.keyTask(task.ID), task)
+	if err == nil {
+		s.events.Publish(store.Event{Kind: store.TaskEvent, TaskID: task.ID})
+	}
+	return err
+}
+
+func (s *BoltStore) UpdateTask(task *definition.Task) error {
+	if task == nil {
+		return errors.New("task should not be nil")
+	}
+	err := s.update(s.keyTask(task.ID), task)
+	if err == nil {
+		s.events.Publish(store.Event{Kind: store.TaskEvent, TaskID: task.ID})
+	}
+	return err
+}
+
+func (s *BoltStore) RemoveTask(id string) error {
+	err := s.remove(s.keyTask(id))
+	if err == nil {
+		s.events.Publish(store.Event{Kind: store.TaskEvent, TaskID: id})
+	}
+	return err
+}
+
+//
+// Task runtimes
+//
+
+func (s *BoltStore) GetTaskRuntime(strategyId, taskId, id string) (*definition.TaskRuntime, error) {
+	obj := &definition.TaskRuntime{}
+	if err := s.getObject(s.keyTaskRuntime(strategyId, taskId, id), obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *BoltStore) GetTaskRuntimes(strategyId, taskId string) ([]*definition.TaskRuntime, error) {
+	arr, err := s.getObjects(s.keyTaskRuntimes(strategyId, taskId), reflect.TypeOf(definition.TaskRuntime{}))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*definition.TaskRuntime, 0, len(arr))
+	for _, obj := range arr {
+		if runtime, ok := obj.(*definition.TaskRuntime); ok {
+			result = append(result, runtime)
+		}
+	}
+	utils.SortTaskRuntimes(result)
+	return result, nil
+}
+
+func (s *BoltStore) SetTaskRuntime(runtime *definition.TaskRuntime) error {
+	if runtime == nil {
+		return errors.New("task runtime should not be nil")
+	}
+	created, err := s.updateOrInsert(s.keyTaskRuntime(runtime.StrategyID, runtime.TaskID, runtime.ID), runtime)
+	if err == nil && created {
+		s.events.Publish(store.Event{Kind: store.TaskRuntimeEvent, StrategyID: runtime.StrategyID, TaskID: runtime.TaskID, ID: runtime.ID})
+	}
+	return err
+}
+
+func (s *BoltStore) RemoveTaskRuntime(strategyId, taskId, id string) error {
+	err := s.remove(s.keyTaskRuntime(strategyId, taskId, id))
+	if err == nil {
+		s.events.Publish(store.Event{Kind: store.TaskRuntimeEvent, StrategyID: strategyId, TaskID: taskId, ID: id})
+	}
+	// ignore not exist
+	if err == store.NotExist {
+		return nil
+	}
+	return err
+}
+
+func (s *BoltStore) GetTaskItemsConfigVersion(strategyId, taskId string) (int64, error) {
+	var version int64
+	err := s.getObject(s.keyTaskReload(strategyId, taskId), &version)
+	if err == store.NotExist {
+		return 0, nil
+	}
+	return version, err
+}
+
+func (s *BoltStore) IncreaseTaskItemsConfigVersion(strategyId, taskId string) error {
+	key := []byte(s.keyTaskReload(strategyId, taskId))
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketData)
+		var version int64
+		if _, data := decode(b.Get(key)); data != nil {
+			if err := json.Unmarshal(data, &version); err != nil {
+				return err
+			}
+		}
+		revision, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(version + 1)
+		if err != nil {
+			return err
+		}
+		return b.Put(key, encode(revision, data))
+	})
+	if err == nil {
+		s.events.Publish(store.Event{Kind: store.TaskItemsConfigVersionEvent, StrategyID: strategyId, TaskID: taskId})
+	}
+	return err
+}
+
+//
+// Task assignments
+//
+
+func (s *BoltStore) GetTaskAssignment(strategyId, taskId, itemId string) (*definition.TaskAssignment, error) {
+	obj := &definition.TaskAssignment{}
+	if err := s.getObject(s.keyTaskAssignment(strategyId, taskId, itemId), obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *BoltStore) GetTaskAssignments(strategyId, taskId string) ([]*definition.TaskAssignment, error) {
+	arr, err := s.getObjects(s.keyTaskAssignments(strategyId, taskId), reflect.TypeOf(definition.TaskAssignment{}))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*definition.TaskAssignment, 0, len(arr))
+	for _, obj := range arr {
+		if assignment, ok := obj.(*definition.TaskAssignment); ok {
+			result = append(result, assignment)
+		}
+	}
+	utils.SortTaskAssignments(result)
+	return result, nil
+}
+
+func (s *BoltStore) SetTaskAssignment(assignment *definition.TaskAssignment) error {
+	if assignment == nil {
+		return errors.New("assignment should not be nil")
+	}
+	_, err := s.updateOrInsert(s.keyTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID), assignment)
+	if err == nil {
+		s.events.Publish(store.Event{Kind: store.TaskAssignmentEvent, StrategyID: assignment.StrategyID, TaskID: assignment.TaskID, ID: assignment.ItemID})
+	}
+	return err
+}
+
+func (s *BoltStore) CompareAndSetTaskAssignment(assignment *definition.TaskAssignment) error {
+	if assignment == nil {
+		return errors.New("assignment should not be nil")
+	}
+	revision, err := s.compareAndSet(s.keyTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID), assignment, assignment.Revision)
+	if err != nil {
+		return err
+	}
+	assignment.Revision = revision
+	s.events.Publish(store.Event{Kind: store.TaskAssignmentEvent, StrategyID: assignment.StrategyID, TaskID: assignment.TaskID, ID: assignment.ItemID})
+	return nil
+}
+
+func (s *BoltStore) RemoveTaskAssignment(strategyId, taskId, itemId string) error {
+	err := s.remove(s.keyTaskAssignment(strategyId, taskId, itemId))
+	if err == nil {
+		s.events.Publish(store.Event{Kind: store.TaskAssignmentEvent, StrategyID: strategyId, TaskID: taskId, ID: itemId})
+	}
+	// ignore not exist
+	if err == store.NotExist {
+		return nil
+	}
+	return err
+}
+
+//
+// Strategy related
+//
+
+func (s *BoltStore) GetStrategy(id string) (*definition.Strategy, error) {
+	obj := &definition.Strategy{}
+	if err := s.getObject(s.keyStrategy(id), obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *BoltStore) GetStrategies() ([]*definition.Strategy, error) {
+	arr, err := s.getObjects(keyStrategies, reflect.TypeOf(definition.Strategy{}))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*definition.Strategy, 0, len(arr))
+	for _, obj := range arr {
+		if strategy, ok := obj.(*definition.Strategy); ok {
+			result = append(result, strategy)
+		}
+	}
+	return result, nil
+}
+
+func (s *BoltStore) CreateStrategy(strategy *definition.Strategy) error {
+	if strategy == nil {
+		return errors.New("strategy should not be nil")
+	}
+	err := s.create(s.keyStrategy(strategy.ID), strategy)
+	if err == nil {
+		s.events.Publish(store.Event{Kind: store.StrategyEvent, StrategyID: strategy.ID})
+	}
+	return err
+}
+
+func (s *BoltStore) UpdateStrategy(strategy *definition.Strategy) error {
+	if strategy == nil {
+		return errors.New("strategy should not be nil")
+	}
+	err := s.update(s.keyStrategy(strategy.ID), strategy)
+	if err == nil {
+		s.events.Publish(store.Event{Kind: store.StrategyEvent, StrategyID: strategy.ID})
+	}
+	return err
+}
+
+func (s *BoltStore) RemoveStrategy(id string) error {
+	s.removeFires(id)
+	err := s.remove(s.keyStrategy(id))
+	if err == nil {
+		s.events.Publish(store.Event{Kind: store.StrategyEvent, StrategyID: id})
+	}
+	return err
+}
+
+//
+// Strategy runtimes
+//
+
+func (s *BoltStore) GetStrategyRuntime(strategyId, schedulerId string) (*definition.StrategyRuntime, error) {
+	obj := &definition.StrategyRuntime{}
+	if err := s.getObject(s.keyRuntime(strategyId, schedulerId), obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *BoltStore) GetStrategyRuntimes(strategyId string) ([]*definition.StrategyRuntime, error) {
+	arr, err := s.getObjects(s.keyRuntimes(strategyId), reflect.TypeOf(definition.StrategyRuntime{}))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*definition.StrategyRuntime, 0, len(arr))
+	for _, obj := range arr {
+		if runtime, ok := obj.(*definition.StrategyRuntime); ok {
+			result = append(result, runtime)
+		}
+	}
+	return result, nil
+}
+
+func (s *BoltStore) SetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	if runtime == nil {
+		return errors.New("runtime should not be nil")
+	}
+	_, err := s.updateOrInsert(s.keyRuntime(runtime.StrategyID, runtime.SchedulerID), runtime)
+	if err == nil {
+		s.events.Publish(store.Event{Kind: store.StrategyRuntimeEvent, StrategyID: runtime.StrategyID, ID: runtime.SchedulerID})
+	}
+	return err
+}
+
+func (s *BoltStore) CompareAndSetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	if runtime == nil {
+		return errors.New("runtime should not be nil")
+	}
+	revision, err := s.compareAndSet(s.keyRuntime(runtime.StrategyID, runtime.SchedulerID), runtime, runtime.Revision)
+	if err != nil {
+		return err
+	}
+	runtime.Revision = revision
+	s.events.Publish(store.Event{Kind: store.StrategyRuntimeEvent, StrategyID: runtime.StrategyID, ID: runtime.SchedulerID})
+	return nil
+}
+
+func (s *BoltStore) RemoveStrategyRuntime(strategyId, schedulerId string) error {
+	err := s.remove(s.keyRuntime(strategyId, schedulerId))
+	if err == nil {
+		s.events.Publish(store.Event{Kind: store.StrategyRuntimeEvent, StrategyID: strategyId, ID: schedulerId})
+	}
+	// ignore not exist
+	if err == store.NotExist {
+		return nil
+	}
+	return err
+}
+
+func (s *BoltStore) Dump() string {
+	b := strings.Builder{}
+	s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketData).ForEach(func(k, v []byte) error {
+			_, data := decode(v)
+			b.Write(k)
+			b.WriteString(": ")
+			b.Write(data)
+			b.WriteString("\n")
+			return nil
+		})
+	})
+	return b.String()
+}
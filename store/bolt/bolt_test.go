@@ -0,0 +1,176 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package bolt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+var testFile = filepath.Join(os.TempDir(), "goschedule-bolt-test.db")
+
+func newStorage() *BoltStore {
+	s, err := New(testFile)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func TestName(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestName(t, s, "bolt")
+	s.Close()
+}
+
+func TestTime(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestTime(t, s)
+	s.Close()
+}
+
+func TestSequence(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestSequence(t, s)
+	s.Close()
+}
+
+func TestTask(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestTask(t, s)
+	s.Close()
+}
+
+func TestTaskRuntime(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestTaskRuntime(t, s)
+	s.Close()
+}
+
+func TestTaskAssignment(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestTaskAssignment(t, s)
+	s.Close()
+}
+
+func TestStrategy(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestStrategy(t, s)
+	s.Close()
+}
+
+func TestStrategyRuntime(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestStrategyRuntime(t, s)
+	s.Close()
+}
+
+func TestScheduler(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestScheduler(t, s)
+	s.Close()
+}
+
+func TestTaskReload(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestTaskReloadItems(t, s)
+	s.Close()
+}
+
+func TestDump(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestDump(t, s)
+	s.Close()
+}
+
+func TestWatch(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestWatch(t, s)
+	s.Close()
+}
+
+func TestLiveness(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestLiveness(t, s)
+	storetest.DoTestLivenessExpiration(t, s)
+	s.Close()
+}
+
+func TestCompareAndSet(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestCompareAndSet(t, s)
+	s.Close()
+}
+
+func TestDeadLetter(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestDeadLetter(t, s)
+	s.Close()
+}
+
+func TestCronStore(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestCronStore(t, s)
+	s.Close()
+}
+
+func TestFireClaim(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestFireClaim(t, s)
+	s.Close()
+}
+
+func TestReopen(t *testing.T) {
+	s := newStorage()
+	task := &definition.Task{ID: "reopen-task"}
+	strategy := &definition.Strategy{ID: "reopen-strategy"}
+	s.RemoveTask(task.ID)
+	s.RemoveStrategy(strategy.ID)
+	assert.Nil(t, s.CreateTask(task))
+	assert.Nil(t, s.CreateStrategy(strategy))
+	assert.Nil(t, s.SetLastFireTime(strategy.ID, 1000))
+	assert.Nil(t, s.RegisterScheduler(&definition.Scheduler{ID: "reopen-scheduler"}))
+	assert.Nil(t, s.SetStrategyRuntime(&definition.StrategyRuntime{StrategyID: strategy.ID, SchedulerID: "reopen-scheduler"}))
+	assert.Nil(t, s.SetTaskRuntime(&definition.TaskRuntime{ID: "reopen-runtime", StrategyID: strategy.ID, TaskID: task.ID}))
+	assert.Nil(t, s.SetTaskAssignment(&definition.TaskAssignment{StrategyID: strategy.ID, TaskID: task.ID, ItemID: "p0"}))
+	seq, _ := s.Sequence()
+	assert.Nil(t, s.Close())
+
+	s = newStorage()
+	defer s.Close()
+	// locked by the opened one
+	_, err := New(testFile, WithTimeout(100*time.Millisecond))
+	assert.NotNil(t, err)
+
+	// persistent
+	_, err = s.GetTask(task.ID)
+	assert.Nil(t, err)
+	_, err = s.GetStrategy(strategy.ID)
+	assert.Nil(t, err)
+	fireTime, _ := s.GetLastFireTime(strategy.ID)
+	assert.Equal(t, int64(1000), fireTime)
+	seq1, _ := s.Sequence()
+	assert.True(t, seq1 > seq)
+
+	// temporary
+	schedulers, err := s.GetSchedulers()
+	assert.Nil(t, err)
+	assert.Empty(t, schedulers)
+	runtimes, _ := s.GetStrategyRuntimes(strategy.ID)
+	assert.Empty(t, runtimes)
+	taskRuntimes, _ := s.GetTaskRuntimes(strategy.ID, task.ID)
+	assert.Empty(t, taskRuntimes)
+	assignments, _ := s.GetTaskAssignments(strategy.ID, task.ID)
+	assert.Empty(t, assignments)
+
+	s.RemoveTask(task.ID)
+	s.RemoveStrategy(strategy.ID)
+}
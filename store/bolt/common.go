@@ -0,0 +1,182 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package bolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"reflect"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/log"
+	"github.com/jasonjoo2010/goschedule/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Values are saved with a leading revision in 8 bytes(big endian) which comes from the sequence
+//	of data bucket. So revisions are increasing only even if a key is removed and created again.
+const revisionSize = 8
+
+func encode(revision uint64, data []byte) []byte {
+	buf := make([]byte, revisionSize+len(data))
+	binary.BigEndian.PutUint64(buf, revision)
+	copy(buf[revisionSize:], data)
+	return buf
+}
+
+func decode(value []byte) (int64, []byte) {
+	if len(value) < revisionSize {
+		return 0, nil
+	}
+	return int64(binary.BigEndian.Uint64(value)), value[revisionSize:]
+}
+
+// setRevision fills the revision of objects supporting compare-and-set
+func setRevision(obj interface{}, revision int64) {
+	switch v := obj.(type) {
+	case *definition.TaskAssignment:
+		v.Revision = revision
+	case *definition.StrategyRuntime:
+		v.Revision = revision
+	}
+}
+
+func (s *BoltStore) getObject(key string, obj interface{}) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(bucketData).Get([]byte(key))
+		if value == nil {
+			return store.NotExist
+		}
+		revision, data := decode(value)
+		if err := json.Unmarshal(data, obj); err != nil {
+			return err
+		}
+		setRevision(obj, revision)
+		return nil
+	})
+}
+
+func (s *BoltStore) getObjects(basepath string, t reflect.Type) ([]interface{}, error) {
+	prefix := []byte(basepath + "/")
+	result := make([]interface{}, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketData).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			revision, data := decode(v)
+			obj := reflect.New(t).Interface()
+			if err := json.Unmarshal(data, obj); err != nil {
+				log.Warnf("Wrong data type during deserializing: %s", string(k))
+				continue
+			}
+			setRevision(obj, revision)
+			result = append(result, obj)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// put saves the object and returns whether it's newly created and its new revision.
+//	check is called with the current revision(0 if not existed) in the same transaction and can abort it by an error.
+func (s *BoltStore) put(key string, obj interface{}, check func(revision int64) error) (bool, int64, error) {
+	if obj == nil {
+		return false, 0, errors.New("Object should not be nil")
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return false, 0, err
+	}
+	var (
+		created  bool
+		revision uint64
+	)
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketData)
+		old := b.Get([]byte(key))
+		current, _ := decode(old)
+		if check != nil {
+			if err := check(current); err != nil {
+				return err
+			}
+		}
+		created = old == nil
+		revision, err = b.NextSequence()
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), encode(revision, data))
+	})
+	if err != nil {
+		return false, 0, err
+	}
+	return created, int64(revision), nil
+}
+
+func (s *BoltStore) create(key string, obj interface{}) error {
+	_, _, err := s.put(key, obj, func(revision int64) error {
+		if revision > 0 {
+			return store.AlreadyExist
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *BoltStore) update(key string, obj interface{}) error {
+	_, _, err := s.put(key, obj, func(revision int64) error {
+		if revision == 0 {
+			return store.NotExist
+		}
+		return nil
+	})
+	return err
+}
+
+// updateOrInsert saves the object and returns whether it's newly created
+func (s *BoltStore) updateOrInsert(key string, obj interface{}) (bool, error) {
+	created, _, err := s.put(key, obj, nil)
+	return created, err
+}
+
+// compareAndSet saves the object only if the revision in storage equals to the given one and returns the new one.
+//	Zero revision means the key should not exist.
+func (s *BoltStore) compareAndSet(key string, obj interface{}, revision int64) (int64, error) {
+	_, newRevision, err := s.put(key, obj, func(current int64) error {
+		if current != revision {
+			return store.Conflict
+		}
+		return nil
+	})
+	return newRevision, err
+}
+
+func (s *BoltStore) remove(key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketData)
+		if b.Get([]byte(key)) == nil {
+			return store.NotExist
+		}
+		return b.Delete([]byte(key))
+	})
+}
+
+// removePrefix removes all keys under the path
+func (s *BoltStore) removePrefix(basepath string) error {
+	prefix := []byte(basepath + "/")
+	return s.db.Update(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketData).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package bolt
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+func (s *BoltStore) keyLastFire(strategyId string) string {
+	return "/cronFires/" + strategyId
+}
+
+func (s *BoltStore) keyFireClaims(strategyId string) string {
+	return "/cronClaims/" + strategyId
+}
+
+func (s *BoltStore) keyFireClaim(strategyId string, fireTime int64) string {
+	return s.keyFireClaims(strategyId) + "/" + strconv.FormatInt(fireTime, 10)
+}
+
+func (s *BoltStore) GetLastFireTime(strategyId string) (int64, error) {
+	var fireTime int64
+	err := s.getObject(s.keyLastFire(strategyId), &fireTime)
+	if err == store.NotExist {
+		return 0, nil
+	}
+	return fireTime, err
+}
+
+func (s *BoltStore) SetLastFireTime(strategyId string, fireTime int64) error {
+	_, err := s.updateOrInsert(s.keyLastFire(strategyId), fireTime)
+	return err
+}
+
+func (s *BoltStore) ClaimFireTime(strategyId string, fireTime int64) error {
+	return s.create(s.keyFireClaim(strategyId, fireTime), fireTime)
+}
+
+func (s *BoltStore) RemoveFireClaims(strategyId string, before int64) error {
+	arr, err := s.getObjects(s.keyFireClaims(strategyId), reflect.TypeOf(int64(0)))
+	if err != nil {
+		return err
+	}
+	for _, obj := range arr {
+		fireTime, ok := obj.(*int64)
+		if !ok || *fireTime >= before {
+			continue
+		}
+		if err := s.remove(s.keyFireClaim(strategyId, *fireTime)); err != nil && err != store.NotExist {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *BoltStore) removeFires(strategyId string) {
+	s.remove(s.keyLastFire(strategyId))
+	s.RemoveFireClaims(strategyId, math.MaxInt64)
+}
@@ -0,0 +1,54 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package bolt
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/utils"
+)
+
+func (s *BoltStore) keyDeadLetter(strategyId, taskId, id string) string {
+	return s.keyDeadLetters(strategyId, taskId) + "/" + id
+}
+
+func (s *BoltStore) keyDeadLetters(strategyId, taskId string) string {
+	return "/deadLetters/" + strategyId + "/" + taskId
+}
+
+func (s *BoltStore) PutDeadLetter(letter *definition.DeadLetter) error {
+	if letter == nil {
+		return errors.New("dead letter should not be nil")
+	}
+	_, err := s.updateOrInsert(s.keyDeadLetter(letter.StrategyID, letter.TaskID, letter.ID), letter)
+	return err
+}
+
+func (s *BoltStore) GetDeadLetters(strategyId, taskId string) ([]*definition.DeadLetter, error) {
+	arr, err := s.getObjects(s.keyDeadLetters(strategyId, taskId), reflect.TypeOf(definition.DeadLetter{}))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*definition.DeadLetter, 0, len(arr))
+	for _, obj := range arr {
+		if l, ok := obj.(*definition.DeadLetter); ok {
+			result = append(result, l)
+		}
+	}
+	utils.SortDeadLetters(result)
+	return result, nil
+}
+
+func (s *BoltStore) RemoveDeadLetter(strategyId, taskId, id string) error {
+	err := s.remove(s.keyDeadLetter(strategyId, taskId, id))
+	// ignore not exist
+	if err == store.NotExist {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,9 @@
+module github.com/jasonjoo2010/goschedule/store/bolt
+
+go 1.14
+
+require (
+	github.com/jasonjoo2010/goschedule v1.1.0
+	github.com/stretchr/testify v1.7.0
+	go.etcd.io/bbolt v1.3.5
+)
@@ -0,0 +1,62 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package bolt
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketData     = []byte("data")
+	bucketSequence = []byte("sequence")
+)
+
+type Option func(opts *bolt.Options)
+
+// WithTimeout sets how long to wait for the lock of file which is held by another process.
+func WithTimeout(timeout time.Duration) Option {
+	return func(opts *bolt.Options) {
+		opts.Timeout = timeout
+	}
+}
+
+// New opens(creates if not existed) the file as a storage.
+//	Temporary records like schedulers and runtimes left by previous process are cleaned.
+func New(path string, opts ...Option) (*BoltStore, error) {
+	options := bolt.Options{
+		Timeout: time.Second,
+	}
+	for _, fn := range opts {
+		fn(&options)
+	}
+	db, err := bolt.Open(path, 0600, &options)
+	if err != nil {
+		return nil, errors.New("Open bolt store failed: " + err.Error())
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(bucketData); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(bucketSequence)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.New("Initialize bolt store failed: " + err.Error())
+	}
+	s := &BoltStore{
+		db:     db,
+		events: store.NewBroadcaster(),
+	}
+	if err = s.cleanTemporary(); err != nil {
+		db.Close()
+		return nil, errors.New("Clean temporary records failed: " + err.Error())
+	}
+	return s, nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+// Package chaos injects faults into storage for testing scheduling logic under errors, latency,
+//	dropped writes, stale reads and partitions. Nodes share an Injector and a storage, each
+//	wraps it with its own ID so faults can be targeted:
+//
+//	injector := chaos.NewInjector(1)
+//	injector.Add(chaos.Rule{Kind: chaos.Error, Methods: []string{"SetTaskRuntime"}, Probability: 0.2})
+//	injector.Partition("node-2")
+//	m, err := core.New(cfg, chaos.New(shared, "node-1", injector))
+package chaos
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+// ChaosStore decorates a storage with faults of an injector for a node
+type ChaosStore struct {
+	store    store.Store
+	node     string
+	injector *Injector
+
+	mu sync.Mutex
+	// last results of reads for stale reads
+	results map[string]interface{}
+}
+
+var (
+	_ store.Wrapper         = (*ChaosStore)(nil)
+	_ store.Watcher         = (*ChaosStore)(nil)
+	_ store.Liveness        = (*ChaosStore)(nil)
+	_ store.DeadLetterStore = (*ChaosStore)(nil)
+	_ store.CronStore       = (*ChaosStore)(nil)
+	_ store.FireClaimStore  = (*ChaosStore)(nil)
+)
+
+func New(s store.Store, node string, injector *Injector) *ChaosStore {
+	return &ChaosStore{
+		store:    s,
+		node:     node,
+		injector: injector,
+		results:  make(map[string]interface{}),
+	}
+}
+
+// Node returns ID of the node
+func (s *ChaosStore) Node() string {
+	return s.node
+}
+
+func (s *ChaosStore) Unwrap() store.Store {
+	return s.store
+}
+
+func (s *ChaosStore) unsupported(feature string) error {
+	return errors.New(feature + " is not supported by storage: " + s.store.Name())
+}
+
+// copyValue copies pointers and slices of pointers so that results kept are never modified by callers
+func copyValue(v interface{}) interface{} {
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Ptr:
+		if val.IsNil() {
+			return v
+		}
+		copied := reflect.New(val.Elem().Type())
+		copied.Elem().Set(val.Elem())
+		return copied.Interface()
+	case reflect.Slice:
+		if val.IsNil() {
+			return v
+		}
+		copied := reflect.MakeSlice(val.Type(), val.Len(), val.Len())
+		for i := 0; i < val.Len(); i++ {
+			copied.Index(i).Set(reflect.ValueOf(copyValue(val.Index(i).Interface())))
+		}
+		return copied.Interface()
+	}
+	return v
+}
+
+func (s *ChaosStore) inject(method string, class callClass) decision {
+	d := s.injector.decide(s.node, method, class)
+	if d.latency > 0 {
+		time.Sleep(d.latency)
+	}
+	return d
+}
+
+func (s *ChaosStore) call(method string, fn func() (interface{}, error)) (interface{}, error) {
+	if d := s.inject(method, classCall); d.err != nil {
+		return nil, d.err
+	}
+	return fn()
+}
+
+func (s *ChaosStore) read(method string, fn func() (interface{}, error), keys ...string) (interface{}, error) {
+	d := s.inject(method, classRead)
+	if d.err != nil {
+		return nil, d.err
+	}
+	key := method + "/" + strings.Join(keys, "/")
+	if d.stale {
+		s.mu.Lock()
+		val, ok := s.results[key]
+		s.mu.Unlock()
+		if ok {
+			return copyValue(val), nil
+		}
+	}
+	val, err := fn()
+	if err == nil {
+		s.mu.Lock()
+		s.results[key] = copyValue(val)
+		s.mu.Unlock()
+	}
+	return val, err
+}
+
+func (s *ChaosStore) write(method string, fn func() error) error {
+	d := s.inject(method, classWrite)
+	if d.err != nil {
+		return d.err
+	}
+	if d.drop {
+		return nil
+	}
+	return fn()
+}
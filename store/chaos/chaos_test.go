@@ -0,0 +1,150 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package chaos
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/store/memory"
+	"github.com/jasonjoo2010/goschedule/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreSuite(t *testing.T) {
+	for name, fn := range map[string]func(*testing.T, store.Store){
+		"Task":            storetest.DoTestTask,
+		"TaskRuntime":     storetest.DoTestTaskRuntime,
+		"TaskAssignment":  storetest.DoTestTaskAssignment,
+		"Strategy":        storetest.DoTestStrategy,
+		"StrategyRuntime": storetest.DoTestStrategyRuntime,
+		"Scheduler":       storetest.DoTestScheduler,
+		"CompareAndSet":   storetest.DoTestCompareAndSet,
+		"Watch":           storetest.DoTestWatch,
+		"Liveness":        storetest.DoTestLiveness,
+		"DeadLetter":      storetest.DoTestDeadLetter,
+		"CronStore":       storetest.DoTestCronStore,
+		"FireClaim":       storetest.DoTestFireClaim,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := New(memory.New(), "node-1", NewInjector(1))
+			fn(t, s)
+			assert.Nil(t, s.Close())
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	shared := memory.New()
+	defer shared.Close()
+	injector := NewInjector(1)
+	s1 := New(shared, "node-1", injector)
+	s2 := New(shared, "node-2", injector)
+
+	broken := errors.New("broken")
+	remove := injector.Add(Rule{Kind: Error, Methods: []string{"CreateTask"}, Nodes: []string{"node-1"}, Err: broken})
+	assert.Equal(t, broken, s1.CreateTask(&definition.Task{ID: "t0"}))
+	assert.Nil(t, s2.CreateTask(&definition.Task{ID: "t0"}))
+	_, err := s1.GetTask("t0")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, injector.Hits(Error))
+
+	remove()
+	assert.Nil(t, s1.CreateTask(&definition.Task{ID: "t1"}))
+
+	injector.Add(Rule{Kind: Error})
+	_, err = s2.Sequence()
+	assert.Equal(t, Injected, err)
+	injector.Clear()
+	_, err = s2.Sequence()
+	assert.Nil(t, err)
+}
+
+func TestProbability(t *testing.T) {
+	s := New(memory.New(), "node-1", NewInjector(1))
+	defer s.Close()
+	s.injector.Add(Rule{Kind: Error, Probability: 0.3})
+	failed := 0
+	for i := 0; i < 1000; i++ {
+		if _, err := s.GetTasks(); err != nil {
+			failed++
+		}
+	}
+	assert.InDelta(t, 300, failed, 60)
+	assert.Equal(t, failed, s.injector.Hits(Error))
+}
+
+func TestLatency(t *testing.T) {
+	injector := NewInjector(1)
+	s := New(memory.New(), "node-1", injector)
+	defer s.Close()
+	injector.Add(Rule{Kind: Latency, Methods: []string{"GetTasks"}, Latency: 100 * time.Millisecond})
+	begin := time.Now()
+	s.GetTasks()
+	assert.True(t, time.Since(begin) >= 100*time.Millisecond)
+	begin = time.Now()
+	s.GetStrategies()
+	assert.True(t, time.Since(begin) < 100*time.Millisecond)
+}
+
+func TestDropWrite(t *testing.T) {
+	injector := NewInjector(1)
+	s := New(memory.New(), "node-1", injector)
+	defer s.Close()
+	injector.Add(Rule{Kind: DropWrite})
+	assert.Nil(t, s.CreateTask(&definition.Task{ID: "t0"}))
+	_, err := s.GetTask("t0")
+	assert.Equal(t, store.NotExist, err)
+	assert.Equal(t, 1, injector.Hits(DropWrite))
+}
+
+func TestStaleRead(t *testing.T) {
+	injector := NewInjector(1)
+	s := New(memory.New(), "node-1", injector)
+	defer s.Close()
+	s.CreateTask(&definition.Task{ID: "t0", Bind: "old"})
+	task, err := s.GetTask("t0")
+	assert.Nil(t, err)
+	// modifying results doesn't affect stale ones
+	task.Bind = "modified"
+
+	remove := injector.Add(Rule{Kind: StaleRead, Methods: []string{"GetTask", "GetTasks"}})
+	s.UpdateTask(&definition.Task{ID: "t0", Bind: "new"})
+	task, err = s.GetTask("t0")
+	assert.Nil(t, err)
+	assert.Equal(t, "old", task.Bind)
+	// nothing to be stale
+	tasks, err := s.GetTasks()
+	assert.Nil(t, err)
+	assert.Equal(t, "new", tasks[0].Bind)
+
+	remove()
+	task, _ = s.GetTask("t0")
+	assert.Equal(t, "new", task.Bind)
+}
+
+func TestPartition(t *testing.T) {
+	shared := memory.New()
+	defer shared.Close()
+	injector := NewInjector(1)
+	s1 := New(shared, "node-1", injector)
+	s2 := New(shared, "node-2", injector)
+
+	injector.Partition("node-1")
+	_, err := s1.GetTasks()
+	assert.Equal(t, Partitioned, err)
+	_, err = s2.GetTasks()
+	assert.Nil(t, err)
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	assert.InDelta(t, now, s1.Time(), 1000)
+	assert.Equal(t, 2, injector.PartitionHits())
+
+	injector.Heal()
+	_, err = s1.GetTasks()
+	assert.Nil(t, err)
+}
@@ -0,0 +1,214 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package chaos
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	Injected    = errors.New("Injected fault of storage")
+	Partitioned = errors.New("Storage is unreachable because of partition")
+)
+
+type Kind int
+
+const (
+	// Error fails the call with Rule.Err, or Injected if not specified
+	Error Kind = iota
+	// Latency delays the call by Rule.Latency
+	Latency
+	// DropWrite pretends writing succeeded without reaching storage
+	DropWrite
+	// StaleRead returns the result of the last same read, if any
+	StaleRead
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Error:
+		return "error"
+	case Latency:
+		return "latency"
+	case DropWrite:
+		return "dropWrite"
+	case StaleRead:
+		return "staleRead"
+	}
+	return "unknown"
+}
+
+// Rule describes a fault and where it's injected
+type Rule struct {
+	Kind Kind
+	// Methods are names of methods in store.Store or optional interfaces, all methods if empty
+	Methods []string
+	// Nodes are IDs of nodes given in New, all nodes if empty
+	Nodes []string
+	// Probability of taking effect on each matched call in (0, 1], zero means always
+	Probability float64
+	Err         error
+	Latency     time.Duration
+}
+
+func contains(list []string, s string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matches(node, method string) bool {
+	return contains(r.Nodes, node) && contains(r.Methods, method)
+}
+
+type callClass int
+
+const (
+	classCall callClass = iota
+	classRead
+	classWrite
+)
+
+type decision struct {
+	latency time.Duration
+	err     error
+	drop    bool
+	stale   bool
+}
+
+// Injector holds faults shared by stores of all nodes, rules can be changed at any time
+type Injector struct {
+	mu          sync.Mutex
+	rand        *rand.Rand
+	rules       []*Rule
+	partitioned map[string]bool
+	hits        map[Kind]int
+	partitions  int
+}
+
+// NewInjector creates an injector whose randomness is decided by seed
+func NewInjector(seed int64) *Injector {
+	return &Injector{
+		rand:        rand.New(rand.NewSource(seed)),
+		partitioned: make(map[string]bool),
+		hits:        make(map[Kind]int),
+	}
+}
+
+// Add injects the fault and returns a function removing it
+func (in *Injector) Add(rule Rule) (remove func()) {
+	r := &rule
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.rules = append(in.rules, r)
+	return func() {
+		in.mu.Lock()
+		defer in.mu.Unlock()
+		for i, v := range in.rules {
+			if v == r {
+				in.rules = append(in.rules[:i], in.rules[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Clear removes all the rules and heals all partitions
+func (in *Injector) Clear() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.rules = nil
+	in.partitioned = make(map[string]bool)
+}
+
+// Partition makes storage unreachable from the nodes, every call fails with Partitioned
+func (in *Injector) Partition(nodes ...string) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	for _, node := range nodes {
+		in.partitioned[node] = true
+	}
+}
+
+// Heal reconnects the nodes, all nodes if omitted
+func (in *Injector) Heal(nodes ...string) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if len(nodes) == 0 {
+		in.partitioned = make(map[string]bool)
+		return
+	}
+	for _, node := range nodes {
+		delete(in.partitioned, node)
+	}
+}
+
+// Hits returns how many times faults of the kind took effect
+func (in *Injector) Hits(kind Kind) int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.hits[kind]
+}
+
+// PartitionHits returns how many calls failed because of partitions
+func (in *Injector) PartitionHits() int {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.partitions
+}
+
+func (in *Injector) decide(node, method string, class callClass) decision {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	d := decision{}
+	if in.partitioned[node] {
+		in.partitions++
+		d.err = Partitioned
+		return d
+	}
+	for _, r := range in.rules {
+		if !r.matches(node, method) {
+			continue
+		}
+		if r.Probability > 0 && in.rand.Float64() >= r.Probability {
+			continue
+		}
+		switch r.Kind {
+		case Error:
+			if d.err != nil {
+				continue
+			}
+			d.err = r.Err
+			if d.err == nil {
+				d.err = Injected
+			}
+		case Latency:
+			d.latency += r.Latency
+		case DropWrite:
+			if class != classWrite || d.drop {
+				continue
+			}
+			d.drop = true
+		case StaleRead:
+			if class != classRead || d.stale {
+				continue
+			}
+			d.stale = true
+		default:
+			continue
+		}
+		in.hits[r.Kind]++
+	}
+	return d
+}
@@ -0,0 +1,380 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package chaos
+
+import (
+	"context"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+func (s *ChaosStore) Name() string {
+	return s.store.Name()
+}
+
+// Time falls back to the local clock if a fault of error is injected
+func (s *ChaosStore) Time() int64 {
+	val, err := s.call("Time", func() (interface{}, error) {
+		return s.store.Time(), nil
+	})
+	if err != nil {
+		return time.Now().UnixNano() / int64(time.Millisecond)
+	}
+	return val.(int64)
+}
+
+func (s *ChaosStore) Sequence() (uint64, error) {
+	val, err := s.call("Sequence", func() (interface{}, error) {
+		return s.store.Sequence()
+	})
+	seq, _ := val.(uint64)
+	return seq, err
+}
+
+func (s *ChaosStore) Close() error {
+	return s.store.Close()
+}
+
+func (s *ChaosStore) Dump() string {
+	return s.store.Dump()
+}
+
+func (s *ChaosStore) RegisterScheduler(scheduler *definition.Scheduler) error {
+	return s.write("RegisterScheduler", func() error {
+		return s.store.RegisterScheduler(scheduler)
+	})
+}
+
+func (s *ChaosStore) UnregisterScheduler(id string) error {
+	return s.write("UnregisterScheduler", func() error {
+		return s.store.UnregisterScheduler(id)
+	})
+}
+
+func (s *ChaosStore) GetSchedulers() ([]*definition.Scheduler, error) {
+	val, err := s.read("GetSchedulers", func() (interface{}, error) {
+		return s.store.GetSchedulers()
+	})
+	list, _ := val.([]*definition.Scheduler)
+	return list, err
+}
+
+func (s *ChaosStore) GetScheduler(id string) (*definition.Scheduler, error) {
+	val, err := s.read("GetScheduler", func() (interface{}, error) {
+		return s.store.GetScheduler(id)
+	}, id)
+	scheduler, _ := val.(*definition.Scheduler)
+	return scheduler, err
+}
+
+func (s *ChaosStore) GetTask(id string) (*definition.Task, error) {
+	val, err := s.read("GetTask", func() (interface{}, error) {
+		return s.store.GetTask(id)
+	}, id)
+	task, _ := val.(*definition.Task)
+	return task, err
+}
+
+func (s *ChaosStore) GetTasks() ([]*definition.Task, error) {
+	val, err := s.read("GetTasks", func() (interface{}, error) {
+		return s.store.GetTasks()
+	})
+	list, _ := val.([]*definition.Task)
+	return list, err
+}
+
+func (s *ChaosStore) CreateTask(task *definition.Task) error {
+	return s.write("CreateTask", func() error {
+		return s.store.CreateTask(task)
+	})
+}
+
+func (s *ChaosStore) UpdateTask(task *definition.Task) error {
+	return s.write("UpdateTask", func() error {
+		return s.store.UpdateTask(task)
+	})
+}
+
+func (s *ChaosStore) RemoveTask(id string) error {
+	return s.write("RemoveTask", func() error {
+		return s.store.RemoveTask(id)
+	})
+}
+
+func (s *ChaosStore) GetTaskRuntime(strategyId, taskId, id string) (*definition.TaskRuntime, error) {
+	val, err := s.read("GetTaskRuntime", func() (interface{}, error) {
+		return s.store.GetTaskRuntime(strategyId, taskId, id)
+	}, strategyId, taskId, id)
+	runtime, _ := val.(*definition.TaskRuntime)
+	return runtime, err
+}
+
+func (s *ChaosStore) GetTaskRuntimes(strategyId, taskId string) ([]*definition.TaskRuntime, error) {
+	val, err := s.read("GetTaskRuntimes", func() (interface{}, error) {
+		return s.store.GetTaskRuntimes(strategyId, taskId)
+	}, strategyId, taskId)
+	list, _ := val.([]*definition.TaskRuntime)
+	return list, err
+}
+
+func (s *ChaosStore) SetTaskRuntime(runtime *definition.TaskRuntime) error {
+	return s.write("SetTaskRuntime", func() error {
+		return s.store.SetTaskRuntime(runtime)
+	})
+}
+
+func (s *ChaosStore) RemoveTaskRuntime(strategyId, taskId, id string) error {
+	return s.write("RemoveTaskRuntime", func() error {
+		return s.store.RemoveTaskRuntime(strategyId, taskId, id)
+	})
+}
+
+func (s *ChaosStore) GetTaskItemsConfigVersion(strategyId, taskId string) (int64, error) {
+	val, err := s.read("GetTaskItemsConfigVersion", func() (interface{}, error) {
+		return s.store.GetTaskItemsConfigVersion(strategyId, taskId)
+	}, strategyId, taskId)
+	version, _ := val.(int64)
+	return version, err
+}
+
+func (s *ChaosStore) IncreaseTaskItemsConfigVersion(strategyId, taskId string) error {
+	return s.write("IncreaseTaskItemsConfigVersion", func() error {
+		return s.store.IncreaseTaskItemsConfigVersion(strategyId, taskId)
+	})
+}
+
+func (s *ChaosStore) GetTaskAssignment(strategyId, taskId, itemId string) (*definition.TaskAssignment, error) {
+	val, err := s.read("GetTaskAssignment", func() (interface{}, error) {
+		return s.store.GetTaskAssignment(strategyId, taskId, itemId)
+	}, strategyId, taskId, itemId)
+	assignment, _ := val.(*definition.TaskAssignment)
+	return assignment, err
+}
+
+func (s *ChaosStore) GetTaskAssignments(strategyId, taskId string) ([]*definition.TaskAssignment, error) {
+	val, err := s.read("GetTaskAssignments", func() (interface{}, error) {
+		return s.store.GetTaskAssignments(strategyId, taskId)
+	}, strategyId, taskId)
+	list, _ := val.([]*definition.TaskAssignment)
+	return list, err
+}
+
+func (s *ChaosStore) SetTaskAssignment(assignment *definition.TaskAssignment) error {
+	return s.write("SetTaskAssignment", func() error {
+		return s.store.SetTaskAssignment(assignment)
+	})
+}
+
+func (s *ChaosStore) CompareAndSetTaskAssignment(assignment *definition.TaskAssignment) error {
+	return s.write("CompareAndSetTaskAssignment", func() error {
+		return s.store.CompareAndSetTaskAssignment(assignment)
+	})
+}
+
+func (s *ChaosStore) RemoveTaskAssignment(strategyId, taskId, itemId string) error {
+	return s.write("RemoveTaskAssignment", func() error {
+		return s.store.RemoveTaskAssignment(strategyId, taskId, itemId)
+	})
+}
+
+func (s *ChaosStore) GetStrategy(id string) (*definition.Strategy, error) {
+	val, err := s.read("GetStrategy", func() (interface{}, error) {
+		return s.store.GetStrategy(id)
+	}, id)
+	strategy, _ := val.(*definition.Strategy)
+	return strategy, err
+}
+
+func (s *ChaosStore) GetStrategies() ([]*definition.Strategy, error) {
+	val, err := s.read("GetStrategies", func() (interface{}, error) {
+		return s.store.GetStrategies()
+	})
+	list, _ := val.([]*definition.Strategy)
+	return list, err
+}
+
+func (s *ChaosStore) CreateStrategy(strategy *definition.Strategy) error {
+	return s.write("CreateStrategy", func() error {
+		return s.store.CreateStrategy(strategy)
+	})
+}
+
+func (s *ChaosStore) UpdateStrategy(strategy *definition.Strategy) error {
+	return s.write("UpdateStrategy", func() error {
+		return s.store.UpdateStrategy(strategy)
+	})
+}
+
+func (s *ChaosStore) RemoveStrategy(id string) error {
+	return s.write("RemoveStrategy", func() error {
+		return s.store.RemoveStrategy(id)
+	})
+}
+
+func (s *ChaosStore) GetStrategyRuntime(strategyId, schedulerId string) (*definition.StrategyRuntime, error) {
+	val, err := s.read("GetStrategyRuntime", func() (interface{}, error) {
+		return s.store.GetStrategyRuntime(strategyId, schedulerId)
+	}, strategyId, schedulerId)
+	runtime, _ := val.(*definition.StrategyRuntime)
+	return runtime, err
+}
+
+func (s *ChaosStore) GetStrategyRuntimes(strategyId string) ([]*definition.StrategyRuntime, error) {
+	val, err := s.read("GetStrategyRuntimes", func() (interface{}, error) {
+		return s.store.GetStrategyRuntimes(strategyId)
+	}, strategyId)
+	list, _ := val.([]*definition.StrategyRuntime)
+	return list, err
+}
+
+func (s *ChaosStore) SetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	return s.write("SetStrategyRuntime", func() error {
+		return s.store.SetStrategyRuntime(runtime)
+	})
+}
+
+func (s *ChaosStore) CompareAndSetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	return s.write("CompareAndSetStrategyRuntime", func() error {
+		return s.store.CompareAndSetStrategyRuntime(runtime)
+	})
+}
+
+func (s *ChaosStore) RemoveStrategyRuntime(strategyId, schedulerId string) error {
+	return s.write("RemoveStrategyRuntime", func() error {
+		return s.store.RemoveStrategyRuntime(strategyId, schedulerId)
+	})
+}
+
+func (s *ChaosStore) Watch(ctx context.Context) (<-chan store.Event, error) {
+	watcher, ok := store.AsWatcher(s.store)
+	if !ok {
+		return nil, s.unsupported("Watching")
+	}
+	val, err := s.call("Watch", func() (interface{}, error) {
+		return watcher.Watch(ctx)
+	})
+	ch, _ := val.(<-chan store.Event)
+	return ch, err
+}
+
+func (s *ChaosStore) KeepSchedulerAlive(scheduler *definition.Scheduler, ttl time.Duration) error {
+	l, ok := store.AsLiveness(s.store)
+	if !ok {
+		return s.unsupported("Liveness")
+	}
+	return s.write("KeepSchedulerAlive", func() error {
+		return l.KeepSchedulerAlive(scheduler, ttl)
+	})
+}
+
+func (s *ChaosStore) IsSchedulerAlive(id string) (bool, error) {
+	l, ok := store.AsLiveness(s.store)
+	if !ok {
+		return false, s.unsupported("Liveness")
+	}
+	val, err := s.read("IsSchedulerAlive", func() (interface{}, error) {
+		return l.IsSchedulerAlive(id)
+	}, id)
+	alive, _ := val.(bool)
+	return alive, err
+}
+
+func (s *ChaosStore) KeepTaskRuntimeAlive(runtime *definition.TaskRuntime, ttl time.Duration) error {
+	l, ok := store.AsLiveness(s.store)
+	if !ok {
+		return s.unsupported("Liveness")
+	}
+	return s.write("KeepTaskRuntimeAlive", func() error {
+		return l.KeepTaskRuntimeAlive(runtime, ttl)
+	})
+}
+
+func (s *ChaosStore) IsTaskRuntimeAlive(strategyId, taskId, id string) (bool, error) {
+	l, ok := store.AsLiveness(s.store)
+	if !ok {
+		return false, s.unsupported("Liveness")
+	}
+	val, err := s.read("IsTaskRuntimeAlive", func() (interface{}, error) {
+		return l.IsTaskRuntimeAlive(strategyId, taskId, id)
+	}, strategyId, taskId, id)
+	alive, _ := val.(bool)
+	return alive, err
+}
+
+func (s *ChaosStore) PutDeadLetter(letter *definition.DeadLetter) error {
+	d, ok := store.AsDeadLetterStore(s.store)
+	if !ok {
+		return s.unsupported("Dead letters")
+	}
+	return s.write("PutDeadLetter", func() error {
+		return d.PutDeadLetter(letter)
+	})
+}
+
+func (s *ChaosStore) GetDeadLetters(strategyId, taskId string) ([]*definition.DeadLetter, error) {
+	d, ok := store.AsDeadLetterStore(s.store)
+	if !ok {
+		return nil, s.unsupported("Dead letters")
+	}
+	val, err := s.read("GetDeadLetters", func() (interface{}, error) {
+		return d.GetDeadLetters(strategyId, taskId)
+	}, strategyId, taskId)
+	list, _ := val.([]*definition.DeadLetter)
+	return list, err
+}
+
+func (s *ChaosStore) RemoveDeadLetter(strategyId, taskId, id string) error {
+	d, ok := store.AsDeadLetterStore(s.store)
+	if !ok {
+		return s.unsupported("Dead letters")
+	}
+	return s.write("RemoveDeadLetter", func() error {
+		return d.RemoveDeadLetter(strategyId, taskId, id)
+	})
+}
+
+func (s *ChaosStore) GetLastFireTime(strategyId string) (int64, error) {
+	c, ok := store.AsCronStore(s.store)
+	if !ok {
+		return 0, s.unsupported("Cron fires")
+	}
+	val, err := s.read("GetLastFireTime", func() (interface{}, error) {
+		return c.GetLastFireTime(strategyId)
+	}, strategyId)
+	t, _ := val.(int64)
+	return t, err
+}
+
+func (s *ChaosStore) SetLastFireTime(strategyId string, fireTime int64) error {
+	c, ok := store.AsCronStore(s.store)
+	if !ok {
+		return s.unsupported("Cron fires")
+	}
+	return s.write("SetLastFireTime", func() error {
+		return c.SetLastFireTime(strategyId, fireTime)
+	})
+}
+
+func (s *ChaosStore) ClaimFireTime(strategyId string, fireTime int64) error {
+	c, ok := store.AsFireClaimStore(s.store)
+	if !ok {
+		return s.unsupported("Fire claims")
+	}
+	return s.write("ClaimFireTime", func() error {
+		return c.ClaimFireTime(strategyId, fireTime)
+	})
+}
+
+func (s *ChaosStore) RemoveFireClaims(strategyId string, before int64) error {
+	c, ok := store.AsFireClaimStore(s.store)
+	if !ok {
+		return s.unsupported("Fire claims")
+	}
+	return s.write("RemoveFireClaims", func() error {
+		return c.RemoveFireClaims(strategyId, before)
+	})
+}
@@ -0,0 +1,203 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package consul
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/log"
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+func toBytes(obj interface{}) ([]byte, error) {
+	if obj == nil {
+		return nil, errors.New("Data should not be nil")
+	}
+	switch v := obj.(type) {
+	case string:
+		return []byte(v), nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// setRevision fills the revision of objects supporting compare-and-set, modify index of key is used.
+func setRevision(obj interface{}, revision int64) {
+	switch v := obj.(type) {
+	case *definition.TaskAssignment:
+		v.Revision = revision
+	case *definition.StrategyRuntime:
+		v.Revision = revision
+	}
+}
+
+func (s *ConsulStore) get(key string) (*api.KVPair, error) {
+	pair, _, err := s.kv.Get(key, nil)
+	return pair, err
+}
+
+func (s *ConsulStore) exists(key string) (bool, error) {
+	pair, err := s.get(key)
+	if err != nil {
+		return false, err
+	}
+	return pair != nil, nil
+}
+
+func (s *ConsulStore) getObject(key string, obj interface{}) error {
+	pair, err := s.get(key)
+	if err != nil {
+		return err
+	}
+	if pair == nil || len(pair.Value) == 0 {
+		return store.NotExist
+	}
+	if err = json.Unmarshal(pair.Value, obj); err != nil {
+		return err
+	}
+	setRevision(obj, int64(pair.ModifyIndex))
+	return nil
+}
+
+func (s *ConsulStore) getChildren(basepath string) (api.KVPairs, error) {
+	pairs, _, err := s.kv.List(basepath+"/", nil)
+	return pairs, err
+}
+
+func (s *ConsulStore) getObjects(basepath string, t reflect.Type) ([]interface{}, error) {
+	pairs, err := s.getChildren(basepath)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]interface{}, 0, len(pairs))
+	for _, pair := range pairs {
+		obj := reflect.New(t).Interface()
+		if err = json.Unmarshal(pair.Value, obj); err != nil {
+			log.Warnf("Wrong data type during deserializing: %s", pair.Key)
+			continue
+		}
+		setRevision(obj, int64(pair.ModifyIndex))
+		result = append(result, obj)
+	}
+	return result, nil
+}
+
+func (s *ConsulStore) create(key string, obj interface{}) error {
+	data, err := toBytes(obj)
+	if err != nil {
+		return err
+	}
+	// cas of zero index succeeds only when the key doesn't exist
+	ok, _, err := s.kv.CAS(&api.KVPair{Key: key, Value: data}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return store.AlreadyExist
+	}
+	return nil
+}
+
+func (s *ConsulStore) update(key string, obj interface{}, mustExisted bool) error {
+	data, err := toBytes(obj)
+	if err != nil {
+		return err
+	}
+	if !mustExisted {
+		_, err = s.kv.Put(&api.KVPair{Key: key, Value: data}, nil)
+		return err
+	}
+	for {
+		pair, err := s.get(key)
+		if err != nil {
+			return err
+		}
+		if pair == nil {
+			return store.NotExist
+		}
+		ok, _, err := s.kv.CAS(&api.KVPair{Key: key, Value: data, ModifyIndex: pair.ModifyIndex}, nil)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+}
+
+// compareAndSet puts the object only if modify index of the key equals to the given one and returns the new index.
+//	Zero index means the key should not exist.
+func (s *ConsulStore) compareAndSet(key string, obj interface{}, revision int64) (int64, error) {
+	data, err := toBytes(obj)
+	if err != nil {
+		return 0, err
+	}
+	// transaction is used to get the new index of key
+	ok, resp, _, err := s.kv.Txn(api.KVTxnOps{
+		&api.KVTxnOp{
+			Verb:  api.KVCAS,
+			Key:   key,
+			Value: data,
+			Index: uint64(revision),
+		},
+	}, nil)
+	if err != nil {
+		return 0, err
+	}
+	if !ok || len(resp.Results) == 0 {
+		return 0, store.Conflict
+	}
+	return int64(resp.Results[0].ModifyIndex), nil
+}
+
+// increase increases the number saved in key by check-and-set and returns the new one
+func (s *ConsulStore) increase(key string) (int64, error) {
+	for {
+		pair, err := s.get(key)
+		if err != nil {
+			return 0, err
+		}
+		var (
+			cur   int64
+			index uint64
+		)
+		if pair != nil {
+			cur, _ = strconv.ParseInt(string(pair.Value), 10, 64)
+			index = pair.ModifyIndex
+		}
+		ok, _, err := s.kv.CAS(&api.KVPair{
+			Key:         key,
+			Value:       []byte(strconv.FormatInt(cur+1, 10)),
+			ModifyIndex: index,
+		}, nil)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return cur + 1, nil
+		}
+	}
+}
+
+func (s *ConsulStore) remove(key string, recursive bool) error {
+	if recursive {
+		_, err := s.kv.DeleteTree(key+"/", nil)
+		return err
+	}
+	existed, err := s.exists(key)
+	if err != nil {
+		return err
+	}
+	if !existed {
+		return store.NotExist
+	}
+	_, err = s.kv.Delete(key, nil)
+	return err
+}
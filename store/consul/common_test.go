@@ -0,0 +1,51 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package consul
+
+import (
+	"testing"
+
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommon(t *testing.T) {
+	s := newStorage()
+	defer s.Close()
+
+	assert.Equal(t, "schedule/demo/test", s.prefix)
+	key := s.prefix + "/test/a"
+	existed, err := s.exists(key)
+	assert.Nil(t, err)
+	assert.False(t, existed)
+
+	assert.Equal(t, store.NotExist, s.update(key, "", true))
+	assert.Nil(t, s.create(key, "nodeA"))
+	assert.Equal(t, store.AlreadyExist, s.create(key, "nodeB"))
+	assert.Nil(t, s.update(key, "nodeC", true))
+	pair, err := s.get(key)
+	assert.Nil(t, err)
+	assert.Equal(t, "nodeC", string(pair.Value))
+
+	revision, err := s.compareAndSet(key, "nodeD", int64(pair.ModifyIndex))
+	assert.Nil(t, err)
+	assert.True(t, revision > int64(pair.ModifyIndex))
+	_, err = s.compareAndSet(key, "nodeE", int64(pair.ModifyIndex))
+	assert.Equal(t, store.Conflict, err)
+
+	n, err := s.increase(s.prefix + "/test/counter")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), n)
+	n, err = s.increase(s.prefix + "/test/counter")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), n)
+
+	assert.Nil(t, s.remove(key, false))
+	assert.Equal(t, store.NotExist, s.remove(key, false))
+	assert.Nil(t, s.remove(s.prefix+"/test", true))
+	list, err := s.getChildren(s.prefix + "/test")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(list))
+}
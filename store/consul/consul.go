@@ -0,0 +1,422 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package consul
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+type ConsulStore struct {
+	client    *api.Client
+	kv        *api.KV
+	session   *api.Session
+	prefix    string
+	timeDelta time.Duration
+
+	sessionMu sync.Mutex
+	sessions  map[string]string
+}
+
+func (s *ConsulStore) keySequenceBase() string {
+	return s.prefix + "/sequence"
+}
+
+func (s *ConsulStore) keyTasks() string {
+	return s.prefix + "/tasks"
+}
+
+func (s *ConsulStore) keyTask(id string) string {
+	return s.keyTasks() + "/" + id
+}
+
+func (s *ConsulStore) keyStrategies() string {
+	return s.prefix + "/strategies"
+}
+
+func (s *ConsulStore) keyStrategy(id string) string {
+	return s.keyStrategies() + "/" + id
+}
+
+func (s *ConsulStore) keySchedulers() string {
+	return s.prefix + "/schedulers"
+}
+
+func (s *ConsulStore) keyScheduler(id string) string {
+	return s.keySchedulers() + "/" + id
+}
+
+func (s *ConsulStore) keyRuntime(strategyId, schedulerId string) string {
+	return s.keyRuntimes(strategyId) + "/" + schedulerId
+}
+
+func (s *ConsulStore) keyRuntimes(strategyId string) string {
+	return s.prefix + "/runtimes/" + strategyId
+}
+
+func (s *ConsulStore) keyTaskRuntime(strategyId, taskId, runtimeId string) string {
+	return s.keyTaskRuntimes(strategyId, taskId) + "/" + runtimeId
+}
+
+func (s *ConsulStore) keyTaskRuntimes(strategyId, taskId string) string {
+	return s.prefix + "/taskRuntimes/" + strategyId + "/" + taskId
+}
+
+func (s *ConsulStore) keyTaskAssignment(strategyId, taskId, itemId string) string {
+	return s.keyTaskAssignments(strategyId, taskId) + "/" + itemId
+}
+
+func (s *ConsulStore) keyTaskAssignments(strategyId, taskId string) string {
+	return s.prefix + "/taskAssignments/" + strategyId + "/" + taskId
+}
+
+func (s *ConsulStore) keyTaskReload(strategyId, taskId string) string {
+	return s.prefix + "/taskReload/" + strategyId + "/" + taskId
+}
+
+func (s *ConsulStore) Name() string {
+	return "consul"
+}
+
+func (s *ConsulStore) Time() int64 {
+	return time.Now().Add(s.timeDelta).UnixNano() / 1e6
+}
+
+func (s *ConsulStore) Sequence() (uint64, error) {
+	seq, err := s.increase(s.keySequenceBase())
+	if err != nil {
+		return 0, err
+	}
+	return uint64(seq), nil
+}
+
+func (s *ConsulStore) Close() error {
+	// nothing to do, sessions expire by themselves
+	return nil
+}
+
+func (s *ConsulStore) RegisterScheduler(scheduler *definition.Scheduler) error {
+	if scheduler == nil {
+		return errors.New("scheduler should not be nil")
+	}
+	return s.update(s.keyScheduler(scheduler.ID), scheduler, false)
+}
+
+func (s *ConsulStore) UnregisterScheduler(id string) error {
+	s.destroySession(s.keyScheduler(id))
+	err := s.remove(s.keyScheduler(id), false)
+	// ignore not exist
+	if err == store.NotExist {
+		return nil
+	}
+	return err
+}
+
+func (s *ConsulStore) GetSchedulers() ([]*definition.Scheduler, error) {
+	arr, err := s.getObjects(s.keySchedulers(), reflect.TypeOf(definition.Scheduler{}))
+	if err == store.NotExist {
+		return []*definition.Scheduler{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*definition.Scheduler, 0, len(arr))
+	for _, obj := range arr {
+		s, ok := obj.(*definition.Scheduler)
+		if !ok {
+			continue
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+func (s *ConsulStore) GetScheduler(id string) (*definition.Scheduler, error) {
+	obj := &definition.Scheduler{}
+	err := s.getObject(s.keyScheduler(id), obj)
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *ConsulStore) GetTask(id string) (*definition.Task, error) {
+	obj := &definition.Task{}
+	err := s.getObject(s.keyTask(id), obj)
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *ConsulStore) GetTasks() ([]*definition.Task, error) {
+	arr, err := s.getObjects(s.keyTasks(), reflect.TypeOf(definition.Task{}))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*definition.Task, 0, len(arr))
+	for _, obj := range arr {
+		task, ok := obj.(*definition.Task)
+		if !ok {
+			continue
+		}
+		result = append(result, task)
+	}
+	return result, nil
+}
+
+func (s *ConsulStore) CreateTask(task *definition.Task) error {
+	if task == nil {
+		return errors.New("task should not be nil")
+	}
+	return s.create(s.keyTask(task.ID), task)
+}
+
+func (s *ConsulStore) UpdateTask(task *definition.Task) error {
+	if task == nil {
+		return errors.New("task should not be nil")
+	}
+	return s.update(s.keyTask(task.ID), task, true)
+}
+
+func (s *ConsulStore) RemoveTask(id string) error {
+	return s.remove(s.keyTask(id), false)
+}
+
+func (s *ConsulStore) GetTaskRuntime(strategyId, taskId, id string) (*definition.TaskRuntime, error) {
+	obj := &definition.TaskRuntime{}
+	err := s.getObject(s.keyTaskRuntime(strategyId, taskId, id), obj)
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *ConsulStore) GetTaskRuntimes(strategyId, taskId string) ([]*definition.TaskRuntime, error) {
+	arr, err := s.getObjects(s.keyTaskRuntimes(strategyId, taskId), reflect.TypeOf(definition.TaskRuntime{}))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*definition.TaskRuntime, 0, len(arr))
+	for _, obj := range arr {
+		r, ok := obj.(*definition.TaskRuntime)
+		if !ok {
+			continue
+		}
+		result = append(result, r)
+	}
+	return result, nil
+}
+
+func (s *ConsulStore) SetTaskRuntime(runtime *definition.TaskRuntime) error {
+	if runtime == nil {
+		return errors.New("task runtime should not be nil")
+	}
+	return s.update(s.keyTaskRuntime(runtime.StrategyID, runtime.TaskID, runtime.ID), runtime, false)
+}
+
+func (s *ConsulStore) RemoveTaskRuntime(strategyId, taskId, id string) error {
+	s.destroySession(s.keyTaskRuntime(strategyId, taskId, id))
+	err := s.remove(s.keyTaskRuntime(strategyId, taskId, id), false)
+	// ignore not exist
+	if err == store.NotExist {
+		return nil
+	}
+	return err
+}
+
+func (s *ConsulStore) GetTaskItemsConfigVersion(strategyId, taskId string) (int64, error) {
+	pair, err := s.get(s.keyTaskReload(strategyId, taskId))
+	if err != nil {
+		return 0, err
+	}
+	if pair == nil {
+		return 0, nil
+	}
+	return strconv.ParseInt(string(pair.Value), 10, 64)
+}
+
+func (s *ConsulStore) IncreaseTaskItemsConfigVersion(strategyId, taskId string) error {
+	_, err := s.increase(s.keyTaskReload(strategyId, taskId))
+	return err
+}
+
+func (s *ConsulStore) GetTaskAssignment(strategyId, taskId, itemId string) (*definition.TaskAssignment, error) {
+	obj := &definition.TaskAssignment{}
+	err := s.getObject(s.keyTaskAssignment(strategyId, taskId, itemId), obj)
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *ConsulStore) GetTaskAssignments(strategyId, taskId string) ([]*definition.TaskAssignment, error) {
+	arr, err := s.getObjects(s.keyTaskAssignments(strategyId, taskId), reflect.TypeOf(definition.TaskAssignment{}))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*definition.TaskAssignment, 0, len(arr))
+	for _, obj := range arr {
+		assign, ok := obj.(*definition.TaskAssignment)
+		if !ok {
+			continue
+		}
+		result = append(result, assign)
+	}
+	return result, nil
+}
+
+func (s *ConsulStore) SetTaskAssignment(assignment *definition.TaskAssignment) error {
+	if assignment == nil {
+		return errors.New("assignment should not be nil")
+	}
+	return s.update(s.keyTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID), assignment, false)
+}
+
+func (s *ConsulStore) CompareAndSetTaskAssignment(assignment *definition.TaskAssignment) error {
+	if assignment == nil {
+		return errors.New("assignment should not be nil")
+	}
+	revision, err := s.compareAndSet(s.keyTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID), assignment, assignment.Revision)
+	if err != nil {
+		return err
+	}
+	assignment.Revision = revision
+	return nil
+}
+
+func (s *ConsulStore) RemoveTaskAssignment(strategyId, taskId, itemId string) error {
+	err := s.remove(s.keyTaskAssignment(strategyId, taskId, itemId), false)
+	// ignore not exist
+	if err == store.NotExist {
+		return nil
+	}
+	return err
+}
+
+func (s *ConsulStore) GetStrategy(id string) (*definition.Strategy, error) {
+	obj := &definition.Strategy{}
+	err := s.getObject(s.keyStrategy(id), obj)
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *ConsulStore) GetStrategies() ([]*definition.Strategy, error) {
+	arr, err := s.getObjects(s.keyStrategies(), reflect.TypeOf(definition.Strategy{}))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*definition.Strategy, 0, len(arr))
+	for _, obj := range arr {
+		strategy, ok := obj.(*definition.Strategy)
+		if !ok {
+			continue
+		}
+		result = append(result, strategy)
+	}
+	return result, nil
+}
+
+func (s *ConsulStore) CreateStrategy(strategy *definition.Strategy) error {
+	if strategy == nil {
+		return errors.New("strategy should not be nil")
+	}
+	return s.create(s.keyStrategy(strategy.ID), strategy)
+}
+
+func (s *ConsulStore) UpdateStrategy(strategy *definition.Strategy) error {
+	if strategy == nil {
+		return errors.New("strategy should not be nil")
+	}
+	return s.update(s.keyStrategy(strategy.ID), strategy, true)
+}
+
+func (s *ConsulStore) RemoveStrategy(id string) error {
+	s.removeFires(id)
+	return s.remove(s.keyStrategy(id), false)
+}
+
+func (s *ConsulStore) GetStrategyRuntime(strategyId, schedulerId string) (*definition.StrategyRuntime, error) {
+	obj := &definition.StrategyRuntime{}
+	err := s.getObject(s.keyRuntime(strategyId, schedulerId), obj)
+	if err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (s *ConsulStore) GetStrategyRuntimes(strategyId string) ([]*definition.StrategyRuntime, error) {
+	arr, err := s.getObjects(s.keyRuntimes(strategyId), reflect.TypeOf(definition.StrategyRuntime{}))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*definition.StrategyRuntime, 0, len(arr))
+	for _, obj := range arr {
+		s, ok := obj.(*definition.StrategyRuntime)
+		if !ok {
+			continue
+		}
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+func (s *ConsulStore) SetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	if runtime == nil {
+		return errors.New("runtime should not be nil")
+	}
+	return s.update(s.keyRuntime(runtime.StrategyID, runtime.SchedulerID), runtime, false)
+}
+
+func (s *ConsulStore) CompareAndSetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	if runtime == nil {
+		return errors.New("runtime should not be nil")
+	}
+	revision, err := s.compareAndSet(s.keyRuntime(runtime.StrategyID, runtime.SchedulerID), runtime, runtime.Revision)
+	if err != nil {
+		return err
+	}
+	runtime.Revision = revision
+	return nil
+}
+
+func (s *ConsulStore) RemoveStrategyRuntime(strategyId, schedulerId string) error {
+	err := s.remove(s.keyRuntime(strategyId, schedulerId), false)
+	// ignore not exist
+	if err == store.NotExist {
+		return nil
+	}
+	return err
+}
+
+func (s *ConsulStore) Dump() string {
+	pairs, _ := s.getChildren(s.prefix)
+	result := make(map[string]string, len(pairs))
+	keys := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		result[pair.Key] = string(pair.Value)
+		keys = append(keys, pair.Key)
+	}
+	sort.Strings(keys)
+	b := strings.Builder{}
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteString(": ")
+		b.WriteString(result[k])
+		b.WriteString("\n")
+	}
+	return b.String()
+}
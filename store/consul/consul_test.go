@@ -0,0 +1,161 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package consul
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	fake     *fakeConsul
+	fakeOnce sync.Once
+)
+
+func newStorage() *ConsulStore {
+	fakeOnce.Do(func() {
+		fake = newFakeConsul()
+	})
+	store, _ := New("/schedule/demo/test", fake.addr())
+	return store
+}
+
+func TestName(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestName(t, s, "consul")
+	s.Close()
+}
+
+func TestTime(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestTime(t, s)
+	s.Close()
+}
+
+func TestSequence(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestSequence(t, s)
+	s.Close()
+}
+
+func TestTask(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestTask(t, s)
+	s.Close()
+}
+
+func TestTaskRuntime(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestTaskRuntime(t, s)
+	s.Close()
+}
+
+func TestTaskAssignment(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestTaskAssignment(t, s)
+	s.Close()
+}
+
+func TestStrategy(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestStrategy(t, s)
+	s.Close()
+}
+
+func TestStrategyRuntime(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestStrategyRuntime(t, s)
+	s.Close()
+}
+
+func TestScheduler(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestScheduler(t, s)
+	s.Close()
+}
+
+func TestDump(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestDump(t, s)
+	s.Close()
+}
+
+func TestTaskReload(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestTaskReloadItems(t, s)
+	s.Close()
+}
+
+func TestLiveness(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestLiveness(t, s)
+	storetest.DoTestLivenessUpgrade(t, s)
+	s.Close()
+}
+
+func TestSessionExpiration(t *testing.T) {
+	s := newStorage()
+	defer s.Close()
+	scheduler := &definition.Scheduler{
+		ID: "demo-scheduler-expiration",
+	}
+	runtime := &definition.TaskRuntime{
+		ID:         "demo-runtime-expiration",
+		StrategyID: "s0",
+		TaskID:     "t0",
+	}
+	assert.Nil(t, s.KeepSchedulerAlive(scheduler, 0))
+	assert.Nil(t, s.KeepTaskRuntimeAlive(runtime, 0))
+	alive, err := s.IsSchedulerAlive(scheduler.ID)
+	assert.Nil(t, err)
+	assert.True(t, alive)
+
+	// owner died
+	fake.expire()
+	alive, err = s.IsSchedulerAlive(scheduler.ID)
+	assert.Nil(t, err)
+	assert.False(t, alive)
+	alive, err = s.IsTaskRuntimeAlive(runtime.StrategyID, runtime.TaskID, runtime.ID)
+	assert.Nil(t, err)
+	assert.False(t, alive)
+	_, err = s.GetScheduler(scheduler.ID)
+	assert.Equal(t, store.NotExist, err)
+
+	// a new session is created on next heartbeat
+	assert.Nil(t, s.KeepSchedulerAlive(scheduler, 0))
+	alive, err = s.IsSchedulerAlive(scheduler.ID)
+	assert.Nil(t, err)
+	assert.True(t, alive)
+	assert.Nil(t, s.UnregisterScheduler(scheduler.ID))
+}
+
+func TestCompareAndSet(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestCompareAndSet(t, s)
+	s.Close()
+}
+
+func TestDeadLetter(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestDeadLetter(t, s)
+	s.Close()
+}
+
+func TestCronStore(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestCronStore(t, s)
+	s.Close()
+}
+
+func TestFireClaim(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestFireClaim(t, s)
+	s.Close()
+}
@@ -0,0 +1,67 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package consul
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+func (s *ConsulStore) keyLastFire(strategyId string) string {
+	return s.prefix + "/cronFires/" + strategyId
+}
+
+func (s *ConsulStore) keyFireClaims(strategyId string) string {
+	return s.prefix + "/cronClaims/" + strategyId
+}
+
+func (s *ConsulStore) keyFireClaim(strategyId string, fireTime int64) string {
+	return s.keyFireClaims(strategyId) + "/" + strconv.FormatInt(fireTime, 10)
+}
+
+func (s *ConsulStore) GetLastFireTime(strategyId string) (int64, error) {
+	var fireTime int64
+	err := s.getObject(s.keyLastFire(strategyId), &fireTime)
+	if err == store.NotExist {
+		return 0, nil
+	}
+	return fireTime, err
+}
+
+func (s *ConsulStore) SetLastFireTime(strategyId string, fireTime int64) error {
+	return s.update(s.keyLastFire(strategyId), fireTime, false)
+}
+
+func (s *ConsulStore) ClaimFireTime(strategyId string, fireTime int64) error {
+	return s.create(s.keyFireClaim(strategyId, fireTime), fireTime)
+}
+
+func (s *ConsulStore) RemoveFireClaims(strategyId string, before int64) error {
+	arr, err := s.getObjects(s.keyFireClaims(strategyId), reflect.TypeOf(int64(0)))
+	if err == store.NotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, obj := range arr {
+		fireTime, ok := obj.(*int64)
+		if !ok || *fireTime >= before {
+			continue
+		}
+		if err := s.remove(s.keyFireClaim(strategyId, *fireTime), false); err != nil && err != store.NotExist {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ConsulStore) removeFires(strategyId string) {
+	s.remove(s.keyLastFire(strategyId), false)
+	s.RemoveFireClaims(strategyId, math.MaxInt64)
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package consul
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/utils"
+)
+
+func (s *ConsulStore) keyDeadLetter(strategyId, taskId, id string) string {
+	return s.keyDeadLetters(strategyId, taskId) + "/" + id
+}
+
+func (s *ConsulStore) keyDeadLetters(strategyId, taskId string) string {
+	return s.prefix + "/deadLetters/" + strategyId + "/" + taskId
+}
+
+func (s *ConsulStore) PutDeadLetter(letter *definition.DeadLetter) error {
+	if letter == nil {
+		return errors.New("dead letter should not be nil")
+	}
+	return s.update(s.keyDeadLetter(letter.StrategyID, letter.TaskID, letter.ID), letter, false)
+}
+
+func (s *ConsulStore) GetDeadLetters(strategyId, taskId string) ([]*definition.DeadLetter, error) {
+	arr, err := s.getObjects(s.keyDeadLetters(strategyId, taskId), reflect.TypeOf(definition.DeadLetter{}))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*definition.DeadLetter, 0, len(arr))
+	for _, obj := range arr {
+		l, ok := obj.(*definition.DeadLetter)
+		if !ok {
+			continue
+		}
+		result = append(result, l)
+	}
+	utils.SortDeadLetters(result)
+	return result, nil
+}
+
+func (s *ConsulStore) RemoveDeadLetter(strategyId, taskId, id string) error {
+	err := s.remove(s.keyDeadLetter(strategyId, taskId, id), false)
+	// ignore not exist
+	if err == store.NotExist {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,243 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package consul
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+type fakeSession struct {
+	ttl      time.Duration
+	deadline time.Time
+}
+
+// fakeConsul serves the subset of http api of consul used by the store so that tests don't need a consul agent
+type fakeConsul struct {
+	sync.Mutex
+	index    uint64
+	kv       map[string]*api.KVPair
+	sessions map[string]*fakeSession
+	server   *httptest.Server
+}
+
+func newFakeConsul() *fakeConsul {
+	f := &fakeConsul{
+		kv:       make(map[string]*api.KVPair),
+		sessions: make(map[string]*fakeSession),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/kv/", f.handleKV)
+	mux.HandleFunc("/v1/txn", f.handleTxn)
+	mux.HandleFunc("/v1/session/", f.handleSession)
+	mux.HandleFunc("/v1/status/leader", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`"127.0.0.1:8300"`))
+	})
+	f.server = httptest.NewServer(mux)
+	return f
+}
+
+func (f *fakeConsul) addr() string {
+	return f.server.Listener.Addr().String()
+}
+
+// expire invalidates all sessions immediately like they have expired
+func (f *fakeConsul) expire() {
+	f.Lock()
+	defer f.Unlock()
+	for id := range f.sessions {
+		f.invalidate(id)
+	}
+}
+
+func (f *fakeConsul) invalidate(id string) {
+	delete(f.sessions, id)
+	for key, pair := range f.kv {
+		if pair.Session == id {
+			// behavior of delete
+			delete(f.kv, key)
+			f.index++
+		}
+	}
+}
+
+func (f *fakeConsul) expireSessions() {
+	now := time.Now()
+	for id, session := range f.sessions {
+		if session.deadline.Before(now) {
+			f.invalidate(id)
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(obj)
+}
+
+func (f *fakeConsul) set(key string, value []byte, session string) *api.KVPair {
+	f.index++
+	pair, ok := f.kv[key]
+	if !ok {
+		pair = &api.KVPair{Key: key, CreateIndex: f.index}
+		f.kv[key] = pair
+	}
+	pair.Value = value
+	pair.ModifyIndex = f.index
+	if session != "" && pair.Session != session {
+		pair.Session = session
+		pair.LockIndex++
+	}
+	return pair
+}
+
+// cas sets the key only if the modify index matches and zero index means the key should not exist
+func (f *fakeConsul) cas(key string, value []byte, index uint64) (*api.KVPair, bool) {
+	pair, ok := f.kv[key]
+	if (index == 0 && ok) || (index > 0 && (!ok || pair.ModifyIndex != index)) {
+		return nil, false
+	}
+	return f.set(key, value, ""), true
+}
+
+func (f *fakeConsul) handleKV(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	f.expireSessions()
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	query := r.URL.Query()
+	_, recurse := query["recurse"]
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+	switch r.Method {
+	case http.MethodGet:
+		pairs := make(api.KVPairs, 0)
+		for k, pair := range f.kv {
+			if k == key || (recurse && strings.HasPrefix(k, key)) {
+				pairs = append(pairs, pair)
+			}
+		}
+		if len(pairs) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		sort.Slice(pairs, func(i, j int) bool {
+			return pairs[i].Key < pairs[j].Key
+		})
+		writeJSON(w, http.StatusOK, pairs)
+	case http.MethodPut:
+		value, _ := ioutil.ReadAll(r.Body)
+		if cas := query.Get("cas"); cas != "" {
+			index, _ := strconv.ParseUint(cas, 10, 64)
+			_, ok := f.cas(key, value, index)
+			writeJSON(w, http.StatusOK, ok)
+			return
+		}
+		if session := query.Get("acquire"); session != "" {
+			if _, ok := f.sessions[session]; !ok {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte("invalid session"))
+				return
+			}
+			if pair, ok := f.kv[key]; ok && pair.Session != "" && pair.Session != session {
+				writeJSON(w, http.StatusOK, false)
+				return
+			}
+			f.set(key, value, session)
+			writeJSON(w, http.StatusOK, true)
+			return
+		}
+		f.set(key, value, "")
+		writeJSON(w, http.StatusOK, true)
+	case http.MethodDelete:
+		for k := range f.kv {
+			if k == key || (recurse && strings.HasPrefix(k, key)) {
+				delete(f.kv, k)
+			}
+		}
+		f.index++
+		writeJSON(w, http.StatusOK, true)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleTxn supports transactions consisting of cas operations only
+func (f *fakeConsul) handleTxn(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	f.expireSessions()
+	var ops api.TxnOps
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	resp := api.TxnResponse{}
+	for i, op := range ops {
+		if op.KV == nil || op.KV.Verb != api.KVCAS {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		pair, ok := f.cas(op.KV.Key, op.KV.Value, op.KV.Index)
+		if !ok {
+			resp.Errors = append(resp.Errors, &api.TxnError{OpIndex: i, What: "failed to set key"})
+			writeJSON(w, http.StatusConflict, resp)
+			return
+		}
+		result := *pair
+		result.Value = nil
+		resp.Results = append(resp.Results, &api.TxnResult{KV: &result})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (f *fakeConsul) handleSession(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+	f.expireSessions()
+	path := strings.TrimPrefix(r.URL.Path, "/v1/session/")
+	switch {
+	case path == "create":
+		var entry struct {
+			TTL string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		ttl, err := time.ParseDuration(entry.TTL)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.index++
+		id := "session-" + strconv.FormatUint(f.index, 10)
+		f.sessions[id] = &fakeSession{ttl: ttl, deadline: time.Now().Add(ttl)}
+		writeJSON(w, http.StatusOK, map[string]string{"ID": id})
+	case strings.HasPrefix(path, "renew/"):
+		id := strings.TrimPrefix(path, "renew/")
+		session, ok := f.sessions[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		session.deadline = time.Now().Add(session.ttl)
+		writeJSON(w, http.StatusOK, []*api.SessionEntry{{ID: id}})
+	case strings.HasPrefix(path, "destroy/"):
+		f.invalidate(strings.TrimPrefix(path, "destroy/"))
+		writeJSON(w, http.StatusOK, true)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
@@ -0,0 +1,9 @@
+module github.com/jasonjoo2010/goschedule/store/consul
+
+go 1.14
+
+require (
+	github.com/hashicorp/consul/api v1.8.1
+	github.com/jasonjoo2010/goschedule v1.1.0
+	github.com/stretchr/testify v1.7.0
+)
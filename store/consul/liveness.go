@@ -0,0 +1,126 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package consul
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+// Temporary objects are acquired by sessions with delete behavior which will be removed by consul
+//	after the sessions are invalidated. Every object holds its own session so that it can be destroyed individually.
+//	Objects without sessions, which are saved without liveness, are reported as NotExist so that their
+//	heartbeats are compared.
+
+// Range of ttl of sessions accepted by consul
+const (
+	minSessionTTL = 10 * time.Second
+	maxSessionTTL = 24 * time.Hour
+)
+
+// sessionTTL formats ttl in seconds for consul limiting it in the range accepted
+func sessionTTL(ttl time.Duration) string {
+	if ttl < minSessionTTL {
+		ttl = minSessionTTL
+	}
+	if ttl > maxSessionTTL {
+		ttl = maxSessionTTL
+	}
+	return strconv.FormatInt(int64((ttl+time.Second-1)/time.Second), 10) + "s"
+}
+
+func (s *ConsulStore) acquireSession(key string, ttl time.Duration) (string, error) {
+	s.sessionMu.Lock()
+	id, ok := s.sessions[key]
+	s.sessionMu.Unlock()
+	if ok {
+		entry, _, err := s.session.Renew(id, nil)
+		if err == nil && entry != nil {
+			return id, nil
+		}
+		// expired or lost
+	}
+	id, _, err := s.session.Create(&api.SessionEntry{
+		Name:     key,
+		TTL:      sessionTTL(ttl),
+		Behavior: api.SessionBehaviorDelete,
+		// keys are allowed to be acquired again immediately after invalidated
+		LockDelay: time.Millisecond,
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	s.sessionMu.Lock()
+	s.sessions[key] = id
+	s.sessionMu.Unlock()
+	return id, nil
+}
+
+func (s *ConsulStore) destroySession(key string) {
+	s.sessionMu.Lock()
+	id, ok := s.sessions[key]
+	delete(s.sessions, key)
+	s.sessionMu.Unlock()
+	if ok {
+		s.session.Destroy(id, nil)
+	}
+}
+
+func (s *ConsulStore) keepAlive(key string, obj interface{}, ttl time.Duration) error {
+	data, err := toBytes(obj)
+	if err != nil {
+		return err
+	}
+	id, err := s.acquireSession(key, ttl)
+	if err != nil {
+		return err
+	}
+	ok, _, err := s.kv.Acquire(&api.KVPair{Key: key, Value: data, Session: id}, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("Key is held by another session: " + key)
+	}
+	return nil
+}
+
+func (s *ConsulStore) alive(key string) (bool, error) {
+	pair, err := s.get(key)
+	if err != nil || pair == nil {
+		return false, err
+	}
+	if pair.Session == "" {
+		return false, store.NotExist
+	}
+	return true, nil
+}
+
+func (s *ConsulStore) KeepSchedulerAlive(scheduler *definition.Scheduler, ttl time.Duration) error {
+	if scheduler == nil {
+		return errors.New("scheduler should not be nil")
+	}
+	return s.keepAlive(s.keyScheduler(scheduler.ID), scheduler, ttl)
+}
+
+func (s *ConsulStore) IsSchedulerAlive(id string) (bool, error) {
+	return s.alive(s.keyScheduler(id))
+}
+
+func (s *ConsulStore) KeepTaskRuntimeAlive(runtime *definition.TaskRuntime, ttl time.Duration) error {
+	if runtime == nil {
+		return errors.New("task runtime should not be nil")
+	}
+	return s.keepAlive(s.keyTaskRuntime(runtime.StrategyID, runtime.TaskID, runtime.ID), runtime, ttl)
+}
+
+func (s *ConsulStore) IsTaskRuntimeAlive(strategyId, taskId, id string) (bool, error) {
+	return s.alive(s.keyTaskRuntime(strategyId, taskId, id))
+}
@@ -0,0 +1,20 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package consul
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionTTL(t *testing.T) {
+	assert.Equal(t, "10s", sessionTTL(0))
+	assert.Equal(t, "10s", sessionTTL(time.Second))
+	assert.Equal(t, "11s", sessionTTL(10001*time.Millisecond))
+	assert.Equal(t, "60s", sessionTTL(time.Minute))
+	assert.Equal(t, "86400s", sessionTTL(48*time.Hour))
+}
@@ -0,0 +1,41 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package consul
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/log"
+)
+
+// Date header of http responses has a precision of one second, so differences
+//	within it are ignored and local clock is used.
+const timePrecision = time.Second
+
+// caculateTimeDifference compares local clock with the one of server reported in Date header.
+//	Local clock is used if the server can't be reached.
+func (s *ConsulStore) caculateTimeDifference(addr string) {
+	client := http.Client{Timeout: 3 * time.Second}
+	begin := time.Now()
+	resp, err := client.Get(addr + "/v1/status/leader")
+	if err != nil {
+		log.Warnf("Failed to get time of consul, use local clock: %s", err.Error())
+		return
+	}
+	resp.Body.Close()
+	end := time.Now()
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		log.Warnf("Failed to get time of consul, use local clock: %s", err.Error())
+		return
+	}
+	// Date is truncated to seconds so take the middle of the second
+	delta := serverTime.Add(timePrecision / 2).Sub(begin.Add(end.Sub(begin) / 2))
+	if delta > -timePrecision && delta < timePrecision {
+		return
+	}
+	s.timeDelta = delta
+}
@@ -0,0 +1,58 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package consul
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+type Option func(cfg *api.Config)
+
+// WithToken sets the ACL token
+func WithToken(token string) Option {
+	return func(cfg *api.Config) {
+		cfg.Token = token
+	}
+}
+
+// WithDatacenter sets the datacenter instead of the one of agent
+func WithDatacenter(datacenter string) Option {
+	return func(cfg *api.Config) {
+		cfg.Datacenter = datacenter
+	}
+}
+
+// WithScheme sets the scheme of address, http by default
+func WithScheme(scheme string) Option {
+	return func(cfg *api.Config) {
+		cfg.Scheme = scheme
+	}
+}
+
+// New creates a storage on the agent of address like 127.0.0.1:8500.
+//	Keys of consul can't begin with '/' so the leading slashes of prefix are trimmed.
+func New(prefix, address string, opts ...Option) (*ConsulStore, error) {
+	cfg := api.DefaultConfig()
+	cfg.Address = address
+	for _, fn := range opts {
+		fn(cfg)
+	}
+	c, err := api.NewClient(cfg)
+	if err != nil {
+		return nil, errors.New("Create consul store failed: " + err.Error())
+	}
+	s := &ConsulStore{
+		client:   c,
+		kv:       c.KV(),
+		session:  c.Session(),
+		prefix:   strings.Trim(prefix, "/"),
+		sessions: make(map[string]string),
+	}
+	s.caculateTimeDifference(cfg.Scheme + "://" + cfg.Address)
+	return s, nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package store
+
+// CronStore is an optional interface which can be implemented by a Store to persist the last fire time
+//	of cron strategies. Then a restarted or moved worker can apply misfire policy correctly.
+type CronStore interface {
+	// GetLastFireTime returns the last fire time(in millis) of strategy, 0 if never fired
+	GetLastFireTime(strategyId string) (int64, error)
+	// SetLastFireTime saves the last fire time(in millis) of strategy
+	SetLastFireTime(strategyId string, fireTime int64) error
+}
+
+// GetLastFireTime returns the last fire time(in millis) of strategy persisted in storage if supported,
+//	0 is returned if never fired, unsupported or failed.
+func GetLastFireTime(s Store, strategyId string) int64 {
+	if c, ok := AsCronStore(s); ok {
+		if t, err := c.GetLastFireTime(strategyId); err == nil {
+			return t
+		}
+	}
+	return 0
+}
+
+// SetLastFireTime persists the last fire time(in millis) of strategy into storage if supported
+func SetLastFireTime(s Store, strategyId string, fireTime int64) error {
+	if c, ok := AsCronStore(s); ok {
+		return c.SetLastFireTime(strategyId, fireTime)
+	}
+	return nil
+}
+
+// FireClaimStore is an optional interface which can be implemented by a Store to claim fire times of
+//	strategies exclusively. It's required by SingletonKind strategies to fire exactly once cluster wide.
+type FireClaimStore interface {
+	// ClaimFireTime claims the fire time(in millis) of strategy atomically,
+	//	AlreadyExist is returned if it has been claimed already.
+	ClaimFireTime(strategyId string, fireTime int64) error
+	// RemoveFireClaims removes claims of strategy whose fire time is before the given one
+	RemoveFireClaims(strategyId string, before int64) error
+}
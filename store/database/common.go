@@ -6,14 +6,107 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"strings"
 
-	"github.com/jasonjoo2010/godao"
-	"github.com/jasonjoo2010/godao/options"
-	"github.com/jasonjoo2010/godao/types"
+	"github.com/jasonjoo2010/goschedule/definition"
 	"github.com/jasonjoo2010/goschedule/store"
 )
 
+// queries are statements on the info table rendered by the dialect
+type queries struct {
+	selectOne    string
+	selectPrefix string
+	insert       string
+	update       string
+	set          string
+	setVersion   string
+	increase     string
+	incVersion   string
+	remove       string
+}
+
+func (s *DatabaseStore) prepareQueries() {
+	d := s.dialect
+	table := d.Quote(s.table)
+	key, value, version := d.Quote("key"), d.Quote("value"), d.Quote("version")
+	columns := key + ", " + value + ", " + version
+	s.q = queries{
+		selectOne:    "SELECT " + columns + " FROM " + table + " WHERE " + key + " = ?",
+		selectPrefix: "SELECT " + columns + " FROM " + table + " WHERE " + key + " LIKE ? ESCAPE '!' ORDER BY " + key + " LIMIT ? OFFSET ?",
+		insert:       d.InsertIgnore(s.table, "key", "value", "version"),
+		update:       "UPDATE " + table + " SET " + value + " = ? WHERE " + key + " = ?",
+		set:          "UPDATE " + table + " SET " + value + " = ?, " + version + " = " + version + " + 1 WHERE " + key + " = ?",
+		setVersion:   "UPDATE " + table + " SET " + value + " = ?, " + version + " = " + version + " + 1 WHERE " + key + " = ? AND " + version + " = ?",
+		increase:     "UPDATE " + table + " SET " + version + " = " + version + " + 1 WHERE " + key + " = ?",
+		incVersion:   "UPDATE " + table + " SET " + version + " = " + version + " + 1 WHERE " + key + " = ? AND " + version + " = ?",
+		remove:       "DELETE FROM " + table + " WHERE " + key + " = ?",
+	}
+	for _, q := range []*string{&s.q.selectOne, &s.q.selectPrefix, &s.q.insert, &s.q.update, &s.q.set,
+		&s.q.setVersion, &s.q.increase, &s.q.incVersion, &s.q.remove} {
+		*q = d.Rebind(*q)
+	}
+}
+
+// escapeLike escapes wildcards in str for a LIKE pattern using '!' as the escape character
+func escapeLike(str string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(str)
+}
+
+// exec executes the statement and returns the count of rows affected
+func (s *DatabaseStore) exec(query string, args ...interface{}) (int64, error) {
+	result, err := s.db.ExecContext(context.Background(), query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// insert inserts a record and returns 0 affected if the key has already existed
+func (s *DatabaseStore) insert(key, value string, version int64) (int64, error) {
+	return s.exec(s.q.insert, key, value, version)
+}
+
+func scanInfo(row interface{ Scan(...interface{}) error }) (*ScheduleInfo, error) {
+	var (
+		info  ScheduleInfo
+		value sql.NullString
+	)
+	if err := row.Scan(&info.Key, &value, &info.Version); err != nil {
+		return nil, err
+	}
+	info.Value = value.String
+	return &info, nil
+}
+
+// selectInfo returns the record of the key or nil if it doesn't exist
+func (s *DatabaseStore) selectInfo(key string) (*ScheduleInfo, error) {
+	info, err := scanInfo(s.db.QueryRowContext(context.Background(), s.q.selectOne, key))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return info, err
+}
+
+// selectInfos returns records whose keys start with prefix ordered by key
+func (s *DatabaseStore) selectInfos(prefix string, offset, limit int) ([]*ScheduleInfo, error) {
+	rows, err := s.db.QueryContext(context.Background(), s.q.selectPrefix, escapeLike(prefix)+"%", limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]*ScheduleInfo, 0)
+	for rows.Next() {
+		info, err := scanInfo(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, info)
+	}
+	return list, rows.Err()
+}
+
 func (s *DatabaseStore) updateOrInsert(key string, obj interface{}) error {
 	if obj == nil {
 		return errors.New("Object should not be nil")
@@ -22,42 +115,57 @@ func (s *DatabaseStore) updateOrInsert(key string, obj interface{}) error {
 	if err != nil {
 		return err
 	}
-	affected, err := s.dao.UpdateBy(context.Background(), (&godao.Query{}).
-		Equal("Key", key).
-		Data(),
-		&types.UpdateEntry{
-			Field: "Value",
-			Value: str,
-		},
-	)
+	affected, err := s.exec(s.q.set, str, key)
 	if err != nil {
 		return err
 	}
 	if affected < 1 {
 		// insert
-		_, _, err = s.dao.Insert(context.Background(),
-			ScheduleInfo{
-				Key:   key,
-				Value: str,
-			},
-			options.WithInsertIgnore(),
-		)
+		_, err = s.insert(key, str, 1)
 	}
 	return err
 }
 
+// setRevision fills the revision of objects supporting compare-and-set, version column is used.
+func setRevision(obj interface{}, revision int64) {
+	switch v := obj.(type) {
+	case *definition.TaskAssignment:
+		v.Revision = revision
+	case *definition.StrategyRuntime:
+		v.Revision = revision
+	}
+}
+
+// compareAndSet saves the object only if the version equals to revision and returns the new one.
+//	Zero revision means the record should not exist or it's saved by older versions which never
+//	increased the version, records saved by compare-and-set always have positive versions.
+func (s *DatabaseStore) compareAndSet(key string, obj interface{}, revision int64) (int64, error) {
+	str, err := toStr(obj)
+	if err != nil {
+		return 0, err
+	}
+	var affected int64
+	if revision == 0 {
+		affected, err = s.insert(key, str, 1)
+	}
+	if err == nil && affected < 1 {
+		affected, err = s.exec(s.q.setVersion, str, key, revision)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if affected < 1 {
+		return 0, store.Conflict
+	}
+	return revision + 1, nil
+}
+
 func (s *DatabaseStore) create(key string, obj interface{}) error {
 	str, err := toStr(obj)
 	if err != nil {
 		return err
 	}
-	affected, _, err := s.dao.Insert(context.Background(),
-		ScheduleInfo{
-			Key:   key,
-			Value: str,
-		},
-		options.WithInsertIgnore(),
-	)
+	affected, err := s.insert(key, str, 0)
 	if err != nil {
 		return err
 	}
@@ -75,14 +183,7 @@ func (s *DatabaseStore) update(key string, obj interface{}) error {
 	if err != nil {
 		return err
 	}
-	affected, err := s.dao.UpdateBy(context.Background(), (&godao.Query{}).
-		Equal("Key", key).
-		Data(),
-		&types.UpdateEntry{
-			Field: "Value",
-			Value: str,
-		},
-	)
+	affected, err := s.exec(s.q.update, str, key)
 	if err != nil {
 		return err
 	}
@@ -93,12 +194,12 @@ func (s *DatabaseStore) update(key string, obj interface{}) error {
 }
 
 func (s *DatabaseStore) remove(key string) error {
-	affected, err := s.dao.DeleteRange(context.Background(), (&godao.Query{}).
-		Equal("Key", key).
-		Data(),
-	)
+	affected, err := s.exec(s.q.remove, key)
+	if err != nil {
+		return err
+	}
 	if affected < 1 {
 		return store.NotExist
 	}
-	return err
+	return nil
 }
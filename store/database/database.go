@@ -5,7 +5,6 @@
 package database
 
 import (
-	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
@@ -13,9 +12,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/jasonjoo2010/godao"
-	"github.com/jasonjoo2010/godao/options"
-	"github.com/jasonjoo2010/godao/types"
 	"github.com/jasonjoo2010/goschedule/definition"
 	"github.com/jasonjoo2010/goschedule/log"
 	"github.com/jasonjoo2010/goschedule/store"
@@ -23,7 +19,9 @@ import (
 
 type DatabaseStore struct {
 	db        *sql.DB
-	dao       *godao.Dao
+	dialect   Dialect
+	table     string
+	q         queries
 	namespace string
 }
 
@@ -84,24 +82,22 @@ func (s *DatabaseStore) keyTaskReload(strategyId, taskId string) string {
 }
 
 func (s *DatabaseStore) getObject(key string, obj interface{}) error {
-	o, err := s.dao.SelectOneBy(context.Background(), "Key", key)
+	info, err := s.selectInfo(key)
 	if err != nil {
 		return err
 	}
-	if o == nil {
+	if info == nil {
 		return store.NotExist
 	}
-	info := o.(*ScheduleInfo)
-	return json.Unmarshal([]byte(info.Value), obj)
+	if err = json.Unmarshal([]byte(info.Value), obj); err != nil {
+		return err
+	}
+	setRevision(obj, info.Version)
+	return nil
 }
 
 func (s *DatabaseStore) getObjects(base_key string, t reflect.Type) ([]interface{}, error) {
-	list, err := s.dao.Select(context.Background(),
-		(&godao.Query{}).
-			StartsWith("Key", strings.TrimRight(base_key, "/")+"/").
-			Limit(2000).
-			Data(),
-	)
+	list, err := s.selectInfos(strings.TrimRight(base_key, "/")+"/", 0, 2000)
 	if err != nil {
 		return nil, err
 	}
@@ -109,14 +105,14 @@ func (s *DatabaseStore) getObjects(base_key string, t reflect.Type) ([]interface
 		return nil, nil
 	}
 	result := make([]interface{}, 0, len(list))
-	for _, o := range list {
-		info := o.(*ScheduleInfo)
+	for _, info := range list {
 		obj := reflect.New(t).Interface()
 		err = json.Unmarshal([]byte(info.Value), obj)
 		if err != nil {
 			log.Warnf("Wrong data type during deserializing: %s", info.Key)
 			continue
 		}
+		setRevision(obj, info.Version)
 		result = append(result, obj)
 	}
 	return result, nil
@@ -136,29 +132,20 @@ func (s *DatabaseStore) Sequence() (uint64, error) {
 	// But now pick optimistic update way
 	retries := 10
 	for retries > 0 {
-		obj, err := s.dao.SelectOneBy(context.Background(), "Key", key)
+		info, err := s.selectInfo(key)
 		if err != nil {
 			log.Warnf("Failed to fetch info from database: %s", err.Error())
 			return 0, err
 		}
-		if obj == nil {
+		if info == nil {
 			// first time, insert
-			_, _, err := s.dao.Insert(context.Background(), ScheduleInfo{
-				Key:     key,
-				Version: 1,
-			}, options.WithInsertIgnore())
+			_, err := s.insert(key, "", 1)
 			if err != nil {
 				return 0, err
 			}
 			continue
 		}
-		info := obj.(*ScheduleInfo)
-		affected, err := s.dao.UpdateBy(context.Background(), (&godao.Query{}).
-			Equal("Key", key).
-			Equal("Version", info.Version).
-			Data(),
-			types.NewIncrease("Version", 1),
-		)
+		affected, err := s.exec(s.q.incVersion, key, info.Version)
 		if err != nil {
 			return 0, err
 		}
@@ -303,24 +290,19 @@ func (s *DatabaseStore) RemoveTaskRuntime(strategyId, taskId, id string) error {
 
 func (s *DatabaseStore) GetTaskItemsConfigVersion(strategyId, taskId string) (int64, error) {
 	key := s.keyTaskReload(strategyId, taskId)
-	obj, err := s.dao.SelectOneBy(context.Background(), "Key", key)
+	info, err := s.selectInfo(key)
 	if err != nil {
 		return 0, err
 	}
-	if obj == nil {
+	if info == nil {
 		return 0, nil
 	}
-	info := obj.(*ScheduleInfo)
 	return info.Version, nil
 }
 func (s *DatabaseStore) IncreaseTaskItemsConfigVersion(strategyId, taskId string) error {
 	key := s.keyTaskReload(strategyId, taskId)
 	for {
-		affected, err := s.dao.UpdateBy(context.Background(), (&godao.Query{}).
-			Equal("Key", key).
-			Data(),
-			types.NewIncrease("Version", 1),
-		)
+		affected, err := s.exec(s.q.increase, key)
 		if err != nil {
 			return err
 		}
@@ -328,12 +310,7 @@ func (s *DatabaseStore) IncreaseTaskItemsConfigVersion(strategyId, taskId string
 			break
 		}
 		// initial
-		s.dao.Insert(context.Background(),
-			ScheduleInfo{
-				Key: key,
-			},
-			options.WithInsertIgnore(),
-		)
+		s.insert(key, "", 0)
 	}
 	return nil
 }
@@ -367,6 +344,17 @@ func (s *DatabaseStore) SetTaskAssignment(assignment *definition.TaskAssignment)
 	}
 	return s.updateOrInsert(s.keyTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID), assignment)
 }
+func (s *DatabaseStore) CompareAndSetTaskAssignment(assignment *definition.TaskAssignment) error {
+	if assignment == nil {
+		return errors.New("assignment should not be nil")
+	}
+	revision, err := s.compareAndSet(s.keyTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID), assignment, assignment.Revision)
+	if err != nil {
+		return err
+	}
+	assignment.Revision = revision
+	return nil
+}
 func (s *DatabaseStore) RemoveTaskAssignment(strategyId, taskId, itemId string) error {
 	err := s.remove(s.keyTaskAssignment(strategyId, taskId, itemId))
 	// ignore not exist
@@ -412,6 +400,7 @@ func (s *DatabaseStore) UpdateStrategy(strategy *definition.Strategy) error {
 	return s.update(s.keyStrategy(strategy.ID), strategy)
 }
 func (s *DatabaseStore) RemoveStrategy(id string) error {
+	s.removeFires(id)
 	return s.remove(s.keyStrategy(id))
 }
 
@@ -444,6 +433,17 @@ func (s *DatabaseStore) SetStrategyRuntime(runtime *definition.StrategyRuntime)
 	}
 	return s.updateOrInsert(s.keyRuntime(runtime.StrategyID, runtime.SchedulerID), runtime)
 }
+func (s *DatabaseStore) CompareAndSetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	if runtime == nil {
+		return errors.New("runtime should not be nil")
+	}
+	revision, err := s.compareAndSet(s.keyRuntime(runtime.StrategyID, runtime.SchedulerID), runtime, runtime.Revision)
+	if err != nil {
+		return err
+	}
+	runtime.Revision = revision
+	return nil
+}
 func (s *DatabaseStore) RemoveStrategyRuntime(strategyId, schedulerId string) error {
 	err := s.remove(s.keyRuntime(strategyId, schedulerId))
 	// ignore not exist
@@ -456,12 +456,9 @@ func (s *DatabaseStore) RemoveStrategyRuntime(strategyId, schedulerId string) er
 func (s *DatabaseStore) Dump() string {
 	page := 1
 	size := 50
-	condition := &godao.Query{}
-	condition.StartsWith("Key", s.namespace+"/").OrderBy("Key", false)
 	b := strings.Builder{}
 	for {
-		condition.Page(page, size)
-		list, err := s.dao.Select(context.Background(), condition.Data())
+		list, err := s.selectInfos(s.namespace+"/", (page-1)*size, size)
 		if err != nil {
 			log.Warnf("Error occurred when dumping: %s", err.Error())
 			break
@@ -469,8 +466,7 @@ func (s *DatabaseStore) Dump() string {
 		if len(list) < 1 {
 			break
 		}
-		for _, obj := range list {
-			item := obj.(*ScheduleInfo)
+		for _, item := range list {
 			b.WriteString(item.Key)
 			b.WriteString(": ")
 			b.WriteString(item.Value)
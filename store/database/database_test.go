@@ -6,18 +6,33 @@ package database
 
 import (
 	"database/sql"
+	"os"
+	"path/filepath"
 	"testing"
 
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
 	"github.com/jasonjoo2010/goschedule/storetest"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
 )
 
+// newStorage runs against a local SQLite file unless MYSQL_DSN is given, e.g. root@tcp(127.0.0.1:3306)/test
 func newStorage() *DatabaseStore {
-	db, err := sql.Open("mysql", "root@tcp(127.0.0.1:3306)/test")
+	if dsn := os.Getenv("MYSQL_DSN"); dsn != "" {
+		db, err := sql.Open("mysql", dsn)
+		if err != nil {
+			panic(err)
+		}
+		return New("/schedule/demo", db)
+	}
+	db, err := sql.Open("sqlite3", filepath.Join(os.TempDir(), "goschedule-database-test.db")+"?_busy_timeout=5000")
 	if err != nil {
 		panic(err)
 	}
-	return New("/schedule/demo", db)
+	db.SetMaxOpenConns(1)
+	return New("/schedule/demo", db, WithDialect(SQLite))
 }
 
 func TestName(t *testing.T) {
@@ -85,3 +100,73 @@ func TestTaskReload(t *testing.T) {
 	storetest.DoTestTaskReloadItems(t, s)
 	s.Close()
 }
+
+func TestCompareAndSet(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestCompareAndSet(t, s)
+	s.Close()
+}
+
+func TestDeadLetter(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestDeadLetter(t, s)
+	s.Close()
+}
+
+func TestCronStore(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestCronStore(t, s)
+	s.Close()
+}
+
+func TestFireClaim(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestFireClaim(t, s)
+	s.Close()
+}
+
+// TestLegacyRevision verifies records saved by older versions, which never increased version, can be compared and set
+func TestLegacyRevision(t *testing.T) {
+	s := newStorage()
+	defer s.Close()
+	assignment := &definition.TaskAssignment{
+		StrategyID: "strategy-legacy",
+		TaskID:     "task-legacy",
+		ItemID:     "a",
+		RuntimeID:  "r0",
+	}
+	runtime := &definition.StrategyRuntime{
+		StrategyID:  "strategy-legacy",
+		SchedulerID: "scheduler-legacy",
+		Num:         1,
+	}
+	assignmentKey := s.keyTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID)
+	runtimeKey := s.keyRuntime(runtime.StrategyID, runtime.SchedulerID)
+	defer s.remove(assignmentKey)
+	defer s.remove(runtimeKey)
+	for key, obj := range map[string]interface{}{assignmentKey: assignment, runtimeKey: runtime} {
+		str, _ := toStr(obj)
+		s.remove(key)
+		_, err := s.insert(key, str, 0)
+		assert.Nil(t, err)
+	}
+
+	got, err := s.GetTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), got.Revision)
+	got.RuntimeID = "r1"
+	assert.Nil(t, s.CompareAndSetTaskAssignment(got))
+	assert.Equal(t, int64(1), got.Revision)
+	// stale
+	assert.Equal(t, store.Conflict, s.CompareAndSetTaskAssignment(assignment))
+	got, _ = s.GetTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID)
+	assert.Equal(t, "r1", got.RuntimeID)
+
+	gotRuntime, err := s.GetStrategyRuntime(runtime.StrategyID, runtime.SchedulerID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), gotRuntime.Revision)
+	gotRuntime.RequestedNum = 2
+	assert.Nil(t, s.CompareAndSetStrategyRuntime(gotRuntime))
+	assert.Equal(t, int64(1), gotRuntime.Revision)
+	assert.Equal(t, store.Conflict, s.CompareAndSetStrategyRuntime(runtime))
+}
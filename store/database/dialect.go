@@ -0,0 +1,170 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dialect hides differences of SQL between databases
+type Dialect interface {
+	Name() string
+	// Quote quotes an identifier like table or column
+	Quote(identifier string) string
+	// Rebind replaces the ? placeholders in query with the ones of database
+	Rebind(query string) string
+	// InsertIgnore returns an insert statement which affects no row rather than failing on duplicated key
+	InsertIgnore(table string, columns ...string) string
+	// Migrations returns statements of every version of schema in order.
+	//	The version of schema is the count of migrations applied.
+	Migrations(table string) [][]string
+	// LockSchema returns statements acquiring and releasing an exclusive lock of the schema table, the
+	//	former is run first in the transaction of migrating and the latter is run in the same session
+	//	after the transaction ends. Releasing is empty if the lock is released with the transaction.
+	LockSchema(schemaTable string) (lock, unlock string)
+}
+
+var (
+	MySQL      Dialect = mysqlDialect{}
+	PostgreSQL Dialect = postgresDialect{}
+	SQLite     Dialect = sqliteDialect{}
+)
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func insertInto(d Dialect, table string, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = d.Quote(c)
+	}
+	return "INTO " + d.Quote(table) + " (" + strings.Join(quoted, ", ") + ") VALUES (" + placeholders(len(columns)) + ")"
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string {
+	return "mysql"
+}
+
+func (mysqlDialect) Quote(identifier string) string {
+	return "`" + identifier + "`"
+}
+
+func (mysqlDialect) Rebind(query string) string {
+	return query
+}
+
+func (d mysqlDialect) InsertIgnore(table string, columns ...string) string {
+	return "INSERT IGNORE " + insertInto(d, table, columns)
+}
+
+func (mysqlDialect) Migrations(table string) [][]string {
+	return [][]string{
+		{
+			"CREATE TABLE IF NOT EXISTS `" + table + "` (" +
+				"`id` bigint NOT NULL AUTO_INCREMENT, " +
+				"`key` varchar(255) NOT NULL DEFAULT '', " +
+				"`value` text NULL, " +
+				"`version` bigint NOT NULL DEFAULT '0', " +
+				"PRIMARY KEY (`id`), " +
+				"UNIQUE KEY `key` (`key`)" +
+				") ENGINE=InnoDB",
+		},
+	}
+}
+
+// LockSchema uses a named lock in session because DDL commits the transaction implicitly
+func (mysqlDialect) LockSchema(schemaTable string) (string, string) {
+	return "SELECT GET_LOCK('" + schemaTable + "', -1)", "SELECT RELEASE_LOCK('" + schemaTable + "')"
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return "postgres"
+}
+
+func (postgresDialect) Quote(identifier string) string {
+	return `"` + identifier + `"`
+}
+
+// Rebind replaces ? placeholders with $1, $2, ...
+//	Question marks in string literals are not supported as they are never used by the store.
+func (postgresDialect) Rebind(query string) string {
+	b := strings.Builder{}
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func (d postgresDialect) InsertIgnore(table string, columns ...string) string {
+	return "INSERT " + insertInto(d, table, columns) + " ON CONFLICT DO NOTHING"
+}
+
+func (postgresDialect) Migrations(table string) [][]string {
+	return [][]string{
+		{
+			`CREATE TABLE IF NOT EXISTS "` + table + `" (` +
+				`"id" BIGSERIAL PRIMARY KEY, ` +
+				`"key" VARCHAR(255) NOT NULL DEFAULT '', ` +
+				`"value" TEXT NULL, ` +
+				`"version" BIGINT NOT NULL DEFAULT 0` +
+				`)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS "` + table + `_key" ON "` + table + `" ("key")`,
+		},
+	}
+}
+
+func (postgresDialect) LockSchema(schemaTable string) (string, string) {
+	return `LOCK TABLE "` + schemaTable + `" IN EXCLUSIVE MODE`, ""
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return "sqlite"
+}
+
+func (sqliteDialect) Quote(identifier string) string {
+	return `"` + identifier + `"`
+}
+
+func (sqliteDialect) Rebind(query string) string {
+	return query
+}
+
+func (d sqliteDialect) InsertIgnore(table string, columns ...string) string {
+	return "INSERT OR IGNORE " + insertInto(d, table, columns)
+}
+
+func (sqliteDialect) Migrations(table string) [][]string {
+	return [][]string{
+		{
+			`CREATE TABLE IF NOT EXISTS "` + table + `" (` +
+				`"id" INTEGER PRIMARY KEY AUTOINCREMENT, ` +
+				`"key" VARCHAR(255) NOT NULL DEFAULT '', ` +
+				`"value" TEXT NULL, ` +
+				`"version" INTEGER NOT NULL DEFAULT 0` +
+				`)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS "` + table + `_key" ON "` + table + `" ("key")`,
+		},
+	}
+}
+
+// LockSchema writes nothing but takes the lock of database for writing, which is deferred until
+//	the first write in a transaction
+func (sqliteDialect) LockSchema(schemaTable string) (string, string) {
+	return `DELETE FROM "` + schemaTable + `" WHERE 0 = 1`, ""
+}
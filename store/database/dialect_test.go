@@ -0,0 +1,112 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialect(t *testing.T) {
+	assert.Equal(t, "INSERT IGNORE INTO `t` (`a`, `b`) VALUES (?, ?)", MySQL.InsertIgnore("t", "a", "b"))
+	assert.Equal(t, `INSERT INTO "t" ("a", "b") VALUES (?, ?) ON CONFLICT DO NOTHING`, PostgreSQL.InsertIgnore("t", "a", "b"))
+	assert.Equal(t, `INSERT OR IGNORE INTO "t" ("a", "b") VALUES (?, ?)`, SQLite.InsertIgnore("t", "a", "b"))
+
+	assert.Equal(t, "a = ? AND b = ?", MySQL.Rebind("a = ? AND b = ?"))
+	assert.Equal(t, "a = $1 AND b = $2", PostgreSQL.Rebind("a = ? AND b = ?"))
+	assert.Equal(t, "a = ? AND b = ?", SQLite.Rebind("a = ? AND b = ?"))
+
+	lock, unlock := MySQL.LockSchema("t_schema")
+	assert.Equal(t, "SELECT GET_LOCK('t_schema', -1)", lock)
+	assert.Equal(t, "SELECT RELEASE_LOCK('t_schema')", unlock)
+	lock, unlock = PostgreSQL.LockSchema("t_schema")
+	assert.Equal(t, `LOCK TABLE "t_schema" IN EXCLUSIVE MODE`, lock)
+	assert.Empty(t, unlock)
+	_, unlock = SQLite.LockSchema("t_schema")
+	assert.Empty(t, unlock)
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, "/a!_b/c!%d!!e/", escapeLike("/a_b/c%d!e/"))
+}
+
+func TestMigrate(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.Nil(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	s := New("/schedule/migrate", db, WithDialect(SQLite), WithInfoTable("info"), WithSkipMigration())
+	_, err = s.SchemaVersion()
+	assert.NotNil(t, err)
+
+	assert.Nil(t, s.Migrate())
+	version, err := s.SchemaVersion()
+	assert.Nil(t, err)
+	assert.Equal(t, len(SQLite.Migrations("info")), version)
+
+	// repeatable
+	assert.Nil(t, s.Migrate())
+	version, err = s.SchemaVersion()
+	assert.Nil(t, err)
+	assert.Equal(t, len(SQLite.Migrations("info")), version)
+
+	// unique version
+	_, err = db.Exec(`INSERT INTO "info_schema" ("version") VALUES (1)`)
+	assert.NotNil(t, err)
+
+	// unique key
+	affected, err := s.insert("/schedule/migrate/a", "1", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), affected)
+	affected, err = s.insert("/schedule/migrate/a", "2", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), affected)
+
+	// prefix is not a pattern
+	assert.Nil(t, s.create("/schedule/migrate/x_y/1", "1"))
+	assert.Nil(t, s.create("/schedule/migrate/xzy/1", "1"))
+	list, err := s.selectInfos("/schedule/migrate/x_y/", 0, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(list))
+}
+
+func TestMigrateConcurrently(t *testing.T) {
+	file := filepath.Join(os.TempDir(), "goschedule-migrate-test.db")
+	os.Remove(file)
+	defer os.Remove(file)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		db, err := sql.Open("sqlite3", file+"?_busy_timeout=5000")
+		assert.Nil(t, err)
+		defer db.Close()
+		s := New("/schedule/migrate", db, WithDialect(SQLite), WithInfoTable("info"), WithSkipMigration())
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.Migrate()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.Nil(t, err)
+	}
+
+	db, err := sql.Open("sqlite3", file)
+	assert.Nil(t, err)
+	defer db.Close()
+	var cnt int
+	assert.Nil(t, db.QueryRow(`SELECT COUNT(*) FROM "info_schema"`).Scan(&cnt))
+	assert.Equal(t, len(SQLite.Migrations("info")), cnt)
+}
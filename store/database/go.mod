@@ -4,6 +4,7 @@ go 1.14
 
 require (
 	github.com/go-sql-driver/mysql v1.5.0 // test
-	github.com/jasonjoo2010/godao v0.0.5
 	github.com/jasonjoo2010/goschedule v1.1.0
+	github.com/mattn/go-sqlite3 v1.14.6 // test
+	github.com/stretchr/testify v1.7.0
 )
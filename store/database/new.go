@@ -7,18 +7,20 @@ package database
 import (
 	"database/sql"
 
-	"github.com/jasonjoo2010/godao"
-	"github.com/jasonjoo2010/godao/options"
+	"github.com/jasonjoo2010/goschedule/log"
 )
 
 type ScheduleConfig struct {
 	InfoTable string
+	Dialect   Dialect
+	// SkipMigration disables creating and migrating schema in New, for accounts without privileges of DDL
+	SkipMigration bool
 }
 
 type Option func(cfg *ScheduleConfig)
 
 type ScheduleInfo struct {
-	Id      int64 `dao:"primary;auto_increment"`
+	Id      int64
 	Key     string
 	Value   string
 	Version int64
@@ -30,6 +32,20 @@ func WithInfoTable(table string) Option {
 	}
 }
 
+// WithDialect specifies the dialect of database, MySQL is used by default
+func WithDialect(dialect Dialect) Option {
+	return func(cfg *ScheduleConfig) {
+		cfg.Dialect = dialect
+	}
+}
+
+// WithSkipMigration leaves the schema untouched in New and tables should be created manually
+func WithSkipMigration() Option {
+	return func(cfg *ScheduleConfig) {
+		cfg.SkipMigration = true
+	}
+}
+
 func New(namespace string, db *sql.DB, opts ...Option) *DatabaseStore {
 	cfg := ScheduleConfig{}
 	for _, fn := range opts {
@@ -38,11 +54,21 @@ func New(namespace string, db *sql.DB, opts ...Option) *DatabaseStore {
 	if cfg.InfoTable == "" {
 		cfg.InfoTable = "schedule_info"
 	}
+	if cfg.Dialect == nil {
+		cfg.Dialect = MySQL
+	}
 
 	store := &DatabaseStore{
 		db:        db,
-		dao:       godao.NewDao(ScheduleInfo{}, db, options.WithTable(cfg.InfoTable)),
+		dialect:   cfg.Dialect,
+		table:     cfg.InfoTable,
 		namespace: namespace,
 	}
+	store.prepareQueries()
+	if !cfg.SkipMigration {
+		if err := store.Migrate(); err != nil {
+			log.Errorf("Failed to migrate schema of %s: %s", cfg.InfoTable, err.Error())
+		}
+	}
 	return store
 }
@@ -0,0 +1,88 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+)
+
+// schemaTable records versions applied of schema of the info table
+func (s *DatabaseStore) schemaTable() string {
+	return s.table + "_schema"
+}
+
+// queryer is implemented by *sql.DB and *sql.Tx
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// SchemaVersion returns the version of schema applied, 0 if nothing has been applied.
+func (s *DatabaseStore) SchemaVersion() (int, error) {
+	return s.schemaVersion(context.Background(), s.db)
+}
+
+func (s *DatabaseStore) schemaVersion(ctx context.Context, q queryer) (int, error) {
+	var version sql.NullInt64
+	err := q.QueryRowContext(ctx,
+		"SELECT MAX("+s.dialect.Quote("version")+") FROM "+s.dialect.Quote(s.schemaTable()),
+	).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// Migrate creates the info table and its index if they don't exist and applies migrations of schema not applied yet.
+//	It's called in New and safe to be called repeatedly or concurrently among nodes, which is serialized by
+//	an exclusive lock of the schema table and versions applied are unique.
+func (s *DatabaseStore) Migrate() error {
+	ctx := context.Background()
+	_, err := s.db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+s.dialect.Quote(s.schemaTable())+
+		" ("+s.dialect.Quote("version")+" INTEGER NOT NULL PRIMARY KEY)")
+	if err != nil {
+		return err
+	}
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	lock, unlock := s.dialect.LockSchema(s.schemaTable())
+	if unlock != "" {
+		defer conn.ExecContext(ctx, unlock)
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.ExecContext(ctx, lock); err != nil {
+		return errors.New("Failed to lock schema: " + err.Error())
+	}
+	current, err := s.schemaVersion(ctx, tx)
+	if err != nil {
+		return err
+	}
+	migrations := s.dialect.Migrations(s.table)
+	if current > len(migrations) {
+		return errors.New("Schema version " + strconv.Itoa(current) + " is newer than supported one " + strconv.Itoa(len(migrations)))
+	}
+	for i := current; i < len(migrations); i++ {
+		for _, stmt := range migrations[i] {
+			if _, err = tx.ExecContext(ctx, stmt); err != nil {
+				return errors.New("Failed to migrate schema to version " + strconv.Itoa(i+1) + ": " + err.Error())
+			}
+		}
+		_, err = tx.ExecContext(ctx, s.dialect.Rebind("INSERT INTO "+s.dialect.Quote(s.schemaTable())+
+			" ("+s.dialect.Quote("version")+") VALUES (?)"), i+1)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
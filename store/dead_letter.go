@@ -0,0 +1,18 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package store
+
+import "github.com/jasonjoo2010/goschedule/definition"
+
+// DeadLetterStore is an optional interface which can be implemented by a Store to keep task data
+//	which still failed after all attempts. They can be inspected, replayed or removed later.
+type DeadLetterStore interface {
+	// PutDeadLetter saves a dead letter, ID should be filled
+	PutDeadLetter(letter *definition.DeadLetter) error
+	// GetDeadLetters returns dead letters of specific task, sorted by ID
+	GetDeadLetters(strategyId, taskId string) ([]*definition.DeadLetter, error)
+	// RemoveDeadLetter removes a dead letter and it's not treated as an error if it doesn't exist
+	RemoveDeadLetter(strategyId, taskId, id string) error
+}
@@ -9,6 +9,8 @@ import (
 	"encoding/json"
 	"errors"
 
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
 	"github.com/labstack/gommon/log"
 	etcd "go.etcd.io/etcd/client"
 )
@@ -105,6 +107,45 @@ func (s *Etcdv2Store) update(path string, obj interface{}, mustExisted bool) err
 	return convertError(err)
 }
 
+// setRevision fills the revision of objects supporting compare-and-set, modified index of node is used.
+func setRevision(obj interface{}, revision uint64) {
+	switch v := obj.(type) {
+	case *definition.TaskAssignment:
+		v.Revision = int64(revision)
+	case *definition.StrategyRuntime:
+		v.Revision = int64(revision)
+	}
+}
+
+// compareAndSet sets the object only if modified index of the node equals to the given one and returns the new index.
+//	Zero revision means the node should not exist.
+func (s *Etcdv2Store) compareAndSet(path string, obj interface{}, revision int64) (int64, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return 0, err
+	}
+	opts := &etcd.SetOptions{
+		PrevExist: etcd.PrevNoExist,
+	}
+	if revision > 0 {
+		opts = &etcd.SetOptions{
+			PrevExist: etcd.PrevExist,
+			PrevIndex: uint64(revision),
+		}
+	}
+	resp, err := s.keysApi.Set(context.Background(), path, string(data), opts)
+	if errEtcd, ok := err.(etcd.Error); ok {
+		switch errEtcd.Code {
+		case etcd.ErrorCodeNodeExist, etcd.ErrorCodeTestFailed, etcd.ErrorCodeKeyNotFound:
+			return 0, store.Conflict
+		}
+	}
+	if err != nil {
+		return 0, convertError(err)
+	}
+	return int64(resp.Node.ModifiedIndex), nil
+}
+
 func (s *Etcdv2Store) verify() {
 
 }
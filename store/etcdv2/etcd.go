@@ -12,6 +12,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jasonjoo2010/goschedule/definition"
@@ -25,6 +26,11 @@ type Etcdv2Store struct {
 	keysApi   etcd.KeysAPI
 	prefix    string
 	timeDelta time.Duration
+
+	ctx       context.Context
+	ctxCancel context.CancelFunc
+	events    *store.Broadcaster
+	watchOnce sync.Once
 }
 
 func (s *Etcdv2Store) keySequenceBase() string {
@@ -95,7 +101,11 @@ func (s *Etcdv2Store) getObject(key string, obj interface{}) error {
 	if str == "" {
 		return store.NotExist
 	}
-	return json.Unmarshal([]byte(str), obj)
+	if err = json.Unmarshal([]byte(str), obj); err != nil {
+		return err
+	}
+	setRevision(obj, resp.Node.ModifiedIndex)
+	return nil
 }
 
 func (s *Etcdv2Store) getObjects(key string, t reflect.Type) ([]interface{}, error) {
@@ -114,6 +124,7 @@ func (s *Etcdv2Store) getObjects(key string, t reflect.Type) ([]interface{}, err
 			log.Warnf("Wrong data type during deserializing: %s", n.Key)
 			continue
 		}
+		setRevision(obj, n.ModifiedIndex)
 		result = append(result, obj)
 	}
 	return result, nil
@@ -149,6 +160,8 @@ func (s *Etcdv2Store) Sequence() (uint64, error) {
 }
 
 func (s *Etcdv2Store) Close() error {
+	s.ctxCancel()
+	s.events.Close()
 	return nil
 }
 
@@ -348,6 +361,18 @@ func (s *Etcdv2Store) SetTaskAssignment(assignment *definition.TaskAssignment) e
 	return s.update(s.keyTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID), assignment, false)
 }
 
+func (s *Etcdv2Store) CompareAndSetTaskAssignment(assignment *definition.TaskAssignment) error {
+	if assignment == nil {
+		return errors.New("assignment should not be nil")
+	}
+	revision, err := s.compareAndSet(s.keyTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID), assignment, assignment.Revision)
+	if err != nil {
+		return err
+	}
+	assignment.Revision = revision
+	return nil
+}
+
 func (s *Etcdv2Store) RemoveTaskAssignment(strategyId, taskId, itemId string) error {
 	err := s.remove(s.keyTaskAssignment(strategyId, taskId, itemId), false)
 	// ignore not exist
@@ -397,6 +422,7 @@ func (s *Etcdv2Store) UpdateStrategy(strategy *definition.Strategy) error {
 }
 
 func (s *Etcdv2Store) RemoveStrategy(id string) error {
+	s.removeFires(id)
 	return s.remove(s.keyStrategy(id), false)
 }
 
@@ -432,6 +458,18 @@ func (s *Etcdv2Store) SetStrategyRuntime(runtime *definition.StrategyRuntime) er
 	return s.update(s.keyRuntime(runtime.StrategyID, runtime.SchedulerID), runtime, false)
 }
 
+func (s *Etcdv2Store) CompareAndSetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	if runtime == nil {
+		return errors.New("runtime should not be nil")
+	}
+	revision, err := s.compareAndSet(s.keyRuntime(runtime.StrategyID, runtime.SchedulerID), runtime, runtime.Revision)
+	if err != nil {
+		return err
+	}
+	runtime.Revision = revision
+	return nil
+}
+
 func (s *Etcdv2Store) RemoveStrategyRuntime(strategyId, schedulerId string) error {
 	err := s.remove(s.keyRuntime(strategyId, schedulerId), false)
 	// ignore not exist
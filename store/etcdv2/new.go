@@ -5,7 +5,10 @@
 package etcdv2
 
 import (
+	"context"
+
 	"github.com/jasonjoo2010/goschedule/log"
+	"github.com/jasonjoo2010/goschedule/store"
 	etcd "go.etcd.io/etcd/client"
 )
 
@@ -30,11 +33,13 @@ func New(prefix string, addrs []string, opts ...Option) *Etcdv2Store {
 		log.Errorf("Create etcd store failed: %s", err.Error())
 		return nil
 	}
-	store := &Etcdv2Store{
+	s := &Etcdv2Store{
 		client:  c,
 		keysApi: etcd.NewKeysAPI(c),
 		prefix:  prefix,
+		events:  store.NewBroadcaster(),
 	}
-	store.caculateTimeDifference()
-	return store
+	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
+	s.caculateTimeDifference()
+	return s
 }
@@ -0,0 +1,85 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package etcdv2
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/log"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/utils"
+	etcd "go.etcd.io/etcd/client"
+)
+
+// parseEvent converts the key changed into an event.
+//	membership indicates the key is created or deleted which is required by schedulers and task runtimes
+//	to filter out heartbeats.
+func (s *Etcdv2Store) parseEvent(key string, membership bool) (store.Event, bool) {
+	if !strings.HasPrefix(key, s.prefix+"/") {
+		return store.Event{}, false
+	}
+	parts := strings.Split(key[len(s.prefix)+1:], "/")
+	switch {
+	case parts[0] == "tasks" && len(parts) == 2:
+		return store.Event{Kind: store.TaskEvent, TaskID: parts[1]}, true
+	case parts[0] == "strategies" && len(parts) == 2:
+		return store.Event{Kind: store.StrategyEvent, StrategyID: parts[1]}, true
+	case parts[0] == "schedulers" && len(parts) == 2 && membership:
+		return store.Event{Kind: store.SchedulerEvent, ID: parts[1]}, true
+	case parts[0] == "runtimes" && len(parts) == 3:
+		return store.Event{Kind: store.StrategyRuntimeEvent, StrategyID: parts[1], ID: parts[2]}, true
+	case parts[0] == "taskRuntimes" && len(parts) == 4 && membership:
+		return store.Event{Kind: store.TaskRuntimeEvent, StrategyID: parts[1], TaskID: parts[2], ID: parts[3]}, true
+	case parts[0] == "taskAssignments" && len(parts) == 4:
+		return store.Event{Kind: store.TaskAssignmentEvent, StrategyID: parts[1], TaskID: parts[2], ID: parts[3]}, true
+	case parts[0] == "taskReload" && len(parts) == 3:
+		return store.Event{Kind: store.TaskItemsConfigVersionEvent, StrategyID: parts[1], TaskID: parts[2]}, true
+	}
+	return store.Event{}, false
+}
+
+func isMembershipChanged(resp *etcd.Response) bool {
+	switch resp.Action {
+	case "create", "delete", "expire", "compareAndDelete":
+		return true
+	case "set", "update", "compareAndSwap":
+		return resp.PrevNode == nil
+	}
+	return false
+}
+
+func (s *Etcdv2Store) watchLoop() {
+	for !utils.ContextDone(s.ctx) {
+		w := s.keysApi.Watcher(s.prefix, &etcd.WatcherOptions{
+			Recursive: true,
+		})
+		for {
+			resp, err := w.Next(s.ctx)
+			if err != nil {
+				if !utils.ContextDone(s.ctx) {
+					log.Warnf("Watching etcd failed: %s", err.Error())
+				}
+				break
+			}
+			if resp.Node == nil {
+				continue
+			}
+			if e, ok := s.parseEvent(resp.Node.Key, isMembershipChanged(resp)); ok {
+				s.events.Publish(e)
+			}
+		}
+		// rewatch after a while
+		utils.DelayContext(s.ctx, time.Second)
+	}
+}
+
+func (s *Etcdv2Store) Watch(ctx context.Context) (<-chan store.Event, error) {
+	s.watchOnce.Do(func() {
+		go s.watchLoop()
+	})
+	return s.events.Subscribe(ctx), nil
+}
@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"errors"
 
+	"github.com/jasonjoo2010/goschedule/definition"
 	"github.com/jasonjoo2010/goschedule/store"
 	"github.com/labstack/gommon/log"
 	etcd "go.etcd.io/etcd/client/v3"
@@ -87,6 +88,36 @@ func (s *Etcdv3Store) create(path string, obj interface{}) error {
 	return nil
 }
 
+// setRevision fills the revision of objects supporting compare-and-set, mod revision of key is used.
+func setRevision(obj interface{}, revision int64) {
+	switch v := obj.(type) {
+	case *definition.TaskAssignment:
+		v.Revision = revision
+	case *definition.StrategyRuntime:
+		v.Revision = revision
+	}
+}
+
+// compareAndSet puts the object only if mod revision of the key equals to the given one and returns the new revision.
+//	Mod revision of a key not existed is zero.
+func (s *Etcdv3Store) compareAndSet(path string, obj interface{}, revision int64) (int64, error) {
+	str, err := toStr(obj)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := s.kvApi.Txn(context.Background()).
+		If(etcd.Compare(etcd.ModRevision(path), "=", revision)).
+		Then(etcd.OpPut(path, str)).
+		Commit()
+	if err != nil {
+		return 0, err
+	}
+	if !resp.Succeeded {
+		return 0, store.Conflict
+	}
+	return resp.Header.Revision, nil
+}
+
 func (s *Etcdv3Store) update(path string, obj interface{}, mustExisted bool) error {
 	str, err := toStr(obj)
 	if err != nil {
@@ -0,0 +1,67 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package etcdv3
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+func (s *Etcdv3Store) keyLastFire(strategyId string) string {
+	return s.prefix + "/cronFires/" + strategyId
+}
+
+func (s *Etcdv3Store) keyFireClaims(strategyId string) string {
+	return s.prefix + "/cronClaims/" + strategyId
+}
+
+func (s *Etcdv3Store) keyFireClaim(strategyId string, fireTime int64) string {
+	return s.keyFireClaims(strategyId) + "/" + strconv.FormatInt(fireTime, 10)
+}
+
+func (s *Etcdv3Store) GetLastFireTime(strategyId string) (int64, error) {
+	var fireTime int64
+	err := s.getObject(s.keyLastFire(strategyId), &fireTime)
+	if err == store.NotExist {
+		return 0, nil
+	}
+	return fireTime, err
+}
+
+func (s *Etcdv3Store) SetLastFireTime(strategyId string, fireTime int64) error {
+	return s.update(s.keyLastFire(strategyId), fireTime, false)
+}
+
+func (s *Etcdv3Store) ClaimFireTime(strategyId string, fireTime int64) error {
+	return s.create(s.keyFireClaim(strategyId, fireTime), fireTime)
+}
+
+func (s *Etcdv3Store) RemoveFireClaims(strategyId string, before int64) error {
+	arr, err := s.getObjects(s.keyFireClaims(strategyId), reflect.TypeOf(int64(0)))
+	if err == store.NotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, obj := range arr {
+		fireTime, ok := obj.(*int64)
+		if !ok || *fireTime >= before {
+			continue
+		}
+		if err := s.remove(s.keyFireClaim(strategyId, *fireTime), false); err != nil && err != store.NotExist {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Etcdv3Store) removeFires(strategyId string) {
+	s.remove(s.keyLastFire(strategyId), false)
+	s.RemoveFireClaims(strategyId, math.MaxInt64)
+}
@@ -12,6 +12,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jasonjoo2010/goschedule/definition"
@@ -26,6 +27,14 @@ type Etcdv3Store struct {
 	leaseApi etcd.Lease
 	prefix   string
 	stopped  bool
+
+	ctx       context.Context
+	ctxCancel context.CancelFunc
+	events    *store.Broadcaster
+	watchOnce sync.Once
+
+	leaseMu sync.Mutex
+	leases  map[string]etcd.LeaseID
 }
 
 func (s *Etcdv3Store) keySequenceBase() string {
@@ -96,7 +105,11 @@ func (s *Etcdv3Store) getObject(key string, obj interface{}) error {
 	if str == "" {
 		return store.NotExist
 	}
-	return json.Unmarshal([]byte(str), obj)
+	if err = json.Unmarshal([]byte(str), obj); err != nil {
+		return err
+	}
+	setRevision(obj, resp.Kvs[0].ModRevision)
+	return nil
 }
 
 func (s *Etcdv3Store) getObjects(basepath string, t reflect.Type) ([]interface{}, error) {
@@ -115,6 +128,7 @@ func (s *Etcdv3Store) getObjects(basepath string, t reflect.Type) ([]interface{}
 			log.Warnf("Wrong data type during deserializing: %s", string(n.Key))
 			continue
 		}
+		setRevision(obj, n.ModRevision)
 		result = append(result, obj)
 	}
 	return result, nil
@@ -144,6 +158,8 @@ func (s *Etcdv3Store) Close() error {
 		return nil
 	}
 	s.stopped = true
+	s.ctxCancel()
+	s.events.Close()
 	s.client.Close()
 	return nil
 }
@@ -156,6 +172,7 @@ func (s *Etcdv3Store) RegisterScheduler(scheduler *definition.Scheduler) error {
 }
 
 func (s *Etcdv3Store) UnregisterScheduler(id string) error {
+	s.revokeLease(s.keyScheduler(id))
 	err := s.remove(s.keyScheduler(id), false)
 	// ignore not exist
 	if err == store.NotExist {
@@ -268,6 +285,7 @@ func (s *Etcdv3Store) SetTaskRuntime(runtime *definition.TaskRuntime) error {
 }
 
 func (s *Etcdv3Store) RemoveTaskRuntime(strategyId, taskId, id string) error {
+	s.revokeLease(s.keyTaskRuntime(strategyId, taskId, id))
 	err := s.remove(s.keyTaskRuntime(strategyId, taskId, id), false)
 	// ignore not exist
 	if err == store.NotExist {
@@ -362,6 +380,18 @@ func (s *Etcdv3Store) SetTaskAssignment(assignment *definition.TaskAssignment) e
 	return s.update(s.keyTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID), assignment, false)
 }
 
+func (s *Etcdv3Store) CompareAndSetTaskAssignment(assignment *definition.TaskAssignment) error {
+	if assignment == nil {
+		return errors.New("assignment should not be nil")
+	}
+	revision, err := s.compareAndSet(s.keyTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID), assignment, assignment.Revision)
+	if err != nil {
+		return err
+	}
+	assignment.Revision = revision
+	return nil
+}
+
 func (s *Etcdv3Store) RemoveTaskAssignment(strategyId, taskId, itemId string) error {
 	err := s.remove(s.keyTaskAssignment(strategyId, taskId, itemId), false)
 	// ignore not exist
@@ -411,6 +441,7 @@ func (s *Etcdv3Store) UpdateStrategy(strategy *definition.Strategy) error {
 }
 
 func (s *Etcdv3Store) RemoveStrategy(id string) error {
+	s.removeFires(id)
 	return s.remove(s.keyStrategy(id), false)
 }
 
@@ -446,6 +477,18 @@ func (s *Etcdv3Store) SetStrategyRuntime(runtime *definition.StrategyRuntime) er
 	return s.update(s.keyRuntime(runtime.StrategyID, runtime.SchedulerID), runtime, false)
 }
 
+func (s *Etcdv3Store) CompareAndSetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	if runtime == nil {
+		return errors.New("runtime should not be nil")
+	}
+	revision, err := s.compareAndSet(s.keyRuntime(runtime.StrategyID, runtime.SchedulerID), runtime, runtime.Revision)
+	if err != nil {
+		return err
+	}
+	runtime.Revision = revision
+	return nil
+}
+
 func (s *Etcdv3Store) RemoveStrategyRuntime(strategyId, schedulerId string) error {
 	err := s.remove(s.keyRuntime(strategyId, schedulerId), false)
 	// ignore not exist
@@ -0,0 +1,106 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package etcdv3
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+	etcd "go.etcd.io/etcd/client/v3"
+)
+
+// Temporary objects are attached to leases which will be removed by etcd after expired.
+//	Every object holds its own lease so that it can be revoked individually. Objects without leases,
+//	which are saved by older versions, are reported as NotExist so that their heartbeats are compared.
+
+func ttlSeconds(ttl time.Duration) int64 {
+	seconds := int64((ttl + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
+
+func (s *Etcdv3Store) lease(key string, ttl time.Duration) (etcd.LeaseID, error) {
+	s.leaseMu.Lock()
+	id, ok := s.leases[key]
+	s.leaseMu.Unlock()
+	if ok {
+		if _, err := s.leaseApi.KeepAliveOnce(context.Background(), id); err == nil {
+			return id, nil
+		}
+		// expired or lost
+	}
+	resp, err := s.leaseApi.Grant(context.Background(), ttlSeconds(ttl))
+	if err != nil {
+		return 0, err
+	}
+	s.leaseMu.Lock()
+	s.leases[key] = resp.ID
+	s.leaseMu.Unlock()
+	return resp.ID, nil
+}
+
+func (s *Etcdv3Store) revokeLease(key string) {
+	s.leaseMu.Lock()
+	id, ok := s.leases[key]
+	delete(s.leases, key)
+	s.leaseMu.Unlock()
+	if ok {
+		s.leaseApi.Revoke(context.Background(), id)
+	}
+}
+
+func (s *Etcdv3Store) keepAlive(key string, obj interface{}, ttl time.Duration) error {
+	str, err := toStr(obj)
+	if err != nil {
+		return err
+	}
+	id, err := s.lease(key, ttl)
+	if err != nil {
+		return err
+	}
+	_, err = s.kvApi.Put(context.Background(), key, str, etcd.WithLease(id))
+	return err
+}
+
+func (s *Etcdv3Store) alive(key string) (bool, error) {
+	resp, err := s.kvApi.Get(context.Background(), key, etcd.WithKeysOnly())
+	if err != nil {
+		return false, err
+	}
+	if len(resp.Kvs) == 0 {
+		return false, nil
+	}
+	if resp.Kvs[0].Lease == 0 {
+		return false, store.NotExist
+	}
+	return true, nil
+}
+
+func (s *Etcdv3Store) KeepSchedulerAlive(scheduler *definition.Scheduler, ttl time.Duration) error {
+	if scheduler == nil {
+		return errors.New("scheduler should not be nil")
+	}
+	return s.keepAlive(s.keyScheduler(scheduler.ID), scheduler, ttl)
+}
+
+func (s *Etcdv3Store) IsSchedulerAlive(id string) (bool, error) {
+	return s.alive(s.keyScheduler(id))
+}
+
+func (s *Etcdv3Store) KeepTaskRuntimeAlive(runtime *definition.TaskRuntime, ttl time.Duration) error {
+	if runtime == nil {
+		return errors.New("task runtime should not be nil")
+	}
+	return s.keepAlive(s.keyTaskRuntime(runtime.StrategyID, runtime.TaskID, runtime.ID), runtime, ttl)
+}
+
+func (s *Etcdv3Store) IsTaskRuntimeAlive(strategyId, taskId, id string) (bool, error) {
+	return s.alive(s.keyTaskRuntime(strategyId, taskId, id))
+}
@@ -0,0 +1,20 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package etcdv3
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTLSeconds(t *testing.T) {
+	assert.Equal(t, int64(1), ttlSeconds(0))
+	assert.Equal(t, int64(1), ttlSeconds(100*time.Millisecond))
+	assert.Equal(t, int64(1), ttlSeconds(time.Second))
+	assert.Equal(t, int64(2), ttlSeconds(1001*time.Millisecond))
+	assert.Equal(t, int64(60), ttlSeconds(time.Minute))
+}
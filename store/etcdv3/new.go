@@ -5,8 +5,11 @@
 package etcdv3
 
 import (
+	"context"
 	"errors"
 
+	"github.com/jasonjoo2010/goschedule/store"
+
 	etcd "go.etcd.io/etcd/client/v3"
 )
 
@@ -30,12 +33,15 @@ func New(prefix string, addrs []string, opts ...Option) (*Etcdv3Store, error) {
 	if err != nil {
 		return nil, errors.New("Create etcd store failed: " + err.Error())
 	}
-	store := &Etcdv3Store{
+	s := &Etcdv3Store{
 		client:   c,
 		kvApi:    etcd.NewKV(c),
 		leaseApi: etcd.NewLease(c),
 		prefix:   prefix,
+		events:   store.NewBroadcaster(),
+		leases:   make(map[string]etcd.LeaseID),
 	}
-	store.caculateTimeDifference()
-	return store, nil
+	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
+	s.caculateTimeDifference()
+	return s, nil
 }
@@ -0,0 +1,53 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package etcdv3
+
+import (
+	"testing"
+
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEvent(t *testing.T) {
+	s := &Etcdv3Store{prefix: "/schedule/demo"}
+
+	e, ok := s.parseEvent("/schedule/demo/tasks/t0", false)
+	assert.True(t, ok)
+	assert.Equal(t, store.Event{Kind: store.TaskEvent, TaskID: "t0"}, e)
+
+	e, ok = s.parseEvent("/schedule/demo/strategies/s0", false)
+	assert.True(t, ok)
+	assert.Equal(t, store.Event{Kind: store.StrategyEvent, StrategyID: "s0"}, e)
+
+	_, ok = s.parseEvent("/schedule/demo/schedulers/a$b$1", false)
+	assert.False(t, ok)
+	e, ok = s.parseEvent("/schedule/demo/schedulers/a$b$1", true)
+	assert.True(t, ok)
+	assert.Equal(t, store.Event{Kind: store.SchedulerEvent, ID: "a$b$1"}, e)
+
+	e, ok = s.parseEvent("/schedule/demo/runtimes/s0/a$b$1", false)
+	assert.True(t, ok)
+	assert.Equal(t, store.Event{Kind: store.StrategyRuntimeEvent, StrategyID: "s0", ID: "a$b$1"}, e)
+
+	_, ok = s.parseEvent("/schedule/demo/taskRuntimes/s0/t0/r0", false)
+	assert.False(t, ok)
+	e, ok = s.parseEvent("/schedule/demo/taskRuntimes/s0/t0/r0", true)
+	assert.True(t, ok)
+	assert.Equal(t, store.Event{Kind: store.TaskRuntimeEvent, StrategyID: "s0", TaskID: "t0", ID: "r0"}, e)
+
+	e, ok = s.parseEvent("/schedule/demo/taskAssignments/s0/t0/p0", false)
+	assert.True(t, ok)
+	assert.Equal(t, store.Event{Kind: store.TaskAssignmentEvent, StrategyID: "s0", TaskID: "t0", ID: "p0"}, e)
+
+	e, ok = s.parseEvent("/schedule/demo/taskReload/s0/t0", false)
+	assert.True(t, ok)
+	assert.Equal(t, store.Event{Kind: store.TaskItemsConfigVersionEvent, StrategyID: "s0", TaskID: "t0"}, e)
+
+	_, ok = s.parseEvent("/schedule/demo/sequence", true)
+	assert.False(t, ok)
+	_, ok = s.parseEvent("/schedule/other/tasks/t0", true)
+	assert.False(t, ok)
+}
@@ -0,0 +1,28 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package store
+
+// HealthReporter is an optional interface of stores knowing whether calls to storage
+//	are failing recently, eg. store/resilient. Unlike other optional interfaces it's
+//	usually implemented by wrappers themselves.
+type HealthReporter interface {
+	// Degraded returns true if storage is failing or unavailable
+	Degraded() bool
+}
+
+// IsDegraded reports whether any store in the chain of wrappers is degraded.
+//	Rebalancing is skipped by schedulers while storage is degraded.
+func IsDegraded(s Store) bool {
+	for {
+		if h, ok := s.(HealthReporter); ok && h.Degraded() {
+			return true
+		}
+		w, ok := s.(Wrapper)
+		if !ok {
+			return false
+		}
+		s = w.Unwrap()
+	}
+}
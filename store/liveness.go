@@ -0,0 +1,75 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+)
+
+// Liveness is an optional interface which can be implemented by a Store supporting native expiration,
+//	eg. leases, ephemeral nodes or keys with ttl. Then the death of schedulers and task runtimes
+//	is decided by storage instead of comparing heartbeat with the clock of each node.
+//	Expired objects should be invisible or be reported as not alive. NotExist should be reported for
+//	objects without liveness, eg. the ones saved by nodes of older versions during a rolling upgrade,
+//	then LastHeartbeat of them is compared with time of storage instead.
+type Liveness interface {
+	// KeepSchedulerAlive registers the scheduler or refreshes it, it should expire after ttl without refreshing.
+	KeepSchedulerAlive(scheduler *definition.Scheduler, ttl time.Duration) error
+	// IsSchedulerAlive returns whether the scheduler is still alive
+	IsSchedulerAlive(id string) (bool, error)
+
+	// KeepTaskRuntimeAlive saves the task runtime or refreshes it, it should expire after ttl without refreshing.
+	KeepTaskRuntimeAlive(runtime *definition.TaskRuntime, ttl time.Duration) error
+	// IsTaskRuntimeAlive returns whether the task runtime is still alive
+	IsTaskRuntimeAlive(strategyId, taskId, id string) (bool, error)
+}
+
+// KeepSchedulerAlive sends heartbeat of scheduler through liveness of storage if supported,
+//	otherwise it's saved with LastHeartbeat in time of storage.
+func KeepSchedulerAlive(s Store, scheduler *definition.Scheduler, ttl time.Duration) error {
+	scheduler.LastHeartbeat = s.Time()
+	if l, ok := AsLiveness(s); ok {
+		return l.KeepSchedulerAlive(scheduler, ttl)
+	}
+	return s.RegisterScheduler(scheduler)
+}
+
+// IsSchedulerAlive decides whether the scheduler is alive through liveness of storage if supported,
+//	otherwise or if it has no liveness LastHeartbeat is compared with time of storage.
+//	Failure of checking is treated as alive to avoid killing healthy ones.
+func IsSchedulerAlive(s Store, scheduler *definition.Scheduler, ttl time.Duration) bool {
+	if l, ok := AsLiveness(s); ok {
+		alive, err := l.IsSchedulerAlive(scheduler.ID)
+		if err != NotExist {
+			return err != nil || alive
+		}
+	}
+	return s.Time()-scheduler.LastHeartbeat <= ttl.Milliseconds()
+}
+
+// KeepTaskRuntimeAlive sends heartbeat of task runtime through liveness of storage if supported,
+//	otherwise it's saved with LastHeartbeat in time of storage.
+func KeepTaskRuntimeAlive(s Store, runtime *definition.TaskRuntime, ttl time.Duration) error {
+	runtime.LastHeartbeat = s.Time()
+	if l, ok := AsLiveness(s); ok {
+		return l.KeepTaskRuntimeAlive(runtime, ttl)
+	}
+	return s.SetTaskRuntime(runtime)
+}
+
+// IsTaskRuntimeAlive decides whether the task runtime is alive through liveness of storage if supported,
+//	otherwise or if it has no liveness LastHeartbeat is compared with time of storage.
+//	Failure of checking is treated as alive to avoid killing healthy ones.
+func IsTaskRuntimeAlive(s Store, runtime *definition.TaskRuntime, ttl time.Duration) bool {
+	if l, ok := AsLiveness(s); ok {
+		alive, err := l.IsTaskRuntimeAlive(runtime.StrategyID, runtime.TaskID, runtime.ID)
+		if err != NotExist {
+			return err != nil || alive
+		}
+	}
+	return s.Time()-runtime.LastHeartbeat <= ttl.Milliseconds()
+}
@@ -5,6 +5,7 @@
 package memory
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"strconv"
@@ -29,6 +30,10 @@ type MemoryStore struct {
 	runtimes        map[runtimeKey]*definition.StrategyRuntime
 	taskRuntimes    map[taskRuntimeKey]*definition.TaskRuntime
 	taskAssignments map[taskRuntimeKey]*definition.TaskAssignment
+	deadLetters     map[taskRuntimeKey]*definition.DeadLetter
+	lastFires       map[string]int64
+	fireClaims      map[string]map[int64]bool
+	events          *store.Broadcaster
 }
 
 type runtimeKey struct {
@@ -66,7 +71,11 @@ func New() *MemoryStore {
 		runtimes:        make(map[runtimeKey]*definition.StrategyRuntime),
 		taskRuntimes:    make(map[taskRuntimeKey]*definition.TaskRuntime),
 		taskAssignments: make(map[taskRuntimeKey]*definition.TaskAssignment),
+		deadLetters:     make(map[taskRuntimeKey]*definition.DeadLetter),
+		lastFires:       make(map[string]int64),
+		fireClaims:      make(map[string]map[int64]bool),
 		taskItemsConfig: make(map[string]int64),
+		events:          store.NewBroadcaster(),
 	}
 }
 
@@ -86,9 +95,14 @@ func (s *MemoryStore) Close() error {
 	}
 
 	s.closed = true
+	s.events.Close()
 	return nil
 }
 
+func (s *MemoryStore) Watch(ctx context.Context) (<-chan store.Event, error) {
+	return s.events.Subscribe(ctx), nil
+}
+
 func (s *MemoryStore) Sequence() (uint64, error) {
 	return atomic.AddUint64(&s.sequence, 1), nil
 }
@@ -127,6 +141,7 @@ func (s *MemoryStore) CreateTask(task *definition.Task) error {
 	}
 	t := *task
 	s.tasks[task.ID] = &t
+	s.events.Publish(store.Event{Kind: store.TaskEvent, TaskID: task.ID})
 	return nil
 }
 
@@ -138,6 +153,7 @@ func (s *MemoryStore) UpdateTask(task *definition.Task) error {
 	}
 	t := *task
 	s.tasks[task.ID] = &t
+	s.events.Publish(store.Event{Kind: store.TaskEvent, TaskID: task.ID})
 	return nil
 }
 
@@ -148,6 +164,7 @@ func (s *MemoryStore) RemoveTask(id string) error {
 		return store.NotExist
 	}
 	delete(s.tasks, id)
+	s.events.Publish(store.Event{Kind: store.TaskEvent, TaskID: id})
 	return nil
 }
 
@@ -184,14 +201,23 @@ func (s *MemoryStore) SetTaskRuntime(runtime *definition.TaskRuntime) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	r := *runtime
-	s.taskRuntimes[taskRuntimeKey{runtime.StrategyID, runtime.TaskID, runtime.ID}] = &r
+	key := taskRuntimeKey{runtime.StrategyID, runtime.TaskID, runtime.ID}
+	_, existed := s.taskRuntimes[key]
+	s.taskRuntimes[key] = &r
+	if !existed {
+		s.events.Publish(store.Event{Kind: store.TaskRuntimeEvent, StrategyID: r.StrategyID, TaskID: r.TaskID, ID: r.ID})
+	}
 	return nil
 }
 
 func (s *MemoryStore) RemoveTaskRuntime(strategyId, taskId, id string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	delete(s.taskRuntimes, taskRuntimeKey{strategyId, taskId, id})
+	key := taskRuntimeKey{strategyId, taskId, id}
+	if _, ok := s.taskRuntimes[key]; ok {
+		delete(s.taskRuntimes, key)
+		s.events.Publish(store.Event{Kind: store.TaskRuntimeEvent, StrategyID: strategyId, TaskID: taskId, ID: id})
+	}
 	return nil
 }
 
@@ -210,6 +236,7 @@ func (s *MemoryStore) IncreaseTaskItemsConfigVersion(strategyId, taskId string)
 	defer s.mutex.Unlock()
 	key := strategyId + "/" + taskId
 	s.taskItemsConfig[key]++
+	s.events.Publish(store.Event{Kind: store.TaskItemsConfigVersionEvent, StrategyID: strategyId, TaskID: taskId})
 	return nil
 }
 
@@ -242,18 +269,48 @@ func (s *MemoryStore) GetTaskAssignments(strategyId, taskId string) ([]*definiti
 	return arr, nil
 }
 
+func (s *MemoryStore) setTaskAssignment(assignment *definition.TaskAssignment) {
+	key := taskRuntimeKey{assignment.StrategyID, assignment.TaskID, assignment.ItemID}
+	r := *assignment
+	r.Revision = 1
+	if old, ok := s.taskAssignments[key]; ok {
+		r.Revision = old.Revision + 1
+	}
+	s.taskAssignments[key] = &r
+	assignment.Revision = r.Revision
+	s.events.Publish(store.Event{Kind: store.TaskAssignmentEvent, StrategyID: r.StrategyID, TaskID: r.TaskID, ID: r.ItemID})
+}
+
 func (s *MemoryStore) SetTaskAssignment(assignment *definition.TaskAssignment) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	r := *assignment
-	s.taskAssignments[taskRuntimeKey{r.StrategyID, r.TaskID, r.ItemID}] = &r
+	s.setTaskAssignment(&r)
+	return nil
+}
+
+func (s *MemoryStore) CompareAndSetTaskAssignment(assignment *definition.TaskAssignment) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var revision int64
+	if old, ok := s.taskAssignments[taskRuntimeKey{assignment.StrategyID, assignment.TaskID, assignment.ItemID}]; ok {
+		revision = old.Revision
+	}
+	if revision != assignment.Revision {
+		return store.Conflict
+	}
+	s.setTaskAssignment(assignment)
 	return nil
 }
 
 func (s *MemoryStore) RemoveTaskAssignment(strategyId, taskId, itemId string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	delete(s.taskAssignments, taskRuntimeKey{strategyId, taskId, itemId})
+	key := taskRuntimeKey{strategyId, taskId, itemId}
+	if _, ok := s.taskAssignments[key]; ok {
+		delete(s.taskAssignments, key)
+		s.events.Publish(store.Event{Kind: store.TaskAssignmentEvent, StrategyID: strategyId, TaskID: taskId, ID: itemId})
+	}
 	return nil
 }
 
@@ -291,6 +348,7 @@ func (s *MemoryStore) CreateStrategy(strategy *definition.Strategy) error {
 	}
 	copyStrategy := *strategy
 	s.strategies[strategy.ID] = &copyStrategy
+	s.events.Publish(store.Event{Kind: store.StrategyEvent, StrategyID: strategy.ID})
 	return nil
 }
 
@@ -302,6 +360,7 @@ func (s *MemoryStore) UpdateStrategy(strategy *definition.Strategy) error {
 	}
 	copyStrategy := *strategy
 	s.strategies[strategy.ID] = &copyStrategy
+	s.events.Publish(store.Event{Kind: store.StrategyEvent, StrategyID: strategy.ID})
 	return nil
 }
 
@@ -312,6 +371,9 @@ func (s *MemoryStore) RemoveStrategy(id string) error {
 		return store.NotExist
 	}
 	delete(s.strategies, id)
+	delete(s.lastFires, id)
+	delete(s.fireClaims, id)
+	s.events.Publish(store.Event{Kind: store.StrategyEvent, StrategyID: id})
 	return nil
 }
 
@@ -344,18 +406,48 @@ func (s *MemoryStore) GetStrategyRuntimes(strategyId string) ([]*definition.Stra
 	return arr, nil
 }
 
+func (s *MemoryStore) setStrategyRuntime(runtime *definition.StrategyRuntime) {
+	key := runtimeKey{runtime.StrategyID, runtime.SchedulerID}
+	r := *runtime
+	r.Revision = 1
+	if old, ok := s.runtimes[key]; ok {
+		r.Revision = old.Revision + 1
+	}
+	s.runtimes[key] = &r
+	runtime.Revision = r.Revision
+	s.events.Publish(store.Event{Kind: store.StrategyRuntimeEvent, StrategyID: r.StrategyID, ID: r.SchedulerID})
+}
+
 func (s *MemoryStore) SetStrategyRuntime(runtime *definition.StrategyRuntime) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	r := *runtime
-	s.runtimes[runtimeKey{runtime.StrategyID, runtime.SchedulerID}] = &r
+	s.setStrategyRuntime(&r)
+	return nil
+}
+
+func (s *MemoryStore) CompareAndSetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var revision int64
+	if old, ok := s.runtimes[runtimeKey{runtime.StrategyID, runtime.SchedulerID}]; ok {
+		revision = old.Revision
+	}
+	if revision != runtime.Revision {
+		return store.Conflict
+	}
+	s.setStrategyRuntime(runtime)
 	return nil
 }
 
 func (s *MemoryStore) RemoveStrategyRuntime(strategyId, schedulerId string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	delete(s.runtimes, runtimeKey{strategyId, schedulerId})
+	key := runtimeKey{strategyId, schedulerId}
+	if _, ok := s.runtimes[key]; ok {
+		delete(s.runtimes, key)
+		s.events.Publish(store.Event{Kind: store.StrategyRuntimeEvent, StrategyID: strategyId, ID: schedulerId})
+	}
 	return nil
 }
 
@@ -367,7 +459,11 @@ func (s *MemoryStore) RegisterScheduler(scheduler *definition.Scheduler) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	copyScheduler := *scheduler
+	_, existed := s.schedulers[scheduler.ID]
 	s.schedulers[scheduler.ID] = &copyScheduler
+	if !existed {
+		s.events.Publish(store.Event{Kind: store.SchedulerEvent, ID: scheduler.ID})
+	}
 	return nil
 }
 func (s *MemoryStore) UnregisterScheduler(id string) error {
@@ -377,6 +473,7 @@ func (s *MemoryStore) UnregisterScheduler(id string) error {
 		return store.NotExist
 	}
 	delete(s.schedulers, id)
+	s.events.Publish(store.Event{Kind: store.SchedulerEvent, ID: id})
 	return nil
 }
 
@@ -403,6 +500,83 @@ func (s *MemoryStore) GetSchedulers() ([]*definition.Scheduler, error) {
 	return list, nil
 }
 
+//
+// Cron fires
+//
+
+func (s *MemoryStore) GetLastFireTime(strategyId string) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.lastFires[strategyId], nil
+}
+
+func (s *MemoryStore) SetLastFireTime(strategyId string, fireTime int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lastFires[strategyId] = fireTime
+	return nil
+}
+
+func (s *MemoryStore) ClaimFireTime(strategyId string, fireTime int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	claims, ok := s.fireClaims[strategyId]
+	if !ok {
+		claims = make(map[int64]bool)
+		s.fireClaims[strategyId] = claims
+	}
+	if claims[fireTime] {
+		return store.AlreadyExist
+	}
+	claims[fireTime] = true
+	return nil
+}
+
+func (s *MemoryStore) RemoveFireClaims(strategyId string, before int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for fireTime := range s.fireClaims[strategyId] {
+		if fireTime < before {
+			delete(s.fireClaims[strategyId], fireTime)
+		}
+	}
+	return nil
+}
+
+//
+// Dead letters
+//
+
+func (s *MemoryStore) PutDeadLetter(letter *definition.DeadLetter) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	l := *letter
+	l.Item = nil
+	s.deadLetters[taskRuntimeKey{letter.StrategyID, letter.TaskID, letter.ID}] = &l
+	return nil
+}
+
+func (s *MemoryStore) GetDeadLetters(strategyId, taskId string) ([]*definition.DeadLetter, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	list := make([]*definition.DeadLetter, 0)
+	for k, v := range s.deadLetters {
+		if k.task == taskId && k.strategy == strategyId {
+			l := *v
+			list = append(list, &l)
+		}
+	}
+	utils.SortDeadLetters(list)
+	return list, nil
+}
+
+func (s *MemoryStore) RemoveDeadLetter(strategyId, taskId, id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.deadLetters, taskRuntimeKey{strategyId, taskId, id})
+	return nil
+}
+
 func dumpMap(b *strings.Builder, k string, v interface{}) {
 	b.WriteString(k)
 	b.WriteString(": ")
@@ -442,6 +616,11 @@ func (s *MemoryStore) Dump() string {
 		dumpMap(b, k.String(), v)
 	}
 
+	b.WriteString("\nDeadLetters:\n")
+	for k, v := range s.deadLetters {
+		dumpMap(b, k.String(), v)
+	}
+
 	b.WriteString("\nStrategies:\n")
 	for k, v := range s.strategies {
 		dumpMap(b, k, v)
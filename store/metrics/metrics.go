@@ -0,0 +1,448 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+// Package metrics instruments a storage recording count, latency and result of calls
+//	per method into a pluggable sink, eg. PrometheusSink:
+//
+//	sink := metrics.NewPrometheusSink()
+//	s := metrics.New(store, sink)
+//	http.Handle("/metrics", sink)
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+// Sink receives calls made to storage. It's called concurrently.
+type Sink interface {
+	// Observe records a call to the method which took elapsed and failed with err if not nil
+	Observe(method string, elapsed time.Duration, err error)
+}
+
+// Result classifies the error of a call, one of "ok", "not_exist", "already_exist",
+//	"conflict" or "error". The first four are expected results in scheduling.
+func Result(err error) string {
+	switch err {
+	case nil:
+		return "ok"
+	case store.NotExist:
+		return "not_exist"
+	case store.AlreadyExist:
+		return "already_exist"
+	case store.Conflict:
+		return "conflict"
+	}
+	return "error"
+}
+
+// InstrumentedStore decorates a storage and observes every call to it except Name
+//	and Dump. Optional interfaces are delegated to the wrapped storage.
+type InstrumentedStore struct {
+	store store.Store
+	sink  Sink
+}
+
+var (
+	_ store.Wrapper         = (*InstrumentedStore)(nil)
+	_ store.Watcher         = (*InstrumentedStore)(nil)
+	_ store.Liveness        = (*InstrumentedStore)(nil)
+	_ store.DeadLetterStore = (*InstrumentedStore)(nil)
+	_ store.CronStore       = (*InstrumentedStore)(nil)
+	_ store.FireClaimStore  = (*InstrumentedStore)(nil)
+)
+
+func New(s store.Store, sink Sink) *InstrumentedStore {
+	return &InstrumentedStore{
+		store: s,
+		sink:  sink,
+	}
+}
+
+func (s *InstrumentedStore) observe(method string, begin time.Time, err error) {
+	s.sink.Observe(method, time.Since(begin), err)
+}
+
+func (s *InstrumentedStore) unsupported(feature string) error {
+	return errors.New(feature + " is not supported by storage: " + s.store.Name())
+}
+
+func (s *InstrumentedStore) Unwrap() store.Store {
+	return s.store
+}
+
+func (s *InstrumentedStore) Name() string {
+	return s.store.Name()
+}
+
+func (s *InstrumentedStore) Time() int64 {
+	begin := time.Now()
+	t := s.store.Time()
+	s.observe("Time", begin, nil)
+	return t
+}
+
+func (s *InstrumentedStore) Sequence() (uint64, error) {
+	begin := time.Now()
+	seq, err := s.store.Sequence()
+	s.observe("Sequence", begin, err)
+	return seq, err
+}
+
+func (s *InstrumentedStore) Close() error {
+	begin := time.Now()
+	err := s.store.Close()
+	s.observe("Close", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) RegisterScheduler(scheduler *definition.Scheduler) error {
+	begin := time.Now()
+	err := s.store.RegisterScheduler(scheduler)
+	s.observe("RegisterScheduler", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) UnregisterScheduler(id string) error {
+	begin := time.Now()
+	err := s.store.UnregisterScheduler(id)
+	s.observe("UnregisterScheduler", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) GetSchedulers() ([]*definition.Scheduler, error) {
+	begin := time.Now()
+	list, err := s.store.GetSchedulers()
+	s.observe("GetSchedulers", begin, err)
+	return list, err
+}
+
+func (s *InstrumentedStore) GetScheduler(id string) (*definition.Scheduler, error) {
+	begin := time.Now()
+	scheduler, err := s.store.GetScheduler(id)
+	s.observe("GetScheduler", begin, err)
+	return scheduler, err
+}
+
+func (s *InstrumentedStore) GetTask(id string) (*definition.Task, error) {
+	begin := time.Now()
+	task, err := s.store.GetTask(id)
+	s.observe("GetTask", begin, err)
+	return task, err
+}
+
+func (s *InstrumentedStore) GetTasks() ([]*definition.Task, error) {
+	begin := time.Now()
+	list, err := s.store.GetTasks()
+	s.observe("GetTasks", begin, err)
+	return list, err
+}
+
+func (s *InstrumentedStore) CreateTask(task *definition.Task) error {
+	begin := time.Now()
+	err := s.store.CreateTask(task)
+	s.observe("CreateTask", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) UpdateTask(task *definition.Task) error {
+	begin := time.Now()
+	err := s.store.UpdateTask(task)
+	s.observe("UpdateTask", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) RemoveTask(id string) error {
+	begin := time.Now()
+	err := s.store.RemoveTask(id)
+	s.observe("RemoveTask", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) GetTaskRuntime(strategyId, taskId, id string) (*definition.TaskRuntime, error) {
+	begin := time.Now()
+	runtime, err := s.store.GetTaskRuntime(strategyId, taskId, id)
+	s.observe("GetTaskRuntime", begin, err)
+	return runtime, err
+}
+
+func (s *InstrumentedStore) GetTaskRuntimes(strategyId, taskId string) ([]*definition.TaskRuntime, error) {
+	begin := time.Now()
+	list, err := s.store.GetTaskRuntimes(strategyId, taskId)
+	s.observe("GetTaskRuntimes", begin, err)
+	return list, err
+}
+
+func (s *InstrumentedStore) SetTaskRuntime(runtime *definition.TaskRuntime) error {
+	begin := time.Now()
+	err := s.store.SetTaskRuntime(runtime)
+	s.observe("SetTaskRuntime", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) RemoveTaskRuntime(strategyId, taskId, id string) error {
+	begin := time.Now()
+	err := s.store.RemoveTaskRuntime(strategyId, taskId, id)
+	s.observe("RemoveTaskRuntime", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) GetTaskItemsConfigVersion(strategyId, taskId string) (int64, error) {
+	begin := time.Now()
+	version, err := s.store.GetTaskItemsConfigVersion(strategyId, taskId)
+	s.observe("GetTaskItemsConfigVersion", begin, err)
+	return version, err
+}
+
+func (s *InstrumentedStore) IncreaseTaskItemsConfigVersion(strategyId, taskId string) error {
+	begin := time.Now()
+	err := s.store.IncreaseTaskItemsConfigVersion(strategyId, taskId)
+	s.observe("IncreaseTaskItemsConfigVersion", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) GetTaskAssignment(strategyId, taskId, itemId string) (*definition.TaskAssignment, error) {
+	begin := time.Now()
+	assignment, err := s.store.GetTaskAssignment(strategyId, taskId, itemId)
+	s.observe("GetTaskAssignment", begin, err)
+	return assignment, err
+}
+
+func (s *InstrumentedStore) GetTaskAssignments(strategyId, taskId string) ([]*definition.TaskAssignment, error) {
+	begin := time.Now()
+	list, err := s.store.GetTaskAssignments(strategyId, taskId)
+	s.observe("GetTaskAssignments", begin, err)
+	return list, err
+}
+
+func (s *InstrumentedStore) SetTaskAssignment(assignment *definition.TaskAssignment) error {
+	begin := time.Now()
+	err := s.store.SetTaskAssignment(assignment)
+	s.observe("SetTaskAssignment", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) CompareAndSetTaskAssignment(assignment *definition.TaskAssignment) error {
+	begin := time.Now()
+	err := s.store.CompareAndSetTaskAssignment(assignment)
+	s.observe("CompareAndSetTaskAssignment", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) RemoveTaskAssignment(strategyId, taskId, itemId string) error {
+	begin := time.Now()
+	err := s.store.RemoveTaskAssignment(strategyId, taskId, itemId)
+	s.observe("RemoveTaskAssignment", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) GetStrategy(id string) (*definition.Strategy, error) {
+	begin := time.Now()
+	strategy, err := s.store.GetStrategy(id)
+	s.observe("GetStrategy", begin, err)
+	return strategy, err
+}
+
+func (s *InstrumentedStore) GetStrategies() ([]*definition.Strategy, error) {
+	begin := time.Now()
+	list, err := s.store.GetStrategies()
+	s.observe("GetStrategies", begin, err)
+	return list, err
+}
+
+func (s *InstrumentedStore) CreateStrategy(strategy *definition.Strategy) error {
+	begin := time.Now()
+	err := s.store.CreateStrategy(strategy)
+	s.observe("CreateStrategy", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) UpdateStrategy(strategy *definition.Strategy) error {
+	begin := time.Now()
+	err := s.store.UpdateStrategy(strategy)
+	s.observe("UpdateStrategy", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) RemoveStrategy(id string) error {
+	begin := time.Now()
+	err := s.store.RemoveStrategy(id)
+	s.observe("RemoveStrategy", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) GetStrategyRuntime(strategyId, schedulerId string) (*definition.StrategyRuntime, error) {
+	begin := time.Now()
+	runtime, err := s.store.GetStrategyRuntime(strategyId, schedulerId)
+	s.observe("GetStrategyRuntime", begin, err)
+	return runtime, err
+}
+
+func (s *InstrumentedStore) GetStrategyRuntimes(strategyId string) ([]*definition.StrategyRuntime, error) {
+	begin := time.Now()
+	list, err := s.store.GetStrategyRuntimes(strategyId)
+	s.observe("GetStrategyRuntimes", begin, err)
+	return list, err
+}
+
+func (s *InstrumentedStore) SetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	begin := time.Now()
+	err := s.store.SetStrategyRuntime(runtime)
+	s.observe("SetStrategyRuntime", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) CompareAndSetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	begin := time.Now()
+	err := s.store.CompareAndSetStrategyRuntime(runtime)
+	s.observe("CompareAndSetStrategyRuntime", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) RemoveStrategyRuntime(strategyId, schedulerId string) error {
+	begin := time.Now()
+	err := s.store.RemoveStrategyRuntime(strategyId, schedulerId)
+	s.observe("RemoveStrategyRuntime", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) Dump() string {
+	return s.store.Dump()
+}
+
+func (s *InstrumentedStore) Watch(ctx context.Context) (<-chan store.Event, error) {
+	watcher, ok := store.AsWatcher(s.store)
+	if !ok {
+		return nil, s.unsupported("Watching")
+	}
+	begin := time.Now()
+	ch, err := watcher.Watch(ctx)
+	s.observe("Watch", begin, err)
+	return ch, err
+}
+
+func (s *InstrumentedStore) KeepSchedulerAlive(scheduler *definition.Scheduler, ttl time.Duration) error {
+	l, ok := store.AsLiveness(s.store)
+	if !ok {
+		return s.unsupported("Liveness")
+	}
+	begin := time.Now()
+	err := l.KeepSchedulerAlive(scheduler, ttl)
+	s.observe("KeepSchedulerAlive", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) IsSchedulerAlive(id string) (bool, error) {
+	l, ok := store.AsLiveness(s.store)
+	if !ok {
+		return false, s.unsupported("Liveness")
+	}
+	begin := time.Now()
+	alive, err := l.IsSchedulerAlive(id)
+	s.observe("IsSchedulerAlive", begin, err)
+	return alive, err
+}
+
+func (s *InstrumentedStore) KeepTaskRuntimeAlive(runtime *definition.TaskRuntime, ttl time.Duration) error {
+	l, ok := store.AsLiveness(s.store)
+	if !ok {
+		return s.unsupported("Liveness")
+	}
+	begin := time.Now()
+	err := l.KeepTaskRuntimeAlive(runtime, ttl)
+	s.observe("KeepTaskRuntimeAlive", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) IsTaskRuntimeAlive(strategyId, taskId, id string) (bool, error) {
+	l, ok := store.AsLiveness(s.store)
+	if !ok {
+		return false, s.unsupported("Liveness")
+	}
+	begin := time.Now()
+	alive, err := l.IsTaskRuntimeAlive(strategyId, taskId, id)
+	s.observe("IsTaskRuntimeAlive", begin, err)
+	return alive, err
+}
+
+func (s *InstrumentedStore) PutDeadLetter(letter *definition.DeadLetter) error {
+	d, ok := store.AsDeadLetterStore(s.store)
+	if !ok {
+		return s.unsupported("Dead letters")
+	}
+	begin := time.Now()
+	err := d.PutDeadLetter(letter)
+	s.observe("PutDeadLetter", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) GetDeadLetters(strategyId, taskId string) ([]*definition.DeadLetter, error) {
+	d, ok := store.AsDeadLetterStore(s.store)
+	if !ok {
+		return nil, s.unsupported("Dead letters")
+	}
+	begin := time.Now()
+	list, err := d.GetDeadLetters(strategyId, taskId)
+	s.observe("GetDeadLetters", begin, err)
+	return list, err
+}
+
+func (s *InstrumentedStore) RemoveDeadLetter(strategyId, taskId, id string) error {
+	d, ok := store.AsDeadLetterStore(s.store)
+	if !ok {
+		return s.unsupported("Dead letters")
+	}
+	begin := time.Now()
+	err := d.RemoveDeadLetter(strategyId, taskId, id)
+	s.observe("RemoveDeadLetter", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) GetLastFireTime(strategyId string) (int64, error) {
+	c, ok := store.AsCronStore(s.store)
+	if !ok {
+		return 0, s.unsupported("Cron fires")
+	}
+	begin := time.Now()
+	t, err := c.GetLastFireTime(strategyId)
+	s.observe("GetLastFireTime", begin, err)
+	return t, err
+}
+
+func (s *InstrumentedStore) SetLastFireTime(strategyId string, fireTime int64) error {
+	c, ok := store.AsCronStore(s.store)
+	if !ok {
+		return s.unsupported("Cron fires")
+	}
+	begin := time.Now()
+	err := c.SetLastFireTime(strategyId, fireTime)
+	s.observe("SetLastFireTime", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) ClaimFireTime(strategyId string, fireTime int64) error {
+	c, ok := store.AsFireClaimStore(s.store)
+	if !ok {
+		return s.unsupported("Fire claims")
+	}
+	begin := time.Now()
+	err := c.ClaimFireTime(strategyId, fireTime)
+	s.observe("ClaimFireTime", begin, err)
+	return err
+}
+
+func (s *InstrumentedStore) RemoveFireClaims(strategyId string, before int64) error {
+	c, ok := store.AsFireClaimStore(s.store)
+	if !ok {
+		return s.unsupported("Fire claims")
+	}
+	begin := time.Now()
+	err := c.RemoveFireClaims(strategyId, before)
+	s.observe("RemoveFireClaims", begin, err)
+	return err
+}
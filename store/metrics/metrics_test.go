@@ -0,0 +1,129 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/store/memory"
+	"github.com/jasonjoo2010/goschedule/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStorage() (*InstrumentedStore, *PrometheusSink) {
+	sink := NewPrometheusSink()
+	return New(memory.New(), sink), sink
+}
+
+func TestStoreSuite(t *testing.T) {
+	for name, fn := range map[string]func(*testing.T, store.Store){
+		"Task":            storetest.DoTestTask,
+		"TaskRuntime":     storetest.DoTestTaskRuntime,
+		"TaskAssignment":  storetest.DoTestTaskAssignment,
+		"Strategy":        storetest.DoTestStrategy,
+		"StrategyRuntime": storetest.DoTestStrategyRuntime,
+		"Scheduler":       storetest.DoTestScheduler,
+		"CompareAndSet":   storetest.DoTestCompareAndSet,
+		"Watch":           storetest.DoTestWatch,
+		"Liveness":        storetest.DoTestLiveness,
+		"DeadLetter":      storetest.DoTestDeadLetter,
+		"CronStore":       storetest.DoTestCronStore,
+		"FireClaim":       storetest.DoTestFireClaim,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, sink := newStorage()
+			fn(t, s)
+			assert.Nil(t, s.Close())
+			assert.Equal(t, uint64(1), sink.Count("Close", "ok"))
+		})
+	}
+}
+
+// bareStore hides optional interfaces of the memory store
+type bareStore struct {
+	store.Store
+}
+
+func TestOptionalInterfaces(t *testing.T) {
+	s, _ := newStorage()
+	_, ok := store.AsDeadLetterStore(s)
+	assert.True(t, ok)
+	_, ok = store.AsWatcher(s)
+	assert.True(t, ok)
+	assert.Equal(t, s.Unwrap(), store.Unwrap(s))
+
+	bare := New(bareStore{memory.New()}, NewPrometheusSink())
+	_, ok = store.AsLiveness(bare)
+	assert.False(t, ok)
+	_, ok = store.AsWatcher(bare)
+	assert.False(t, ok)
+	_, ok = store.AsDeadLetterStore(bare)
+	assert.False(t, ok)
+	_, ok = store.AsCronStore(bare)
+	assert.False(t, ok)
+	_, ok = store.AsFireClaimStore(bare)
+	assert.False(t, ok)
+	assert.NotNil(t, bare.KeepSchedulerAlive(&definition.Scheduler{ID: "a"}, time.Second))
+
+	// fallback to heartbeats
+	scheduler := &definition.Scheduler{ID: "a"}
+	assert.Nil(t, store.KeepSchedulerAlive(bare, scheduler, time.Second))
+	assert.True(t, store.IsSchedulerAlive(bare, scheduler, time.Second))
+}
+
+func TestResult(t *testing.T) {
+	assert.Equal(t, "ok", Result(nil))
+	assert.Equal(t, "not_exist", Result(store.NotExist))
+	assert.Equal(t, "already_exist", Result(store.AlreadyExist))
+	assert.Equal(t, "conflict", Result(store.Conflict))
+	assert.Equal(t, "error", Result(errors.New("broken")))
+}
+
+func TestPrometheusSink(t *testing.T) {
+	s, sink := newStorage()
+	defer s.Close()
+	s.CreateTask(&definition.Task{ID: "t0"})
+	s.CreateTask(&definition.Task{ID: "t0"})
+	s.GetTask("t0")
+	s.GetTask("t1")
+	s.Sequence()
+	sink.Observe("GetTasks", 30*time.Millisecond, nil)
+	sink.Observe("GetTasks", time.Minute, errors.New("timeout"))
+
+	assert.Equal(t, uint64(2), sink.Count("CreateTask", ""))
+	assert.Equal(t, uint64(1), sink.Count("CreateTask", "already_exist"))
+	assert.Equal(t, uint64(1), sink.Count("GetTask", "not_exist"))
+	assert.Equal(t, uint64(0), sink.Count("RemoveTask", ""))
+
+	b := &strings.Builder{}
+	n, err := sink.WriteTo(b)
+	assert.Nil(t, err)
+	text := b.String()
+	assert.Equal(t, int64(len(text)), n)
+	assert.Contains(t, text, "# TYPE goschedule_store_calls_total counter\n")
+	assert.Contains(t, text, "goschedule_store_calls_total{method=\"CreateTask\",result=\"already_exist\"} 1\n")
+	assert.Contains(t, text, "goschedule_store_calls_total{method=\"CreateTask\",result=\"ok\"} 1\n")
+	assert.Contains(t, text, "goschedule_store_calls_total{method=\"GetTasks\",result=\"error\"} 1\n")
+	assert.Contains(t, text, "goschedule_store_calls_total{method=\"Sequence\",result=\"ok\"} 1\n")
+	assert.Contains(t, text, "# TYPE goschedule_store_call_duration_seconds histogram\n")
+	assert.Contains(t, text, "goschedule_store_call_duration_seconds_bucket{method=\"GetTasks\",le=\"0.025\"} 0\n")
+	assert.Contains(t, text, "goschedule_store_call_duration_seconds_bucket{method=\"GetTasks\",le=\"0.05\"} 1\n")
+	assert.Contains(t, text, "goschedule_store_call_duration_seconds_bucket{method=\"GetTasks\",le=\"5\"} 1\n")
+	assert.Contains(t, text, "goschedule_store_call_duration_seconds_bucket{method=\"GetTasks\",le=\"+Inf\"} 2\n")
+	assert.Contains(t, text, "goschedule_store_call_duration_seconds_sum{method=\"GetTasks\"} 60.03\n")
+	assert.Contains(t, text, "goschedule_store_call_duration_seconds_count{method=\"GetTasks\"} 2\n")
+
+	recorder := httptest.NewRecorder()
+	sink.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/plain")
+	assert.Equal(t, text, recorder.Body.String())
+}
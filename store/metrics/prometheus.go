@@ -0,0 +1,148 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are upper bounds of latency histograms in seconds
+var DefaultBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+
+type methodStats struct {
+	results map[string]uint64
+	// buckets[i] counts calls fall in (bounds[i-1], bounds[i]], the last one is +Inf
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// PrometheusSink aggregates calls in memory and exposes them in the text format of Prometheus:
+//	goschedule_store_calls_total{method,result} counts calls by result(see Result), and
+//	goschedule_store_call_duration_seconds{method} is the histogram of latency.
+//	It's also an http.Handler serving the exposition.
+type PrometheusSink struct {
+	mu      sync.Mutex
+	bounds  []float64
+	methods map[string]*methodStats
+}
+
+var _ Sink = (*PrometheusSink)(nil)
+
+// NewPrometheusSink creates a sink with buckets of latency in seconds, DefaultBuckets if omitted
+func NewPrometheusSink(buckets ...float64) *PrometheusSink {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	bounds := make([]float64, len(buckets))
+	copy(bounds, buckets)
+	sort.Float64s(bounds)
+	return &PrometheusSink{
+		bounds:  bounds,
+		methods: make(map[string]*methodStats),
+	}
+}
+
+func (p *PrometheusSink) Observe(method string, elapsed time.Duration, err error) {
+	seconds := elapsed.Seconds()
+	bucket := sort.SearchFloat64s(p.bounds, seconds)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats, ok := p.methods[method]
+	if !ok {
+		stats = &methodStats{
+			results: make(map[string]uint64),
+			buckets: make([]uint64, len(p.bounds)+1),
+		}
+		p.methods[method] = stats
+	}
+	stats.results[Result(err)]++
+	stats.buckets[bucket]++
+	stats.count++
+	stats.sum += seconds
+}
+
+// Count returns the number of calls to the method with specific result, all results if empty
+func (p *PrometheusSink) Count(method, result string) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats, ok := p.methods[method]
+	if !ok {
+		return 0
+	}
+	if result == "" {
+		return stats.count
+	}
+	return stats.results[result]
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// WriteTo writes all the metrics in the text exposition format
+func (p *PrometheusSink) WriteTo(w io.Writer) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	methods := make([]string, 0, len(p.methods))
+	for method := range p.methods {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	var n int64
+	out := bufio.NewWriter(w)
+	write := func(s ...string) {
+		for _, str := range s {
+			written, _ := out.WriteString(str)
+			n += int64(written)
+		}
+	}
+	write("# HELP goschedule_store_calls_total Calls to storage by method and result.\n",
+		"# TYPE goschedule_store_calls_total counter\n")
+	for _, method := range methods {
+		stats := p.methods[method]
+		results := make([]string, 0, len(stats.results))
+		for result := range stats.results {
+			results = append(results, result)
+		}
+		sort.Strings(results)
+		for _, result := range results {
+			write("goschedule_store_calls_total{method=\"", method, "\",result=\"", result, "\"} ",
+				strconv.FormatUint(stats.results[result], 10), "\n")
+		}
+	}
+	write("# HELP goschedule_store_call_duration_seconds Latency of calls to storage by method.\n",
+		"# TYPE goschedule_store_call_duration_seconds histogram\n")
+	for _, method := range methods {
+		stats := p.methods[method]
+		var cumulative uint64
+		for i, cnt := range stats.buckets {
+			cumulative += cnt
+			le := "+Inf"
+			if i < len(p.bounds) {
+				le = formatFloat(p.bounds[i])
+			}
+			write("goschedule_store_call_duration_seconds_bucket{method=\"", method, "\",le=\"", le, "\"} ",
+				strconv.FormatUint(cumulative, 10), "\n")
+		}
+		write("goschedule_store_call_duration_seconds_sum{method=\"", method, "\"} ", formatFloat(stats.sum), "\n",
+			"goschedule_store_call_duration_seconds_count{method=\"", method, "\"} ", strconv.FormatUint(stats.count, 10), "\n")
+	}
+	return n, out.Flush()
+}
+
+func (p *PrometheusSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	p.WriteTo(w)
+}
@@ -0,0 +1,289 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+// Package migrate copies scheduling definitions between storages offline, eg. moving from redis to etcd.
+//	Temporary records like schedulers and runtimes are never copied, they are generated again by schedulers.
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+// ConflictPolicy decides what to do when an object exists in target but differs from the source
+type ConflictPolicy int
+
+const (
+	// ConflictSkip keeps the one in target
+	ConflictSkip ConflictPolicy = iota
+	// ConflictOverwrite replaces the one in target with the source
+	ConflictOverwrite
+	// ConflictFail aborts the migration before anything is written
+	ConflictFail
+)
+
+type Kind string
+
+const (
+	KindTask         Kind = "task"
+	KindStrategy     Kind = "strategy"
+	KindAssignment   Kind = "assignment"
+	KindItemsVersion Kind = "itemsVersion"
+)
+
+type Action int
+
+const (
+	Created Action = iota
+	Overwritten
+	Skipped
+	Unchanged
+)
+
+func (a Action) String() string {
+	switch a {
+	case Created:
+		return "created"
+	case Overwritten:
+		return "overwritten"
+	case Skipped:
+		return "skipped"
+	case Unchanged:
+		return "unchanged"
+	}
+	return "unknown"
+}
+
+type Options struct {
+	// DryRun plans the changes without writing anything into target
+	DryRun   bool
+	Conflict ConflictPolicy
+	// Assignments copies task assignments of TaskKind strategies as well.
+	//	Runtimes in them belong to source so they are useful only when the same schedulers will be restarted on target.
+	//	Fencing tokens are cleared because they are issued by the sequence of source, new ones are issued by
+	//	target once task items are claimed again.
+	Assignments bool
+	// ItemsVersion raises the version of task items config in target to the one in source.
+	//	Versions are increasing only, so those greater in target are left unchanged.
+	ItemsVersion bool
+}
+
+// Change is what is done or to be done(in dry-run) on an object
+type Change struct {
+	Kind Kind
+	// ID of task or strategy, strategyId/taskId/itemId of assignment or strategyId/taskId of version
+	ID     string
+	Action Action
+}
+
+func (c Change) String() string {
+	return string(c.Kind) + " " + c.ID + ": " + c.Action.String()
+}
+
+// Result lists changes in order of applying
+type Result struct {
+	Changes []Change
+}
+
+// Count returns the count of changes of the action
+func (r *Result) Count(action Action) int {
+	n := 0
+	for _, c := range r.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// entry is an object to be migrated
+type entry struct {
+	kind Kind
+	id   string
+	// existed in target
+	existed bool
+	// equal to the one in target
+	equal bool
+	write func() error
+}
+
+// same compares two objects by their serialized form which is how storages save them
+func same(a, b interface{}) bool {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(ja) == string(jb)
+}
+
+// taskPairs returns pairs of strategy id and task id of TaskKind strategies
+func taskPairs(strategies []*definition.Strategy) [][2]string {
+	pairs := make([][2]string, 0)
+	for _, s := range strategies {
+		if s.Kind == definition.TaskKind {
+			pairs = append(pairs, [2]string{s.ID, s.Bind})
+		}
+	}
+	return pairs
+}
+
+func plan(src, dst store.Store, opts Options) ([]entry, error) {
+	entries := make([]entry, 0)
+
+	// tasks come first as they are referred by strategies
+	tasks, err := src.GetTasks()
+	if err != nil {
+		return nil, errors.New("Failed to get tasks from source: " + err.Error())
+	}
+	// in a stable order as not all storages sort them
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].ID < tasks[j].ID
+	})
+	for _, task := range tasks {
+		task := task
+		target, err := dst.GetTask(task.ID)
+		if err != nil && err != store.NotExist {
+			return nil, errors.New("Failed to get task " + task.ID + " from target: " + err.Error())
+		}
+		e := entry{kind: KindTask, id: task.ID, existed: target != nil}
+		e.equal = e.existed && same(task, target)
+		if e.existed {
+			e.write = func() error { return dst.UpdateTask(task) }
+		} else {
+			e.write = func() error { return dst.CreateTask(task) }
+		}
+		entries = append(entries, e)
+	}
+
+	strategies, err := src.GetStrategies()
+	if err != nil {
+		return nil, errors.New("Failed to get strategies from source: " + err.Error())
+	}
+	sort.Slice(strategies, func(i, j int) bool {
+		return strategies[i].ID < strategies[j].ID
+	})
+	for _, strategy := range strategies {
+		strategy := strategy
+		target, err := dst.GetStrategy(strategy.ID)
+		if err != nil && err != store.NotExist {
+			return nil, errors.New("Failed to get strategy " + strategy.ID + " from target: " + err.Error())
+		}
+		e := entry{kind: KindStrategy, id: strategy.ID, existed: target != nil}
+		e.equal = e.existed && same(strategy, target)
+		if e.existed {
+			e.write = func() error { return dst.UpdateStrategy(strategy) }
+		} else {
+			e.write = func() error { return dst.CreateStrategy(strategy) }
+		}
+		entries = append(entries, e)
+	}
+
+	pairs := taskPairs(strategies)
+	if opts.Assignments {
+		for _, pair := range pairs {
+			assignments, err := src.GetTaskAssignments(pair[0], pair[1])
+			if err != nil {
+				return nil, errors.New("Failed to get assignments of " + pair[0] + "/" + pair[1] + " from source: " + err.Error())
+			}
+			for _, assignment := range assignments {
+				assignment := copyAssignment(assignment)
+				id := assignment.StrategyID + "/" + assignment.TaskID + "/" + assignment.ItemID
+				target, err := dst.GetTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID)
+				if err != nil && err != store.NotExist {
+					return nil, errors.New("Failed to get assignment " + id + " from target: " + err.Error())
+				}
+				e := entry{kind: KindAssignment, id: id, existed: target != nil}
+				if e.existed {
+					e.equal = same(assignment, copyAssignment(target))
+				}
+				e.write = func() error { return dst.SetTaskAssignment(assignment) }
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	if opts.ItemsVersion {
+		for _, pair := range pairs {
+			strategyId, taskId := pair[0], pair[1]
+			id := strategyId + "/" + taskId
+			version, err := src.GetTaskItemsConfigVersion(strategyId, taskId)
+			if err != nil {
+				return nil, errors.New("Failed to get items version of " + id + " from source: " + err.Error())
+			}
+			if version == 0 {
+				continue
+			}
+			current, err := dst.GetTaskItemsConfigVersion(strategyId, taskId)
+			if err != nil {
+				return nil, errors.New("Failed to get items version of " + id + " from target: " + err.Error())
+			}
+			entries = append(entries, entry{
+				kind:    KindItemsVersion,
+				id:      id,
+				existed: current > 0,
+				// versions are increasing only
+				equal: current >= version,
+				write: func() error {
+					for ; current < version; current++ {
+						if err := dst.IncreaseTaskItemsConfigVersion(strategyId, taskId); err != nil {
+							return err
+						}
+					}
+					return nil
+				},
+			})
+		}
+	}
+	return entries, nil
+}
+
+// Copy copies tasks and strategies, optionally assignments and versions of task items config, from src to dst.
+//	Conflicts are checked before writing so ConflictFail leaves target untouched.
+func Copy(src, dst store.Store, opts Options) (*Result, error) {
+	entries, err := plan(src, dst, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Conflict == ConflictFail {
+		conflicts := make([]string, 0)
+		for _, e := range entries {
+			if e.existed && !e.equal {
+				conflicts = append(conflicts, string(e.kind)+" "+e.id)
+			}
+		}
+		if len(conflicts) > 0 {
+			return nil, errors.New("Conflicted with target: " + strings.Join(conflicts, ", "))
+		}
+	}
+	result := &Result{Changes: make([]Change, 0, len(entries))}
+	for _, e := range entries {
+		change := Change{Kind: e.kind, ID: e.id}
+		switch {
+		case e.equal:
+			change.Action = Unchanged
+		case !e.existed:
+			change.Action = Created
+		case opts.Conflict == ConflictOverwrite:
+			change.Action = Overwritten
+		default:
+			change.Action = Skipped
+		}
+		if !opts.DryRun && (change.Action == Created || change.Action == Overwritten) {
+			if err := e.write(); err != nil {
+				return result, errors.New("Failed to write " + string(e.kind) + " " + e.id + " into target: " + err.Error())
+			}
+		}
+		result.Changes = append(result.Changes, change)
+	}
+	return result, nil
+}
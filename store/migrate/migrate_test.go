@@ -0,0 +1,151 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package migrate
+
+import (
+	"testing"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSource() *memory.MemoryStore {
+	s := memory.New()
+	s.CreateTask(&definition.Task{ID: "t0", Bind: "task0", Items: []definition.TaskItem{{ID: "a"}, {ID: "b"}}})
+	s.CreateTask(&definition.Task{ID: "t1", Bind: "task1"})
+	s.CreateStrategy(&definition.Strategy{ID: "s0", Kind: definition.TaskKind, Bind: "t0", Total: 2})
+	s.CreateStrategy(&definition.Strategy{ID: "s1", Kind: definition.SimpleKind, Bind: "simple", Total: 1})
+	s.SetTaskAssignment(&definition.TaskAssignment{StrategyID: "s0", TaskID: "t0", ItemID: "a", RuntimeID: "r0"})
+	s.SetTaskAssignment(&definition.TaskAssignment{StrategyID: "s0", TaskID: "t0", ItemID: "b", RuntimeID: "r1"})
+	s.IncreaseTaskItemsConfigVersion("s0", "t0")
+	s.IncreaseTaskItemsConfigVersion("s0", "t0")
+	return s
+}
+
+var fullOptions = Options{Assignments: true, ItemsVersion: true}
+
+func TestCopy(t *testing.T) {
+	src, dst := newSource(), memory.New()
+
+	result, err := Copy(src, dst, fullOptions)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, result.Count(Created))
+	diffs, err := Verify(src, dst, fullOptions)
+	assert.Nil(t, err)
+	assert.Empty(t, diffs)
+	version, _ := dst.GetTaskItemsConfigVersion("s0", "t0")
+	assert.Equal(t, int64(2), version)
+
+	// again
+	result, err = Copy(src, dst, fullOptions)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, result.Count(Unchanged))
+}
+
+func TestDryRun(t *testing.T) {
+	src, dst := newSource(), memory.New()
+
+	result, err := Copy(src, dst, Options{DryRun: true, Assignments: true, ItemsVersion: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 7, result.Count(Created))
+	tasks, _ := dst.GetTasks()
+	assert.Empty(t, tasks)
+
+	diffs, err := Verify(src, dst, fullOptions)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, len(diffs))
+	assert.Equal(t, Difference{Kind: KindTask, ID: "t0", Reason: "missing in target"}, diffs[0])
+}
+
+func TestConflict(t *testing.T) {
+	src, dst := newSource(), memory.New()
+	dst.CreateTask(&definition.Task{ID: "t0", Bind: "another"})
+	dst.CreateTask(&definition.Task{ID: "t2", Bind: "task2"})
+
+	// fail
+	result, err := Copy(src, dst, Options{Conflict: ConflictFail})
+	assert.NotNil(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "task t0")
+	strategies, _ := dst.GetStrategies()
+	assert.Empty(t, strategies)
+
+	// skip
+	result, err = Copy(src, dst, Options{Conflict: ConflictSkip})
+	assert.Nil(t, err)
+	assert.Equal(t, Change{Kind: KindTask, ID: "t0", Action: Skipped}, result.Changes[0])
+	task, _ := dst.GetTask("t0")
+	assert.Equal(t, "another", task.Bind)
+	diffs, err := Verify(src, dst, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, []Difference{
+		{Kind: KindTask, ID: "t0", Reason: "different"},
+		{Kind: KindTask, ID: "t2", Reason: "only in target"},
+	}, diffs)
+
+	// overwrite
+	result, err = Copy(src, dst, Options{Conflict: ConflictOverwrite})
+	assert.Nil(t, err)
+	assert.Equal(t, Change{Kind: KindTask, ID: "t0", Action: Overwritten}, result.Changes[0])
+	task, _ = dst.GetTask("t0")
+	assert.Equal(t, "task0", task.Bind)
+	diffs, err = Verify(src, dst, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, []Difference{{Kind: KindTask, ID: "t2", Reason: "only in target"}}, diffs)
+}
+
+func TestItemsVersion(t *testing.T) {
+	src, dst := newSource(), memory.New()
+	for i := 0; i < 3; i++ {
+		dst.IncreaseTaskItemsConfigVersion("s0", "t0")
+	}
+
+	// greater in target
+	result, err := Copy(src, dst, Options{ItemsVersion: true})
+	assert.Nil(t, err)
+	assert.Equal(t, Change{Kind: KindItemsVersion, ID: "s0/t0", Action: Unchanged}, result.Changes[len(result.Changes)-1])
+	diffs, err := Verify(src, dst, Options{ItemsVersion: true})
+	assert.Nil(t, err)
+	assert.Empty(t, diffs)
+
+	// older in target
+	dst = memory.New()
+	dst.IncreaseTaskItemsConfigVersion("s0", "t0")
+	diffs, err = Verify(src, dst, Options{ItemsVersion: true})
+	assert.Nil(t, err)
+	assert.Equal(t, KindItemsVersion, diffs[len(diffs)-1].Kind)
+	_, err = Copy(src, dst, Options{ItemsVersion: true, Conflict: ConflictOverwrite})
+	assert.Nil(t, err)
+	version, _ := dst.GetTaskItemsConfigVersion("s0", "t0")
+	assert.Equal(t, int64(2), version)
+}
+
+func TestFencingToken(t *testing.T) {
+	src, dst := newSource(), memory.New()
+	for i := 0; i < 100; i++ {
+		src.Sequence()
+	}
+	token, _ := src.Sequence()
+	assignment, _ := src.GetTaskAssignment("s0", "t0", "a")
+	assignment.Token = token
+	src.SetTaskAssignment(assignment)
+
+	_, err := Copy(src, dst, Options{Assignments: true})
+	assert.Nil(t, err)
+	copied, err := dst.GetTaskAssignment("s0", "t0", "a")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), copied.Token)
+	// tokens issued by target are always newer
+	issued, _ := dst.Sequence()
+	assert.True(t, issued > copied.Token)
+
+	diffs, err := Verify(src, dst, Options{Assignments: true})
+	assert.Nil(t, err)
+	assert.Empty(t, diffs)
+	result, err := Copy(src, dst, Options{Assignments: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, result.Count(Created)+result.Count(Overwritten))
+}
@@ -0,0 +1,151 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package migrate
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+// Difference is an object not the same between source and target
+type Difference struct {
+	Kind Kind
+	ID   string
+	// Reason is one of "missing in target", "only in target", "different" or "older in target"
+	Reason string
+}
+
+func (d Difference) String() string {
+	return string(d.Kind) + " " + d.ID + ": " + d.Reason
+}
+
+// diffObjects compares objects indexed by id in both sides
+func diffObjects(kind Kind, src, dst map[string]interface{}) []Difference {
+	diffs := make([]Difference, 0)
+	for id, obj := range src {
+		target, ok := dst[id]
+		if !ok {
+			diffs = append(diffs, Difference{Kind: kind, ID: id, Reason: "missing in target"})
+		} else if !same(obj, target) {
+			diffs = append(diffs, Difference{Kind: kind, ID: id, Reason: "different"})
+		}
+	}
+	for id := range dst {
+		if _, ok := src[id]; !ok {
+			diffs = append(diffs, Difference{Kind: kind, ID: id, Reason: "only in target"})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].ID < diffs[j].ID
+	})
+	return diffs
+}
+
+func tasksOf(s store.Store) (map[string]interface{}, error) {
+	tasks, err := s.GetTasks()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{}, len(tasks))
+	for _, t := range tasks {
+		result[t.ID] = t
+	}
+	return result, nil
+}
+
+func strategiesOf(strategies []*definition.Strategy) map[string]interface{} {
+	result := make(map[string]interface{}, len(strategies))
+	for _, s := range strategies {
+		result[s.ID] = s
+	}
+	return result
+}
+
+func assignmentsOf(s store.Store, strategyId, taskId string) (map[string]interface{}, error) {
+	assignments, err := s.GetTaskAssignments(strategyId, taskId)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{}, len(assignments))
+	for _, a := range assignments {
+		result[a.StrategyID+"/"+a.TaskID+"/"+a.ItemID] = copyAssignment(a)
+	}
+	return result, nil
+}
+
+// copyAssignment returns a copy of the assignment without what is issued by the storage,
+//	which are revision and fencing token.
+func copyAssignment(a *definition.TaskAssignment) *definition.TaskAssignment {
+	copied := *a
+	copied.Revision = 0
+	copied.Token = 0
+	return &copied
+}
+
+// Verify diffs source and target on what Copy copies with the same options.
+//	Objects only in target are reported too but a greater version of task items config in target is not.
+func Verify(src, dst store.Store, opts Options) ([]Difference, error) {
+	diffs := make([]Difference, 0)
+
+	srcTasks, err := tasksOf(src)
+	if err != nil {
+		return nil, errors.New("Failed to get tasks from source: " + err.Error())
+	}
+	dstTasks, err := tasksOf(dst)
+	if err != nil {
+		return nil, errors.New("Failed to get tasks from target: " + err.Error())
+	}
+	diffs = append(diffs, diffObjects(KindTask, srcTasks, dstTasks)...)
+
+	strategies, err := src.GetStrategies()
+	if err != nil {
+		return nil, errors.New("Failed to get strategies from source: " + err.Error())
+	}
+	targetStrategies, err := dst.GetStrategies()
+	if err != nil {
+		return nil, errors.New("Failed to get strategies from target: " + err.Error())
+	}
+	diffs = append(diffs, diffObjects(KindStrategy, strategiesOf(strategies), strategiesOf(targetStrategies))...)
+
+	pairs := taskPairs(strategies)
+	if opts.Assignments {
+		for _, pair := range pairs {
+			srcAssignments, err := assignmentsOf(src, pair[0], pair[1])
+			if err != nil {
+				return nil, errors.New("Failed to get assignments from source: " + err.Error())
+			}
+			dstAssignments, err := assignmentsOf(dst, pair[0], pair[1])
+			if err != nil {
+				return nil, errors.New("Failed to get assignments from target: " + err.Error())
+			}
+			diffs = append(diffs, diffObjects(KindAssignment, srcAssignments, dstAssignments)...)
+		}
+	}
+
+	if opts.ItemsVersion {
+		for _, pair := range pairs {
+			version, err := src.GetTaskItemsConfigVersion(pair[0], pair[1])
+			if err != nil {
+				return nil, errors.New("Failed to get items version from source: " + err.Error())
+			}
+			current, err := dst.GetTaskItemsConfigVersion(pair[0], pair[1])
+			if err != nil {
+				return nil, errors.New("Failed to get items version from target: " + err.Error())
+			}
+			if current < version {
+				diffs = append(diffs, Difference{
+					Kind:   KindItemsVersion,
+					ID:     pair[0] + "/" + pair[1],
+					Reason: "older in target(" + strconv.FormatInt(current, 10) + " < " + strconv.FormatInt(version, 10) + ")",
+				})
+			}
+		}
+	}
+	return diffs, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package redis
+
+import (
+	"strconv"
+
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+func (s *RedisStore) keyLastFires() string {
+	return s.key("cronFires")
+}
+
+func (s *RedisStore) keyFireClaims(strategyId string) string {
+	return s.key("cronClaims/" + strategyId)
+}
+
+func (s *RedisStore) GetLastFireTime(strategyId string) (int64, error) {
+	val, err := s.client.HGet(s.keyLastFires(), strategyId).Int64()
+	if hasError(err) {
+		return 0, err
+	}
+	return val, nil
+}
+
+func (s *RedisStore) SetLastFireTime(strategyId string, fireTime int64) error {
+	return s.client.HSet(s.keyLastFires(), strategyId, fireTime).Err()
+}
+
+func (s *RedisStore) ClaimFireTime(strategyId string, fireTime int64) error {
+	ok, err := s.client.HSetNX(s.keyFireClaims(strategyId), strconv.FormatInt(fireTime, 10), fireTime).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return store.AlreadyExist
+	}
+	return nil
+}
+
+func (s *RedisStore) RemoveFireClaims(strategyId string, before int64) error {
+	key := s.keyFireClaims(strategyId)
+	fields, err := s.client.HKeys(key).Result()
+	if hasError(err) {
+		return err
+	}
+	for _, field := range fields {
+		fireTime, err := strconv.ParseInt(field, 10, 64)
+		if err != nil || fireTime >= before {
+			continue
+		}
+		if err := s.client.HDel(key, field).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RedisStore) removeFires(strategyId string) {
+	s.client.HDel(s.keyLastFires(), strategyId)
+	s.client.Del(s.keyFireClaims(strategyId))
+}
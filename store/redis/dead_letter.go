@@ -0,0 +1,50 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package redis
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/utils"
+)
+
+func (s *RedisStore) keyDeadLetters(strategyId, taskId string) string {
+	return s.key("deadLetters/" + strategyId + "/" + taskId)
+}
+
+func (s *RedisStore) PutDeadLetter(letter *definition.DeadLetter) error {
+	if letter == nil {
+		return errors.New("dead letter should not be nil")
+	}
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(s.keyDeadLetters(letter.StrategyID, letter.TaskID), letter.ID, string(data)).Err()
+}
+
+func (s *RedisStore) GetDeadLetters(strategyId, taskId string) ([]*definition.DeadLetter, error) {
+	valMap, err := s.client.HGetAll(s.keyDeadLetters(strategyId, taskId)).Result()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*definition.DeadLetter, 0, len(valMap))
+	for _, v := range valMap {
+		letter := &definition.DeadLetter{}
+		if err := json.Unmarshal([]byte(v), letter); err != nil {
+			// ignore
+			continue
+		}
+		list = append(list, letter)
+	}
+	utils.SortDeadLetters(list)
+	return list, nil
+}
+
+func (s *RedisStore) RemoveDeadLetter(strategyId, taskId, id string) error {
+	return s.client.HDel(s.keyDeadLetters(strategyId, taskId), id).Err()
+}
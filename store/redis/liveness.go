@@ -0,0 +1,86 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package redis
+
+import (
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+// Fields of hash can't expire individually, so a key with ttl is attached to every temporary object
+//	and the object is marked in a set of members kept alive. Marked objects without keys have expired,
+//	while objects never marked are written by older versions and are reported as NotExist so that
+//	their heartbeats are compared instead.
+
+func (s *RedisStore) keySchedulerAlive(id string) string {
+	return s.key("alive/schedulers/" + id)
+}
+
+func (s *RedisStore) keySchedulersAlive() string {
+	return s.key("alive/schedulers")
+}
+
+func (s *RedisStore) keyTaskRuntimeAlive(strategyId, taskId, id string) string {
+	return s.key("alive/taskRuntimes/" + strategyId + "/" + taskId + "/" + id)
+}
+
+func (s *RedisStore) keyTaskRuntimesAlive(strategyId, taskId string) string {
+	return s.key("alive/taskRuntimes/" + strategyId + "/" + taskId)
+}
+
+// keepAlive refreshes the key with ttl of the object and marks it in members kept alive
+func (s *RedisStore) keepAlive(key, members, field string, heartbeat int64, ttl time.Duration) error {
+	if err := s.client.Set(key, heartbeat, ttl).Err(); err != nil {
+		return err
+	}
+	return s.client.SAdd(members, field).Err()
+}
+
+// alive checks the key with ttl of the object which is saved in field of hash
+func (s *RedisStore) alive(key, members, hash, field string) (bool, error) {
+	cnt, err := s.client.Exists(key).Result()
+	if err != nil {
+		return false, err
+	}
+	if cnt > 0 {
+		return true, nil
+	}
+	marked, err := s.client.SIsMember(members, field).Result()
+	if err != nil || marked {
+		// expired
+		return false, err
+	}
+	existed, err := s.client.HExists(hash, field).Result()
+	if err != nil || !existed {
+		return false, err
+	}
+	return false, store.NotExist
+}
+
+func (s *RedisStore) KeepSchedulerAlive(scheduler *definition.Scheduler, ttl time.Duration) error {
+	if err := s.RegisterScheduler(scheduler); err != nil {
+		return err
+	}
+	return s.keepAlive(s.keySchedulerAlive(scheduler.ID), s.keySchedulersAlive(), scheduler.ID, scheduler.LastHeartbeat, ttl)
+}
+
+func (s *RedisStore) IsSchedulerAlive(id string) (bool, error) {
+	return s.alive(s.keySchedulerAlive(id), s.keySchedulersAlive(), s.keySchedulers(), id)
+}
+
+func (s *RedisStore) KeepTaskRuntimeAlive(runtime *definition.TaskRuntime, ttl time.Duration) error {
+	if err := s.SetTaskRuntime(runtime); err != nil {
+		return err
+	}
+	return s.keepAlive(s.keyTaskRuntimeAlive(runtime.StrategyID, runtime.TaskID, runtime.ID),
+		s.keyTaskRuntimesAlive(runtime.StrategyID, runtime.TaskID), runtime.ID, runtime.LastHeartbeat, ttl)
+}
+
+func (s *RedisStore) IsTaskRuntimeAlive(strategyId, taskId, id string) (bool, error) {
+	return s.alive(s.keyTaskRuntimeAlive(strategyId, taskId, id), s.keyTaskRuntimesAlive(strategyId, taskId),
+		s.keyTaskRuntimes(strategyId, taskId), id)
+}
@@ -5,12 +5,14 @@
 package redis
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"math"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-redis/redis"
@@ -27,6 +29,11 @@ sequence [autoincrement uint64]
 tasks [map[string]json]
 strategies [map[string]json]
 schedulers [map[string]json]
+events [pub/sub channel of json]
+alive/schedulers/{id} [string with ttl]
+alive/schedulers [set of ids kept alive]
+alive/taskRuntimes/{strategy}/{task}/{id} [string with ttl]
+alive/taskRuntimes/{strategy}/{task} [set of ids kept alive]
 **/
 
 type RedisStoreConfig struct {
@@ -39,6 +46,11 @@ type RedisStoreConfig struct {
 type RedisStore struct {
 	client redis.UniversalClient
 	prefix string
+
+	ctx       context.Context
+	ctxCancel context.CancelFunc
+	events    *store.Broadcaster
+	watchOnce sync.Once
 }
 
 func NewFromConfig(config *RedisStoreConfig) *RedisStore {
@@ -53,10 +65,13 @@ func NewFromConfig(config *RedisStoreConfig) *RedisStore {
 		PoolTimeout:        time.Second * 300,
 		IdleCheckFrequency: time.Second * 60,
 	})
-	return &RedisStore{
+	s := &RedisStore{
 		client: client,
 		prefix: config.Prefix,
+		events: store.NewBroadcaster(),
 	}
+	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
+	return s
 }
 
 func hasError(err error) bool {
@@ -214,6 +229,8 @@ func (s *RedisStore) Time() int64 {
 }
 
 func (s *RedisStore) Close() error {
+	s.ctxCancel()
+	s.events.Close()
 	return s.client.Close()
 }
 
@@ -278,8 +295,16 @@ func (s *RedisStore) CreateTask(task *definition.Task) error {
 	if err != nil {
 		return err
 	}
-	_, err = s.client.HSetNX(key, task.ID, string(data)).Result()
-	return err
+	ok, err := s.client.HSetNX(key, task.ID, string(data)).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// created concurrently
+		return store.AlreadyExist
+	}
+	s.notify(store.Event{Kind: store.TaskEvent, TaskID: task.ID})
+	return nil
 }
 
 func (s *RedisStore) UpdateTask(task *definition.Task) error {
@@ -291,6 +316,9 @@ func (s *RedisStore) UpdateTask(task *definition.Task) error {
 		return err
 	}
 	_, err = s.client.HSet(s.keyTasks(), task.ID, string(data)).Result()
+	if err == nil {
+		s.notify(store.Event{Kind: store.TaskEvent, TaskID: task.ID})
+	}
 	return err
 }
 
@@ -299,6 +327,7 @@ func (s *RedisStore) RemoveTask(id string) error {
 	if cnt == 0 {
 		return store.NotExist
 	}
+	s.notify(store.Event{Kind: store.TaskEvent, TaskID: id})
 	return err
 }
 
@@ -337,13 +366,21 @@ func (s *RedisStore) SetTaskRuntime(runtime *definition.TaskRuntime) error {
 	if err != nil {
 		return err
 	}
-	_, err = s.client.HSet(key, runtime.ID, string(data)).Result()
+	created, err := s.client.HSet(key, runtime.ID, string(data)).Result()
+	if created {
+		s.notify(store.Event{Kind: store.TaskRuntimeEvent, StrategyID: runtime.StrategyID, TaskID: runtime.TaskID, ID: runtime.ID})
+	}
 	return err
 }
 
 func (s *RedisStore) RemoveTaskRuntime(strategyId, taskId, id string) error {
 	key := s.keyTaskRuntimes(strategyId, taskId)
-	_, err := s.client.HDel(key, id).Result()
+	s.client.Del(s.keyTaskRuntimeAlive(strategyId, taskId, id))
+	s.client.SRem(s.keyTaskRuntimesAlive(strategyId, taskId), id)
+	cnt, err := s.client.HDel(key, id).Result()
+	if cnt > 0 {
+		s.notify(store.Event{Kind: store.TaskRuntimeEvent, StrategyID: strategyId, TaskID: taskId, ID: id})
+	}
 	return err
 }
 
@@ -360,7 +397,11 @@ func (s *RedisStore) GetTaskItemsConfigVersion(strategyId, taskId string) (int64
 func (s *RedisStore) IncreaseTaskItemsConfigVersion(strategyId, taskId string) error {
 	key := s.keyTaskItemsConfigVersion()
 	subKey := strategyId + "/" + taskId
-	return s.client.HIncrBy(key, subKey, 1).Err()
+	err := s.client.HIncrBy(key, subKey, 1).Err()
+	if err == nil {
+		s.notify(store.Event{Kind: store.TaskItemsConfigVersionEvent, StrategyID: strategyId, TaskID: taskId})
+	}
+	return err
 }
 
 //
@@ -391,19 +432,39 @@ func (s *RedisStore) GetTaskAssignments(strategyId, taskId string) ([]*definitio
 	return list, nil
 }
 
-func (s *RedisStore) SetTaskAssignment(assignment *definition.TaskAssignment) error {
+func (s *RedisStore) setTaskAssignment(assignment *definition.TaskAssignment, expected int64) (int64, error) {
 	key := s.keyTaskAssignments(assignment.StrategyID, assignment.TaskID)
-	data, err := json.Marshal(assignment)
+	revision, err := s.setWithRevision(key, assignment.ItemID, expected, func(revision int64) ([]byte, error) {
+		r := *assignment
+		r.Revision = revision
+		return json.Marshal(&r)
+	})
+	if err == nil {
+		s.notify(store.Event{Kind: store.TaskAssignmentEvent, StrategyID: assignment.StrategyID, TaskID: assignment.TaskID, ID: assignment.ItemID})
+	}
+	return revision, err
+}
+
+func (s *RedisStore) SetTaskAssignment(assignment *definition.TaskAssignment) error {
+	_, err := s.setTaskAssignment(assignment, -1)
+	return err
+}
+
+func (s *RedisStore) CompareAndSetTaskAssignment(assignment *definition.TaskAssignment) error {
+	revision, err := s.setTaskAssignment(assignment, assignment.Revision)
 	if err != nil {
 		return err
 	}
-	_, err = s.client.HSet(key, assignment.ItemID, string(data)).Result()
-	return err
+	assignment.Revision = revision
+	return nil
 }
 
 func (s *RedisStore) RemoveTaskAssignment(strategyId, taskId, itemId string) error {
 	key := s.keyTaskAssignments(strategyId, taskId)
-	_, err := s.client.HDel(key, itemId).Result()
+	cnt, err := s.client.HDel(key, itemId).Result()
+	if cnt > 0 {
+		s.notify(store.Event{Kind: store.TaskAssignmentEvent, StrategyID: strategyId, TaskID: taskId, ID: itemId})
+	}
 	return err
 }
 
@@ -453,8 +514,16 @@ func (s *RedisStore) CreateStrategy(strategy *definition.Strategy) error {
 	if err != nil {
 		return err
 	}
-	_, err = s.client.HSetNX(key, strategy.ID, string(data)).Result()
-	return err
+	ok, err := s.client.HSetNX(key, strategy.ID, string(data)).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// created concurrently
+		return store.AlreadyExist
+	}
+	s.notify(store.Event{Kind: store.StrategyEvent, StrategyID: strategy.ID})
+	return nil
 }
 
 func (s *RedisStore) UpdateStrategy(strategy *definition.Strategy) error {
@@ -466,14 +535,19 @@ func (s *RedisStore) UpdateStrategy(strategy *definition.Strategy) error {
 		return err
 	}
 	_, err = s.client.HSet(s.keyStrategies(), strategy.ID, string(data)).Result()
+	if err == nil {
+		s.notify(store.Event{Kind: store.StrategyEvent, StrategyID: strategy.ID})
+	}
 	return err
 }
 
 func (s *RedisStore) RemoveStrategy(id string) error {
+	s.removeFires(id)
 	cnt, err := s.client.HDel(s.keyStrategies(), id).Result()
 	if cnt == 0 {
 		return store.NotExist
 	}
+	s.notify(store.Event{Kind: store.StrategyEvent, StrategyID: id})
 	return err
 }
 
@@ -506,19 +580,39 @@ func (s *RedisStore) GetStrategyRuntimes(strategyId string) ([]*definition.Strat
 	return list, nil
 }
 
-func (s *RedisStore) SetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+func (s *RedisStore) setStrategyRuntime(runtime *definition.StrategyRuntime, expected int64) (int64, error) {
 	key := s.keyRuntimes(runtime.StrategyID)
-	data, err := json.Marshal(runtime)
+	revision, err := s.setWithRevision(key, runtime.SchedulerID, expected, func(revision int64) ([]byte, error) {
+		r := *runtime
+		r.Revision = revision
+		return json.Marshal(&r)
+	})
+	if err == nil {
+		s.notify(store.Event{Kind: store.StrategyRuntimeEvent, StrategyID: runtime.StrategyID, ID: runtime.SchedulerID})
+	}
+	return revision, err
+}
+
+func (s *RedisStore) SetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	_, err := s.setStrategyRuntime(runtime, -1)
+	return err
+}
+
+func (s *RedisStore) CompareAndSetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	revision, err := s.setStrategyRuntime(runtime, runtime.Revision)
 	if err != nil {
 		return err
 	}
-	_, err = s.client.HSet(key, runtime.SchedulerID, string(data)).Result()
-	return err
+	runtime.Revision = revision
+	return nil
 }
 
 func (s *RedisStore) RemoveStrategyRuntime(strategyId, schedulerId string) error {
 	key := s.keyRuntimes(strategyId)
-	_, err := s.client.HDel(key, schedulerId).Result()
+	cnt, err := s.client.HDel(key, schedulerId).Result()
+	if cnt > 0 {
+		s.notify(store.Event{Kind: store.StrategyRuntimeEvent, StrategyID: strategyId, ID: schedulerId})
+	}
 	return err
 }
 
@@ -533,12 +627,18 @@ func (s *RedisStore) RegisterScheduler(scheduler *definition.Scheduler) error {
 		// Now just ignore it
 		return errors.New("Serialize scheduler object failed")
 	}
-	s.client.HSet(key, scheduler.ID, string(data)).Result()
+	if created, _ := s.client.HSet(key, scheduler.ID, string(data)).Result(); created {
+		s.notify(store.Event{Kind: store.SchedulerEvent, ID: scheduler.ID})
+	}
 	return nil
 }
 func (s *RedisStore) UnregisterScheduler(id string) error {
 	key := s.keySchedulers()
-	s.client.HDel(key, id)
+	s.client.Del(s.keySchedulerAlive(id))
+	s.client.SRem(s.keySchedulersAlive(), id)
+	if cnt, _ := s.client.HDel(key, id).Result(); cnt > 0 {
+		s.notify(store.Event{Kind: store.SchedulerEvent, ID: id})
+	}
 	return nil
 }
 
@@ -83,3 +83,42 @@ func TestTaskReload(t *testing.T) {
 	storetest.DoTestTaskReloadItems(t, s)
 	s.Close()
 }
+
+func TestWatch(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestWatch(t, s)
+	s.Close()
+}
+
+func TestLiveness(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestLiveness(t, s)
+	storetest.DoTestLivenessExpiration(t, s)
+	storetest.DoTestLivenessTTL(t, s)
+	storetest.DoTestLivenessUpgrade(t, s)
+	s.Close()
+}
+
+func TestCompareAndSet(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestCompareAndSet(t, s)
+	s.Close()
+}
+
+func TestDeadLetter(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestDeadLetter(t, s)
+	s.Close()
+}
+
+func TestCronStore(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestCronStore(t, s)
+	s.Close()
+}
+
+func TestFireClaim(t *testing.T) {
+	s := newStorage()
+	storetest.DoTestFireClaim(t, s)
+	s.Close()
+}
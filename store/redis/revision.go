@@ -0,0 +1,75 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package redis
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/go-redis/redis"
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+// Revisions are embedded in values and generated from a global counter,
+//	so a recreated field never reuses an old revision.
+
+const maxWatchRetries = 10
+
+func (s *RedisStore) keyRevision() string {
+	return s.key("revision")
+}
+
+// setWithRevision writes the field of hash with a new revision in a transaction and returns the revision.
+//	Current revision must equal to expected unless expected is negative.
+func (s *RedisStore) setWithRevision(key, field string, expected int64, encode func(revision int64) ([]byte, error)) (int64, error) {
+	var revision int64
+	fn := func(tx *redis.Tx) error {
+		str, err := tx.HGet(key, field).Result()
+		if hasError(err) {
+			return err
+		}
+		if expected >= 0 {
+			var current struct {
+				Revision int64
+			}
+			if str != "" {
+				if err = json.Unmarshal([]byte(str), &current); err != nil {
+					return err
+				}
+			}
+			if current.Revision != expected {
+				return store.Conflict
+			}
+		}
+		revision, err = s.client.Incr(s.keyRevision()).Result()
+		if err != nil {
+			return err
+		}
+		data, err := encode(revision)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Pipelined(func(pipe redis.Pipeliner) error {
+			pipe.HSet(key, field, string(data))
+			return nil
+		})
+		return err
+	}
+	for i := 0; i < maxWatchRetries; i++ {
+		err := s.client.Watch(fn, key)
+		if err == redis.TxFailedErr {
+			// other fields may be changed, check again
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		return revision, nil
+	}
+	if expected >= 0 {
+		return 0, store.Conflict
+	}
+	return 0, errors.New("Too many concurrent modifications on " + key)
+}
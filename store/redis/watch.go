@@ -0,0 +1,59 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package redis
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jasonjoo2010/goschedule/log"
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+// Events are broadcasted through pub/sub of redis because there is no reliable keyspace notification,
+//	all the mutations are done through store so it's fine.
+
+func (s *RedisStore) keyEvents() string {
+	return s.key("events")
+}
+
+func (s *RedisStore) notify(e store.Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	if err = s.client.Publish(s.keyEvents(), string(data)).Err(); err != nil {
+		log.Warnf("Publish event %v failed: %s", e, err.Error())
+	}
+}
+
+func (s *RedisStore) watchLoop() {
+	pubsub := s.client.Subscribe(s.keyEvents())
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var e store.Event
+			if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+				continue
+			}
+			s.events.Publish(e)
+		}
+	}
+}
+
+func (s *RedisStore) Watch(ctx context.Context) (<-chan store.Event, error) {
+	s.watchOnce.Do(func() {
+		go s.watchLoop()
+	})
+	return s.events.Subscribe(ctx), nil
+}
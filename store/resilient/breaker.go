@@ -0,0 +1,92 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package resilient
+
+import (
+	"sync"
+	"time"
+)
+
+type BreakerState int
+
+const (
+	// Closed lets calls pass through
+	Closed BreakerState = iota
+	// Open fails calls fast until cooling down
+	Open
+	// HalfOpen lets a single probing call pass through to decide closing or opening again
+	HalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "halfOpen"
+	}
+	return "unknown"
+}
+
+// breaker opens after continuous failures reaching threshold, zero threshold disables it
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	coolDown  time.Duration
+	state     BreakerState
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case Open:
+		if time.Since(b.openedAt) < b.coolDown {
+			return false
+		}
+		b.state = HalfOpen
+		b.probing = true
+		return true
+	case HalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+func (b *breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failed {
+		b.failures = 0
+		b.state = Closed
+		b.probing = false
+		return
+	}
+	b.failures++
+	if b.state == HalfOpen || (b.threshold > 0 && b.failures >= b.threshold) {
+		b.state = Open
+		b.openedAt = time.Now()
+		b.probing = false
+	}
+}
+
+func (b *breaker) status() (BreakerState, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := b.state
+	if state == Open && time.Since(b.openedAt) >= b.coolDown {
+		state = HalfOpen
+	}
+	return state, b.failures
+}
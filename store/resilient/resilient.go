@@ -0,0 +1,188 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+// Package resilient protects schedulers and workers from a hung or failing storage.
+//	Every call has a deadline, idempotent reads are retried with backoff and a circuit
+//	breaker fails calls fast after continuous failures:
+//
+//	s := resilient.New(store, resilient.WithTimeout(3*time.Second))
+//
+//	A call timed out is abandoned rather than canceled as store.Store has no context,
+//	so the goroutine running it exits only when the storage returns.
+package resilient
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+var (
+	Timeout     = errors.New("Calling storage timed out")
+	CircuitOpen = errors.New("Storage is unavailable as circuit is open")
+)
+
+type Config struct {
+	// Timeout is the deadline of every call, zero means no deadline
+	Timeout time.Duration
+	// Retries is the max number of retries of reads failed, writes are never retried
+	Retries int
+	// Backoff is the delay before the first retry and it doubles until MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// FailureThreshold is the number of continuous failures opening the circuit, zero disables breaking
+	FailureThreshold int
+	// CoolDown is how long the circuit stays open before probing storage again
+	CoolDown time.Duration
+}
+
+type Option func(cfg *Config)
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.Timeout = timeout
+	}
+}
+
+func WithRetries(retries int, backoff, maxBackoff time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.Retries = retries
+		cfg.Backoff = backoff
+		cfg.MaxBackoff = maxBackoff
+	}
+}
+
+func WithBreaker(threshold int, coolDown time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.FailureThreshold = threshold
+		cfg.CoolDown = coolDown
+	}
+}
+
+// ResilientStore decorates a storage with deadlines, retries and circuit breaking.
+//	NotExist, AlreadyExist and Conflict are normal results rather than failures.
+type ResilientStore struct {
+	store   store.Store
+	cfg     Config
+	breaker *breaker
+}
+
+type result struct {
+	val interface{}
+	err error
+}
+
+var (
+	_ store.Wrapper         = (*ResilientStore)(nil)
+	_ store.HealthReporter  = (*ResilientStore)(nil)
+	_ store.Watcher         = (*ResilientStore)(nil)
+	_ store.Liveness        = (*ResilientStore)(nil)
+	_ store.DeadLetterStore = (*ResilientStore)(nil)
+	_ store.CronStore       = (*ResilientStore)(nil)
+	_ store.FireClaimStore  = (*ResilientStore)(nil)
+)
+
+// New wraps the storage, by default with 5s of timeout, 2 retries from 100ms to 1s of backoff
+//	and opening circuit after 5 continuous failures for 10s.
+func New(s store.Store, opts ...Option) *ResilientStore {
+	cfg := Config{
+		Timeout:          5 * time.Second,
+		Retries:          2,
+		Backoff:          100 * time.Millisecond,
+		MaxBackoff:       time.Second,
+		FailureThreshold: 5,
+		CoolDown:         10 * time.Second,
+	}
+	for _, fn := range opts {
+		fn(&cfg)
+	}
+	return &ResilientStore{
+		store: s,
+		cfg:   cfg,
+		breaker: &breaker{
+			threshold: cfg.FailureThreshold,
+			coolDown:  cfg.CoolDown,
+		},
+	}
+}
+
+func failed(err error) bool {
+	return err != nil && err != store.NotExist && err != store.AlreadyExist && err != store.Conflict
+}
+
+// do runs fn with the deadline
+func (s *ResilientStore) do(fn func() (interface{}, error)) (interface{}, error) {
+	ch := make(chan result, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				ch <- result{err: fmt.Errorf("Panic in storage: %v", r)}
+			}
+		}()
+		val, err := fn()
+		ch <- result{val: val, err: err}
+	}()
+	if s.cfg.Timeout <= 0 {
+		r := <-ch
+		return r.val, r.err
+	}
+	timer := time.NewTimer(s.cfg.Timeout)
+	defer timer.Stop()
+	select {
+	case r := <-ch:
+		return r.val, r.err
+	case <-timer.C:
+		return nil, Timeout
+	}
+}
+
+func (s *ResilientStore) call(retry bool, fn func() (interface{}, error)) (interface{}, error) {
+	backoff := s.cfg.Backoff
+	for i := 0; ; i++ {
+		if !s.breaker.allow() {
+			return nil, CircuitOpen
+		}
+		val, err := s.do(fn)
+		s.breaker.record(failed(err))
+		if !failed(err) || !retry || i >= s.cfg.Retries {
+			return val, err
+		}
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > s.cfg.MaxBackoff {
+			backoff = s.cfg.MaxBackoff
+		}
+	}
+}
+
+func (s *ResilientStore) read(fn func() (interface{}, error)) (interface{}, error) {
+	return s.call(true, fn)
+}
+
+func (s *ResilientStore) write(fn func() error) error {
+	_, err := s.call(false, func() (interface{}, error) {
+		return nil, fn()
+	})
+	return err
+}
+
+func (s *ResilientStore) unsupported(feature string) error {
+	return errors.New(feature + " is not supported by storage: " + s.store.Name())
+}
+
+// State returns the state of circuit and the number of continuous failures
+func (s *ResilientStore) State() (BreakerState, int) {
+	return s.breaker.status()
+}
+
+// Degraded returns true if the last call failed or the circuit isn't closed
+func (s *ResilientStore) Degraded() bool {
+	state, failures := s.breaker.status()
+	return state != Closed || failures > 0
+}
+
+func (s *ResilientStore) Unwrap() store.Store {
+	return s.store
+}
@@ -0,0 +1,199 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package resilient
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/store/memory"
+	"github.com/jasonjoo2010/goschedule/storetest"
+	"github.com/stretchr/testify/assert"
+)
+
+var broken = errors.New("broken")
+
+// faultyStore hangs or fails on demand
+type faultyStore struct {
+	*memory.MemoryStore
+	hang     chan struct{}
+	failures int32
+	calls    int32
+}
+
+func newFaultyStore() *faultyStore {
+	return &faultyStore{MemoryStore: memory.New()}
+}
+
+func (s *faultyStore) fault() error {
+	atomic.AddInt32(&s.calls, 1)
+	if s.hang != nil {
+		<-s.hang
+	}
+	if atomic.AddInt32(&s.failures, -1) >= 0 {
+		return broken
+	}
+	return nil
+}
+
+func (s *faultyStore) GetTasks() ([]*definition.Task, error) {
+	if err := s.fault(); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.GetTasks()
+}
+
+func (s *faultyStore) CreateTask(task *definition.Task) error {
+	if err := s.fault(); err != nil {
+		return err
+	}
+	return s.MemoryStore.CreateTask(task)
+}
+
+func (s *faultyStore) Time() int64 {
+	s.fault()
+	return s.MemoryStore.Time()
+}
+
+func TestStoreSuite(t *testing.T) {
+	for name, fn := range map[string]func(*testing.T, store.Store){
+		"Time":            storetest.DoTestTime,
+		"Sequence":        storetest.DoTestSequence,
+		"Task":            storetest.DoTestTask,
+		"TaskRuntime":     storetest.DoTestTaskRuntime,
+		"TaskAssignment":  storetest.DoTestTaskAssignment,
+		"Strategy":        storetest.DoTestStrategy,
+		"StrategyRuntime": storetest.DoTestStrategyRuntime,
+		"Scheduler":       storetest.DoTestScheduler,
+		"CompareAndSet":   storetest.DoTestCompareAndSet,
+		"Watch":           storetest.DoTestWatch,
+		"Liveness":        storetest.DoTestLiveness,
+		"DeadLetter":      storetest.DoTestDeadLetter,
+		"CronStore":       storetest.DoTestCronStore,
+		"FireClaim":       storetest.DoTestFireClaim,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := New(memory.New())
+			fn(t, s)
+			assert.False(t, s.Degraded())
+			assert.Nil(t, s.Close())
+		})
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	faulty := newFaultyStore()
+	faulty.hang = make(chan struct{})
+	s := New(faulty, WithTimeout(50*time.Millisecond), WithRetries(0, 0, 0))
+	defer s.Close()
+
+	begin := time.Now()
+	_, err := s.GetTasks()
+	assert.Equal(t, Timeout, err)
+	assert.True(t, time.Since(begin) < time.Second)
+	assert.True(t, s.Degraded())
+	assert.True(t, store.IsDegraded(s))
+
+	// falls back to local clock
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	assert.InDelta(t, now, s.Time(), 1000)
+
+	close(faulty.hang)
+	_, err = s.GetTasks()
+	assert.Nil(t, err)
+	assert.False(t, s.Degraded())
+}
+
+func TestRetry(t *testing.T) {
+	faulty := newFaultyStore()
+	s := New(faulty, WithRetries(2, time.Millisecond, 2*time.Millisecond))
+	defer s.Close()
+
+	// reads are retried
+	atomic.StoreInt32(&faulty.failures, 2)
+	_, err := s.GetTasks()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&faulty.calls))
+
+	atomic.StoreInt32(&faulty.failures, 3)
+	atomic.StoreInt32(&faulty.calls, 0)
+	_, err = s.GetTasks()
+	assert.Equal(t, broken, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&faulty.calls))
+
+	// writes are not
+	atomic.StoreInt32(&faulty.failures, 1)
+	atomic.StoreInt32(&faulty.calls, 0)
+	assert.Equal(t, broken, s.CreateTask(&definition.Task{ID: "t0"}))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&faulty.calls))
+
+	// expected results are not failures
+	assert.Nil(t, s.CreateTask(&definition.Task{ID: "t0"}))
+	assert.Equal(t, store.AlreadyExist, s.CreateTask(&definition.Task{ID: "t0"}))
+	_, err = s.GetTask("t1")
+	assert.Equal(t, store.NotExist, err)
+	assert.False(t, s.Degraded())
+}
+
+func TestBreaker(t *testing.T) {
+	faulty := newFaultyStore()
+	s := New(faulty, WithRetries(0, 0, 0), WithBreaker(3, 100*time.Millisecond))
+	defer s.Close()
+
+	atomic.StoreInt32(&faulty.failures, 100)
+	for i := 0; i < 3; i++ {
+		_, err := s.GetTasks()
+		assert.Equal(t, broken, err)
+	}
+	state, failures := s.State()
+	assert.Equal(t, Open, state)
+	assert.Equal(t, 3, failures)
+
+	// fail fast
+	_, err := s.GetTasks()
+	assert.Equal(t, CircuitOpen, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&faulty.calls))
+
+	// probing fails
+	time.Sleep(100 * time.Millisecond)
+	state, _ = s.State()
+	assert.Equal(t, HalfOpen, state)
+	_, err = s.GetTasks()
+	assert.Equal(t, broken, err)
+	state, _ = s.State()
+	assert.Equal(t, Open, state)
+
+	// recovered
+	atomic.StoreInt32(&faulty.failures, 0)
+	time.Sleep(100 * time.Millisecond)
+	_, err = s.GetTasks()
+	assert.Nil(t, err)
+	state, failures = s.State()
+	assert.Equal(t, Closed, state)
+	assert.Equal(t, 0, failures)
+	assert.False(t, s.Degraded())
+}
+
+func TestBreakerProbing(t *testing.T) {
+	b := &breaker{threshold: 1, coolDown: 0}
+	b.record(true)
+	// only one probe at the same time
+	assert.True(t, b.allow())
+	assert.False(t, b.allow())
+	b.record(false)
+	assert.True(t, b.allow())
+	assert.True(t, b.allow())
+
+	// disabled
+	b = &breaker{}
+	for i := 0; i < 100; i++ {
+		b.record(true)
+	}
+	assert.True(t, b.allow())
+}
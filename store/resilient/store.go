@@ -0,0 +1,390 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package resilient
+
+import (
+	"context"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+)
+
+func (s *ResilientStore) Name() string {
+	return s.store.Name()
+}
+
+// Time falls back to the local clock if storage fails
+func (s *ResilientStore) Time() int64 {
+	val, err := s.call(false, func() (interface{}, error) {
+		return s.store.Time(), nil
+	})
+	if err != nil {
+		return time.Now().UnixNano() / int64(time.Millisecond)
+	}
+	return val.(int64)
+}
+
+func (s *ResilientStore) Sequence() (uint64, error) {
+	val, err := s.call(false, func() (interface{}, error) {
+		return s.store.Sequence()
+	})
+	seq, _ := val.(uint64)
+	return seq, err
+}
+
+// Close is bounded by the deadline but never refused by the circuit
+func (s *ResilientStore) Close() error {
+	_, err := s.do(func() (interface{}, error) {
+		return nil, s.store.Close()
+	})
+	return err
+}
+
+func (s *ResilientStore) Dump() string {
+	val, err := s.do(func() (interface{}, error) {
+		return s.store.Dump(), nil
+	})
+	if err != nil {
+		return err.Error()
+	}
+	return val.(string)
+}
+
+func (s *ResilientStore) RegisterScheduler(scheduler *definition.Scheduler) error {
+	return s.write(func() error {
+		return s.store.RegisterScheduler(scheduler)
+	})
+}
+
+func (s *ResilientStore) UnregisterScheduler(id string) error {
+	return s.write(func() error {
+		return s.store.UnregisterScheduler(id)
+	})
+}
+
+func (s *ResilientStore) GetSchedulers() ([]*definition.Scheduler, error) {
+	val, err := s.read(func() (interface{}, error) {
+		return s.store.GetSchedulers()
+	})
+	list, _ := val.([]*definition.Scheduler)
+	return list, err
+}
+
+func (s *ResilientStore) GetScheduler(id string) (*definition.Scheduler, error) {
+	val, err := s.read(func() (interface{}, error) {
+		return s.store.GetScheduler(id)
+	})
+	scheduler, _ := val.(*definition.Scheduler)
+	return scheduler, err
+}
+
+func (s *ResilientStore) GetTask(id string) (*definition.Task, error) {
+	val, err := s.read(func() (interface{}, error) {
+		return s.store.GetTask(id)
+	})
+	task, _ := val.(*definition.Task)
+	return task, err
+}
+
+func (s *ResilientStore) GetTasks() ([]*definition.Task, error) {
+	val, err := s.read(func() (interface{}, error) {
+		return s.store.GetTasks()
+	})
+	list, _ := val.([]*definition.Task)
+	return list, err
+}
+
+func (s *ResilientStore) CreateTask(task *definition.Task) error {
+	return s.write(func() error {
+		return s.store.CreateTask(task)
+	})
+}
+
+func (s *ResilientStore) UpdateTask(task *definition.Task) error {
+	return s.write(func() error {
+		return s.store.UpdateTask(task)
+	})
+}
+
+func (s *ResilientStore) RemoveTask(id string) error {
+	return s.write(func() error {
+		return s.store.RemoveTask(id)
+	})
+}
+
+func (s *ResilientStore) GetTaskRuntime(strategyId, taskId, id string) (*definition.TaskRuntime, error) {
+	val, err := s.read(func() (interface{}, error) {
+		return s.store.GetTaskRuntime(strategyId, taskId, id)
+	})
+	runtime, _ := val.(*definition.TaskRuntime)
+	return runtime, err
+}
+
+func (s *ResilientStore) GetTaskRuntimes(strategyId, taskId string) ([]*definition.TaskRuntime, error) {
+	val, err := s.read(func() (interface{}, error) {
+		return s.store.GetTaskRuntimes(strategyId, taskId)
+	})
+	list, _ := val.([]*definition.TaskRuntime)
+	return list, err
+}
+
+func (s *ResilientStore) SetTaskRuntime(runtime *definition.TaskRuntime) error {
+	return s.write(func() error {
+		return s.store.SetTaskRuntime(runtime)
+	})
+}
+
+func (s *ResilientStore) RemoveTaskRuntime(strategyId, taskId, id string) error {
+	return s.write(func() error {
+		return s.store.RemoveTaskRuntime(strategyId, taskId, id)
+	})
+}
+
+func (s *ResilientStore) GetTaskItemsConfigVersion(strategyId, taskId string) (int64, error) {
+	val, err := s.read(func() (interface{}, error) {
+		return s.store.GetTaskItemsConfigVersion(strategyId, taskId)
+	})
+	version, _ := val.(int64)
+	return version, err
+}
+
+func (s *ResilientStore) IncreaseTaskItemsConfigVersion(strategyId, taskId string) error {
+	return s.write(func() error {
+		return s.store.IncreaseTaskItemsConfigVersion(strategyId, taskId)
+	})
+}
+
+func (s *ResilientStore) GetTaskAssignment(strategyId, taskId, itemId string) (*definition.TaskAssignment, error) {
+	val, err := s.read(func() (interface{}, error) {
+		return s.store.GetTaskAssignment(strategyId, taskId, itemId)
+	})
+	assignment, _ := val.(*definition.TaskAssignment)
+	return assignment, err
+}
+
+func (s *ResilientStore) GetTaskAssignments(strategyId, taskId string) ([]*definition.TaskAssignment, error) {
+	val, err := s.read(func() (interface{}, error) {
+		return s.store.GetTaskAssignments(strategyId, taskId)
+	})
+	list, _ := val.([]*definition.TaskAssignment)
+	return list, err
+}
+
+func (s *ResilientStore) SetTaskAssignment(assignment *definition.TaskAssignment) error {
+	return s.write(func() error {
+		return s.store.SetTaskAssignment(assignment)
+	})
+}
+
+func (s *ResilientStore) CompareAndSetTaskAssignment(assignment *definition.TaskAssignment) error {
+	return s.write(func() error {
+		return s.store.CompareAndSetTaskAssignment(assignment)
+	})
+}
+
+func (s *ResilientStore) RemoveTaskAssignment(strategyId, taskId, itemId string) error {
+	return s.write(func() error {
+		return s.store.RemoveTaskAssignment(strategyId, taskId, itemId)
+	})
+}
+
+func (s *ResilientStore) GetStrategy(id string) (*definition.Strategy, error) {
+	val, err := s.read(func() (interface{}, error) {
+		return s.store.GetStrategy(id)
+	})
+	strategy, _ := val.(*definition.Strategy)
+	return strategy, err
+}
+
+func (s *ResilientStore) GetStrategies() ([]*definition.Strategy, error) {
+	val, err := s.read(func() (interface{}, error) {
+		return s.store.GetStrategies()
+	})
+	list, _ := val.([]*definition.Strategy)
+	return list, err
+}
+
+func (s *ResilientStore) CreateStrategy(strategy *definition.Strategy) error {
+	return s.write(func() error {
+		return s.store.CreateStrategy(strategy)
+	})
+}
+
+func (s *ResilientStore) UpdateStrategy(strategy *definition.Strategy) error {
+	return s.write(func() error {
+		return s.store.UpdateStrategy(strategy)
+	})
+}
+
+func (s *ResilientStore) RemoveStrategy(id string) error {
+	return s.write(func() error {
+		return s.store.RemoveStrategy(id)
+	})
+}
+
+func (s *ResilientStore) GetStrategyRuntime(strategyId, schedulerId string) (*definition.StrategyRuntime, error) {
+	val, err := s.read(func() (interface{}, error) {
+		return s.store.GetStrategyRuntime(strategyId, schedulerId)
+	})
+	runtime, _ := val.(*definition.StrategyRuntime)
+	return runtime, err
+}
+
+func (s *ResilientStore) GetStrategyRuntimes(strategyId string) ([]*definition.StrategyRuntime, error) {
+	val, err := s.read(func() (interface{}, error) {
+		return s.store.GetStrategyRuntimes(strategyId)
+	})
+	list, _ := val.([]*definition.StrategyRuntime)
+	return list, err
+}
+
+func (s *ResilientStore) SetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	return s.write(func() error {
+		return s.store.SetStrategyRuntime(runtime)
+	})
+}
+
+func (s *ResilientStore) CompareAndSetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	return s.write(func() error {
+		return s.store.CompareAndSetStrategyRuntime(runtime)
+	})
+}
+
+func (s *ResilientStore) RemoveStrategyRuntime(strategyId, schedulerId string) error {
+	return s.write(func() error {
+		return s.store.RemoveStrategyRuntime(strategyId, schedulerId)
+	})
+}
+
+func (s *ResilientStore) Watch(ctx context.Context) (<-chan store.Event, error) {
+	watcher, ok := store.AsWatcher(s.store)
+	if !ok {
+		return nil, s.unsupported("Watching")
+	}
+	val, err := s.call(false, func() (interface{}, error) {
+		return watcher.Watch(ctx)
+	})
+	ch, _ := val.(<-chan store.Event)
+	return ch, err
+}
+
+func (s *ResilientStore) KeepSchedulerAlive(scheduler *definition.Scheduler, ttl time.Duration) error {
+	l, ok := store.AsLiveness(s.store)
+	if !ok {
+		return s.unsupported("Liveness")
+	}
+	return s.write(func() error {
+		return l.KeepSchedulerAlive(scheduler, ttl)
+	})
+}
+
+func (s *ResilientStore) IsSchedulerAlive(id string) (bool, error) {
+	l, ok := store.AsLiveness(s.store)
+	if !ok {
+		return false, s.unsupported("Liveness")
+	}
+	val, err := s.read(func() (interface{}, error) {
+		return l.IsSchedulerAlive(id)
+	})
+	alive, _ := val.(bool)
+	return alive, err
+}
+
+func (s *ResilientStore) KeepTaskRuntimeAlive(runtime *definition.TaskRuntime, ttl time.Duration) error {
+	l, ok := store.AsLiveness(s.store)
+	if !ok {
+		return s.unsupported("Liveness")
+	}
+	return s.write(func() error {
+		return l.KeepTaskRuntimeAlive(runtime, ttl)
+	})
+}
+
+func (s *ResilientStore) IsTaskRuntimeAlive(strategyId, taskId, id string) (bool, error) {
+	l, ok := store.AsLiveness(s.store)
+	if !ok {
+		return false, s.unsupported("Liveness")
+	}
+	val, err := s.read(func() (interface{}, error) {
+		return l.IsTaskRuntimeAlive(strategyId, taskId, id)
+	})
+	alive, _ := val.(bool)
+	return alive, err
+}
+
+func (s *ResilientStore) PutDeadLetter(letter *definition.DeadLetter) error {
+	d, ok := store.AsDeadLetterStore(s.store)
+	if !ok {
+		return s.unsupported("Dead letters")
+	}
+	return s.write(func() error {
+		return d.PutDeadLetter(letter)
+	})
+}
+
+func (s *ResilientStore) GetDeadLetters(strategyId, taskId string) ([]*definition.DeadLetter, error) {
+	d, ok := store.AsDeadLetterStore(s.store)
+	if !ok {
+		return nil, s.unsupported("Dead letters")
+	}
+	val, err := s.read(func() (interface{}, error) {
+		return d.GetDeadLetters(strategyId, taskId)
+	})
+	list, _ := val.([]*definition.DeadLetter)
+	return list, err
+}
+
+func (s *ResilientStore) RemoveDeadLetter(strategyId, taskId, id string) error {
+	d, ok := store.AsDeadLetterStore(s.store)
+	if !ok {
+		return s.unsupported("Dead letters")
+	}
+	return s.write(func() error {
+		return d.RemoveDeadLetter(strategyId, taskId, id)
+	})
+}
+
+func (s *ResilientStore) GetLastFireTime(strategyId string) (int64, error) {
+	c, ok := store.AsCronStore(s.store)
+	if !ok {
+		return 0, s.unsupported("Cron fires")
+	}
+	val, err := s.read(func() (interface{}, error) {
+		return c.GetLastFireTime(strategyId)
+	})
+	t, _ := val.(int64)
+	return t, err
+}
+
+func (s *ResilientStore) SetLastFireTime(strategyId string, fireTime int64) error {
+	c, ok := store.AsCronStore(s.store)
+	if !ok {
+		return s.unsupported("Cron fires")
+	}
+	return s.write(func() error {
+		return c.SetLastFireTime(strategyId, fireTime)
+	})
+}
+
+func (s *ResilientStore) ClaimFireTime(strategyId string, fireTime int64) error {
+	c, ok := store.AsFireClaimStore(s.store)
+	if !ok {
+		return s.unsupported("Fire claims")
+	}
+	return s.write(func() error {
+		return c.ClaimFireTime(strategyId, fireTime)
+	})
+}
+
+func (s *ResilientStore) RemoveFireClaims(strategyId string, before int64) error {
+	c, ok := store.AsFireClaimStore(s.store)
+	if !ok {
+		return s.unsupported("Fire claims")
+	}
+	return s.write(func() error {
+		return c.RemoveFireClaims(strategyId, before)
+	})
+}
@@ -22,6 +22,7 @@ import (
 var (
 	NotExist     = errors.New("Specified item is not existed")
 	AlreadyExist = errors.New("Specified item is already existed")
+	Conflict     = errors.New("Specified item has been changed by others")
 )
 
 type Store interface {
@@ -69,6 +70,10 @@ type Store interface {
 	GetTaskAssignment(strategyId, taskId, itemId string) (*definition.TaskAssignment, error)
 	GetTaskAssignments(strategyId, taskId string) ([]*definition.TaskAssignment, error)
 	SetTaskAssignment(assignment *definition.TaskAssignment) error
+	// CompareAndSetTaskAssignment saves the assignment only if its Revision equals to the one in storage,
+	//	zero Revision means it must not exist. Revision of assignment will be updated on success,
+	//	otherwise Conflict is returned and caller should retry with fresh state.
+	CompareAndSetTaskAssignment(assignment *definition.TaskAssignment) error
 	RemoveTaskAssignment(strategyId, taskId, itemId string) error
 
 	// strategy related
@@ -82,6 +87,9 @@ type Store interface {
 	GetStrategyRuntime(strategyId, schedulerId string) (*definition.StrategyRuntime, error)
 	GetStrategyRuntimes(strategyId string) ([]*definition.StrategyRuntime, error)
 	SetStrategyRuntime(runtime *definition.StrategyRuntime) error
+	// CompareAndSetStrategyRuntime saves the runtime only if its Revision equals to the one in storage,
+	//	the same as CompareAndSetTaskAssignment.
+	CompareAndSetStrategyRuntime(runtime *definition.StrategyRuntime) error
 	RemoveStrategyRuntime(strategyId, schedulerId string) error
 
 	// Dump dump data in storage in string format.
@@ -0,0 +1,156 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type EventKind int
+
+const (
+	UnknownEvent EventKind = iota
+	// Scheduler joined or left, heartbeats are not included
+	SchedulerEvent
+	TaskEvent
+	StrategyEvent
+	StrategyRuntimeEvent
+	// Task runtime joined or left, heartbeats are not included
+	TaskRuntimeEvent
+	TaskAssignmentEvent
+	TaskItemsConfigVersionEvent
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case SchedulerEvent:
+		return "scheduler"
+	case TaskEvent:
+		return "task"
+	case StrategyEvent:
+		return "strategy"
+	case StrategyRuntimeEvent:
+		return "strategyRuntime"
+	case TaskRuntimeEvent:
+		return "taskRuntime"
+	case TaskAssignmentEvent:
+		return "taskAssignment"
+	case TaskItemsConfigVersionEvent:
+		return "taskItemsConfigVersion"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a change happened in storage.
+//	Only the fields related to the kind are filled:
+//	SchedulerEvent - ID
+//	TaskEvent - TaskID
+//	StrategyEvent - StrategyID
+//	StrategyRuntimeEvent - StrategyID, ID(scheduler id)
+//	TaskRuntimeEvent - StrategyID, TaskID, ID(runtime id)
+//	TaskAssignmentEvent - StrategyID, TaskID, ID(item id)
+//	TaskItemsConfigVersionEvent - StrategyID, TaskID
+type Event struct {
+	Kind       EventKind
+	StrategyID string
+	TaskID     string
+	ID         string
+}
+
+func (e Event) String() string {
+	return fmt.Sprint("{kind=", e.Kind, ",strategy=", e.StrategyID, ",task=", e.TaskID, ",id=", e.ID, "}")
+}
+
+// Watcher is an optional interface which can be implemented by a Store to notify changes.
+//	Events are only hints to speed up reacting, they may be merged or dropped under pressure,
+//	so the polling should still be kept as the fallback.
+type Watcher interface {
+	// Watch returns a channel receiving events until the given context is done.
+	//	The channel will be closed when the context is done or the store is closed.
+	Watch(ctx context.Context) (<-chan Event, error)
+}
+
+// Broadcaster is a helper to fan out events to multiple subscribers for implementations.
+type Broadcaster struct {
+	mu          sync.Mutex
+	closed      bool
+	subscribers map[chan Event]struct{}
+}
+
+const subscriberBufferSize = 64
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber which will be removed when ctx is done.
+func (b *Broadcaster) Subscribe(ctx context.Context) <-chan Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, subscriberBufferSize)
+	if b.closed {
+		close(ch)
+		return ch
+	}
+	b.subscribers[ch] = struct{}{}
+	go func() {
+		<-ctx.Done()
+		b.unsubscribe(ch)
+	}()
+	return ch
+}
+
+func (b *Broadcaster) unsubscribe(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// Publish sends the event to all subscribers without blocking.
+//	Event will be dropped for subscribers whose buffer is full.
+func (b *Broadcaster) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribers returns the count of active subscribers
+func (b *Broadcaster) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subscribers)
+}
+
+// Close closes all the subscribers and rejects new ones
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for ch := range b.subscribers {
+		close(ch)
+	}
+	b.subscribers = nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package store
+
+// Wrapper is implemented by stores decorating another store, eg. collecting metrics.
+//	A wrapper implements all the optional interfaces(Watcher, Liveness, DeadLetterStore,
+//	CronStore and FireClaimStore) and delegates them to the wrapped store, so whether one
+//	is really supported is decided by the innermost store. Use AsWatcher, AsLiveness, etc.
+//	instead of type assertions to find them.
+type Wrapper interface {
+	Store
+	// Unwrap returns the wrapped store
+	Unwrap() Store
+}
+
+// Unwrap returns the innermost store of wrappers
+func Unwrap(s Store) Store {
+	for {
+		w, ok := s.(Wrapper)
+		if !ok {
+			return s
+		}
+		s = w.Unwrap()
+	}
+}
+
+func AsWatcher(s Store) (Watcher, bool) {
+	if _, ok := Unwrap(s).(Watcher); !ok {
+		return nil, false
+	}
+	w, ok := s.(Watcher)
+	return w, ok
+}
+
+func AsLiveness(s Store) (Liveness, bool) {
+	if _, ok := Unwrap(s).(Liveness); !ok {
+		return nil, false
+	}
+	l, ok := s.(Liveness)
+	return l, ok
+}
+
+func AsDeadLetterStore(s Store) (DeadLetterStore, bool) {
+	if _, ok := Unwrap(s).(DeadLetterStore); !ok {
+		return nil, false
+	}
+	d, ok := s.(DeadLetterStore)
+	return d, ok
+}
+
+func AsCronStore(s Store) (CronStore, bool) {
+	if _, ok := Unwrap(s).(CronStore); !ok {
+		return nil, false
+	}
+	c, ok := s.(CronStore)
+	return c, ok
+}
+
+func AsFireClaimStore(s Store) (FireClaimStore, bool) {
+	if _, ok := Unwrap(s).(FireClaimStore); !ok {
+		return nil, false
+	}
+	c, ok := s.(FireClaimStore)
+	return c, ok
+}
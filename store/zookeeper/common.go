@@ -6,9 +6,13 @@ package zookeeper
 
 import (
 	"container/list"
+	"encoding/json"
+	"path"
 	"strings"
 
 	"github.com/jasonjoo2010/goschedule/log"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/samuel/go-zookeeper/zk"
 )
 
 func (s *ZookeeperStore) exists(path string) bool {
@@ -82,3 +86,48 @@ func (s *ZookeeperStore) createPath(path string, createParent bool) error {
 	}
 	return nil
 }
+
+// compareAndSet saves the object only if the modification zxid of node equals to revision and returns the new one.
+//	Zero revision means the node should not exist and it will be created with given flags.
+//	Zxid is used instead of version because version restarts after the node is recreated.
+func (s *ZookeeperStore) compareAndSet(key string, obj interface{}, revision int64, flags int32) (int64, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return 0, err
+	}
+	if revision == 0 {
+		_, err = s.conn.Create(key, data, flags, s.acl)
+		if err == zk.ErrNoNode {
+			// make sure parent existed and recreate
+			s.createPath(path.Dir(key), true)
+			_, err = s.conn.Create(key, data, flags, s.acl)
+		}
+		if err == zk.ErrNodeExists {
+			return 0, store.Conflict
+		}
+		if err != nil {
+			return 0, err
+		}
+		existed, stat, err := s.conn.Exists(key)
+		if err != nil || !existed || stat.Version != 0 {
+			// changed by others already, make sure next comparing fails
+			return -1, nil
+		}
+		return stat.Mzxid, nil
+	}
+	existed, stat, err := s.conn.Exists(key)
+	if err != nil {
+		return 0, err
+	}
+	if !existed || stat.Mzxid != revision {
+		return 0, store.Conflict
+	}
+	stat, err = s.conn.Set(key, data, stat.Version)
+	if err == zk.ErrBadVersion || err == zk.ErrNoNode {
+		return 0, store.Conflict
+	}
+	if err != nil {
+		return 0, err
+	}
+	return stat.Mzxid, nil
+}
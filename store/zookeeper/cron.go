@@ -0,0 +1,91 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package zookeeper
+
+import (
+	"math"
+	"path"
+	"strconv"
+
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+func (s *ZookeeperStore) keyLastFire(strategyId string) string {
+	return s.key("/cronFires/" + strategyId)
+}
+
+func (s *ZookeeperStore) keyFireClaims(strategyId string) string {
+	return s.key("/cronClaims/" + strategyId)
+}
+
+func (s *ZookeeperStore) keyFireClaim(strategyId string, fireTime int64) string {
+	return s.keyFireClaims(strategyId) + "/" + strconv.FormatInt(fireTime, 10)
+}
+
+func (s *ZookeeperStore) GetLastFireTime(strategyId string) (int64, error) {
+	data, _, err := s.conn.Get(s.keyLastFire(strategyId))
+	if err == zk.ErrNoNode {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(string(data), 10, 64)
+}
+
+func (s *ZookeeperStore) SetLastFireTime(strategyId string, fireTime int64) error {
+	key := s.keyLastFire(strategyId)
+	data := []byte(strconv.FormatInt(fireTime, 10))
+	_, err := s.conn.Set(key, data, -1)
+	if err == zk.ErrNoNode {
+		s.createPath(key, true)
+		_, err = s.conn.Set(key, data, -1)
+	}
+	return err
+}
+
+func (s *ZookeeperStore) ClaimFireTime(strategyId string, fireTime int64) error {
+	key := s.keyFireClaim(strategyId, fireTime)
+	_, err := s.conn.Create(key, nil, 0, s.acl)
+	if err == zk.ErrNoNode {
+		// make sure parent existed and recreate
+		s.createPath(path.Dir(key), true)
+		_, err = s.conn.Create(key, nil, 0, s.acl)
+	}
+	if err == zk.ErrNodeExists {
+		return store.AlreadyExist
+	}
+	return err
+}
+
+func (s *ZookeeperStore) RemoveFireClaims(strategyId string, before int64) error {
+	children, _, err := s.conn.Children(s.keyFireClaims(strategyId))
+	if err == zk.ErrNoNode {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		fireTime, err := strconv.ParseInt(child, 10, 64)
+		if err != nil || fireTime >= before {
+			continue
+		}
+		err = s.conn.Delete(s.keyFireClaim(strategyId, fireTime), -1)
+		if err != nil && err != zk.ErrNoNode {
+			return err
+		}
+	}
+	if before == math.MaxInt64 {
+		s.conn.Delete(s.keyFireClaims(strategyId), -1)
+	}
+	return nil
+}
+
+func (s *ZookeeperStore) removeFires(strategyId string) {
+	s.conn.Delete(s.keyLastFire(strategyId), -1)
+	s.RemoveFireClaims(strategyId, math.MaxInt64)
+}
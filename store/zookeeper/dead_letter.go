@@ -0,0 +1,85 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package zookeeper
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/utils"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+func (s *ZookeeperStore) keyDeadLetter(strategyId, taskId, id string) string {
+	return s.keyDeadLetters(strategyId, taskId) + "/" + id
+}
+
+func (s *ZookeeperStore) keyDeadLetters(strategyId, taskId string) string {
+	return s.keyTaskInfo(strategyId, taskId) + "/deadLetters"
+}
+
+func (s *ZookeeperStore) PutDeadLetter(letter *definition.DeadLetter) error {
+	if letter == nil {
+		return errors.New("dead letter should not be nil")
+	}
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	key := s.keyDeadLetter(letter.StrategyID, letter.TaskID, letter.ID)
+	_, err = s.conn.Create(key, data, 0, s.acl)
+	if err == zk.ErrNoNode {
+		// make sure parent existed and recreate
+		s.createPath(s.keyDeadLetters(letter.StrategyID, letter.TaskID), true)
+		_, err = s.conn.Create(key, data, 0, s.acl)
+	}
+	if err == zk.ErrNodeExists {
+		_, err = s.conn.Set(key, data, -1)
+	}
+	return err
+}
+
+func (s *ZookeeperStore) getDeadLetter(strategyId, taskId, id string) (*definition.DeadLetter, error) {
+	data, _, err := s.conn.Get(s.keyDeadLetter(strategyId, taskId, id))
+	if err == zk.ErrNoNode {
+		return nil, store.NotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	letter := &definition.DeadLetter{}
+	if err = json.Unmarshal(data, letter); err != nil {
+		return nil, err
+	}
+	return letter, nil
+}
+
+func (s *ZookeeperStore) GetDeadLetters(strategyId, taskId string) ([]*definition.DeadLetter, error) {
+	arr, err := s.getItems(s.keyDeadLetters(strategyId, taskId), func(id string) (interface{}, error) {
+		return s.getDeadLetter(strategyId, taskId, id)
+	})
+	if err == zk.ErrNoNode {
+		return []*definition.DeadLetter{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*definition.DeadLetter, len(arr))
+	for i := range arr {
+		result[i] = arr[i].(*definition.DeadLetter)
+	}
+	utils.SortDeadLetters(result)
+	return result, nil
+}
+
+func (s *ZookeeperStore) RemoveDeadLetter(strategyId, taskId, id string) error {
+	err := s.conn.Delete(s.keyDeadLetter(strategyId, taskId, id), -1)
+	if err == zk.ErrNoNode {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package zookeeper
+
+import (
+	"encoding/json"
+	"path"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// Temporary objects are stored as ephemeral nodes which will be removed by zookeeper after
+//	the session expired. So the ttl is actually determined by session timeout of connection.
+//	Persistent nodes, which are saved by older versions, are reported as NotExist so that their
+//	heartbeats are compared.
+
+// setEphemeralNode saves data into an ephemeral node owned by current session,
+//	persistent nodes or nodes left by expired sessions will be taken over.
+func (s *ZookeeperStore) setEphemeralNode(key string, obj interface{}) error {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	existed, stat, err := s.conn.Exists(key)
+	if err != nil {
+		return err
+	}
+	if existed {
+		if stat.EphemeralOwner == s.conn.SessionID() {
+			_, err = s.conn.Set(key, data, -1)
+			return err
+		}
+		if err = s.conn.Delete(key, -1); err != nil && err != zk.ErrNoNode {
+			return err
+		}
+	}
+	_, err = s.conn.Create(key, data, zk.FlagEphemeral, s.acl)
+	if err == zk.ErrNoNode {
+		// make sure parent existed and recreate
+		s.createPath(path.Dir(key), true)
+		_, err = s.conn.Create(key, data, zk.FlagEphemeral, s.acl)
+	}
+	return err
+}
+
+func (s *ZookeeperStore) alive(key string) (bool, error) {
+	existed, stat, err := s.conn.Exists(key)
+	if err != nil || !existed {
+		return false, err
+	}
+	if stat.EphemeralOwner == 0 {
+		return false, store.NotExist
+	}
+	return true, nil
+}
+
+func (s *ZookeeperStore) KeepSchedulerAlive(scheduler *definition.Scheduler, ttl time.Duration) error {
+	return s.setEphemeralNode(s.keyScheduler(scheduler.ID), scheduler)
+}
+
+func (s *ZookeeperStore) IsSchedulerAlive(id string) (bool, error) {
+	return s.alive(s.keyScheduler(id))
+}
+
+func (s *ZookeeperStore) KeepTaskRuntimeAlive(runtime *definition.TaskRuntime, ttl time.Duration) error {
+	return s.setEphemeralNode(s.keyTaskRuntime(runtime.StrategyID, runtime.TaskID, runtime.ID), runtime)
+}
+
+func (s *ZookeeperStore) IsTaskRuntimeAlive(strategyId, taskId, id string) (bool, error) {
+	return s.alive(s.keyTaskRuntime(strategyId, taskId, id))
+}
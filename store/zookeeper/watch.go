@@ -0,0 +1,219 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package zookeeper
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jasonjoo2010/goschedule/log"
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/jasonjoo2010/goschedule/utils"
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// Watches in zookeeper are one-shot and bound to single node, so a tree of watches is maintained:
+//	/strategies/{id}                                   data
+//	/tasks/{id}                                        data
+//	/schedulers/{id}                                   membership only
+//	/runtimes/{strategy}/{scheduler}                   data
+//	/runningInfo/{strategy}/{task}/assignments         data(config version)
+//	/runningInfo/{strategy}/{task}/assignments/{item}  data
+//	/runningInfo/{strategy}/{task}/runtimes/{id}       membership only
+
+func (s *ZookeeperStore) relativeParts(path string) []string {
+	return splitPath(strings.TrimPrefix(path, s.prefix))
+}
+
+// watchRule tells whether data and children should be watched on specific node
+func watchRule(parts []string) (data, children bool) {
+	if len(parts) == 0 {
+		return false, false
+	}
+	switch parts[0] {
+	case "strategies", "tasks":
+		return len(parts) == 2, len(parts) == 1
+	case "schedulers":
+		return false, len(parts) == 1
+	case "runtimes":
+		return len(parts) == 3, len(parts) < 3
+	case "runningInfo":
+		if len(parts) < 4 {
+			return false, true
+		}
+		switch parts[3] {
+		case "assignments":
+			return len(parts) <= 5, len(parts) == 4
+		case "runtimes":
+			return false, len(parts) == 4
+		}
+	}
+	return false, false
+}
+
+func (s *ZookeeperStore) parseEvent(path string) (store.Event, bool) {
+	parts := s.relativeParts(path)
+	switch {
+	case len(parts) == 2 && parts[0] == "tasks":
+		return store.Event{Kind: store.TaskEvent, TaskID: parts[1]}, true
+	case len(parts) == 2 && parts[0] == "strategies":
+		return store.Event{Kind: store.StrategyEvent, StrategyID: parts[1]}, true
+	case len(parts) == 2 && parts[0] == "schedulers":
+		return store.Event{Kind: store.SchedulerEvent, ID: parts[1]}, true
+	case len(parts) == 3 && parts[0] == "runtimes":
+		return store.Event{Kind: store.StrategyRuntimeEvent, StrategyID: parts[1], ID: parts[2]}, true
+	case len(parts) == 4 && parts[0] == "runningInfo" && parts[3] == "assignments":
+		return store.Event{Kind: store.TaskItemsConfigVersionEvent, StrategyID: parts[1], TaskID: parts[2]}, true
+	case len(parts) == 5 && parts[0] == "runningInfo" && parts[3] == "assignments":
+		return store.Event{Kind: store.TaskAssignmentEvent, StrategyID: parts[1], TaskID: parts[2], ID: parts[4]}, true
+	case len(parts) == 5 && parts[0] == "runningInfo" && parts[3] == "runtimes":
+		return store.Event{Kind: store.TaskRuntimeEvent, StrategyID: parts[1], TaskID: parts[2], ID: parts[4]}, true
+	}
+	return store.Event{}, false
+}
+
+func (s *ZookeeperStore) publish(path string) {
+	if e, ok := s.parseEvent(path); ok {
+		s.events.Publish(e)
+	}
+}
+
+// waitExists blocks until the node is created, return false if the store is closed
+func (s *ZookeeperStore) waitExists(conn *zk.Conn, path string) bool {
+	for !utils.ContextDone(s.ctx) {
+		existed, _, ch, err := conn.ExistsW(path)
+		if err != nil {
+			utils.DelayContext(s.ctx, time.Second)
+			continue
+		}
+		if existed {
+			return true
+		}
+		select {
+		case <-s.ctx.Done():
+			return false
+		case <-ch:
+		}
+	}
+	return false
+}
+
+// nodeRemoved returns true if the node was removed and not recreated
+func (s *ZookeeperStore) nodeRemoved(conn *zk.Conn, path string) bool {
+	existed, _, err := conn.Exists(path)
+	return err == nil && !existed
+}
+
+func (s *ZookeeperStore) watchData(conn *zk.Conn, path string) {
+	for !utils.ContextDone(s.ctx) {
+		_, _, ch, err := conn.GetW(path)
+		if err == zk.ErrNoNode {
+			return
+		}
+		if err != nil {
+			log.Warnf("Watch data of %s failed: %s", path, err.Error())
+			utils.DelayContext(s.ctx, time.Second)
+			continue
+		}
+		select {
+		case <-s.ctx.Done():
+			return
+		case ev := <-ch:
+			if ev.Type == zk.EventNodeDataChanged {
+				s.publish(path)
+			}
+			if ev.Type == zk.EventNodeDeleted && s.nodeRemoved(conn, path) {
+				return
+			}
+		}
+	}
+}
+
+func (s *ZookeeperStore) watchChildren(conn *zk.Conn, path string, root bool) {
+	var (
+		known map[string]bool
+		alive sync.Map
+	)
+	for !utils.ContextDone(s.ctx) {
+		children, _, ch, err := conn.ChildrenW(path)
+		if err == zk.ErrNoNode {
+			if !root || !s.waitExists(conn, path) {
+				return
+			}
+			continue
+		}
+		if err != nil {
+			log.Warnf("Watch children of %s failed: %s", path, err.Error())
+			utils.DelayContext(s.ctx, time.Second)
+			continue
+		}
+		current := make(map[string]bool, len(children))
+		for _, child := range children {
+			childPath := path + "/" + child
+			current[child] = true
+			if known != nil && !known[child] {
+				s.publish(childPath)
+			}
+			if _, loaded := alive.LoadOrStore(childPath, true); !loaded {
+				go func() {
+					defer alive.Delete(childPath)
+					s.watchTree(conn, childPath, false)
+				}()
+			}
+		}
+		for child := range known {
+			if !current[child] {
+				s.publish(path + "/" + child)
+			}
+		}
+		known = current
+		select {
+		case <-s.ctx.Done():
+			return
+		case ev := <-ch:
+			if ev.Type == zk.EventNodeDeleted && !root && s.nodeRemoved(conn, path) {
+				return
+			}
+		}
+	}
+}
+
+func (s *ZookeeperStore) watchTree(conn *zk.Conn, path string, root bool) {
+	data, children := watchRule(s.relativeParts(path))
+	wg := sync.WaitGroup{}
+	if data {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.watchData(conn, path)
+		}()
+	}
+	if children {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.watchChildren(conn, path, root)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *ZookeeperStore) Watch(ctx context.Context) (<-chan store.Event, error) {
+	s.watchOnce.Do(func() {
+		conn := s.conn
+		for _, path := range []string{
+			s.keyStrategies(),
+			s.keyTasks(),
+			s.keySchedulers(),
+			s.keyStrategyRuntimesBase(),
+			s.keyTaskInfoBase(),
+		} {
+			go s.watchTree(conn, path, true)
+		}
+	})
+	return s.events.Subscribe(ctx), nil
+}
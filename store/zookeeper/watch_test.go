@@ -0,0 +1,57 @@
+// Copyright 2020 The GoSchedule Authors. All rights reserved.
+// Use of this source code is governed by BSD
+// license that can be found in the LICENSE file.
+
+package zookeeper
+
+import (
+	"testing"
+
+	"github.com/jasonjoo2010/goschedule/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchRule(t *testing.T) {
+	rule := func(path string) []bool {
+		data, children := watchRule(splitPath(path))
+		return []bool{data, children}
+	}
+	assert.Equal(t, []bool{false, true}, rule("/strategies"))
+	assert.Equal(t, []bool{true, false}, rule("/strategies/s0"))
+	assert.Equal(t, []bool{false, true}, rule("/tasks"))
+	assert.Equal(t, []bool{true, false}, rule("/tasks/t0"))
+	assert.Equal(t, []bool{false, true}, rule("/schedulers"))
+	assert.Equal(t, []bool{false, false}, rule("/schedulers/a$b$1"))
+	assert.Equal(t, []bool{false, true}, rule("/runtimes"))
+	assert.Equal(t, []bool{false, true}, rule("/runtimes/s0"))
+	assert.Equal(t, []bool{true, false}, rule("/runtimes/s0/a$b$1"))
+	assert.Equal(t, []bool{false, true}, rule("/runningInfo/s0/t0"))
+	assert.Equal(t, []bool{true, true}, rule("/runningInfo/s0/t0/assignments"))
+	assert.Equal(t, []bool{true, false}, rule("/runningInfo/s0/t0/assignments/p0"))
+	assert.Equal(t, []bool{false, true}, rule("/runningInfo/s0/t0/runtimes"))
+	assert.Equal(t, []bool{false, false}, rule("/runningInfo/s0/t0/runtimes/r0"))
+	assert.Equal(t, []bool{false, false}, rule("/time0000000001"))
+}
+
+func TestParseEvent(t *testing.T) {
+	s := &ZookeeperStore{prefix: "/schedule/demo"}
+
+	e, ok := s.parseEvent("/schedule/demo/strategies/s0")
+	assert.True(t, ok)
+	assert.Equal(t, store.Event{Kind: store.StrategyEvent, StrategyID: "s0"}, e)
+
+	e, ok = s.parseEvent("/schedule/demo/runningInfo/s0/t0/assignments")
+	assert.True(t, ok)
+	assert.Equal(t, store.Event{Kind: store.TaskItemsConfigVersionEvent, StrategyID: "s0", TaskID: "t0"}, e)
+
+	e, ok = s.parseEvent("/schedule/demo/runningInfo/s0/t0/assignments/p0")
+	assert.True(t, ok)
+	assert.Equal(t, store.Event{Kind: store.TaskAssignmentEvent, StrategyID: "s0", TaskID: "t0", ID: "p0"}, e)
+
+	e, ok = s.parseEvent("/schedule/demo/runningInfo/s0/t0/runtimes/r0")
+	assert.True(t, ok)
+	assert.Equal(t, store.Event{Kind: store.TaskRuntimeEvent, StrategyID: "s0", TaskID: "t0", ID: "r0"}, e)
+
+	_, ok = s.parseEvent("/schedule/demo/seq:0000000001")
+	assert.False(t, ok)
+}
@@ -5,9 +5,11 @@
 package zookeeper
 
 import (
+	"context"
 	"encoding/json"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jasonjoo2010/goschedule/definition"
@@ -32,6 +34,11 @@ type ZookeeperStore struct {
 	conn      *zk.Conn
 	acl       []zk.ACL
 	timeDelta time.Duration
+
+	ctx       context.Context
+	ctxCancel context.CancelFunc
+	events    *store.Broadcaster
+	watchOnce sync.Once
 }
 
 func New(basePath, addr string, port int) *ZookeeperStore {
@@ -44,7 +51,9 @@ func New(basePath, addr string, port int) *ZookeeperStore {
 func NewFromConfig(config *ZookeeperStoreConfig) *ZookeeperStore {
 	s := &ZookeeperStore{
 		prefix: strings.TrimRight(config.BasePath, "/"),
+		events: store.NewBroadcaster(),
 	}
+	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
 
 	conn, eventC, err := zk.Connect(config.Addrs, 60*time.Second,
 		zk.WithLogger(&logger{}),
@@ -109,6 +118,8 @@ func (s *ZookeeperStore) Sequence() (uint64, error) {
 }
 
 func (s *ZookeeperStore) Close() error {
+	s.ctxCancel()
+	s.events.Close()
 	s.conn.Close()
 	s.conn = nil
 	return nil
@@ -439,7 +450,7 @@ func (s *ZookeeperStore) IncreaseTaskItemsConfigVersion(strategyId, taskId strin
 
 func (s *ZookeeperStore) GetTaskAssignment(strategyId, taskId, itemId string) (*definition.TaskAssignment, error) {
 	key := s.keyTaskAssignment(strategyId, taskId, itemId)
-	data, _, err := s.conn.Get(key)
+	data, stat, err := s.conn.Get(key)
 	if err == zk.ErrNoNode {
 		return nil, store.NotExist
 	}
@@ -451,6 +462,7 @@ func (s *ZookeeperStore) GetTaskAssignment(strategyId, taskId, itemId string) (*
 	if err != nil {
 		return nil, err
 	}
+	runtime.Revision = stat.Mzxid
 	return runtime, nil
 }
 func (s *ZookeeperStore) GetTaskAssignments(strategyId, taskId string) ([]*definition.TaskAssignment, error) {
@@ -493,6 +505,15 @@ func (s *ZookeeperStore) SetTaskAssignment(assignment *definition.TaskAssignment
 	}
 	return err
 }
+func (s *ZookeeperStore) CompareAndSetTaskAssignment(assignment *definition.TaskAssignment) error {
+	key := s.keyTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID)
+	revision, err := s.compareAndSet(key, assignment, assignment.Revision, 0)
+	if err != nil {
+		return err
+	}
+	assignment.Revision = revision
+	return nil
+}
 func (s *ZookeeperStore) RemoveTaskAssignment(strategyId, taskId, itemId string) error {
 	err := s.conn.Delete(s.keyTaskAssignment(strategyId, taskId, itemId), -1)
 	if err == zk.ErrNoNode {
@@ -559,6 +580,7 @@ func (s *ZookeeperStore) UpdateStrategy(strategy *definition.Strategy) error {
 }
 
 func (s *ZookeeperStore) RemoveStrategy(id string) error {
+	s.removeFires(id)
 	err := s.conn.Delete(s.keyStrategy(id), -1)
 	if err == zk.ErrNoNode {
 		return store.NotExist
@@ -570,7 +592,7 @@ func (s *ZookeeperStore) RemoveStrategy(id string) error {
 
 func (s *ZookeeperStore) GetStrategyRuntime(strategyId, schedulerId string) (*definition.StrategyRuntime, error) {
 	key := s.keyStrategyRuntime(strategyId, schedulerId)
-	data, _, err := s.conn.Get(key)
+	data, stat, err := s.conn.Get(key)
 	if err == zk.ErrNoNode {
 		return nil, store.NotExist
 	}
@@ -582,6 +604,7 @@ func (s *ZookeeperStore) GetStrategyRuntime(strategyId, schedulerId string) (*de
 	if err != nil {
 		return nil, err
 	}
+	runtime.Revision = stat.Mzxid
 	return runtime, nil
 }
 
@@ -627,6 +650,16 @@ func (s *ZookeeperStore) SetStrategyRuntime(runtime *definition.StrategyRuntime)
 	return err
 }
 
+func (s *ZookeeperStore) CompareAndSetStrategyRuntime(runtime *definition.StrategyRuntime) error {
+	key := s.keyStrategyRuntime(runtime.StrategyID, runtime.SchedulerID)
+	revision, err := s.compareAndSet(key, runtime, runtime.Revision, zk.FlagEphemeral)
+	if err != nil {
+		return err
+	}
+	runtime.Revision = revision
+	return nil
+}
+
 func (s *ZookeeperStore) RemoveStrategyRuntime(strategyId, schedulerId string) error {
 	err := s.conn.Delete(s.keyStrategyRuntime(strategyId, schedulerId), -1)
 	if err == zk.ErrNoNode {
@@ -5,6 +5,7 @@
 package storetest
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -484,8 +485,16 @@ func DoTestDump(t *testing.T, s store.Store) {
 	}
 	s.RegisterScheduler(scheduler)
 
+	task := &definition.Task{
+		ID: "demo-task",
+	}
+	s.CreateTask(task)
+
+	// assignments are dumped under task strategies bound to existing tasks
 	strategy := &definition.Strategy{
-		ID: "demo-strategy",
+		ID:   "demo-strategy",
+		Kind: definition.TaskKind,
+		Bind: task.ID,
 	}
 	s.CreateStrategy(strategy)
 
@@ -496,13 +505,456 @@ func DoTestDump(t *testing.T, s store.Store) {
 	}
 	s.SetStrategyRuntime(runtime)
 
+	assignment := &definition.TaskAssignment{
+		StrategyID: "demo-strategy",
+		TaskID:     "demo-task",
+		ItemID:     "demo-item",
+		Weight:     76213,
+	}
+	s.SetTaskAssignment(assignment)
+
 	str := s.Dump()
 	fmt.Println(str)
 	assert.Contains(t, str, "demo-scheduler")
 	assert.Contains(t, str, "demo-strategy")
 	assert.Contains(t, str, "93944")
+	assert.Contains(t, str, "76213")
 
+	s.RemoveTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID)
 	s.RemoveStrategyRuntime(runtime.StrategyID, runtime.SchedulerID)
 	s.RemoveStrategy(strategy.ID)
+	s.RemoveTask(task.ID)
+	s.UnregisterScheduler(scheduler.ID)
+}
+
+func waitEvent(t *testing.T, ch <-chan store.Event, kind store.EventKind) store.Event {
+	timeout := time.NewTimer(5 * time.Second)
+	defer timeout.Stop()
+	for {
+		select {
+		case e, ok := <-ch:
+			if !ok {
+				assert.Fail(t, "Event channel closed unexpectedly")
+				return store.Event{}
+			}
+			if e.Kind == kind {
+				return e
+			}
+		case <-timeout.C:
+			assert.Fail(t, "Wait event timeout", kind.String())
+			return store.Event{}
+		}
+	}
+}
+
+func DoTestWatch(t *testing.T, s store.Store) {
+	watcher, ok := store.AsWatcher(s)
+	if !assert.True(t, ok, "Store should implement Watcher") {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ch, err := watcher.Watch(ctx)
+	assert.Nil(t, err)
+	// give some time to underlying watches to be ready
+	time.Sleep(200 * time.Millisecond)
+
+	task := &definition.Task{ID: "watch-task"}
+	s.CreateTask(task)
+	e := waitEvent(t, ch, store.TaskEvent)
+	assert.Equal(t, task.ID, e.TaskID)
+
+	strategy := &definition.Strategy{ID: "watch-strategy"}
+	s.CreateStrategy(strategy)
+	e = waitEvent(t, ch, store.StrategyEvent)
+	assert.Equal(t, strategy.ID, e.StrategyID)
+
+	scheduler := &definition.Scheduler{ID: "watch-scheduler"}
+	s.RegisterScheduler(scheduler)
+	e = waitEvent(t, ch, store.SchedulerEvent)
+	assert.Equal(t, scheduler.ID, e.ID)
+
+	runtime := &definition.StrategyRuntime{
+		StrategyID:  strategy.ID,
+		SchedulerID: scheduler.ID,
+	}
+	s.SetStrategyRuntime(runtime)
+	e = waitEvent(t, ch, store.StrategyRuntimeEvent)
+	assert.Equal(t, strategy.ID, e.StrategyID)
+	assert.Equal(t, scheduler.ID, e.ID)
+
+	taskRuntime := &definition.TaskRuntime{
+		ID:         "watch-runtime",
+		StrategyID: strategy.ID,
+		TaskID:     task.ID,
+	}
+	s.SetTaskRuntime(taskRuntime)
+	e = waitEvent(t, ch, store.TaskRuntimeEvent)
+	assert.Equal(t, strategy.ID, e.StrategyID)
+	assert.Equal(t, task.ID, e.TaskID)
+	assert.Equal(t, taskRuntime.ID, e.ID)
+
+	assignment := &definition.TaskAssignment{
+		StrategyID: strategy.ID,
+		TaskID:     task.ID,
+		ItemID:     "watch-item",
+	}
+	s.SetTaskAssignment(assignment)
+	e = waitEvent(t, ch, store.TaskAssignmentEvent)
+	assert.Equal(t, strategy.ID, e.StrategyID)
+	assert.Equal(t, task.ID, e.TaskID)
+	assert.Equal(t, assignment.ItemID, e.ID)
+
+	s.IncreaseTaskItemsConfigVersion(strategy.ID, task.ID)
+	e = waitEvent(t, ch, store.TaskItemsConfigVersionEvent)
+	assert.Equal(t, strategy.ID, e.StrategyID)
+	assert.Equal(t, task.ID, e.TaskID)
+
+	s.RemoveTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID)
+	s.RemoveTaskRuntime(taskRuntime.StrategyID, taskRuntime.TaskID, taskRuntime.ID)
+	s.RemoveStrategyRuntime(runtime.StrategyID, runtime.SchedulerID)
+	s.UnregisterScheduler(scheduler.ID)
+	s.RemoveStrategy(strategy.ID)
+	s.RemoveTask(task.ID)
+
+	// closed after cancelled
+	cancel()
+	timeout := time.NewTimer(5 * time.Second)
+	defer timeout.Stop()
+	for {
+		select {
+		case _, ok := <-ch:
+			if ok {
+				continue
+			}
+			return
+		case <-timeout.C:
+			assert.Fail(t, "Event channel should be closed after cancelled")
+			return
+		}
+	}
+}
+
+func DoTestLiveness(t *testing.T, s store.Store) {
+	scheduler := &definition.Scheduler{
+		ID: "demo-scheduler-liveness",
+	}
+	runtime := &definition.TaskRuntime{
+		ID:         "demo-runtime-liveness",
+		StrategyID: "s0",
+		TaskID:     "t0",
+	}
+
+	assert.Nil(t, store.KeepSchedulerAlive(s, scheduler, 10*time.Second))
+	assert.True(t, scheduler.LastHeartbeat > 0)
+	assert.True(t, store.IsSchedulerAlive(s, scheduler, 10*time.Second))
+	// refreshing
+	assert.Nil(t, store.KeepSchedulerAlive(s, scheduler, 10*time.Second))
+	got, err := s.GetScheduler(scheduler.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, scheduler.ID, got.ID)
+	assert.True(t, store.IsSchedulerAlive(s, got, 10*time.Second))
+
+	assert.Nil(t, store.KeepTaskRuntimeAlive(s, runtime, 10*time.Second))
+	assert.True(t, runtime.LastHeartbeat > 0)
+	assert.Nil(t, store.KeepTaskRuntimeAlive(s, runtime, 10*time.Second))
+	gotRuntime, err := s.GetTaskRuntime(runtime.StrategyID, runtime.TaskID, runtime.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, runtime.ID, gotRuntime.ID)
+	assert.True(t, store.IsTaskRuntimeAlive(s, gotRuntime, 10*time.Second))
+
 	s.UnregisterScheduler(scheduler.ID)
+	s.RemoveTaskRuntime(runtime.StrategyID, runtime.TaskID, runtime.ID)
+	_, err = s.GetScheduler(scheduler.ID)
+	assert.Equal(t, store.NotExist, err)
+	_, err = s.GetTaskRuntime(runtime.StrategyID, runtime.TaskID, runtime.ID)
+	assert.Equal(t, store.NotExist, err)
+	if l, ok := store.AsLiveness(s); ok {
+		alive, err := l.IsSchedulerAlive(scheduler.ID)
+		assert.Nil(t, err)
+		assert.False(t, alive)
+		alive, err = l.IsTaskRuntimeAlive(runtime.StrategyID, runtime.TaskID, runtime.ID)
+		assert.Nil(t, err)
+		assert.False(t, alive)
+	}
+}
+
+// DoTestLivenessUpgrade verifies objects saved without liveness, eg. by nodes of older versions during
+//	a rolling upgrade, are decided by their heartbeats.
+func DoTestLivenessUpgrade(t *testing.T, s store.Store) {
+	scheduler := &definition.Scheduler{
+		ID:            "demo-scheduler-upgrade",
+		LastHeartbeat: s.Time(),
+	}
+	runtime := &definition.TaskRuntime{
+		ID:            "demo-runtime-upgrade",
+		StrategyID:    "s0",
+		TaskID:        "t0",
+		LastHeartbeat: s.Time(),
+	}
+	defer s.UnregisterScheduler(scheduler.ID)
+	defer s.RemoveTaskRuntime(runtime.StrategyID, runtime.TaskID, runtime.ID)
+
+	assert.Nil(t, s.RegisterScheduler(scheduler))
+	assert.Nil(t, s.SetTaskRuntime(runtime))
+	assert.True(t, store.IsSchedulerAlive(s, scheduler, 10*time.Second))
+	assert.True(t, store.IsTaskRuntimeAlive(s, runtime, 10*time.Second))
+
+	// stopped heartbeats
+	runtime.LastHeartbeat = s.Time() - time.Minute.Milliseconds()
+	assert.Nil(t, s.SetTaskRuntime(runtime))
+	assert.False(t, store.IsTaskRuntimeAlive(s, runtime, 10*time.Second))
+}
+
+// DoTestLivenessExpiration verifies objects expire without refreshing.
+//	It's not suitable for storage whose expiration can't be specified per object, eg. zookeeper.
+func DoTestLivenessExpiration(t *testing.T, s store.Store) {
+	scheduler := &definition.Scheduler{
+		ID: "demo-scheduler-expiration",
+	}
+	runtime := &definition.TaskRuntime{
+		ID:         "demo-runtime-expiration",
+		StrategyID: "s0",
+		TaskID:     "t0",
+	}
+	defer s.UnregisterScheduler(scheduler.ID)
+	defer s.RemoveTaskRuntime(runtime.StrategyID, runtime.TaskID, runtime.ID)
+
+	assert.Nil(t, store.KeepSchedulerAlive(s, scheduler, time.Second))
+	assert.Nil(t, store.KeepTaskRuntimeAlive(s, runtime, time.Second))
+	assert.True(t, store.IsSchedulerAlive(s, scheduler, time.Second))
+	assert.True(t, store.IsTaskRuntimeAlive(s, runtime, time.Second))
+
+	time.Sleep(3 * time.Second)
+	assert.False(t, store.IsSchedulerAlive(s, scheduler, time.Second))
+	assert.False(t, store.IsTaskRuntimeAlive(s, runtime, time.Second))
+}
+
+// DoTestLivenessTTL verifies objects kept alive are dead once expired even if their heartbeats are fresh.
+//	It's only suitable for storage implementing Liveness with per object expiration.
+func DoTestLivenessTTL(t *testing.T, s store.Store) {
+	scheduler := &definition.Scheduler{
+		ID: "demo-scheduler-ttl",
+	}
+	runtime := &definition.TaskRuntime{
+		ID:         "demo-runtime-ttl",
+		StrategyID: "s0",
+		TaskID:     "t0",
+	}
+	defer s.UnregisterScheduler(scheduler.ID)
+	defer s.RemoveTaskRuntime(runtime.StrategyID, runtime.TaskID, runtime.ID)
+
+	assert.Nil(t, store.KeepSchedulerAlive(s, scheduler, time.Second))
+	assert.Nil(t, store.KeepTaskRuntimeAlive(s, runtime, time.Second))
+
+	time.Sleep(3 * time.Second)
+	// heartbeats are still fresh comparing to a long timeout
+	assert.False(t, store.IsSchedulerAlive(s, scheduler, time.Minute))
+	assert.False(t, store.IsTaskRuntimeAlive(s, runtime, time.Minute))
+	l, ok := store.AsLiveness(s)
+	assert.True(t, ok)
+	alive, err := l.IsSchedulerAlive(scheduler.ID)
+	assert.Nil(t, err)
+	assert.False(t, alive)
+	alive, err = l.IsTaskRuntimeAlive(runtime.StrategyID, runtime.TaskID, runtime.ID)
+	assert.Nil(t, err)
+	assert.False(t, alive)
+}
+
+func DoTestCompareAndSet(t *testing.T, s store.Store) {
+	assignment := &definition.TaskAssignment{
+		StrategyID: "strategy-cas",
+		TaskID:     "task-cas",
+		ItemID:     "a",
+		RuntimeID:  "r0",
+	}
+	defer s.RemoveTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID)
+
+	// create
+	assert.Nil(t, s.CompareAndSetTaskAssignment(assignment))
+	assert.True(t, assignment.Revision > 0)
+	fetched, err := s.GetTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID)
+	assert.Nil(t, err)
+	assert.Equal(t, assignment.Revision, fetched.Revision)
+
+	// recreate
+	recreated := *assignment
+	recreated.Revision = 0
+	assert.Equal(t, store.Conflict, s.CompareAndSetTaskAssignment(&recreated))
+
+	// concurrent updating
+	stale := *fetched
+	fetched.RequestedRuntimeID = "r1"
+	assert.Nil(t, s.CompareAndSetTaskAssignment(fetched))
+	assert.NotEqual(t, stale.Revision, fetched.Revision)
+	stale.RuntimeID = "r2"
+	assert.Equal(t, store.Conflict, s.CompareAndSetTaskAssignment(&stale))
+	got, _ := s.GetTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID)
+	assert.Equal(t, "r0", got.RuntimeID)
+	assert.Equal(t, "r1", got.RequestedRuntimeID)
+	assert.Equal(t, fetched.Revision, got.Revision)
+
+	// plain setting changes revision too
+	assert.Nil(t, s.SetTaskAssignment(got))
+	assert.Equal(t, store.Conflict, s.CompareAndSetTaskAssignment(got))
+	list, err := s.GetTaskAssignments(assignment.StrategyID, assignment.TaskID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(list))
+	assert.Nil(t, s.CompareAndSetTaskAssignment(list[0]))
+
+	// removed
+	s.RemoveTaskAssignment(assignment.StrategyID, assignment.TaskID, assignment.ItemID)
+	assert.Equal(t, store.Conflict, s.CompareAndSetTaskAssignment(list[0]))
+	list[0].Revision = 0
+	assert.Nil(t, s.CompareAndSetTaskAssignment(list[0]))
+
+	runtime := &definition.StrategyRuntime{
+		StrategyID:  "strategy-cas",
+		SchedulerID: "scheduler1",
+	}
+	defer s.RemoveStrategyRuntime(runtime.StrategyID, runtime.SchedulerID)
+
+	assert.Nil(t, s.CompareAndSetStrategyRuntime(runtime))
+	assert.True(t, runtime.Revision > 0)
+	recreatedRuntime := *runtime
+	recreatedRuntime.Revision = 0
+	assert.Equal(t, store.Conflict, s.CompareAndSetStrategyRuntime(&recreatedRuntime))
+
+	fetchedRuntime, err := s.GetStrategyRuntime(runtime.StrategyID, runtime.SchedulerID)
+	assert.Nil(t, err)
+	assert.Equal(t, runtime.Revision, fetchedRuntime.Revision)
+	staleRuntime := *fetchedRuntime
+	fetchedRuntime.RequestedNum = 2
+	assert.Nil(t, s.CompareAndSetStrategyRuntime(fetchedRuntime))
+	staleRuntime.RequestedNum = 3
+	assert.Equal(t, store.Conflict, s.CompareAndSetStrategyRuntime(&staleRuntime))
+
+	runtimes, err := s.GetStrategyRuntimes(runtime.StrategyID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(runtimes))
+	assert.Equal(t, 2, runtimes[0].RequestedNum)
+	assert.Equal(t, fetchedRuntime.Revision, runtimes[0].Revision)
+
+	assert.Nil(t, s.SetStrategyRuntime(runtimes[0]))
+	assert.Equal(t, store.Conflict, s.CompareAndSetStrategyRuntime(runtimes[0]))
+}
+
+func DoTestDeadLetter(t *testing.T, s store.Store) {
+	deadLetters, ok := store.AsDeadLetterStore(s)
+	if !ok {
+		t.Skip("dead letters are not supported by ", s.Name())
+	}
+	strategyId := "strategy-dead"
+	taskId := "task-dead"
+	list, err := deadLetters.GetDeadLetters(strategyId, taskId)
+	assert.Nil(t, err)
+	assert.Empty(t, list)
+
+	for i := 3; i > 0; i-- {
+		letter := &definition.DeadLetter{
+			ID:         fmt.Sprintf("host$name$letter$%010d", i),
+			StrategyID: strategyId,
+			TaskID:     taskId,
+			RuntimeID:  "r0",
+			Attempts:   3,
+			Reason:     "failed",
+			Createtime: s.Time(),
+			Data:       fmt.Sprint(i),
+			Item:       i,
+		}
+		assert.Nil(t, deadLetters.PutDeadLetter(letter))
+	}
+	// other task
+	assert.Nil(t, deadLetters.PutDeadLetter(&definition.DeadLetter{
+		ID:         "host$name$letter$0000000004",
+		StrategyID: strategyId,
+		TaskID:     "task-dead-other",
+	}))
+	defer deadLetters.RemoveDeadLetter(strategyId, "task-dead-other", "host$name$letter$0000000004")
+
+	list, err = deadLetters.GetDeadLetters(strategyId, taskId)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(list))
+	for i, letter := range list {
+		assert.Equal(t, fmt.Sprint(i+1), letter.Data)
+		assert.Equal(t, 3, letter.Attempts)
+		assert.Equal(t, "failed", letter.Reason)
+		assert.Equal(t, "r0", letter.RuntimeID)
+		assert.Nil(t, letter.Item)
+	}
+
+	assert.Nil(t, deadLetters.RemoveDeadLetter(strategyId, taskId, list[1].ID))
+	assert.Nil(t, deadLetters.RemoveDeadLetter(strategyId, taskId, list[1].ID))
+	list, err = deadLetters.GetDeadLetters(strategyId, taskId)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(list))
+	assert.Equal(t, "1", list[0].Data)
+	assert.Equal(t, "3", list[1].Data)
+
+	for _, letter := range list {
+		assert.Nil(t, deadLetters.RemoveDeadLetter(strategyId, taskId, letter.ID))
+	}
+	list, err = deadLetters.GetDeadLetters(strategyId, taskId)
+	assert.Nil(t, err)
+	assert.Empty(t, list)
+}
+
+func DoTestCronStore(t *testing.T, s store.Store) {
+	crons, ok := store.AsCronStore(s)
+	if !ok {
+		t.Skip("cron fires are not supported by ", s.Name())
+	}
+	strategy := &definition.Strategy{
+		ID:   "strategy-cron",
+		Kind: definition.FuncKind,
+	}
+	s.RemoveStrategy(strategy.ID)
+	assert.Equal(t, int64(0), store.GetLastFireTime(s, strategy.ID))
+
+	assert.Nil(t, s.CreateStrategy(strategy))
+	assert.Nil(t, crons.SetLastFireTime(strategy.ID, 1000))
+	fireTime, err := crons.GetLastFireTime(strategy.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1000), fireTime)
+	assert.Nil(t, store.SetLastFireTime(s, strategy.ID, 2000))
+	assert.Equal(t, int64(2000), store.GetLastFireTime(s, strategy.ID))
+	assert.Equal(t, int64(0), store.GetLastFireTime(s, "strategy-cron-other"))
+
+	// removed together with strategy
+	assert.Nil(t, s.RemoveStrategy(strategy.ID))
+	assert.Equal(t, int64(0), store.GetLastFireTime(s, strategy.ID))
+}
+
+func DoTestFireClaim(t *testing.T, s store.Store) {
+	claims, ok := store.AsFireClaimStore(s)
+	if !ok {
+		t.Skip("fire claims are not supported by ", s.Name())
+	}
+	strategy := &definition.Strategy{
+		ID:   "strategy-claim",
+		Kind: definition.FuncKind,
+	}
+	s.RemoveStrategy(strategy.ID)
+	assert.Nil(t, s.CreateStrategy(strategy))
+
+	assert.Nil(t, claims.ClaimFireTime(strategy.ID, 1000))
+	assert.Equal(t, store.AlreadyExist, claims.ClaimFireTime(strategy.ID, 1000))
+	assert.Nil(t, claims.ClaimFireTime(strategy.ID, 2000))
+	assert.Nil(t, claims.ClaimFireTime(strategy.ID, 3000))
+	assert.Nil(t, claims.ClaimFireTime("strategy-claim-other", 1000))
+
+	// only claims before are removed
+	assert.Nil(t, claims.RemoveFireClaims(strategy.ID, 3000))
+	assert.Nil(t, claims.ClaimFireTime(strategy.ID, 1000))
+	assert.Nil(t, claims.ClaimFireTime(strategy.ID, 2000))
+	assert.Equal(t, store.AlreadyExist, claims.ClaimFireTime(strategy.ID, 3000))
+	assert.Nil(t, claims.RemoveFireClaims("strategy-claim-none", 3000))
+
+	// other strategies are not affected
+	assert.Equal(t, store.AlreadyExist, claims.ClaimFireTime("strategy-claim-other", 1000))
+	assert.Nil(t, claims.RemoveFireClaims("strategy-claim-other", 2000))
+
+	// removed together with strategy
+	assert.Nil(t, s.RemoveStrategy(strategy.ID))
+	assert.Nil(t, claims.ClaimFireTime(strategy.ID, 3000))
+	assert.Nil(t, claims.RemoveFireClaims(strategy.ID, 4000))
 }
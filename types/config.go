@@ -24,4 +24,22 @@ type ScheduleConfig struct {
 	// ShutdownTimeout indicates whether to wait a maxmum time when closing
 	//	Default to wait with no limitation
 	ShutdownTimeout time.Duration
+
+	// Labels of the scheduler matched by selectors of strategies, eg. {"zone": "us-east-1a"}
+	Labels map[string]string
+
+	// Weight represents the capacity of the scheduler comparing to others, workers of a strategy
+	//	are assigned proportionally to weights of schedulers.
+	//	Default to 1
+	Weight int
+
+	// MaxWorkers limits total workers over all strategies running on the scheduler.
+	//	Default to no limitation
+	MaxWorkers int
+
+	// FencingMargin represents how long before DeathTimeout the manager stops all its workers
+	//	when heartbeats keep failing, so that work taken over by others is not running here as well.
+	//	Workers are resumed when heartbeats succeed again.
+	//	Default to 2 * HeartbeatInterval but less than DeathTimeout, negative value disables self-fencing
+	FencingMargin time.Duration
 }
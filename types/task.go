@@ -1,6 +1,10 @@
 package types
 
-import "github.com/jasonjoo2010/goschedule/definition"
+import (
+	"context"
+
+	"github.com/jasonjoo2010/goschedule/definition"
+)
 
 // TaskBase defines the task used in scheduling.
 type TaskBase interface {
@@ -9,6 +13,8 @@ type TaskBase interface {
 	//	parameter, items, eachFetchNum are from definition of task
 	//	ownSign is from name of strategy bond in the form of 'name$ownsign'
 	//	It's a kind of relation to strategy but generally task doesn't care about strategy in user's view.
+	//	Token of each item is the fencing token of ownership, it can be stored with the work so
+	//	stale work of previous owners can be rejected by comparing tokens.
 	Select(parameter, ownSign string, items []definition.TaskItem, eachFetchNum int) []interface{}
 }
 
@@ -26,6 +32,74 @@ type TaskBatch interface {
 	Execute(tasks []interface{}, ownSign string) bool
 }
 
+// TaskBaseContext is the context-aware variant of TaskBase.
+//	ctx is cancelled when the worker is stopping or the timeout defined in task is reached,
+//	long-running calls should return as soon as possible after that.
+type TaskBaseContext interface {
+	SelectContext(ctx context.Context, parameter, ownSign string, items []definition.TaskItem, eachFetchNum int) []interface{}
+}
+
+// TaskSingleContext is the context-aware variant of TaskSingle
+type TaskSingleContext interface {
+	TaskBaseContext
+	// return true if succ false otherwise, but things will still go on
+	ExecuteContext(ctx context.Context, task interface{}, ownSign string) bool
+}
+
+// TaskBatchContext is the context-aware variant of TaskBatch
+type TaskBatchContext interface {
+	TaskBaseContext
+	// return true if succ false otherwise, but things will still go on
+	ExecuteContext(ctx context.Context, tasks []interface{}, ownSign string) bool
+}
+
+type taskSingleAdapter struct {
+	TaskSingle
+}
+
+func (a taskSingleAdapter) SelectContext(ctx context.Context, parameter, ownSign string, items []definition.TaskItem, eachFetchNum int) []interface{} {
+	return a.Select(parameter, ownSign, items, eachFetchNum)
+}
+
+func (a taskSingleAdapter) ExecuteContext(ctx context.Context, task interface{}, ownSign string) bool {
+	return a.Execute(task, ownSign)
+}
+
+type taskBatchAdapter struct {
+	TaskBatch
+}
+
+func (a taskBatchAdapter) SelectContext(ctx context.Context, parameter, ownSign string, items []definition.TaskItem, eachFetchNum int) []interface{} {
+	return a.Select(parameter, ownSign, items, eachFetchNum)
+}
+
+func (a taskBatchAdapter) ExecuteContext(ctx context.Context, tasks []interface{}, ownSign string) bool {
+	return a.Execute(tasks, ownSign)
+}
+
+// SingleContext adapts a TaskSingle into TaskSingleContext, ctx is ignored
+func SingleContext(task TaskSingle) TaskSingleContext {
+	if t, ok := task.(TaskSingleContext); ok {
+		return t
+	}
+	return taskSingleAdapter{task}
+}
+
+// BatchContext adapts a TaskBatch into TaskBatchContext, ctx is ignored
+func BatchContext(task TaskBatch) TaskBatchContext {
+	if t, ok := task.(TaskBatchContext); ok {
+		return t
+	}
+	return taskBatchAdapter{task}
+}
+
+// TaskItemResolver can be implemented optionally by tasks to tell which task item the data was
+//	selected for. Then failed data is retried only while that task item is still owned, otherwise
+//	it's retried while any of task items owned when it failed is still owned.
+type TaskItemResolver interface {
+	ItemOf(task interface{}) string
+}
+
 type TaskComparable interface {
 	Less(a, b interface{}) bool
 }
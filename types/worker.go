@@ -1,10 +1,23 @@
 package types
 
+import "context"
+
 // FuncInterface defines the func used in scheduling.
 //	Generally it's better keeping invocation fast but if it costs much more time
 //	maybe you should carefully set a suitable timeout during shutdown.
 type FuncInterface func(strategyId, parameter string)
 
+// FuncContextInterface is the context-aware variant of FuncInterface.
+//	ctx is cancelled when the worker is stopping or the timeout defined in strategy is reached.
+type FuncContextInterface func(ctx context.Context, strategyId, parameter string)
+
+// WithContext adapts the func into FuncContextInterface, ctx is ignored
+func (fn FuncInterface) WithContext() FuncContextInterface {
+	return func(ctx context.Context, strategyId, parameter string) {
+		fn(strategyId, parameter)
+	}
+}
+
 // Worker manages data of scheduling for bond strategy
 type Worker interface {
 	Start(strategyId, parameter string) error
@@ -5,49 +5,40 @@
 package utils
 
 import (
-	"math/rand"
+	"crypto/md5"
+	"encoding/binary"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/jasonjoo2010/goschedule/definition"
 )
 
-// shuffleRange shuffle specific range of slice
-//	from(inclusive) to(exclusive)
-func shuffleRange(runtimes []*definition.StrategyRuntime, from, to int) {
-	if from >= to || to-from < 2 {
-		return
-	}
-	N := to - from
-	rand.Seed(time.Now().UnixNano())
-	rand.Shuffle(N, func(i, j int) {
-		i += from
-		j += from
-		runtimes[i], runtimes[j] = runtimes[j], runtimes[i]
-	})
-}
-
-// SortRuntimes sort the runtimes based on requestedNum in descending order
-//	A shuffle will be made between same num for better ditribution of workers
-func SortRuntimesWithShuffle(runtimes []*definition.StrategyRuntime) {
+// SortRuntimes sorts the runtimes based on requestedNum in descending order then by scheduler id
+//	rotated by seed, eg. id of the strategy. The order is always the same for the same runtimes and
+//	seed, while strategies start from different schedulers to balance workers among strategies.
+func SortRuntimes(runtimes []*definition.StrategyRuntime, seed string) {
 	if len(runtimes) <= 1 {
 		return
 	}
+	ids := make([]string, len(runtimes))
+	for i, runtime := range runtimes {
+		ids[i] = runtime.SchedulerID
+	}
+	sort.Strings(ids)
+	sum := md5.Sum([]byte(seed))
+	offset := int(binary.BigEndian.Uint32(sum[:4]) % uint32(len(ids)))
+	rank := make(map[string]int, len(ids))
+	for i, id := range ids {
+		rank[id] = (i - offset + len(ids)) % len(ids)
+	}
 	sort.Slice(runtimes, func(i, j int) bool {
-		return runtimes[i].RequestedNum > runtimes[j].RequestedNum
-	})
-	pos := 0
-	pre := runtimes[0].RequestedNum
-	for i := 1; i < len(runtimes); i++ {
-		if pre != runtimes[i].RequestedNum {
-			shuffleRange(runtimes, pos, i)
-			pos = i
-			pre = runtimes[i].RequestedNum
+		if runtimes[i].RequestedNum != runtimes[j].RequestedNum {
+			return runtimes[i].RequestedNum > runtimes[j].RequestedNum
 		}
-	}
-	shuffleRange(runtimes, pos, len(runtimes))
+		return rank[runtimes[i].SchedulerID] < rank[runtimes[j].SchedulerID]
+	})
 }
 
 func compareWithSequence(s1, s2 string) bool {
@@ -97,6 +88,15 @@ func SortSchedulers(schedulers []*definition.Scheduler) {
 	})
 }
 
+func SortDeadLetters(letters []*definition.DeadLetter) {
+	if len(letters) <= 1 {
+		return
+	}
+	sort.Slice(letters, func(i, j int) bool {
+		return compareWithSequence(letters[i].ID, letters[j].ID)
+	})
+}
+
 // AssignWorkers assigns workers between nodes and limit maximum per node.
 //	limit = 0 indicates no limit at all
 func AssignWorkers(nodeCount, workerCount, limit int) []int {
@@ -115,6 +115,150 @@ func AssignWorkers(nodeCount, workerCount, limit int) []int {
 	return workers
 }
 
+// WeightedWorkers assigns workers between nodes proportionally to their weights, limits[i] is the maximum
+//	of node i. Remainders go to nodes with larger fractions, then nodes in front, so the result is decided
+//	by the order. Nodes with non-positive weights get nothing.
+func WeightedWorkers(weights, limits []int, workerCount int) []int {
+	return StickyWorkers(nil, weights, limits, workerCount)
+}
+
+// StickyWorkers assigns workers like WeightedWorkers but keeps current placements as much as possible,
+//	current[i] is the number of workers on node i now and nil means none.
+//	Remainders go to nodes which would lose workers otherwise first, so only the minimum is moved.
+func StickyWorkers(current, weights, limits []int, workerCount int) []int {
+	workers := make([]int, len(weights))
+	active := make([]int, 0, len(weights))
+	for i, weight := range weights {
+		if weight > 0 && limits[i] > 0 {
+			active = append(active, i)
+		}
+	}
+	for workerCount > 0 && len(active) > 0 {
+		total := 0
+		for _, i := range active {
+			total += weights[i]
+		}
+		shares := make([]int, len(active))
+		fractions := make([]int, len(active))
+		left := workerCount
+		for k, i := range active {
+			shares[k] = workerCount * weights[i] / total
+			fractions[k] = workerCount * weights[i] % total
+			left -= shares[k]
+		}
+		order := make([]int, len(active))
+		for k := range order {
+			order[k] = k
+		}
+		kept := func(k int) bool {
+			return current != nil && current[active[k]] > shares[k]
+		}
+		sort.SliceStable(order, func(x, y int) bool {
+			if kept(order[x]) != kept(order[y]) {
+				return kept(order[x])
+			}
+			return fractions[order[x]] > fractions[order[y]]
+		})
+		for k := 0; k < left; k++ {
+			shares[order[k]]++
+		}
+		// fix nodes exceeding limits and share the rest again
+		remained := make([]int, 0, len(active))
+		capped := false
+		for k, i := range active {
+			if shares[k] >= limits[i] {
+				workers[i] = limits[i]
+				workerCount -= limits[i]
+				capped = true
+				continue
+			}
+			remained = append(remained, i)
+		}
+		if !capped {
+			for k, i := range active {
+				workers[i] = shares[k]
+			}
+			break
+		}
+		active = remained
+	}
+	return workers
+}
+
+// SpreadWorkers assigns workers between groups of nodes evenly first, eg. zones, then between nodes
+//	of each group like StickyWorkers. groups[i] is the group of node i.
+//	Groups in front are preferred when it's even, and groups having no capacity are skipped.
+func SpreadWorkers(groups []string, current, weights, limits []int, workerCount int) []int {
+	index := make(map[string]int)
+	members := make([][]int, 0)
+	for i, group := range groups {
+		g, ok := index[group]
+		if !ok {
+			g = len(members)
+			index[group] = g
+			members = append(members, nil)
+		}
+		members[g] = append(members[g], i)
+	}
+	capacity := make([]int, len(members))
+	kept := make([]int, len(members))
+	for g, nodes := range members {
+		for _, i := range nodes {
+			if weights[i] <= 0 || limits[i] <= 0 {
+				continue
+			}
+			if capacity[g] < math.MaxInt32 {
+				capacity[g] += limits[i]
+			}
+			if current != nil {
+				kept[g] += current[i]
+			}
+		}
+	}
+	perGroup := make([]int, len(members))
+	for n := 0; n < workerCount; n++ {
+		chosen := -1
+		for g := range members {
+			if perGroup[g] >= capacity[g] {
+				continue
+			}
+			if chosen < 0 || perGroup[g] < perGroup[chosen] {
+				chosen = g
+				continue
+			}
+			// the one losing workers otherwise
+			if perGroup[g] == perGroup[chosen] && kept[g] > perGroup[g] && kept[chosen] <= perGroup[chosen] {
+				chosen = g
+			}
+		}
+		if chosen < 0 {
+			// no capacity
+			break
+		}
+		perGroup[chosen]++
+	}
+	workers := make([]int, len(groups))
+	for g, nodes := range members {
+		var cur []int
+		if current != nil {
+			cur = make([]int, len(nodes))
+		}
+		w := make([]int, len(nodes))
+		l := make([]int, len(nodes))
+		for k, i := range nodes {
+			if cur != nil {
+				cur[k] = current[i]
+			}
+			w[k] = weights[i]
+			l[k] = limits[i]
+		}
+		for k, num := range StickyWorkers(cur, w, l, perGroup[g]) {
+			workers[nodes[k]] = num
+		}
+	}
+	return workers
+}
+
 // CanSchedule returns whether current scheduler can join into the specified strategy (based on its iplist)
 //	ipList is the range that can be scheduled on including hostnames and ip addresses
 //	hostname indicates current node's hostname
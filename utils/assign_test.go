@@ -5,6 +5,9 @@
 package utils
 
 import (
+	"math"
+	"math/rand"
+	"strconv"
 	"testing"
 
 	"github.com/jasonjoo2010/goschedule/definition"
@@ -36,6 +39,184 @@ func TestAssignWorkers(t *testing.T) {
 	assert.Equal(t, []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}, AssignWorkers(10, 10, 3))
 }
 
+// evenly returns equal weights and limits for n nodes, limit = 0 indicates no limit
+func evenly(n, limit int) ([]int, []int) {
+	if limit == 0 {
+		limit = math.MaxInt32
+	}
+	weights := make([]int, n)
+	limits := make([]int, n)
+	for i := 0; i < n; i++ {
+		weights[i] = 1
+		limits[i] = limit
+	}
+	return weights, limits
+}
+
+func TestWeightedWorkers(t *testing.T) {
+	// same as AssignWorkers with equal weights
+	for nodes := 1; nodes <= 10; nodes++ {
+		for _, limit := range []int{0, 1, 3} {
+			weights, limits := evenly(nodes, limit)
+			assert.Equal(t, AssignWorkers(nodes, 10, limit), WeightedWorkers(weights, limits, 10))
+		}
+	}
+	_, limits := evenly(3, 0)
+	assert.Equal(t, []int{6, 3, 1}, WeightedWorkers([]int{6, 3, 1}, limits, 10))
+	assert.Equal(t, []int{2, 1, 1}, WeightedWorkers([]int{2, 1, 1}, limits, 4))
+	// remainder goes to larger fractions first
+	assert.Equal(t, []int{3, 1, 1}, WeightedWorkers([]int{2, 1, 1}, limits, 5))
+	assert.Equal(t, []int{1, 2, 2}, WeightedWorkers([]int{1, 2, 2}, limits, 5))
+	assert.Equal(t, []int{0, 0, 1}, WeightedWorkers([]int{1, 1, 3}, limits, 1))
+	// non-positive weights
+	assert.Equal(t, []int{0, 5, 0}, WeightedWorkers([]int{0, 1, -1}, limits, 5))
+	// capped nodes share the rest
+	assert.Equal(t, []int{2, 4, 4}, WeightedWorkers([]int{8, 1, 1}, []int{2, 10, 10}, 10))
+	assert.Equal(t, []int{2, 0, 1}, WeightedWorkers([]int{8, 1, 1}, []int{2, 0, 1}, 10))
+	assert.Equal(t, []int{0, 0}, WeightedWorkers([]int{1, 1}, []int{0, 0}, 10))
+	assert.Equal(t, []int{}, WeightedWorkers([]int{}, []int{}, 10))
+}
+
+// minimalMoves returns the least workers to be moved from current to reach an even assignment
+func minimalMoves(current []int, workerCount int) int {
+	avg := workerCount / len(current)
+	other := workerCount % len(current)
+	kept := 0
+	for _, cur := range current {
+		if cur > avg {
+			kept += avg
+			if other > 0 {
+				kept++
+				other--
+			}
+		} else {
+			kept += cur
+		}
+	}
+	return workerCount - kept
+}
+
+func moves(current, workers []int) int {
+	n := 0
+	for i := range workers {
+		if workers[i] > current[i] {
+			n += workers[i] - current[i]
+		}
+	}
+	return n
+}
+
+func TestStickyWorkers(t *testing.T) {
+	_, limits := evenly(4, 0)
+	ones, _ := evenly(4, 0)
+	assert.Equal(t, []int{1, 1, 1, 1}, StickyWorkers(nil, ones, limits, 4))
+	assert.Equal(t, []int{1, 1, 1, 0}, StickyWorkers([]int{1, 1, 1, 0}, ones, limits, 3))
+	assert.Equal(t, []int{0, 1, 1, 1}, StickyWorkers([]int{0, 1, 1, 1}, ones, limits, 3))
+	assert.Equal(t, []int{1, 2, 1, 2}, StickyWorkers([]int{0, 3, 0, 3}, ones, limits, 6))
+	// balance goes first
+	assert.Equal(t, []int{2, 2, 1, 1}, StickyWorkers([]int{6, 0, 0, 0}, ones, limits, 6))
+	// weighted
+	assert.Equal(t, []int{1, 0, 2}, StickyWorkers([]int{0, 0, 2}, []int{1, 1, 2}, limits[:3], 3))
+	assert.Equal(t, []int{1, 1, 1}, StickyWorkers([]int{0, 0, 0}, []int{1, 1, 2}, limits[:3], 3))
+	// limited
+	assert.Equal(t, []int{1, 2, 2}, StickyWorkers([]int{0, 3, 3}, []int{1, 1, 1}, []int{5, 5, 2}, 5))
+	assert.Equal(t, []int{2, 2, 1}, StickyWorkers(nil, []int{1, 1, 1}, []int{5, 5, 2}, 5))
+}
+
+func TestStickyWorkersMovement(t *testing.T) {
+	r := rand.New(rand.NewSource(2020))
+	current := []int{4}
+	workerCount := 4
+	for step := 0; step < 500; step++ {
+		switch n := r.Intn(10); {
+		case n < 4 && len(current) < 20:
+			// join
+			current = append(current, 0)
+		case n < 8 && len(current) > 1:
+			// leave, workers on it are lost
+			i := r.Intn(len(current))
+			current = append(current[:i], current[i+1:]...)
+		default:
+			workerCount = r.Intn(50)
+		}
+		weights, limits := evenly(len(current), 0)
+		workers := StickyWorkers(current, weights, limits, workerCount)
+		sum, min, max := 0, workerCount, 0
+		for _, num := range workers {
+			sum += num
+			if num < min {
+				min = num
+			}
+			if num > max {
+				max = num
+			}
+		}
+		assert.Equal(t, workerCount, sum)
+		assert.True(t, max-min <= 1, "unbalanced: %v", workers)
+		assert.Equal(t, minimalMoves(current, workerCount), moves(current, workers), "%v to %v", current, workers)
+		current = workers
+	}
+
+	// weighted, never moving more than assigning without current placements
+	for step := 0; step < 500; step++ {
+		n := 1 + r.Intn(10)
+		current := make([]int, n)
+		weights := make([]int, n)
+		_, limits := evenly(n, 0)
+		for i := range current {
+			current[i] = r.Intn(10)
+			weights[i] = 1 + r.Intn(5)
+		}
+		workerCount := r.Intn(50)
+		workers := StickyWorkers(current, weights, limits, workerCount)
+		total := 0
+		for _, weight := range weights {
+			total += weight
+		}
+		for i, num := range workers {
+			// within the floor and ceil of the exact share
+			assert.True(t, num*total <= workerCount*weights[i]+total-1)
+			assert.True(t, num*total >= workerCount*weights[i]-total+1)
+		}
+		assert.True(t, moves(current, workers) <= moves(current, WeightedWorkers(weights, limits, workerCount)))
+	}
+}
+
+func TestSpreadWorkersSticky(t *testing.T) {
+	groups := []string{"a", "a", "b", "b"}
+	ones, limits := evenly(4, 0)
+	assert.Equal(t, []int{0, 1, 0, 1}, SpreadWorkers(groups, []int{0, 2, 0, 2}, ones, limits, 2))
+	assert.Equal(t, []int{1, 0, 1, 0}, SpreadWorkers(groups, nil, ones, limits, 2))
+	// groups losing workers otherwise are preferred
+	assert.Equal(t, []int{0, 0, 1, 0}, SpreadWorkers(groups, []int{0, 0, 1, 1}, ones, limits, 1))
+	assert.Equal(t, []int{1, 1, 2, 1}, SpreadWorkers(groups, []int{1, 1, 2, 1}, ones, limits, 5))
+}
+
+func TestSpreadWorkers(t *testing.T) {
+	spread := func(groups []string, workerCount, limit int) []int {
+		weights, limits := evenly(len(groups), limit)
+		return SpreadWorkers(groups, nil, weights, limits, workerCount)
+	}
+	// same as AssignWorkers in one group
+	for nodes := 1; nodes <= 10; nodes++ {
+		groups := make([]string, nodes)
+		assert.Equal(t, AssignWorkers(nodes, 10, 0), spread(groups, 10, 0))
+		assert.Equal(t, AssignWorkers(nodes, 10, 3), spread(groups, 10, 3))
+	}
+	assert.Equal(t, []int{1, 0, 1}, spread([]string{"a", "a", "b"}, 2, 0))
+	assert.Equal(t, []int{1, 1, 2}, spread([]string{"a", "a", "b"}, 4, 0))
+	assert.Equal(t, []int{1, 1, 2, 1}, spread([]string{"a", "a", "b", "c"}, 5, 2))
+	// soft rule, overflow to other groups
+	assert.Equal(t, []int{2, 1, 2}, spread([]string{"a", "a", "b"}, 5, 2))
+	assert.Equal(t, []int{2, 2, 1}, spread([]string{"a", "b", "b"}, 5, 2))
+	assert.Equal(t, []int{1, 1}, spread([]string{"a", "b"}, 5, 1))
+	assert.Equal(t, []int{}, spread([]string{}, 5, 1))
+	// weighted in groups
+	_, limits := evenly(3, 0)
+	assert.Equal(t, []int{3, 1, 4}, SpreadWorkers([]string{"a", "a", "b"}, nil, []int{3, 1, 1}, limits, 8))
+	assert.Equal(t, []int{0, 2, 2}, SpreadWorkers([]string{"a", "a", "b"}, nil, []int{0, 1, 1}, limits, 4))
+}
+
 func TestCanSchedule(t *testing.T) {
 	assert.True(t, CanSchedule([]string{"127.0.0.1"}, "", "192.168.123.1"))
 	assert.True(t, CanSchedule([]string{"localhost"}, "", "192.168.123.1"))
@@ -51,39 +232,44 @@ func TestCanSchedule(t *testing.T) {
 	assert.True(t, CanSchedule([]string{"demo1", "demo2", "192.168.0.1"}, "demo", "192.168.0.1"))
 }
 
-func TestSortRuntimesWithShuffle(t *testing.T) {
-	runtimes := make([]*definition.StrategyRuntime, 5)
-	runtimes[0] = &definition.StrategyRuntime{
-		SchedulerID:  "A",
-		StrategyID:   "S",
-		RequestedNum: 0,
+func TestSortRuntimes(t *testing.T) {
+	runtimes := []*definition.StrategyRuntime{
+		{SchedulerID: "C", RequestedNum: 0},
+		{SchedulerID: "D", RequestedNum: 2},
+		{SchedulerID: "A", RequestedNum: 0},
+		{SchedulerID: "B", RequestedNum: 2},
+		{SchedulerID: "E", RequestedNum: 1},
 	}
-	runtimes[1] = &definition.StrategyRuntime{
-		SchedulerID:  "B",
-		StrategyID:   "S",
-		RequestedNum: 2,
-	}
-	runtimes[2] = &definition.StrategyRuntime{
-		SchedulerID:  "C",
-		StrategyID:   "S",
-		RequestedNum: 0,
-	}
-	runtimes[3] = &definition.StrategyRuntime{
-		SchedulerID:  "D",
-		StrategyID:   "S",
-		RequestedNum: 2,
-	}
-	runtimes[4] = &definition.StrategyRuntime{
-		SchedulerID:  "E",
-		StrategyID:   "S",
-		RequestedNum: 0,
+
+	idsOf := func(runtimes []*definition.StrategyRuntime) []string {
+		ids := make([]string, len(runtimes))
+		for i, runtime := range runtimes {
+			ids[i] = runtime.SchedulerID
+		}
+		return ids
 	}
+	SortRuntimes(runtimes, "S")
+	sorted := idsOf(runtimes)
+	assert.Equal(t, "E", sorted[2])
+	assert.ElementsMatch(t, []string{"B", "D"}, sorted[:2])
+	assert.ElementsMatch(t, []string{"A", "C"}, sorted[3:])
 
-	SortRuntimesWithShuffle(runtimes)
+	// same order for the same seed
+	for i := 0; i < 10; i++ {
+		rand.Shuffle(len(runtimes), func(i, j int) {
+			runtimes[i], runtimes[j] = runtimes[j], runtimes[i]
+		})
+		SortRuntimes(runtimes, "S")
+		assert.Equal(t, sorted, idsOf(runtimes))
+	}
 
-	for i := 1; i < len(runtimes); i++ {
-		assert.GreaterOrEqual(t, runtimes[i-1].RequestedNum, runtimes[i].RequestedNum)
+	// rotated by seed
+	first := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		SortRuntimes(runtimes, "S"+strconv.Itoa(i))
+		first[runtimes[0].SchedulerID] = true
 	}
+	assert.Equal(t, 2, len(first))
 }
 
 func TestSortStrategyRuntimes(t *testing.T) {
@@ -27,3 +27,25 @@ func LoopContext(ctx context.Context, interval time.Duration, loopFn, cleanupFn
 		}
 	}
 }
+
+// LoopContextWithTrigger acts like LoopContext but the waiting between loops can be interrupted
+//	by trigger to start next loop immediately.
+func LoopContextWithTrigger(ctx context.Context, interval time.Duration, trigger <-chan struct{}, loopFn, cleanupFn func()) {
+	defer cleanupFn()
+
+	for !ContextDone(ctx) {
+		loopFn()
+		if !DelayContextWithTrigger(ctx, interval, trigger) {
+			break
+		}
+	}
+}
+
+// Trigger notifies the trigger channel without blocking.
+//	Notifications are merged if the channel is full.
+func Trigger(trigger chan<- struct{}) {
+	select {
+	case trigger <- struct{}{}:
+	default:
+	}
+}
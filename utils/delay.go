@@ -13,10 +13,6 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-var (
-	cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-)
-
 // DelayContext try to wait and return true if normally completed.
 func DelayContext(ctx context.Context, duration time.Duration) bool {
 	if duration < 1 {
@@ -39,11 +35,31 @@ func DelayContext(ctx context.Context, duration time.Duration) bool {
 	}
 }
 
-func parseCron(cronStr string) cron.Schedule {
+// DelayContextWithTrigger acts like DelayContext but it can be woken up earlier by trigger.
+//	It returns false only when the context is done.
+func DelayContextWithTrigger(ctx context.Context, duration time.Duration, trigger <-chan struct{}) bool {
+	if duration < 1 {
+		return DelayContext(ctx, duration)
+	}
+
+	timeout := time.NewTimer(duration)
+	defer timeout.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-trigger:
+		return true
+	case <-timeout.C:
+		return true
+	}
+}
+
+func parseCron(cronStr, timeZone string) cron.Schedule {
 	if cronStr == "" {
 		return nil
 	}
-	sched, err := cronParser.Parse(cronStr)
+	sched, err := definition.ParseCronIn(cronStr, timeZone)
 	if err != nil {
 		logrus.Warn("Cron expression parsing failed: ", err.Error())
 		return nil
@@ -52,8 +68,11 @@ func parseCron(cronStr string) cron.Schedule {
 }
 
 // ParseStrategyCron parse cron expressions of begin and end from given strategy
+//	Expressions are evaluated in TimeZone of strategy, or local time zone if it's empty.
+//	A CRON_TZ= prefix of expression takes precedence over TimeZone.
+//	Use Validate() of strategy to reject illegal expressions first.
 func ParseStrategyCron(strategy *definition.Strategy) (cron.Schedule, cron.Schedule) {
-	return parseCron(strategy.CronBegin), parseCron(strategy.CronEnd)
+	return parseCron(strategy.CronBegin, strategy.TimeZone), parseCron(strategy.CronEnd, strategy.TimeZone)
 }
 
 // CronDelay add suitable delay according to cron settings
@@ -65,14 +84,16 @@ func ParseStrategyCron(strategy *definition.Strategy) (cron.Schedule, cron.Sched
 //	   |==|
 //	         |-->|=====|        |=====|        |=====|
 func CronDelay(begin cron.Schedule, end cron.Schedule) time.Duration {
+	return cronDelayAt(begin, end, time.Now())
+}
+
+func cronDelayAt(begin cron.Schedule, end cron.Schedule, now time.Time) time.Duration {
 	if begin != nil && end == nil {
-		now := time.Now()
 		next := begin.Next(now)
 		diff := next.Sub(now)
 		return diff
 	}
 	if begin != nil && end != nil {
-		now := time.Now()
 		next1 := begin.Next(now)
 		next2 := end.Next(now)
 		diff1 := next1.Sub(now)
@@ -85,3 +106,41 @@ func CronDelay(begin cron.Schedule, end cron.Schedule) time.Duration {
 	}
 	return 0
 }
+
+// CronMissed returns the count of fire times in (last, now] and the latest one of them.
+//	Counting stops at limit but the latest is still a real fire time so that it's the same
+//	between nodes, eg. as a key to claim the fire time.
+//	last is returned as the latest if nothing missed.
+func CronMissed(sched cron.Schedule, last, now time.Time, limit int) (int, time.Time) {
+	cnt := 0
+	latest := last
+	for next := sched.Next(last); !next.After(now); next = sched.Next(next) {
+		if next.IsZero() {
+			break
+		}
+		if cnt >= limit {
+			return cnt, latestFire(sched, latest, now)
+		}
+		cnt++
+		latest = next
+	}
+	return cnt, latest
+}
+
+// latestFire returns the latest fire time in (after, now], or after if there is none.
+//	It searches backward from now in doubling steps so it's cheap even if lots of fire times passed.
+func latestFire(sched cron.Schedule, after, now time.Time) time.Time {
+	for d := time.Second; ; d *= 2 {
+		from := now.Add(-d)
+		if !from.After(after) {
+			from = after
+		}
+		latest := after
+		for next := sched.Next(from); !next.IsZero() && !next.After(now); next = sched.Next(next) {
+			latest = next
+		}
+		if latest.After(after) || from.Equal(after) {
+			return latest
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jasonjoo2010/goschedule/definition"
 	"github.com/robfig/cron/v3"
 	"github.com/stretchr/testify/assert"
 )
@@ -52,6 +53,26 @@ func TestDelayContext(t *testing.T) {
 	}
 }
 
+func TestDelayContextWithTrigger(t *testing.T) {
+	trigger := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	Trigger(trigger)
+	Trigger(trigger)
+	t0 := time.Now()
+	assert.True(t, DelayContextWithTrigger(ctx, 600*time.Millisecond, trigger))
+	assert.True(t, time.Since(t0) < 10*time.Millisecond)
+
+	t0 = time.Now()
+	assert.True(t, DelayContextWithTrigger(ctx, 60*time.Millisecond, trigger))
+	diff := time.Since(t0)
+	assert.True(t, diff > 50*time.Millisecond)
+	assert.True(t, diff < 100*time.Millisecond)
+
+	cancel()
+	assert.False(t, DelayContextWithTrigger(ctx, 600*time.Millisecond, trigger))
+}
+
 func TestCronDelay(t *testing.T) {
 	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
 	begin, _ := parser.Parse("0/3 * * * * ?")
@@ -84,3 +105,125 @@ func TestCronDelay(t *testing.T) {
 	delay = CronDelay(begin, end)
 	assert.True(t, delay > 1900*time.Millisecond)
 }
+
+func TestCronMissed(t *testing.T) {
+	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+	sched, _ := parser.Parse("0 */10 * * * ?")
+	last := time.Date(2020, 1, 1, 10, 0, 0, 0, time.Local)
+
+	cnt, latest := CronMissed(sched, last, last.Add(5*time.Minute), 100)
+	assert.Equal(t, 0, cnt)
+	assert.Equal(t, last, latest)
+
+	cnt, latest = CronMissed(sched, last, last.Add(35*time.Minute), 100)
+	assert.Equal(t, 3, cnt)
+	assert.Equal(t, last.Add(30*time.Minute), latest)
+
+	cnt, latest = CronMissed(sched, last, last.Add(30*time.Minute), 100)
+	assert.Equal(t, 3, cnt)
+	assert.Equal(t, last.Add(30*time.Minute), latest)
+
+	// the latest is a real fire time even if counting stopped
+	now := last.Add(24 * time.Hour)
+	cnt, latest = CronMissed(sched, last, now.Add(5*time.Minute), 10)
+	assert.Equal(t, 10, cnt)
+	assert.Equal(t, now, latest)
+	cnt, latest = CronMissed(sched, last, now, 10)
+	assert.Equal(t, 10, cnt)
+	assert.Equal(t, now, latest)
+
+	// every second for a long time
+	every, _ := parser.Parse("* * * * * ?")
+	cnt, latest = CronMissed(every, last, now.Add(500*time.Millisecond), 10)
+	assert.Equal(t, 10, cnt)
+	assert.Equal(t, now, latest)
+}
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skip("time zone data is not available: ", err.Error())
+	}
+	return loc
+}
+
+func TestParseStrategyCronTimeZone(t *testing.T) {
+	shanghai := loadLocation(t, "Asia/Shanghai")
+	newYork := loadLocation(t, "America/New_York")
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	begin, end := ParseStrategyCron(&definition.Strategy{
+		CronBegin: "0 0 9 * * ?",
+		CronEnd:   "0 0 18 * * ?",
+		TimeZone:  "Asia/Shanghai",
+	})
+	assert.Equal(t, time.Date(2021, 6, 1, 9, 0, 0, 0, shanghai).Unix(), begin.Next(now).Unix())
+	assert.Equal(t, time.Date(2021, 6, 1, 18, 0, 0, 0, shanghai).Unix(), end.Next(now).Unix())
+
+	// prefix takes precedence
+	begin, _ = ParseStrategyCron(&definition.Strategy{
+		CronBegin: "CRON_TZ=America/New_York 0 0 9 * * ?",
+		TimeZone:  "Asia/Shanghai",
+	})
+	assert.Equal(t, time.Date(2021, 6, 1, 9, 0, 0, 0, newYork).Unix(), begin.Next(now).Unix())
+
+	// same wall clock time on different nodes
+	begin, _ = ParseStrategyCron(&definition.Strategy{
+		CronBegin: "0 0 9 * * ?",
+		TimeZone:  "America/New_York",
+	})
+	assert.Equal(t, time.Date(2021, 6, 1, 9, 0, 0, 0, newYork).Unix(), begin.Next(now.In(shanghai)).Unix())
+
+	// wrong time zone
+	begin, _ = ParseStrategyCron(&definition.Strategy{
+		CronBegin: "0 0 9 * * ?",
+		TimeZone:  "Nowhere/Unknown",
+	})
+	assert.Nil(t, begin)
+}
+
+func TestCronTimeZoneDST(t *testing.T) {
+	newYork := loadLocation(t, "America/New_York")
+	strategy := &definition.Strategy{
+		CronBegin: "0 0 9 * * ?",
+		TimeZone:  "America/New_York",
+	}
+	daily, _ := ParseStrategyCron(strategy)
+
+	// spring forward on 2021-03-14, the day lasts 23 hours
+	now := time.Date(2021, 3, 13, 9, 0, 0, 0, newYork)
+	next := daily.Next(now)
+	assert.Equal(t, time.Date(2021, 3, 14, 9, 0, 0, 0, newYork).Unix(), next.Unix())
+	assert.Equal(t, 23*time.Hour, next.Sub(now))
+	assert.Equal(t, 23*time.Hour, cronDelayAt(daily, nil, now))
+
+	// fall back on 2021-11-07, the day lasts 25 hours
+	now = time.Date(2021, 11, 6, 9, 0, 0, 0, newYork)
+	next = daily.Next(now)
+	assert.Equal(t, time.Date(2021, 11, 7, 9, 0, 0, 0, newYork).Unix(), next.Unix())
+	assert.Equal(t, 25*time.Hour, cronDelayAt(daily, nil, now))
+
+	// wall clock time skipped by spring forward doesn't fire
+	strategy.CronBegin = "0 30 2 * * ?"
+	skipped, _ := ParseStrategyCron(strategy)
+	now = time.Date(2021, 3, 13, 12, 0, 0, 0, newYork)
+	assert.Equal(t, time.Date(2021, 3, 15, 2, 30, 0, 0, newYork).Unix(), skipped.Next(now).Unix())
+
+	// wall clock time repeated by fall back fires in both offsets
+	strategy.CronBegin = "0 30 1 * * ?"
+	repeated, _ := ParseStrategyCron(strategy)
+	now = time.Date(2021, 11, 6, 12, 0, 0, 0, newYork)
+	first := repeated.Next(now)
+	second := repeated.Next(first)
+	assert.Equal(t, time.Hour, second.Sub(first))
+	cnt, _ := CronMissed(repeated, now, now.Add(24*time.Hour), 100)
+	assert.Equal(t, 2, cnt)
+
+	// range across spring forward, 08:00-10:00 every day
+	strategy.CronBegin = "0 0 8 * * ?"
+	strategy.CronEnd = "0 0 10 * * ?"
+	begin, end := ParseStrategyCron(strategy)
+	now = time.Date(2021, 3, 13, 23, 0, 0, 0, newYork)
+	assert.Equal(t, 8*time.Hour, cronDelayAt(begin, end, now))
+	assert.Equal(t, time.Duration(0), cronDelayAt(begin, end, time.Date(2021, 3, 14, 9, 0, 0, 0, newYork)))
+}